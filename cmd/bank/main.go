@@ -3,66 +3,84 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"mini-bank/internal/api"
+	"mini-bank/internal/blob"
+	"mini-bank/internal/config"
+	"mini-bank/internal/events"
+	"mini-bank/internal/grpcapi"
+	pb "mini-bank/internal/grpcapi/minibankv1"
+	"mini-bank/internal/health"
+	"mini-bank/internal/maturity"
+	"mini-bank/internal/metrics"
+	"mini-bank/internal/monitor"
+	"mini-bank/internal/outbox"
+	"mini-bank/internal/ratelimit"
+	"mini-bank/internal/reconcile"
+	"mini-bank/internal/sanctions"
 	"mini-bank/internal/service"
 	pg "mini-bank/internal/storage/postgres"
+	"mini-bank/internal/stream"
+	"mini-bank/internal/telemetry"
+	"mini-bank/internal/webhook"
 
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 )
 
-// config holds the application configuration.
-type config struct {
-	Port       string
-	DB_DSN     string
-	JWT_KEY    string
-	REDIS_ADDR string
-}
-
 func main() {
-	// Setup structured logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	// Setup structured logger, tagging lines with the request and trace
+	// they belong to
+	logger := slog.New(telemetry.NewLogHandler(slog.NewJSONHandler(os.Stdout, nil)))
 
-	if err := godotenv.Load(); err != nil {
+	// A .env file is optional: settings may come from the environment, a
+	// config file or flags instead.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		logger.Error("failed to load env", "err", err)
 		os.Exit(1)
 	}
 
 	// Load configuration
-	cfg := config{
-		Port:       ":8080", // Default port
-		DB_DSN:     os.Getenv("DATABASE_URL"),
-		JWT_KEY:    os.Getenv("JWT_SECRET"),
-		REDIS_ADDR: os.Getenv("REDIS_ADDR"),
+	cfg, err := config.Load("bank", os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
 	}
-	if portEnv := os.Getenv("PORT"); portEnv != "" {
-		cfg.Port = ":" + portEnv
+	if err != nil {
+		logger.Error("failed to load config", "err", err)
+		os.Exit(2)
 	}
+	logger.Info("loaded config", "config", cfg)
 
-	if cfg.DB_DSN == "" {
-		logger.Error("DATABASE_URL environment variable is not set")
-		os.Exit(1)
-	}
-	if cfg.JWT_KEY == "" {
-		logger.Error("JWT_SECRET environment variable is not set")
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Telemetry.TracesExporter)
+	if err != nil {
+		logger.Error("failed to set up tracing", "err", err)
 		os.Exit(1)
 	}
 
-	db, err := pg.NewDB(cfg.DB_DSN)
+	db, err := pg.NewDB(cfg.Storage)
 	if err != nil {
 		logger.Error("failed to connect to db", "err", err)
 		os.Exit(1)
 	}
 
 	rdb := redis.NewClient(&redis.Options{
-		Addr: cfg.REDIS_ADDR,
+		Addr:     cfg.Storage.RedisAddr,
+		Password: cfg.Storage.RedisPassword.Reveal(),
 	})
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
@@ -70,22 +88,146 @@ func main() {
 		os.Exit(1)
 	}
 
+	// metrics are kept in memory and served at /metrics for scraping
+	m := metrics.New()
+	m.RegisterDB(db.DB, "postgres")
+	m.InstrumentRedis(rdb)
+
+	// readiness depends on the database and the redis session store
+	checker := health.New(cfg.Server.HealthCheckTimeout, logger)
+	checker.Add("postgres", db.PingContext)
+	checker.Add("redis", func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+
+	var limiter ratelimit.Limiter
+	switch cfg.Limits.RateLimitBackend {
+	case "memory":
+		limiter = ratelimit.NewMemoryLimiter()
+	case "redis":
+		limiter = ratelimit.NewRedisLimiter(rdb)
+	}
+
+	rules, err := monitor.New(cfg.Monitoring.Rules)
+	if err != nil {
+		logger.Error("failed to set up monitoring rules", "err", err)
+		os.Exit(1)
+	}
+
+	var watchlist *sanctions.Watchlist
+	if sc := cfg.Screening; sc.ListFile != "" {
+		if watchlist, err = sanctions.Open(sc.ListFile, sc.ReviewThreshold, sc.BlockThreshold); err != nil {
+			logger.Error("failed to load watchlist", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	documents, err := blob.NewFileStore(cfg.KYC.DocumentDir)
+	if err != nil {
+		logger.Error("failed to set up document store", "err", err)
+		os.Exit(1)
+	}
+
+	webhookTargets := webhook.Targets{AllowPrivate: cfg.Webhooks.AllowPrivateTargets}
+	if webhookTargets.AllowPrivate {
+		logger.Warn("webhooks may be delivered to private addresses")
+	}
+
 	repo := pg.NewRepo(db)
-	service := service.New(repo)
-	a := api.NewAPI(service, logger, rdb, cfg.JWT_KEY)
+	service := service.WithTracing(service.WithMetrics(service.New(repo, service.Options{
+		Rules:            rules,
+		Watchlist:        watchlist,
+		Documents:        documents,
+		MaxDocumentSize:  int64(cfg.KYC.MaxDocumentSize),
+		CoolingOff:       cfg.Beneficiaries.CoolingOff,
+		CoolingOffAmount: int64(cfg.Beneficiaries.CoolingOffAmount),
+		WebhookTargets:   webhookTargets,
+	}), m))
+	hub := stream.NewHub(rdb, logger)
+	a := api.NewAPI(service, logger, rdb, hub, cfg.Auth)
 	handler := a.Router()
-	handler = a.TimeoutMiddleware(handler, 15*time.Second)
+	handler = a.TimeoutMiddleware(handler, cfg.Server.RequestTimeout)
+	policy := api.NewRateLimitPolicy(cfg.Limits)
+	handler = a.RateLimitMiddleware(handler, limiter, policy)
+	handler = a.MetricsMiddleware(handler, m)
 	handler = a.LoggingMiddleware(handler)
+	handler = a.RequestIDMiddleware(handler)
+	handler = otelhttp.NewHandler(handler, "http.server")
+
+	// Serve metrics and probes next to the API, outside its middleware so
+	// scrapes and probes are neither rate limited nor counted.
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	mux.Handle("GET /healthz", checker.LivenessHandler())
+	mux.Handle("GET /readyz", checker.ReadinessHandler())
+	mux.Handle("/", handler)
 
 	// http server
 	srv := &http.Server{
-		Addr:         cfg.Port,
-		Handler:      handler,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:         cfg.Server.Addr,
+		Handler:      mux,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	// End open account streams so shutdown does not wait on them.
+	srv.RegisterOnShutdown(hub.Close)
+
+	// grpc server for internal services, with the same auth, logging, rate
+	// limits and timeouts as the http API
+	g := grpcapi.NewServer(service, hub, a, logger)
+	grpcSrv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(g.LoggingUnaryInterceptor, grpcapi.TimeoutUnaryInterceptor(cfg.Server.RequestTimeout), g.AuthUnaryInterceptor, g.RateLimitUnaryInterceptor(limiter, policy)),
+		grpc.ChainStreamInterceptor(g.LoggingStreamInterceptor, g.AuthStreamInterceptor),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// Ping idle connections so dead stream clients are noticed.
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: 30 * time.Second, Timeout: 10 * time.Second}),
+	)
+	pb.RegisterBankServiceServer(grpcSrv, g)
+	reflection.Register(grpcSrv)
+
+	reconciler, err := reconcile.New(service, cfg.Reconcile.Interval, cfg.Reconcile.At, logger, m)
+	if err != nil {
+		logger.Error("failed to set up reconciliation", "err", err)
+		os.Exit(1)
 	}
 
+	// relay outbox events, deliver queued webhooks, fan out stream updates,
+	// reconcile the ledger, rescan recent activity and pay out matured term
+	// deposits in the background
+	publisher := events.Multi{events.NewLogPublisher(logger), webhook.NewDispatcher(repo, logger), hub}
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(7)
+	go func() {
+		defer workers.Done()
+		hub.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		outbox.NewRelay(repo, publisher, logger).Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		webhook.NewWorker(repo, webhookTargets.Client(10*time.Second), logger).Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		reconciler.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		monitor.NewJob(service, cfg.Monitoring.ScanInterval, cfg.Monitoring.ScanWindow, logger, m).Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		sanctions.NewJob(watchlist, service, cfg.Screening.CheckInterval, logger, m).Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		maturity.NewJob(service, cfg.TermDeposits.MaturityInterval, logger, m).Run(workerCtx)
+	}()
+
 	// run server in goroutine
 	go func() {
 		logger.Info("listening on", "addr", srv.Addr)
@@ -95,23 +237,67 @@ func main() {
 		}
 	}()
 
+	lis, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+	if err != nil {
+		logger.Error("grpc listen failed", "err", err)
+		os.Exit(1)
+	}
+	go func() {
+		logger.Info("grpc listening on", "addr", lis.Addr().String())
+		if err := grpcSrv.Serve(lis); err != nil {
+			logger.Error("grpc server failed", "err", err)
+			os.Exit(1)
+		}
+	}()
+
 	// graceful shutdown on SIGINT/SIGTERM
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	logger.Info("shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Fail readiness first and keep serving while load balancers notice,
+	// so no new requests arrive at a server that has stopped listening.
+	checker.Drain()
+	logger.Info("draining", "delay", cfg.Server.ShutdownDelay)
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("server shutdown failed", "err", err)
 		os.Exit(1)
 	}
 
+	// End account streams so GracefulStop does not wait on them, and cut
+	// off any calls still running at the deadline.
+	hub.Close()
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcSrv.Stop()
+	}
+
+	// Stop the background workers before closing the database they use.
+	stopWorkers()
+	workers.Wait()
+
 	// Close the database connection.
 	if err := db.Close(); err != nil {
 		logger.Error("database shutdown failed", "err", err)
 	}
 
+	// Flush spans still waiting to be exported.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("tracing shutdown failed", "err", err)
+	}
+
 	logger.Info("server stopped gracefully")
 }
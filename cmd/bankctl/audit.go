@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+func (c *cli) audit(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: audit needs list or verify", errUsage)
+	}
+	switch args[0] {
+	case "list":
+		return c.auditList(ctx, args[1:])
+	case "verify":
+		return c.auditVerify(ctx, args[1:])
+	default:
+		return fmt.Errorf("%w: unknown audit command %q", errUsage, args[0])
+	}
+}
+
+func (c *cli) auditList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var f core.AuditFilter
+	fs.StringVar(&f.ActorType, "actor-type", "", "only entries by this kind of actor")
+	fs.StringVar(&f.ActorID, "actor", "", "only entries by this actor ID")
+	fs.StringVar(&f.Action, "action", "", "only this action")
+	fs.StringVar(&f.TargetType, "target-type", "", "only entries about this kind of target")
+	fs.StringVar(&f.TargetID, "target", "", "only entries about this target ID")
+	from := fs.String("from", "", "earliest time (RFC 3339)")
+	to := fs.String("to", "", "latest time, exclusive (RFC 3339)")
+	fs.Int64Var(&f.AfterID, "after", 0, "only entries after this ID")
+	fs.IntVar(&f.Limit, "limit", 100, "how many entries to list, at most 1000")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: audit list: %v", errUsage, err)
+	}
+	if err := noArgs("audit list", fs.Args()); err != nil {
+		return err
+	}
+	var err error
+	if f.From, err = parseTime("from", *from); err != nil {
+		return err
+	}
+	if f.To, err = parseTime("to", *to); err != nil {
+		return err
+	}
+
+	entries, err := c.service.ListAuditEntries(ctx, f)
+	if err != nil {
+		return err
+	}
+	w := c.table()
+	fmt.Fprintln(w, "ID\tTIME\tACTOR\tACTION\tTARGET\tREQUEST\tBEFORE\tAFTER")
+	for _, e := range entries {
+		actor := e.Actor.Type
+		if e.Actor.ID != "" {
+			actor += ":" + e.Actor.ID
+		}
+		target := e.TargetType
+		if e.TargetID != "" {
+			target += ":" + e.TargetID
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.OccurredAt.UTC().Format(timeFormat), actor, e.Action, target, e.RequestID, e.Before, e.After)
+	}
+	return w.Flush()
+}
+
+func parseTime(name, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid -%s %q", errUsage, name, v)
+	}
+	return t, nil
+}
+
+// auditVerify walks the hash chain. Passing the head printed by an
+// earlier run also catches entries deleted from the end of the log.
+func (c *cli) auditVerify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	head := fs.String("head", "", "hash of the last entry seen by an earlier run")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: audit verify: %v", errUsage, err)
+	}
+	if err := noArgs("audit verify", fs.Args()); err != nil {
+		return err
+	}
+
+	res, err := c.service.VerifyAudit(ctx, *head)
+	if err != nil {
+		return err
+	}
+
+	w := c.table()
+	fmt.Fprintf(w, "Checked at\t%s\n", res.CheckedAt.Format(timeFormat))
+	fmt.Fprintf(w, "Entries\t%d\n", res.Entries)
+	fmt.Fprintf(w, "Head\t%s\n", res.Head)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if res.OK() {
+		fmt.Fprintln(c.out, "\naudit log ok: every entry matches its hash and links to the one before it")
+		return nil
+	}
+
+	fmt.Fprintln(c.out, "\nBroken links:")
+	w = c.table()
+	fmt.Fprintln(w, "ENTRY\tPROBLEM")
+	for _, b := range res.Breaks {
+		entry := "-"
+		if b.EntryID != 0 {
+			entry = strconv.FormatInt(b.EntryID, 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", entry, b.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return fmt.Errorf("audit log has %d broken links", len(res.Breaks))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"mini-bank/internal/core"
+)
+
+func (c *cli) deposits(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: deposits needs rates, set-rate or mature", errUsage)
+	}
+	switch args[0] {
+	case "rates":
+		if err := noArgs("deposits rates", args[1:]); err != nil {
+			return err
+		}
+		rates, err := c.service.TermDepositRates(ctx)
+		if err != nil {
+			return err
+		}
+		w := c.table()
+		fmt.Fprintln(w, "TERM MONTHS\tRATE BPS\tBREAK RATE BPS")
+		for _, r := range rates {
+			fmt.Fprintf(w, "%d\t%d\t%d\n", r.TermMonths, r.RateBPS, r.BreakRateBPS)
+		}
+		return w.Flush()
+	case "set-rate":
+		fs := flag.NewFlagSet("deposits set-rate", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		rate := fs.Int("rate", -1, "yearly rate in basis points")
+		breakRate := fs.Int("break-rate", 0, "yearly rate in basis points on deposits broken early")
+		if err := fs.Parse(args[1:]); err != nil {
+			return fmt.Errorf("%w: deposits set-rate: %v", errUsage, err)
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%w: deposits set-rate takes one term in months", errUsage)
+		}
+		months, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("%w: invalid term %q", errUsage, fs.Arg(0))
+		}
+		if *rate < 0 {
+			return fmt.Errorf("%w: deposits set-rate needs -rate", errUsage)
+		}
+		err = c.service.SetTermDepositRate(ctx, core.TermDepositRate{TermMonths: months, RateBPS: *rate, BreakRateBPS: *breakRate})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "rates of %d-month term deposits updated\n", months)
+		return nil
+	case "mature":
+		if err := noArgs("deposits mature", args[1:]); err != nil {
+			return err
+		}
+		run, err := c.service.ProcessMaturities(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "%d due, %d paid out, %d rolled over, %d failed\n", run.Due, run.PaidOut, run.RolledOver, len(run.Failures))
+		for _, f := range run.Failures {
+			fmt.Fprintf(c.out, "term deposit %d: %s\n", f.TermDepositID, f.Err)
+		}
+		if len(run.Failures) > 0 {
+			return fmt.Errorf("%d term deposits could not mature", len(run.Failures))
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown deposits command %q", errUsage, args[0])
+	}
+}
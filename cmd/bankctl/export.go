@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type exportUser struct {
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type exportAccount struct {
+	ID        int       `json:"id"`
+	Number    string    `json:"number"`
+	UserID    int       `json:"user_id"`
+	Balance   int64     `json:"balance"`
+	Status    string    `json:"status"`
+	Product   string    `json:"product"`
+	Name      string    `json:"name"`
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportTransaction struct {
+	ID            int       `json:"id"`
+	AccountID     int       `json:"account_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	Reference     string    `json:"reference,omitempty"`
+	FromAccountID *int      `json:"from_account_id,omitempty"`
+	ToAccountID   *int      `json:"to_account_id,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	ReversesID    *int      `json:"reverses_id,omitempty"`
+	PotID         *int      `json:"pot_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type exportPot struct {
+	ID           int        `json:"id"`
+	AccountID    int        `json:"account_id"`
+	Name         string     `json:"name"`
+	Balance      int64      `json:"balance"`
+	TargetAmount *int64     `json:"target_amount,omitempty"`
+	TargetDate   *time.Time `json:"target_date,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	RoundUp      bool       `json:"round_up"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type export struct {
+	ExportedAt   time.Time           `json:"exported_at"`
+	Users        []exportUser        `json:"users"`
+	Accounts     []exportAccount     `json:"accounts"`
+	Pots         []exportPot         `json:"pots"`
+	Transactions []exportTransaction `json:"transactions"`
+}
+
+// export writes every user, account, pot and transaction as one JSON
+// document, or every transaction as CSV.
+func (c *cli) export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "json", "json or csv (transactions only)")
+	output := fs.String("o", "", "file to write instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: export: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: export takes no arguments", errUsage)
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("%w: unknown export format %q", errUsage, *format)
+	}
+
+	data, err := c.collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := c.out
+	var f *os.File
+	if *output != "" {
+		if f, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "csv" {
+		err = writeTransactionsCSV(w, data.Transactions)
+	} else {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(data)
+	}
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		return f.Close()
+	}
+	return nil
+}
+
+func (c *cli) collect(ctx context.Context) (*export, error) {
+	data := &export{ExportedAt: time.Now().UTC()}
+
+	users, err := c.service.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		data.Users = append(data.Users, exportUser{ID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName})
+	}
+
+	accs, err := c.service.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accs {
+		data.Accounts = append(data.Accounts, exportAccount{
+			ID:        a.ID,
+			Number:    a.Number,
+			UserID:    a.UserID,
+			Balance:   a.Balance,
+			Status:    a.Status,
+			Product:   a.Product,
+			Name:      a.Name,
+			Primary:   a.Primary,
+			CreatedAt: a.CreatedAt.UTC(),
+		})
+		pots, err := c.service.ListPots(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pots {
+			data.Pots = append(data.Pots, exportPot{
+				ID:           p.ID,
+				AccountID:    p.AccountID,
+				Name:         p.Name,
+				Balance:      p.Balance,
+				TargetAmount: p.TargetAmount,
+				TargetDate:   p.TargetDate,
+				LockedUntil:  p.LockedUntil,
+				RoundUp:      p.RoundUp,
+				CreatedAt:    p.CreatedAt.UTC(),
+			})
+		}
+		txns, err := c.service.ListTransactions(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range txns {
+			data.Transactions = append(data.Transactions, toExportTransaction(t))
+		}
+	}
+	return data, nil
+}
+
+func toExportTransaction(t *core.Transaction) exportTransaction {
+	return exportTransaction{
+		ID:            t.ID,
+		AccountID:     t.AccountID,
+		Type:          t.Type,
+		Amount:        t.Amount,
+		Reference:     t.Reference,
+		FromAccountID: t.FromAccountID,
+		ToAccountID:   t.ToAccountID,
+		Reason:        t.Reason,
+		ReversesID:    t.ReversesID,
+		PotID:         t.PotID,
+		CreatedAt:     t.Timestamp.UTC(),
+	}
+}
+
+func writeTransactionsCSV(w io.Writer, txns []exportTransaction) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "account_id", "type", "amount", "reference", "from_account_id", "to_account_id", "reason", "reverses_id", "pot_id", "created_at"})
+	for _, t := range txns {
+		cw.Write([]string{
+			strconv.Itoa(t.ID),
+			strconv.Itoa(t.AccountID),
+			t.Type,
+			strconv.FormatInt(t.Amount, 10),
+			t.Reference,
+			optionalID(t.FromAccountID),
+			optionalID(t.ToAccountID),
+			t.Reason,
+			optionalID(t.ReversesID),
+			optionalID(t.PotID),
+			t.CreatedAt.Format(timeFormat),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
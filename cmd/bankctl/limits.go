@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"mini-bank/internal/core"
+)
+
+func (c *cli) limits(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: limits needs get, set, set-product or set-tier", errUsage)
+	}
+	switch args[0] {
+	case "get":
+		id, err := parse(flag.NewFlagSet("limits get", flag.ContinueOnError), args[1:], "account id")
+		if err != nil {
+			return err
+		}
+		lim, err := c.service.GetAccountLimits(ctx, id)
+		if err != nil {
+			return err
+		}
+		return c.printLimits(lim)
+	case "set":
+		fs := flag.NewFlagSet("limits set", flag.ContinueOnError)
+		limits := limitFlags(fs, "the product's")
+		id, err := parse(fs, args[1:], "account id")
+		if err != nil {
+			return err
+		}
+		lim, err := c.service.SetAccountLimits(ctx, id, limits.get(fs))
+		if err != nil {
+			return err
+		}
+		return c.printLimits(lim)
+	case "set-product":
+		fs := flag.NewFlagSet("limits set-product", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		limits := limitFlags(fs, "no limit")
+		if err := fs.Parse(args[1:]); err != nil {
+			return fmt.Errorf("%w: limits set-product: %v", errUsage, err)
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%w: limits set-product takes one product", errUsage)
+		}
+		if err := c.service.SetProductLimits(ctx, fs.Arg(0), limits.get(fs)); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "limits of product %s updated\n", fs.Arg(0))
+		return nil
+	case "set-tier":
+		fs := flag.NewFlagSet("limits set-tier", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		limits := limitFlags(fs, "no limit")
+		if err := fs.Parse(args[1:]); err != nil {
+			return fmt.Errorf("%w: limits set-tier: %v", errUsage, err)
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%w: limits set-tier takes one tier", errUsage)
+		}
+		if err := c.service.SetTierLimits(ctx, fs.Arg(0), limits.get(fs)); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "limits of tier %s updated\n", fs.Arg(0))
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown limits command %q", errUsage, args[0])
+	}
+}
+
+// limitValues holds the limit flags of a command.
+type limitValues struct {
+	maxSingle, daily, monthly, hourly *int64
+}
+
+// limitFlags defines a flag per limit. Limits whose flag is not given
+// become unset, which means fallback.
+func limitFlags(fs *flag.FlagSet, fallback string) *limitValues {
+	return &limitValues{
+		maxSingle: fs.Int64("max-single", 0, "largest single withdrawal or transfer (default "+fallback+")"),
+		daily:     fs.Int64("daily", 0, "total sent per day (default "+fallback+")"),
+		monthly:   fs.Int64("monthly", 0, "total sent per month (default "+fallback+")"),
+		hourly:    fs.Int64("hourly", 0, "withdrawals and transfers per hour (default "+fallback+")"),
+	}
+}
+
+// get returns the limits given on the command line.
+func (v *limitValues) get(fs *flag.FlagSet) core.Limits {
+	var l core.Limits
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-single":
+			l.MaxSingle = v.maxSingle
+		case "daily":
+			l.DailyOutbound = v.daily
+		case "monthly":
+			l.MonthlyOutbound = v.monthly
+		case "hourly":
+			l.HourlyCount = v.hourly
+		}
+	})
+	return l
+}
+
+func (c *cli) printLimits(lim *core.AccountLimits) error {
+	w := c.table()
+	fmt.Fprintf(w, "Account\t%d\n", lim.AccountID)
+	fmt.Fprintf(w, "Product\t%s\n", lim.Product)
+	fmt.Fprintf(w, "KYC tier\t%s\n", lim.Tier)
+	fmt.Fprintf(w, "Max single\t%s\n", optionalAmount(lim.MaxSingle))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out)
+	w = c.table()
+	fmt.Fprintln(w, "LIMIT\tMAX\tUSED\tREMAINING\tRESETS")
+	for _, u := range []struct {
+		name  string
+		usage core.LimitUsage
+	}{
+		{"daily outbound", lim.Daily},
+		{"monthly outbound", lim.Monthly},
+		{"hourly count", lim.Hourly},
+	} {
+		resets := ""
+		if u.usage.ResetsAt != nil {
+			resets = u.usage.ResetsAt.UTC().Format(timeFormat)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", u.name, optionalAmount(u.usage.Limit), u.usage.Used, optionalAmount(u.usage.Remaining()), resets)
+	}
+	return w.Flush()
+}
+
+func optionalAmount(v *int64) string {
+	if v == nil {
+		return "none"
+	}
+	return strconv.FormatInt(*v, 10)
+}
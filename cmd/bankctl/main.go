@@ -0,0 +1,453 @@
+// Command bankctl is the support tool for investigating and correcting
+// accounts. It goes through the same service as the API, so business rules
+// such as non-negative balances and recorded transactions still apply.
+//
+//	go run ./cmd/bankctl [config flags] <command> [arguments]
+//
+// It reads the storage settings the same way as the server: from the file
+// named by -config or CONFIG_FILE, the environment and flags. Run it with
+// no command to list the commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/config"
+	"mini-bank/internal/core"
+	"mini-bank/internal/service"
+	pg "mini-bank/internal/storage/postgres"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: bankctl [config flags] <command> [arguments]
+
+Commands:
+  users list                                  list users
+  users get <user-id>                         show a user
+  users grant -role <role> <user-id>          grant a staff role, such as auditor
+  users revoke -role <role> <user-id>         revoke a staff role
+  accounts list                               list accounts
+  accounts get <account-id>                   show an account
+  transactions <account-id>                   list an account's transactions
+  freeze <account-id>                         stop payments and transfers on an account
+  unfreeze <account-id>                       lift a freeze
+  adjust -amount <n> -reason <text> <account-id>
+                                              credit (n > 0) or debit (n < 0) an account
+  reverse -reason <text> <transaction-id>     reverse a transaction
+  limits get <account-id>                     show an account's limits and their use
+  limits set [limit flags] <account-id>       override an account's product limits
+  limits set-product [limit flags] <product>  set a product's limits
+  limits set-tier [limit flags] <tier>        set a KYC tier's limits
+                                              (limit flags: -max-single, -daily,
+                                              -monthly, -hourly; omitted ones are
+                                              unset)
+  deposits rates                              list the term deposit rates
+  deposits set-rate -rate <bps> [-break-rate <bps>] <months>
+                                              offer a term at new rates
+  deposits mature                             pay out or roll over matured deposits
+  export [-format json|csv] [-o file]         export users, accounts and transactions
+  verify                                      reconcile balances with transaction history
+  audit list [filters]                        list audit log entries, oldest first
+  audit verify [-head <hash>]                 check the audit log's hash chain
+
+Changes are recorded in the audit log as made by the operator named in
+BANKCTL_OPERATOR, or by the current OS user.
+
+Run "bankctl -h" for the config flags.
+`
+
+const timeFormat = time.RFC3339
+
+// errUsage marks a mistake in the command line, reported with exit code 2.
+var errUsage = errors.New("usage")
+
+func main() {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "bankctl: failed to load .env:", err)
+		os.Exit(1)
+	}
+
+	cfg, args, err := config.LoadCommand("bankctl", os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(os.Stderr, "\n"+usage)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bankctl:", err)
+		os.Exit(2)
+	}
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = audit.WithActor(ctx, core.Actor{Type: core.ActorAdmin, ID: operator()})
+
+	db, err := pg.NewDB(cfg.Storage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bankctl: failed to connect to db:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	c := &cli{service: service.New(pg.NewRepo(db), service.Options{}), out: os.Stdout}
+	err = c.run(ctx, args)
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, "bankctl:", err)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "bankctl:", err)
+		os.Exit(1)
+	}
+}
+
+// operator names whoever runs bankctl, for the audit log.
+func operator() string {
+	if name := os.Getenv("BANKCTL_OPERATOR"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+type cli struct {
+	service service.Service
+	out     io.Writer
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "users":
+		return c.users(ctx, args)
+	case "accounts":
+		return c.accounts(ctx, args)
+	case "transactions":
+		return c.transactions(ctx, args)
+	case "freeze":
+		return c.freeze(ctx, args, true)
+	case "unfreeze":
+		return c.freeze(ctx, args, false)
+	case "adjust":
+		return c.adjust(ctx, args)
+	case "reverse":
+		return c.reverse(ctx, args)
+	case "limits":
+		return c.limits(ctx, args)
+	case "deposits":
+		return c.deposits(ctx, args)
+	case "export":
+		return c.export(ctx, args)
+	case "verify":
+		return c.verify(ctx, args)
+	case "audit":
+		return c.audit(ctx, args)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+// parse parses a command's flags and returns its one positional ID.
+func parse(fs *flag.FlagSet, args []string, what string) (int, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", errUsage, fs.Name(), err)
+	}
+	if fs.NArg() != 1 {
+		return 0, fmt.Errorf("%w: %s takes one %s", errUsage, fs.Name(), what)
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid %s %q", errUsage, what, fs.Arg(0))
+	}
+	return id, nil
+}
+
+// noArgs checks that a command was given no arguments.
+func noArgs(name string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: %s takes no arguments", errUsage, name)
+	}
+	return nil
+}
+
+func (c *cli) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+}
+
+func (c *cli) users(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: users needs list, get, grant or revoke", errUsage)
+	}
+	switch args[0] {
+	case "list":
+		if err := noArgs("users list", args[1:]); err != nil {
+			return err
+		}
+		users, err := c.service.GetUsers(ctx)
+		if err != nil {
+			return err
+		}
+		w := c.table()
+		fmt.Fprintln(w, "ID\tEMAIL\tFIRST NAME\tLAST NAME")
+		for _, u := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.ID, u.Email, u.FirstName, u.LastName)
+		}
+		return w.Flush()
+	case "get":
+		id, err := parse(flag.NewFlagSet("users get", flag.ContinueOnError), args[1:], "user id")
+		if err != nil {
+			return err
+		}
+		u, err := c.service.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		w := c.table()
+		fmt.Fprintf(w, "ID\t%d\n", u.ID)
+		fmt.Fprintf(w, "Email\t%s\n", u.Email)
+		fmt.Fprintf(w, "Name\t%s %s\n", u.FirstName, u.LastName)
+		if u.Balance != nil {
+			fmt.Fprintf(w, "Balance\t%d\n", *u.Balance)
+		}
+		return w.Flush()
+	case "grant", "revoke":
+		fs := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+		role := fs.String("role", "", "the role: "+strings.Join(core.Roles, ", "))
+		id, err := parse(fs, args[1:], "user id")
+		if err != nil {
+			return err
+		}
+		change := c.service.RevokeRole
+		if args[0] == "grant" {
+			change = c.service.GrantRole
+		}
+		roles, err := change(ctx, id, *role)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "user %d has roles: %s\n", id, strings.Join(roles, ", "))
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown users command %q", errUsage, args[0])
+	}
+}
+
+func (c *cli) accounts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: accounts needs list or get", errUsage)
+	}
+	switch args[0] {
+	case "list":
+		if err := noArgs("accounts list", args[1:]); err != nil {
+			return err
+		}
+		accs, err := c.service.ListAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		w := c.table()
+		fmt.Fprintln(w, "ID\tNUMBER\tUSER\tPRODUCT\tPRIMARY\tBALANCE\tSTATUS\tCREATED")
+		for _, a := range accs {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%t\t%d\t%s\t%s\n", a.ID, a.Number, a.UserID, a.Product, a.Primary, a.Balance, a.Status, a.CreatedAt.UTC().Format(timeFormat))
+		}
+		return w.Flush()
+	case "get":
+		id, err := parse(flag.NewFlagSet("accounts get", flag.ContinueOnError), args[1:], "account id")
+		if err != nil {
+			return err
+		}
+		a, err := c.service.GetAccount(ctx, id)
+		if err != nil {
+			return err
+		}
+		w := c.table()
+		fmt.Fprintf(w, "ID\t%d\n", a.ID)
+		fmt.Fprintf(w, "Number\t%s\n", a.Number)
+		fmt.Fprintf(w, "User\t%d\n", a.UserID)
+		fmt.Fprintf(w, "Name\t%s\n", a.Name)
+		fmt.Fprintf(w, "Product\t%s\n", a.Product)
+		fmt.Fprintf(w, "Primary\t%t\n", a.Primary)
+		fmt.Fprintf(w, "Balance\t%d\n", a.Balance)
+		fmt.Fprintf(w, "Status\t%s\n", a.Status)
+		fmt.Fprintf(w, "Created\t%s\n", a.CreatedAt.UTC().Format(timeFormat))
+		return w.Flush()
+	default:
+		return fmt.Errorf("%w: unknown accounts command %q", errUsage, args[0])
+	}
+}
+
+func (c *cli) transactions(ctx context.Context, args []string) error {
+	id, err := parse(flag.NewFlagSet("transactions", flag.ContinueOnError), args, "account id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.service.GetAccount(ctx, id); err != nil {
+		return err
+	}
+	txns, err := c.service.ListTransactions(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.printTransactions(txns)
+}
+
+func (c *cli) freeze(ctx context.Context, args []string, freeze bool) error {
+	name := "unfreeze"
+	if freeze {
+		name = "freeze"
+	}
+	id, err := parse(flag.NewFlagSet(name, flag.ContinueOnError), args, "account id")
+	if err != nil {
+		return err
+	}
+	change := c.service.UnfreezeAccount
+	if freeze {
+		change = c.service.FreezeAccount
+	}
+	acc, err := change(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "account %d is %s\n", acc.ID, acc.Status)
+	return nil
+}
+
+func (c *cli) adjust(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("adjust", flag.ContinueOnError)
+	amount := fs.Int64("amount", 0, "signed amount in minor units")
+	reason := fs.String("reason", "", "why the adjustment is made")
+	id, err := parse(fs, args, "account id")
+	if err != nil {
+		return err
+	}
+	txn, err := c.service.AdjustBalance(ctx, id, *amount, *reason)
+	if err != nil {
+		return err
+	}
+	acc, err := c.service.GetAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "adjusted account %d by %d (transaction %d, reference %s); balance is now %d\n",
+		acc.ID, txn.Amount, txn.ID, txn.Reference, acc.Balance)
+	return nil
+}
+
+func (c *cli) reverse(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reverse", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the transaction is reversed")
+	id, err := parse(fs, args, "transaction id")
+	if err != nil {
+		return err
+	}
+	txns, err := c.service.ReverseTransaction(ctx, id, *reason)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "reversed transaction %d\n", id)
+	return c.printTransactions(txns)
+}
+
+func (c *cli) verify(ctx context.Context, args []string) error {
+	if err := noArgs("verify", args); err != nil {
+		return err
+	}
+	rec, err := c.service.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := c.table()
+	fmt.Fprintf(w, "Checked at\t%s\n", rec.CheckedAt.Format(timeFormat))
+	fmt.Fprintf(w, "Accounts\t%d\n", rec.Accounts)
+	fmt.Fprintf(w, "Transactions\t%d\n", rec.Transactions)
+	fmt.Fprintf(w, "Total balance\t%d\n", rec.TotalBalance)
+	fmt.Fprintf(w, "In minus out\t%d\n", rec.ExternalNet)
+	fmt.Fprintf(w, "Transfers net\t%d\n", rec.InternalNet)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if rec.OK() {
+		fmt.Fprintln(c.out, "\nledger ok: every balance matches its transactions and money is conserved")
+		return nil
+	}
+
+	if !rec.Conserved() {
+		fmt.Fprintf(c.out, "\nmoney is not conserved: balances total %d but %d came in net and transfers net %d\n",
+			rec.TotalBalance, rec.ExternalNet, rec.InternalNet)
+	}
+	if len(rec.Mismatches) > 0 {
+		fmt.Fprintln(c.out, "\nBalances that do not match their transactions:")
+		w := c.table()
+		fmt.Fprintln(w, "ACCOUNT\tPOT\tBALANCE\tLEDGER\tDELTA")
+		for _, m := range rec.Mismatches {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%+d\n", m.AccountID, optionalID(m.PotID), m.Balance, m.LedgerBalance, m.Delta())
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(rec.ImbalancedTransfers) > 0 {
+		fmt.Fprintln(c.out, "\nTransfers whose legs do not cancel out:")
+		w := c.table()
+		fmt.Fprintln(w, "REFERENCE\tACCOUNTS\tLEGS\tNET")
+		for _, t := range rec.ImbalancedTransfers {
+			fmt.Fprintf(w, "%s\t%v\t%d\t%+d\n", t.Reference, t.AccountIDs, t.Legs, t.Net)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("ledger has %d mismatched accounts and %d imbalanced transfers", len(rec.Mismatches), len(rec.ImbalancedTransfers))
+}
+
+// printTransactions lists transactions as a table.
+func (c *cli) printTransactions(txns []*core.Transaction) error {
+	w := c.table()
+	fmt.Fprintln(w, "ID\tTYPE\tAMOUNT\tCOUNTERPARTY\tREFERENCE\tREASON\tCREATED")
+	for _, t := range txns {
+		counterparty := optionalID(t.FromAccountID)
+		switch {
+		case t.ToAccountID != nil:
+			counterparty = optionalID(t.ToAccountID)
+		case t.PotID != nil:
+			counterparty = "pot " + optionalID(t.PotID)
+		}
+		reason := t.Reason
+		if t.ReversesID != nil {
+			reason = fmt.Sprintf("reverses %d: %s", *t.ReversesID, reason)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			t.ID, t.Type, t.Amount, counterparty, t.Reference, reason, t.Timestamp.UTC().Format(timeFormat))
+	}
+	return w.Flush()
+}
+
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
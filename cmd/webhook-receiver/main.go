@@ -0,0 +1,66 @@
+// Command webhook-receiver is a local endpoint for testing webhook
+// subscriptions. It verifies each request's signature and logs the payload.
+//
+//	WEBHOOK_SECRET=whsec_... go run ./cmd/webhook-receiver
+//
+// Set FAIL_RATE (0-1) to answer a share of requests with 500 and exercise
+// retries.
+package main
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/webhook"
+)
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		logger.Error("WEBHOOK_SECRET environment variable is not set")
+		os.Exit(1)
+	}
+	addr := ":9090"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	failRate, _ := strconv.ParseFloat(os.Getenv("FAIL_RATE"), 64)
+
+	http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if err := webhook.Verify(secret, r.Header.Get(webhook.HeaderSignature), body, 5*time.Minute); err != nil {
+			logger.Warn("rejected webhook", "delivery", r.Header.Get(webhook.HeaderDelivery), "err", err)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if rand.Float64() < failRate {
+			logger.Info("simulating failure", "delivery", r.Header.Get(webhook.HeaderDelivery))
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info("received webhook",
+			"event", r.Header.Get(webhook.HeaderEvent),
+			"delivery", r.Header.Get(webhook.HeaderDelivery),
+			"payload", string(body),
+		)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	logger.Info("listening on", "addr", addr)
+	if err := http.ListenAndServe(addr, nil); err != nil {
+		logger.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type createAPIKeyRequest struct {
+	Name           string   `json:"name"`
+	Scopes         []string `json:"scopes"`
+	AccountNumbers []string `json:"account_numbers"`
+}
+
+type apiKeyResponse struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Scopes         []string   `json:"scopes"`
+	AccountNumbers []string   `json:"account_numbers"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	// Key is the plaintext secret. It is only returned on creation and rotation.
+	Key string `json:"key,omitempty"`
+}
+
+// newAPIKeyResponse shows k with the numbers of the accounts it is
+// restricted to, looked up in numbers.
+func newAPIKeyResponse(k *core.APIKey, numbers map[int]string, secret string) *apiKeyResponse {
+	return &apiKeyResponse{
+		ID:             k.ID,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.Scopes,
+		AccountNumbers: accountNumbersOf(k.AccountIDs, numbers),
+		CreatedAt:      k.CreatedAt,
+		LastUsedAt:     k.LastUsedAt,
+		RevokedAt:      k.RevokedAt,
+		Key:            secret,
+	}
+}
+
+// accountNumbersOf returns the numbers of the accounts ids, in order,
+// never nil.
+func accountNumbersOf(ids []int, numbers map[int]string) []string {
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := numbers[id]; ok {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func validateCreateAPIKeyRequest(req createAPIKeyRequest) error {
+	if req.Name == "" {
+		return core.InvalidField("name", "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return core.InvalidField("scopes", "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !core.ValidScope(scope) {
+			return core.InvalidField("scopes", fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+	return nil
+}
+
+func (a *API) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+
+	if err := validateCreateAPIKeyRequest(req); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	// Keys can only be restricted to accounts the caller owns.
+	var accountIDs []int
+	numbers := map[int]string{}
+	for _, number := range req.AccountNumbers {
+		acc := a.getAuthorizedAccount(w, r, "account_numbers", number)
+		if acc == nil {
+			return
+		}
+		accountIDs = append(accountIDs, acc.ID)
+		numbers[acc.ID] = acc.Number
+	}
+
+	key, secret, err := a.service.CreateAPIKey(ctx, userID, req.Name, req.Scopes, accountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newAPIKeyResponse(key, numbers, secret))
+}
+
+func (a *API) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	keys, err := a.service.ListAPIKeys(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	var accountIDs []int
+	for _, k := range keys {
+		accountIDs = append(accountIDs, k.AccountIDs...)
+	}
+	numbers, err := a.service.AccountNumbers(ctx, accountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := make([]*apiKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, newAPIKeyResponse(k, numbers, ""))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) RotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid api key id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	key, secret, err := a.service.RotateAPIKey(ctx, userID, id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	numbers, err := a.service.AccountNumbers(ctx, key.AccountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newAPIKeyResponse(key, numbers, secret))
+}
+
+func (a *API) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid api key id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if err := a.service.RevokeAPIKey(ctx, userID, id); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "api key revoked"})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type auditLogResponse struct {
+	Entries []core.AuditEntry `json:"entries"`
+	// NextAfterID is passed as after_id to fetch the next page. It is
+	// omitted when no entries matched; an empty page ends the listing.
+	NextAfterID *int64 `json:"next_after_id,omitempty"`
+}
+
+// RequireRole lets only users holding role through. It goes inside
+// AuthMiddleware on a session-only route, so the caller is always the user
+// in person.
+func (a *API) RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(contextKeyUserID).(int)
+		if !ok {
+			a.writeError(w, r, core.ErrUnauthenticated)
+			return
+		}
+		has, err := a.service.HasRole(ctx, userID, role)
+		if err != nil {
+			a.writeError(w, r, err)
+			return
+		}
+		if !has {
+			a.writeError(w, r, core.Forbidden("the %s role is required", role))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// GetAuditLogHandler returns audit entries matching the query, oldest
+// first, a page at a time.
+func (a *API) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := core.AuditFilter{
+		ActorType:  q.Get("actor_type"),
+		ActorID:    q.Get("actor_id"),
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+	}
+
+	var fields []core.FieldError
+	parseTime := func(name string) time.Time {
+		v := q.Get(name)
+		if v == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields = append(fields, core.FieldError{Field: name, Message: name + " must be an RFC 3339 timestamp"})
+		}
+		return t
+	}
+	filter.From = parseTime("from")
+	filter.To = parseTime("to")
+	if v := q.Get("after_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			fields = append(fields, core.FieldError{Field: "after_id", Message: "after_id must be a non-negative integer"})
+		}
+		filter.AfterID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fields = append(fields, core.FieldError{Field: "limit", Message: "limit must be a positive integer"})
+		}
+		filter.Limit = n
+	}
+	if len(fields) > 0 {
+		a.writeError(w, r, core.InvalidFields(fields...))
+		return
+	}
+
+	entries, err := a.service.ListAuditEntries(r.Context(), filter)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := auditLogResponse{Entries: entries}
+	if resp.Entries == nil {
+		resp.Entries = []core.AuditEntry{}
+	}
+	if n := len(entries); n > 0 {
+		next := entries[n-1].ID
+		resp.NextAfterID = &next
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type createBeneficiaryRequest struct {
+	Nickname      string `json:"nickname"`
+	AccountNumber string `json:"account_number"`
+	// Name is who the user believes owns the account, checked against
+	// the owner's name.
+	Name string `json:"name"`
+}
+
+type confirmPayeeRequest struct {
+	AccountNumber string `json:"account_number"`
+	Name          string `json:"name"`
+}
+
+type payeeCheckResponse struct {
+	AccountNumber string `json:"account_number"`
+	Result        string `json:"result"`
+	PayeeName     string `json:"payee_name,omitempty"`
+}
+
+func newPayeeCheckResponse(c *core.PayeeCheck) *payeeCheckResponse {
+	return &payeeCheckResponse{
+		AccountNumber: c.AccountNumber,
+		Result:        c.Result,
+		PayeeName:     c.PayeeName,
+	}
+}
+
+type beneficiaryResponse struct {
+	ID              int        `json:"id"`
+	Nickname        string     `json:"nickname"`
+	AccountNumber   string     `json:"account_number"`
+	PayeeName       string     `json:"payee_name"`
+	NameMatch       string     `json:"name_match"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CoolingOffUntil *time.Time `json:"cooling_off_until,omitempty"`
+}
+
+func newBeneficiaryResponse(b *core.Beneficiary) *beneficiaryResponse {
+	return &beneficiaryResponse{
+		ID:              b.ID,
+		Nickname:        b.Nickname,
+		AccountNumber:   b.AccountNumber,
+		PayeeName:       b.PayeeName,
+		NameMatch:       b.NameMatch,
+		CreatedAt:       b.CreatedAt,
+		CoolingOffUntil: b.CoolingOffUntil,
+	}
+}
+
+// payeeAccountID returns the ID of the account a request names by
+// account number.
+func (a *API) payeeAccountID(ctx context.Context, number string) (int, error) {
+	if !core.ValidAccountNumber(number) {
+		return 0, core.InvalidField("account_number", "invalid account number")
+	}
+	acc, err := a.service.GetAccountByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	return acc.ID, nil
+}
+
+// ConfirmPayeeHandler checks the name a user gives for an account against
+// its owner's before they save or pay it.
+func (a *API) ConfirmPayeeHandler(w http.ResponseWriter, r *http.Request) {
+	var req confirmPayeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	accountID, err := a.payeeAccountID(r.Context(), req.AccountNumber)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	check, err := a.service.CheckPayee(r.Context(), accountID, req.Name)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newPayeeCheckResponse(check))
+}
+
+func (a *API) CreateBeneficiaryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req createBeneficiaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	accountID, err := a.payeeAccountID(ctx, req.AccountNumber)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	b, err := a.service.CreateBeneficiary(ctx, userID, req.Nickname, accountID, req.Name)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newBeneficiaryResponse(b))
+}
+
+func (a *API) GetBeneficiariesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	bs, err := a.service.ListBeneficiaries(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	res := make([]*beneficiaryResponse, 0, len(bs))
+	for _, b := range bs {
+		res = append(res, newBeneficiaryResponse(b))
+	}
+	jsonResponse(w, http.StatusOK, res)
+}
+
+func (a *API) DeleteBeneficiaryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid beneficiary id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if err := a.service.DeleteBeneficiary(ctx, userID, id); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "beneficiary deleted"})
+}
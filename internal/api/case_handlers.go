@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type caseResponse struct {
+	ID              int        `json:"id"`
+	AccountID       int        `json:"account_id"`
+	Rule            string     `json:"rule"`
+	Decision        string     `json:"decision"`
+	Reason          string     `json:"reason"`
+	Reference       string     `json:"reference"`
+	TransactionType string     `json:"transaction_type"`
+	Amount          int64      `json:"amount"`
+	Source          string     `json:"source"`
+	Status          string     `json:"status"`
+	Note            string     `json:"note,omitempty"`
+	ResolvedBy      string     `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func newCaseResponse(c *core.Case) *caseResponse {
+	return &caseResponse{
+		ID:              c.ID,
+		AccountID:       c.AccountID,
+		Rule:            c.Rule,
+		Decision:        c.Decision,
+		Reason:          c.Reason,
+		Reference:       c.Reference,
+		TransactionType: c.TransactionType,
+		Amount:          c.Amount,
+		Source:          c.Source,
+		Status:          c.Status,
+		Note:            c.Note,
+		ResolvedBy:      c.ResolvedBy,
+		ResolvedAt:      c.ResolvedAt,
+		CreatedAt:       c.CreatedAt,
+	}
+}
+
+type caseListResponse struct {
+	Cases []*caseResponse `json:"cases"`
+	// NextAfterID is passed as after_id to fetch the next page. It is
+	// omitted when no cases matched.
+	NextAfterID *int `json:"next_after_id,omitempty"`
+}
+
+type resolveCaseRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// GetCasesHandler returns monitoring cases matching the query, oldest
+// first, a page at a time.
+func (a *API) GetCasesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := core.CaseFilter{Status: q.Get("status"), Rule: q.Get("rule")}
+
+	var fields []core.FieldError
+	parseInt := func(name string, min int) int {
+		v := q.Get(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min {
+			fields = append(fields, core.FieldError{Field: name, Message: name + " must be an integer of at least " + strconv.Itoa(min)})
+		}
+		return n
+	}
+	filter.AccountID = parseInt("account_id", 1)
+	filter.AfterID = parseInt("after_id", 0)
+	filter.Limit = parseInt("limit", 1)
+	if len(fields) > 0 {
+		a.writeError(w, r, core.InvalidFields(fields...))
+		return
+	}
+
+	cases, err := a.service.ListCases(r.Context(), filter)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := caseListResponse{Cases: make([]*caseResponse, len(cases))}
+	for i, c := range cases {
+		resp.Cases[i] = newCaseResponse(c)
+	}
+	if n := len(cases); n > 0 {
+		next := cases[n-1].ID
+		resp.NextAfterID = &next
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) GetCaseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid case id"))
+		return
+	}
+
+	c, err := a.service.GetCase(r.Context(), id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newCaseResponse(c))
+}
+
+// ResolveCaseHandler closes an open case as cleared or confirmed.
+func (a *API) ResolveCaseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid case id"))
+		return
+	}
+
+	var req resolveCaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+
+	c, err := a.service.ResolveCase(r.Context(), id, req.Status, req.Note)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newCaseResponse(c))
+}
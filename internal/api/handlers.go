@@ -10,9 +10,11 @@ import (
 	"strconv"
 	"time"
 
+	"mini-bank/internal/config"
 	"mini-bank/internal/core"
 	"mini-bank/internal/service"
 	"mini-bank/internal/storage"
+	"mini-bank/internal/stream"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -23,11 +25,23 @@ type API struct {
 	service   service.Service
 	logger    *slog.Logger
 	redis     *redis.Client
+	hub       *stream.Hub
 	jwtSecret string
+	// accessTokenTTL and refreshTokenTTL bound user sessions.
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
 }
 
-func NewAPI(s service.Service, logger *slog.Logger, rdb *redis.Client, jwtSecret string) *API {
-	return &API{service: s, logger: logger, redis: rdb, jwtSecret: jwtSecret}
+func NewAPI(s service.Service, logger *slog.Logger, rdb *redis.Client, hub *stream.Hub, auth config.Auth) *API {
+	return &API{
+		service:         s,
+		logger:          logger,
+		redis:           rdb,
+		hub:             hub,
+		jwtSecret:       auth.JWTSecret.Reveal(),
+		accessTokenTTL:  auth.AccessTokenTTL,
+		refreshTokenTTL: auth.RefreshTokenTTL,
+	}
 }
 
 func jsonResponse(w http.ResponseWriter, status int, data any) {
@@ -37,44 +51,96 @@ func jsonResponse(w http.ResponseWriter, status int, data any) {
 }
 
 type createAccountRequest struct {
-	UserID         int   `json:"user_id"`
-	InitialBalance int64 `json:"initial_balance"`
+	UserID         int    `json:"user_id"`
+	InitialBalance int64  `json:"initial_balance"`
+	Product        string `json:"product"`
+	Name           string `json:"name"`
+}
+
+type updateAccountRequest struct {
+	Name *string `json:"name"`
+	// Primary can only be set; an account stops being primary when
+	// another one is made primary.
+	Primary *bool `json:"primary"`
 }
 
-type createAccountResponse struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Balance   int64     `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+// accountResponse is an account as shown to its owner. Accounts are
+// identified by their account number; internal ids are not exposed.
+type accountResponse struct {
+	AccountNumber string    `json:"account_number"`
+	Balance       int64     `json:"balance"`
+	Product       string    `json:"product"`
+	Name          string    `json:"name"`
+	Primary       bool      `json:"primary"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
-type getAccountResponse struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Balance   int64     `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+func newAccountResponse(acc *core.Account) *accountResponse {
+	return &accountResponse{
+		AccountNumber: acc.Number,
+		Balance:       acc.Balance,
+		Product:       acc.Product,
+		Name:          acc.Name,
+		Primary:       acc.Primary,
+		CreatedAt:     acc.CreatedAt,
+	}
 }
 
 type getAccountsResponse struct {
-	Accounts []*getAccountResponse `json:"accounts"`
+	Accounts []*accountResponse `json:"accounts"`
 }
 
+// transferResponse shows the receiver's account by number only: the
+// sender has no claim to its balance or owner.
 type transferResponse struct {
-	FromAccount *getAccountResponse `json:"from_account"`
-	ToAccount   *getAccountResponse `json:"to_account"`
-	Reference   string              `json:"reference,omitempty"`
+	FromAccount     *accountResponse `json:"from_account"`
+	ToAccountNumber string           `json:"to_account_number"`
+	Reference       string           `json:"reference,omitempty"`
+}
+
+type transactionResponse struct {
+	ID                int       `json:"id"`
+	AccountNumber     string    `json:"account_number"`
+	Type              string    `json:"type"`
+	Amount            int64     `json:"amount"`
+	Timestamp         time.Time `json:"timestamp"`
+	Reference         string    `json:"reference"`
+	FromAccountNumber string    `json:"from_account_number,omitempty"`
+	ToAccountNumber   string    `json:"to_account_number,omitempty"`
+	Reason            string    `json:"reason,omitempty"`
+	ReversesID        *int      `json:"reverses_id,omitempty"`
+	PotID             *int      `json:"pot_id,omitempty"`
+}
+
+func newTransactionResponse(txn *core.Transaction) *transactionResponse {
+	return &transactionResponse{
+		ID:                txn.ID,
+		AccountNumber:     txn.AccountNumber,
+		Type:              txn.Type,
+		Amount:            txn.Amount,
+		Timestamp:         txn.Timestamp,
+		Reference:         txn.Reference,
+		FromAccountNumber: txn.FromAccountNumber,
+		ToAccountNumber:   txn.ToAccountNumber,
+		Reason:            txn.Reason,
+		ReversesID:        txn.ReversesID,
+		PotID:             txn.PotID,
+	}
 }
 
 type transferRequest struct {
-	FromID int   `json:"from_id"`
-	ToID   int   `json:"to_id"`
-	Amount int64 `json:"amount"`
+	FromAccountNumber string `json:"from_account_number"`
+	// Exactly one of ToAccountNumber and BeneficiaryID names the
+	// receiver.
+	ToAccountNumber string `json:"to_account_number"`
+	BeneficiaryID   int    `json:"beneficiary_id"`
+	Amount          int64  `json:"amount"`
 }
 
 type paymentRequest struct {
-	AccountID int                 `json:"account_id"`
-	Amount    int64               `json:"amount"`
-	Type      storage.PaymentType `json:"type"`
+	AccountNumber string              `json:"account_number"`
+	Amount        int64               `json:"amount"`
+	Type          storage.PaymentType `json:"type"`
 }
 
 type createUserRequest struct {
@@ -127,157 +193,190 @@ type RefreshTokenResponse struct {
 func (a *API) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
 	var req createAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httpError(w, http.StatusBadRequest, "invalid JSON body")
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
 		return
 	}
 
 	if err := validateCreateAccount(req); err != nil {
-		httpError(w, http.StatusBadRequest, err.Error())
+		a.writeError(w, r, err)
 		return
 	}
 
 	ctx := r.Context()
-	acc, err := a.service.CreateAccount(ctx, req.UserID, req.InitialBalance)
+	acc, err := a.service.CreateAccount(ctx, req.UserID, req.InitialBalance, req.Product, req.Name)
 	if err != nil {
-		a.logger.Error("failed to create account", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to create account")
-
+		a.writeError(w, r, err)
 		return
 	}
 
-	resp := createAccountResponse{
-		ID:        acc.ID,
-		UserID:    acc.UserID,
-		Balance:   acc.Balance,
-		CreatedAt: acc.CreatedAt,
-	}
-
-	jsonResponse(w, http.StatusCreated, resp)
+	jsonResponse(w, http.StatusCreated, newAccountResponse(acc))
 }
 
 func validateCreateAccount(req createAccountRequest) error {
+	var fields []core.FieldError
 	if req.UserID <= 0 {
-		return errors.New("invalid user id")
+		fields = append(fields, core.FieldError{Field: "user_id", Message: "invalid user id"})
 	}
 
 	if req.InitialBalance < 0 {
-		return errors.New("initial balance must be positive")
+		fields = append(fields, core.FieldError{Field: "initial_balance", Message: "initial balance must be positive"})
 	}
 
-	return nil
+	return fieldsError(fields)
 }
 
 func validateTransferRequest(req transferRequest) error {
+	var fields []core.FieldError
 	if req.Amount <= 0 {
-		return errors.New("amount must be greater than zero")
+		fields = append(fields, core.FieldError{Field: "amount", Message: "amount must be greater than zero"})
 	}
-	if req.FromID == req.ToID {
-		return errors.New("sender and receiver accounts cannot be the same")
+	if !core.ValidAccountNumber(req.FromAccountNumber) {
+		fields = append(fields, core.FieldError{Field: "from_account_number", Message: "invalid account number"})
 	}
-	if req.FromID <= 0 {
-		return errors.New("invalid sender account id")
-	}
-	if req.ToID <= 0 {
-		return errors.New("invalid receiver account id")
+	if req.ToAccountNumber != "" && req.BeneficiaryID != 0 {
+		fields = append(fields, core.FieldError{Field: "beneficiary_id", Message: "give only one of to_account_number and beneficiary_id"})
+	} else if req.BeneficiaryID != 0 {
+		if req.BeneficiaryID < 0 {
+			fields = append(fields, core.FieldError{Field: "beneficiary_id", Message: "invalid beneficiary id"})
+		}
+	} else if !core.ValidAccountNumber(req.ToAccountNumber) {
+		fields = append(fields, core.FieldError{Field: "to_account_number", Message: "invalid account number"})
+	} else if req.FromAccountNumber == req.ToAccountNumber {
+		fields = append(fields, core.FieldError{Field: "to_account_number", Message: "sender and receiver accounts cannot be the same"})
 	}
-	return nil
+	return fieldsError(fields)
 }
 
 func validatePaymentRequest(req paymentRequest) error {
+	var fields []core.FieldError
 	if req.Amount <= 0 {
-		return errors.New("amount must be greater than zero")
+		fields = append(fields, core.FieldError{Field: "amount", Message: "amount must be greater than zero"})
 	}
-	if req.AccountID <= 0 {
-		return errors.New("account ID must be greater than zero")
+	if !core.ValidAccountNumber(req.AccountNumber) {
+		fields = append(fields, core.FieldError{Field: "account_number", Message: "invalid account number"})
 	}
-	return nil
+	return fieldsError(fields)
 }
 
-func httpError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+// fieldsError returns a validation error listing fields, or nil if there
+// are none.
+func fieldsError(fields []core.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return core.InvalidFields(fields...)
 }
 
 
-func (a *API) getAuthorizedAccount(w http.ResponseWriter, r *http.Request, accountID int) *core.Account {
+// getAuthorizedAccount loads one of the caller's accounts by the account
+// number given in field of the request. It writes an error and returns
+// nil unless the caller owns the account and may use it; other users'
+// accounts are reported as not found.
+func (a *API) getAuthorizedAccount(w http.ResponseWriter, r *http.Request, field, number string) *core.Account {
 	ctx := r.Context()
+	if !core.ValidAccountNumber(number) {
+		a.writeError(w, r, core.InvalidField(field, "invalid account number"))
+		return nil
+	}
 
 	userID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return nil
 	}
 
-	acc, err := a.service.GetAccount(ctx, accountID)
+	acc, err := a.service.GetAccountByNumber(ctx, number)
 	if err != nil {
-		if errors.Is(err, storage.ErrAccountNotFound) {
-			httpError(w, http.StatusNotFound, "account not found")
-			return nil
-		}
-		a.logger.Error("failed to get account", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to retrieve account")
+		a.writeError(w, r, err)
 		return nil
 	}
 
-	if acc.UserID != userID {
-		httpError(w, http.StatusForbidden, "forbidden")
+	if acc.UserID != userID || !principalFrom(ctx).allowsAccount(acc.ID) {
+		a.writeError(w, r, core.ErrAccountNotFound)
 		return nil
 	}
 
 	return acc
 }
 
+// accountFromPath loads the caller's account whose number is in the path.
+func (a *API) accountFromPath(w http.ResponseWriter, r *http.Request) *core.Account {
+	return a.getAuthorizedAccount(w, r, "number", r.PathValue("number"))
+}
+
 func (a *API) GetAccountHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		httpError(w, http.StatusBadRequest, "invalid account id")
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newAccountResponse(acc))
+}
+
+// UpdateAccountHandler renames one of the caller's accounts or makes it
+// their primary account.
+func (a *API) UpdateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req updateAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if req.Name == nil && req.Primary == nil {
+		a.writeError(w, r, core.Invalid("give a name or primary to update"))
+		return
+	}
+	if req.Primary != nil && !*req.Primary {
+		a.writeError(w, r, core.InvalidField("primary", "make another account primary instead"))
 		return
 	}
 
-	acc := a.getAuthorizedAccount(w, r, id)
+	acc := a.accountFromPath(w, r)
 	if acc == nil {
 		return
 	}
 
-	resp := getAccountResponse{
-		ID:        acc.ID,
-		UserID:    acc.UserID,
-		Balance:   acc.Balance,
-		CreatedAt: acc.CreatedAt,
+	acc, err := a.service.UpdateAccount(r.Context(), acc.ID, req.Name, req.Primary != nil)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
 	}
 
-	jsonResponse(w, http.StatusOK, resp)
+	jsonResponse(w, http.StatusOK, newAccountResponse(acc))
+}
+
+// LookupAccountHandler finds one of the caller's accounts by its account
+// number. Other users' accounts are reported as not found.
+func (a *API) LookupAccountHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.getAuthorizedAccount(w, r, "number", r.URL.Query().Get("number"))
+	if acc == nil {
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newAccountResponse(acc))
 }
 
 func (a *API) GetAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	accounts, err := a.service.ListAccounts(ctx)
 	if err != nil {
-		a.logger.Error("failed to get accounts", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to get accounts")
+		a.writeError(w, r, err)
 		return
 	}
 
-	var accountsResponse []*getAccountResponse
+	var accountsResponse []*accountResponse
 	
 	userID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return
 	}
 
+	caller := principalFrom(ctx)
 	for _, acc := range accounts {
-		if acc.UserID != userID {
+		if acc.UserID != userID || !caller.allowsAccount(acc.ID) {
 			continue
 		}
-		accountsResponse = append(accountsResponse, &getAccountResponse{
-			ID:        acc.ID,
-			UserID:    acc.UserID,
-			Balance:   acc.Balance,
-			CreatedAt: acc.CreatedAt,
-		})
+		accountsResponse = append(accountsResponse, newAccountResponse(acc))
 	}
 
 	resp := getAccountsResponse{
@@ -291,67 +390,68 @@ func (a *API) TransferHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req transferRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httpError(w, http.StatusBadRequest, "invalid JSON body")
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
 		return
 	}
 
 	if err := validateTransferRequest(req); err != nil {
-		httpError(w, http.StatusBadRequest, err.Error())
+		a.writeError(w, r, err)
 		return
 	}
 
 	userID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return
 	}
 
-	fromAccount, err := a.service.GetAccount(ctx, req.FromID)
-	if err != nil {
-		if errors.Is(err, storage.ErrAccountNotFound) {
-			httpError(w, http.StatusNotFound, "sender account not found")
-			return
-		}
-		a.logger.Error("failed to get sender account", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to process transfer")
+	fromAccount := a.getAuthorizedAccount(w, r, "from_account_number", req.FromAccountNumber)
+	if fromAccount == nil {
 		return
 	}
 
-	if fromAccount.UserID != userID {
-		httpError(w, http.StatusForbidden, "you can only transfer from your own accounts")
-		return
+	var toID int
+	var err error
+	if req.BeneficiaryID > 0 {
+		toID, err = a.service.BeneficiaryAccount(ctx, userID, req.BeneficiaryID)
+		if err != nil {
+			a.writeError(w, r, err)
+			return
+		}
+		if toID == fromAccount.ID {
+			a.writeError(w, r, core.InvalidField("beneficiary_id", "sender and receiver accounts cannot be the same"))
+			return
+		}
+	}
+	if req.ToAccountNumber != "" {
+		toAccount, err := a.service.GetAccountByNumber(ctx, req.ToAccountNumber)
+		if err != nil {
+			if errors.Is(err, storage.ErrAccountNotFound) {
+				a.writeError(w, r, core.ErrAccountNotFound.WithMessage("receiver account not found"))
+				return
+			}
+			a.writeError(w, r, err)
+			return
+		}
+		if toAccount.ID == fromAccount.ID {
+			a.writeError(w, r, core.InvalidField("to_account_number", "sender and receiver accounts cannot be the same"))
+			return
+		}
+		toID = toAccount.ID
 	}
 
 	reference := uuid.NewString()
 
-	fromAcc, toAcc, err := a.service.Transfer(ctx, req.FromID, req.ToID, req.Amount, reference)
+	fromAcc, toAcc, err := a.service.Transfer(ctx, fromAccount.ID, toID, req.Amount, reference)
 	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrAccountNotFound):
-			httpError(w, http.StatusNotFound, err.Error())
-		case errors.Is(err, storage.ErrInsufficientFunds):
-			httpError(w, http.StatusUnprocessableEntity, err.Error())
-		default:
-			a.logger.Error("transfer failed", "err", err)
-			httpError(w, http.StatusInternalServerError, "transfer failed")
-		}
+		a.writeError(w, r, err)
 		return
 	}
 
 	resp := transferResponse{
-		FromAccount: &getAccountResponse{
-			ID:        fromAcc.ID,
-			UserID:    fromAcc.UserID,
-			Balance:   fromAcc.Balance,
-			CreatedAt: fromAcc.CreatedAt,
-		},
-		ToAccount: &getAccountResponse{
-			ID:        toAcc.ID,
-			UserID:    toAcc.UserID,
-			Balance:   toAcc.Balance,
-			CreatedAt: toAcc.CreatedAt,
-		},
-		Reference: reference,
+		FromAccount:     newAccountResponse(fromAcc),
+		ToAccountNumber: toAcc.Number,
+		Reference:   reference,
 	}
 
 	jsonResponse(w, http.StatusOK, resp)
@@ -361,65 +461,46 @@ func (a *API) PaymentHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req paymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httpError(w, http.StatusBadRequest, "Invalid JSON body")
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
 		return
 	}
 	if err := validatePaymentRequest(req); err != nil {
-		httpError(w, http.StatusBadRequest, err.Error())
+		a.writeError(w, r, err)
 		return
 	}
 
-	acc := a.getAuthorizedAccount(w, r, req.AccountID)
+	acc := a.getAuthorizedAccount(w, r, "account_number", req.AccountNumber)
 	if acc == nil {
 		return
 	}
 
 	reference := uuid.NewString()
 
-	paymentResp, err := a.service.Payment(ctx, req.AccountID, req.Amount, req.Type, reference)
+	paymentResp, err := a.service.Payment(ctx, acc.ID, req.Amount, req.Type, reference)
 	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrAccountNotFound):
-			httpError(w, http.StatusNotFound, err.Error())
-		case errors.Is(err, storage.ErrInsufficientFunds):
-			httpError(w, http.StatusUnprocessableEntity, err.Error())
-		default:
-			a.logger.Error("payment failed", "type", req.Type, "err", err)
-			errorMessage := fmt.Sprintf("%s failed", req.Type)
-			httpError(w, http.StatusInternalServerError, errorMessage)
-		}
+		a.writeError(w, r, err)
 		return
 	}
 
-	resp := getAccountResponse{
-		ID:        paymentResp.ID,
-		UserID:    paymentResp.UserID,
-		Balance:   paymentResp.Balance,
-		CreatedAt: paymentResp.CreatedAt,
-	}
-	jsonResponse(w, http.StatusOK, resp)
+	jsonResponse(w, http.StatusOK, newAccountResponse(paymentResp))
 }
 
 func (a *API) GetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	idStr := r.PathValue("id")
-	accountID, err := strconv.Atoi(idStr)
-	if err != nil || accountID <= 0 {
-		httpError(w, http.StatusBadRequest, "Invalid account ID")
-		return
-	}
-
-	acc := a.getAuthorizedAccount(w, r, accountID)
+	acc := a.accountFromPath(w, r)
 	if acc == nil {
 		return
 	}
 
-	response, err := a.service.ListTransactions(ctx, accountID)
+	txns, err := a.service.ListTransactions(ctx, acc.ID)
 	if err != nil {
-		a.logger.Error("failed to list transactions", "err", err)
-		httpError(w, http.StatusInternalServerError, "could not retrieve transactions")
+		a.writeError(w, r, err)
 		return
 	}
+	response := make([]*transactionResponse, 0, len(txns))
+	for _, txn := range txns {
+		response = append(response, newTransactionResponse(txn))
+	}
 	jsonResponse(w, http.StatusOK, response)
 }
 
@@ -428,22 +509,60 @@ func (a *API) GetTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("ref")
 
 	if idStr == "" {
-		httpError(w, http.StatusBadRequest, "Invalid transaction reference")
+		a.writeError(w, r, core.InvalidField("ref", "invalid transaction reference"))
 		return
 	}
 	resp, err := a.service.GetTransaction(ctx, idStr)
 	if err != nil {
-		if errors.Is(err, storage.ErrTransactionNotFound) {
-			httpError(w, http.StatusNotFound, "transaction not found")
-			return
-		}
+		a.writeError(w, r, err)
+		return
+	}
 
-		a.logger.Error("failed to get transaction", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to retrieve transaction")
+	// Transactions are only shown to the owner of the account they were
+	// booked on or of its counterparty; to anyone else they do not exist.
+	allowed, err := a.canSeeTransaction(ctx, resp)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	if !allowed {
+		a.writeError(w, r, core.ErrTransactionNotFound)
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, resp)
+	jsonResponse(w, http.StatusOK, newTransactionResponse(resp))
+}
+
+// canSeeTransaction reports whether the caller owns, and may use, the
+// account a transaction was booked on or the counterparty's account.
+func (a *API) canSeeTransaction(ctx context.Context, txn *core.Transaction) (bool, error) {
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		return false, core.ErrUnauthenticated
+	}
+	ids := []int{txn.AccountID}
+	for _, id := range []*int{txn.FromAccountID, txn.ToAccountID} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	caller := principalFrom(ctx)
+	for _, id := range ids {
+		if !caller.allowsAccount(id) {
+			continue
+		}
+		acc, err := a.service.GetAccount(ctx, id)
+		if errors.Is(err, core.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if acc.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (a *API) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -451,27 +570,18 @@ func (a *API) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	var user createUserRequest
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		httpError(w, http.StatusBadRequest, "Invalid user data")
+		a.writeError(w, r, core.Invalid("invalid user data"))
+		return
 	}
 	resp, err := a.service.CreateUser(ctx, user.FirstName, user.LastName, user.Email, user.Password)
 	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrDuplicateEmail):
-			jsonResponse(w, http.StatusConflict, map[string]string{
-				"error": "A user with this email already exists",
-			})
-		default:
-			a.logger.Error("failed to create user", "err", err)
-			jsonResponse(w, http.StatusInternalServerError, map[string]string{
-				"error": "Failed to create user",
-			})
-		}
+		a.writeError(w, r, err)
 		return
 	}
 
 	tokenString, err := a.generateJWTToken(resp.ID)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "failed to generate JWT token")
+		a.writeError(w, r, fmt.Errorf("generate JWT token: %w", err))
 		return
 	}
 	userResponse := createUserResponse{
@@ -488,8 +598,7 @@ func (a *API) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	resp, err := a.service.GetUsers(ctx)
 	if err != nil {
-		a.logger.Error("failed to get users", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to retrieve users")
+		a.writeError(w, r, err)
 		return
 	}
 
@@ -511,32 +620,31 @@ func (a *API) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	userId := r.PathValue("id")
 	id, err := strconv.Atoi(userId)
 	if err != nil {
-		a.logger.Error("invalid user id", "id", userId)
-		httpError(w, http.StatusBadRequest, "invalid user id")
+		a.logger.ErrorContext(r.Context(), "invalid user id", "id", userId)
+		a.writeError(w, r, core.InvalidField("id", "invalid user id"))
 		return
 	}
 
 	if userId == "" {
-		a.logger.Error("missing user id")
-		httpError(w, http.StatusBadRequest, "missing user id")
+		a.logger.ErrorContext(r.Context(), "missing user id")
+		a.writeError(w, r, core.InvalidField("id", "missing user id"))
 		return
 	}
 
 	authUserID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return
 	}
 
 	if id != authUserID {
-		httpError(w, http.StatusForbidden, "forbidden")
+		a.writeError(w, r, core.ErrForbidden)
 		return
 	}
 
 	user, err := a.service.GetUser(ctx, id)
 	if err != nil {
-		a.logger.Error("failed to get user", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to retrieve user")
+		a.writeError(w, r, err)
 		return
 	}
 
@@ -555,6 +663,72 @@ func (a *API) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, response)
 }
 
+type productBalanceResponse struct {
+	Product    string `json:"product"`
+	Accounts   int    `json:"accounts"`
+	Balance    int64  `json:"balance"`
+	PotBalance int64  `json:"pot_balance"`
+}
+
+type userBalancesResponse struct {
+	UserID               int                      `json:"user_id"`
+	Total                int64                    `json:"total"`
+	PrimaryAccountNumber *string                  `json:"primary_account_number"`
+	Products             []productBalanceResponse `json:"products"`
+}
+
+func newUserBalancesResponse(b *core.UserBalances) *userBalancesResponse {
+	res := &userBalancesResponse{
+		UserID:   b.UserID,
+		Total:    b.Total,
+		Products: make([]productBalanceResponse, 0, len(b.Products)),
+	}
+	if b.PrimaryAccountNumber != "" {
+		res.PrimaryAccountNumber = &b.PrimaryAccountNumber
+	}
+	for _, p := range b.Products {
+		res.Products = append(res.Products, productBalanceResponse{
+			Product:    p.Product,
+			Accounts:   p.Accounts,
+			Balance:    p.Balance,
+			PotBalance: p.PotBalance,
+		})
+	}
+	return res
+}
+
+// GetUserBalancesHandler totals the caller's account balances by product.
+// API keys limited to some accounts cannot see the total of all of them.
+func (a *API) GetUserBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid user id"))
+		return
+	}
+
+	authUserID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+	if id != authUserID {
+		a.writeError(w, r, core.ErrForbidden)
+		return
+	}
+	if p := principalFrom(ctx); p.APIKey != nil && len(p.APIKey.AccountIDs) > 0 {
+		a.writeError(w, r, core.Forbidden("API key is limited to some accounts"))
+		return
+	}
+
+	balances, err := a.service.GetUserBalances(ctx, id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newUserBalancesResponse(balances))
+}
+
 func (a *API) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -567,31 +741,30 @@ func (a *API) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	userId := r.PathValue("id")
 	id, err := strconv.Atoi(userId)
 	if err != nil {
-		a.logger.Error("invalid user id", "id", userId)
-		httpError(w, http.StatusBadRequest, "invalid user id")
+		a.logger.ErrorContext(r.Context(), "invalid user id", "id", userId)
+		a.writeError(w, r, core.InvalidField("id", "invalid user id"))
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
 		return
 	}
 
 	authUserID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return
 	}
 
 	if id != authUserID {
-		httpError(w, http.StatusForbidden, "forbidden")
+		a.writeError(w, r, core.ErrForbidden)
 		return
 	}
 
 	user, err := a.service.UpdateUser(ctx, id, updateData.FirstName, updateData.LastName, updateData.Email)
 	if err != nil {
-		a.logger.Error("failed to update user", "err", err)
-		httpError(w, http.StatusInternalServerError, "failed to update user")
+		a.writeError(w, r, err)
 		return
 	}
 
@@ -616,25 +789,24 @@ func (a *API) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	userId := r.PathValue("id")
 	id, err := strconv.Atoi(userId)
 	if err != nil {
-		http.Error(w, "invalid user id", http.StatusBadRequest)
+		a.writeError(w, r, core.InvalidField("id", "invalid user id"))
 		return
 	}
 
 	authUserID, ok := ctx.Value(contextKeyUserID).(int)
 	if !ok {
-		httpError(w, http.StatusUnauthorized, "unauthorized")
+		a.writeError(w, r, core.ErrUnauthenticated)
 		return
 	}
 
 	if id != authUserID {
-		httpError(w, http.StatusForbidden, "forbidden")
+		a.writeError(w, r, core.ErrForbidden)
 		return
 	}
 
 	err = a.service.DeleteUser(ctx, id)
 	if err != nil {
-		a.logger.Error("failed to delete user", "err", err)
-		httpError(w, http.StatusInternalServerError, err.Error())
+		a.writeError(w, r, err)
 		return
 	}
 
@@ -647,32 +819,40 @@ func (a *API) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var request LoginRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		a.writeError(w, r, core.Invalid("invalid request body"))
 		return
 	}
 
-	if request.Email == "" || request.Password == "" {
-		http.Error(w, "email and password are required", http.StatusBadRequest)
+	var fields []core.FieldError
+	if request.Email == "" {
+		fields = append(fields, core.FieldError{Field: "email", Message: "email is required"})
+	}
+	if request.Password == "" {
+		fields = append(fields, core.FieldError{Field: "password", Message: "password is required"})
+	}
+	if err := fieldsError(fields); err != nil {
+		a.writeError(w, r, err)
 		return
 	}
 
 	data, err := a.service.Login(ctx, request.Email, request.Password)
 	if err != nil {
-		// We log the actual error for debugging but return a generic message to the user
-		a.logger.Warn("login failed", "email", request.Email, "err", err)
-		jsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
+		// Unknown emails and wrong passwords are the same error, so the
+		// response does not reveal which accounts exist.
+		a.logger.WarnContext(r.Context(), "login failed", "email", request.Email, "err", err)
+		a.writeError(w, r, err)
 		return
 	}
 
 	token, err := a.generateJWTToken(data.ID)
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		a.writeError(w, r, fmt.Errorf("generate token: %w", err))
 		return
 	}
 
 	refreshToken, err := a.generateRefreshToken(data.ID)
 	if err != nil {
-		http.Error(w, "failed to generate refresh token", http.StatusInternalServerError)
+		a.writeError(w, r, fmt.Errorf("generate refresh token: %w", err))
 		return
 	}
 
@@ -684,30 +864,30 @@ func (a *API) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	var request RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		a.writeError(w, r, core.Invalid("invalid request body"))
 		return
 	}
 
 	key := fmt.Sprintf("session:%s", request.RefreshToken)
 	userIDstr, err := a.redis.Get(ctx, key).Result()
 	if err == redis.Nil {
-		http.Error(w, "invalid token", http.StatusUnauthorized)
+		a.writeError(w, r, core.ErrInvalidToken.WithMessage("invalid refresh token"))
 		return
 	} else if err != nil {
-		http.Error(w, "failed to get token", http.StatusInternalServerError)
+		a.writeError(w, r, fmt.Errorf("get refresh token: %w", err))
 		return
 	}
 	userID, _ := strconv.Atoi(userIDstr)
-	a.logger.Info("Refreshing token for user", "user_id", userIDstr)
+	a.logger.InfoContext(r.Context(), "Refreshing token for user", "user_id", userIDstr)
 	newToken, err := a.generateJWTToken(userID)
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		a.writeError(w, r, fmt.Errorf("generate token: %w", err))
 		return
 	}
 
 	newRefreshToken, err := a.generateRefreshToken(userID)
 	if err != nil {
-		http.Error(w, "failed to generate refresh token", http.StatusInternalServerError)
+		a.writeError(w, r, fmt.Errorf("generate refresh token: %w", err))
 		return
 	}
 
@@ -715,14 +895,16 @@ func (a *API) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *API) generateJWTToken(userID int) (string, error) {
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.MapClaims{
-			"user_id": userID,
-			"exp":     time.Now().Add(time.Minute * 10).Unix(),
-			"app":     "mini-bank",
-		},
-	)
+	return a.signToken(jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(a.accessTokenTTL).Unix(),
+		"app":     "mini-bank",
+	})
+}
+
+// signToken signs claims with the API's JWT secret.
+func (a *API) signToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(a.jwtSecret))
 	if err != nil {
 		return "", err
@@ -736,7 +918,7 @@ func (a *API) generateRefreshToken(userID int) (string, error) {
 
 	key := fmt.Sprintf("session:%s", token)
 
-	err := a.redis.Set(context.Background(), key, userID, time.Hour*24*7).Err()
+	err := a.redis.Set(context.Background(), key, userID, a.refreshTokenTTL).Err()
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,326 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+// maxKYCRequestSize caps a whole submission, documents included. Each
+// document is held to the configured document size by the service.
+const maxKYCRequestSize = 48 << 20
+
+type kycDocumentResponse struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newKYCDocumentResponses(docs []*core.KYCDocument) []*kycDocumentResponse {
+	if docs == nil {
+		return nil
+	}
+	resp := make([]*kycDocumentResponse, len(docs))
+	for i, d := range docs {
+		resp[i] = &kycDocumentResponse{
+			ID:          d.ID,
+			Kind:        d.Kind,
+			Filename:    d.Filename,
+			ContentType: d.ContentType,
+			Size:        d.Size,
+			SHA256:      d.SHA256,
+			CreatedAt:   d.CreatedAt,
+		}
+	}
+	return resp
+}
+
+// kycSubmissionResponse is a submission as its customer sees it, without
+// who reviewed it.
+type kycSubmissionResponse struct {
+	ID             int                    `json:"id"`
+	Tier           string                 `json:"tier"`
+	LegalName      string                 `json:"legal_name"`
+	DateOfBirth    time.Time              `json:"date_of_birth"`
+	Address        string                 `json:"address"`
+	Nationality    string                 `json:"nationality"`
+	DocumentType   string                 `json:"document_type"`
+	DocumentNumber string                 `json:"document_number"`
+	Status         string                 `json:"status"`
+	Note           string                 `json:"note,omitempty"`
+	ReviewedAt     *time.Time             `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Documents      []*kycDocumentResponse `json:"documents"`
+}
+
+func newKYCSubmissionResponse(sub *core.KYCSubmission) *kycSubmissionResponse {
+	return &kycSubmissionResponse{
+		ID:             sub.ID,
+		Tier:           sub.Tier,
+		LegalName:      sub.LegalName,
+		DateOfBirth:    sub.DateOfBirth,
+		Address:        sub.Address,
+		Nationality:    sub.Nationality,
+		DocumentType:   sub.DocumentType,
+		DocumentNumber: sub.DocumentNumber,
+		Status:         sub.Status,
+		Note:           sub.Note,
+		ReviewedAt:     sub.ReviewedAt,
+		CreatedAt:      sub.CreatedAt,
+		Documents:      newKYCDocumentResponses(sub.Documents),
+	}
+}
+
+// kycReviewResponse is a submission as compliance staff see it.
+type kycReviewResponse struct {
+	*kycSubmissionResponse
+	UserID     int    `json:"user_id"`
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+}
+
+func newKYCReviewResponse(sub *core.KYCSubmission) *kycReviewResponse {
+	return &kycReviewResponse{
+		kycSubmissionResponse: newKYCSubmissionResponse(sub),
+		UserID:                sub.UserID,
+		ReviewedBy:            sub.ReviewedBy,
+	}
+}
+
+type kycStatusResponse struct {
+	Tier   string                 `json:"tier"`
+	Latest *kycSubmissionResponse `json:"latest_submission,omitempty"`
+}
+
+func newKYCStatusResponse(st *core.KYCStatus) *kycStatusResponse {
+	resp := &kycStatusResponse{Tier: st.Tier}
+	if st.Latest != nil {
+		resp.Latest = newKYCSubmissionResponse(st.Latest)
+	}
+	return resp
+}
+
+type kycSubmissionListResponse struct {
+	Submissions []*kycReviewResponse `json:"submissions"`
+	// NextAfterID is passed as after_id to fetch the next page. It is
+	// omitted when no submissions matched.
+	NextAfterID *int `json:"next_after_id,omitempty"`
+}
+
+type reviewKYCRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// kycUserID returns the user ID in the path if it is the caller's, or
+// writes an error and returns false.
+func (a *API) kycUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid user id"))
+		return 0, false
+	}
+	authUserID, ok := r.Context().Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return 0, false
+	}
+	if id != authUserID {
+		a.writeError(w, r, core.ErrForbidden)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetKYCStatusHandler returns the caller's KYC tier and latest
+// submission.
+func (a *API) GetKYCStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.kycUserID(w, r)
+	if !ok {
+		return
+	}
+	status, err := a.service.GetKYCStatus(r.Context(), id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newKYCStatusResponse(status))
+}
+
+// SubmitKYCHandler takes the caller's identity data and documents as a
+// multipart form and puts them in the review queue.
+func (a *API) SubmitKYCHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.kycUserID(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxKYCRequestSize)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			a.writeError(w, r, core.Invalid("request must be at most %d bytes", maxKYCRequestSize))
+			return
+		}
+		a.writeError(w, r, core.Invalid("invalid multipart form"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	sub := core.KYCSubmission{
+		Tier:           r.FormValue("tier"),
+		LegalName:      r.FormValue("legal_name"),
+		Address:        r.FormValue("address"),
+		Nationality:    r.FormValue("nationality"),
+		DocumentType:   r.FormValue("document_type"),
+		DocumentNumber: r.FormValue("document_number"),
+	}
+	if v := r.FormValue("date_of_birth"); v != "" {
+		dob, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			a.writeError(w, r, core.InvalidField("date_of_birth", "date_of_birth must be a date, YYYY-MM-DD"))
+			return
+		}
+		sub.DateOfBirth = dob
+	}
+
+	var uploads []core.KYCUpload
+	for _, kind := range []string{core.DocumentIdentity, core.DocumentProofOfAddress} {
+		for _, fh := range r.MultipartForm.File[kind] {
+			f, err := fh.Open()
+			if err != nil {
+				a.writeError(w, r, err)
+				return
+			}
+			defer f.Close()
+			uploads = append(uploads, core.KYCUpload{Kind: kind, Filename: fh.Filename, Body: f})
+		}
+	}
+
+	created, err := a.service.SubmitKYC(r.Context(), id, sub, uploads)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newKYCSubmissionResponse(created))
+}
+
+// GetKYCSubmissionsHandler returns KYC submissions matching the query,
+// oldest first, a page at a time. Pending submissions are the review
+// queue.
+func (a *API) GetKYCSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := core.KYCFilter{Status: q.Get("status")}
+
+	var fields []core.FieldError
+	parseInt := func(name string, min int) int {
+		v := q.Get(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min {
+			fields = append(fields, core.FieldError{Field: name, Message: name + " must be an integer of at least " + strconv.Itoa(min)})
+		}
+		return n
+	}
+	filter.UserID = parseInt("user_id", 1)
+	filter.AfterID = parseInt("after_id", 0)
+	filter.Limit = parseInt("limit", 1)
+	if len(fields) > 0 {
+		a.writeError(w, r, core.InvalidFields(fields...))
+		return
+	}
+
+	subs, err := a.service.ListKYCSubmissions(r.Context(), filter)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := kycSubmissionListResponse{Submissions: make([]*kycReviewResponse, len(subs))}
+	for i, sub := range subs {
+		resp.Submissions[i] = newKYCReviewResponse(sub)
+	}
+	if n := len(subs); n > 0 {
+		next := subs[n-1].ID
+		resp.NextAfterID = &next
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) GetKYCSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid submission id"))
+		return
+	}
+
+	sub, err := a.service.GetKYCSubmission(r.Context(), id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newKYCReviewResponse(sub))
+}
+
+// GetKYCDocumentHandler sends the content of an uploaded document as an
+// attachment, so browsers do not render it inline.
+func (a *API) GetKYCDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid submission id"))
+		return
+	}
+	docID, err := strconv.Atoi(r.PathValue("document_id"))
+	if err != nil || docID <= 0 {
+		a.writeError(w, r, core.InvalidField("document_id", "invalid document id"))
+		return
+	}
+
+	doc, body, err := a.service.OpenKYCDocument(r.Context(), id, docID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil && !errors.Is(err, r.Context().Err()) {
+		a.logger.ErrorContext(r.Context(), "failed to send kyc document", "err", err)
+	}
+}
+
+// ReviewKYCSubmissionHandler approves or rejects a pending submission.
+func (a *API) ReviewKYCSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid submission id"))
+		return
+	}
+
+	var req reviewKYCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+
+	sub, err := a.service.ReviewKYCSubmission(r.Context(), id, req.Status, req.Note)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newKYCReviewResponse(sub))
+}
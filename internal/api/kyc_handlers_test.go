@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+func TestKYCResponsesHideReviewerFromCustomer(t *testing.T) {
+	reviewed := time.Now()
+	sub := &core.KYCSubmission{ID: 3, UserID: 7, Status: core.KYCRejected, Note: "blurry scan", ReviewedBy: "user:1", ReviewedAt: &reviewed}
+
+	customer, err := json.Marshal(newKYCStatusResponse(&core.KYCStatus{UserID: 7, Tier: "basic", Latest: sub}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"reviewed_by"`, `"user_id"`, `"user:1"`} {
+		if strings.Contains(string(customer), field) {
+			t.Errorf("customer response %s contains %s", customer, field)
+		}
+	}
+	if !strings.Contains(string(customer), `"blurry scan"`) {
+		t.Errorf("customer response %s is missing the reviewer's note", customer)
+	}
+
+	staff, err := json.Marshal(newKYCReviewResponse(sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"reviewed_by":"user:1"`, `"user_id":7`, `"id":3`} {
+		if !strings.Contains(string(staff), field) {
+			t.Errorf("staff response %s is missing %s", staff, field)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type limitUsageResponse struct {
+	// Limit and Remaining are null when there is no limit.
+	Limit     *int64     `json:"limit"`
+	Used      int64      `json:"used"`
+	Remaining *int64     `json:"remaining"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+type accountLimitsResponse struct {
+	AccountNumber   string             `json:"account_number"`
+	Product         string             `json:"product"`
+	KYCTier         string             `json:"kyc_tier"`
+	MaxSingle       *int64             `json:"max_single"`
+	DailyOutbound   limitUsageResponse `json:"daily_outbound"`
+	MonthlyOutbound limitUsageResponse `json:"monthly_outbound"`
+	HourlyCount     limitUsageResponse `json:"hourly_count"`
+}
+
+func newLimitUsageResponse(u core.LimitUsage) limitUsageResponse {
+	return limitUsageResponse{Limit: u.Limit, Used: u.Used, Remaining: u.Remaining(), ResetsAt: u.ResetsAt}
+}
+
+// GetAccountLimitsHandler returns the limits on money leaving an account
+// and how much of each is left.
+func (a *API) GetAccountLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return
+	}
+
+	lim, err := a.service.GetAccountLimits(r.Context(), acc.ID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, accountLimitsResponse{
+		AccountNumber:   acc.Number,
+		Product:         lim.Product,
+		KYCTier:         lim.Tier,
+		MaxSingle:       lim.MaxSingle,
+		DailyOutbound:   newLimitUsageResponse(lim.Daily),
+		MonthlyOutbound: newLimitUsageResponse(lim.Monthly),
+		HourlyCount:     newLimitUsageResponse(lim.Hourly),
+	})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"mini-bank/internal/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so scans of
+// random paths do not each get their own series.
+const unmatchedRoute = "unmatched"
+
+// routeHolder is filled in by the router with the pattern a request
+// matched, for middleware that runs outside the router to read.
+type routeHolder struct {
+	pattern string
+}
+
+func setRoute(ctx context.Context, pattern string) {
+	if h, ok := ctx.Value(contextKeyRoute).(*routeHolder); ok {
+		h.pattern = pattern
+	}
+}
+
+// MetricsMiddleware counts requests and their latency by method, route
+// and status.
+func (a *API) MetricsMiddleware(next http.Handler, m *metrics.Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := &routeHolder{pattern: unmatchedRoute}
+		ctx := context.WithValue(r.Context(), contextKeyRoute, route)
+
+		rr := newResponseRecorder(w)
+		next.ServeHTTP(rr, r.WithContext(ctx))
+
+		m.ObserveHTTPRequest(r.Method, route.pattern, rr.statusCode, time.Since(start))
+	})
+}
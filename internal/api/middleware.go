@@ -1,13 +1,26 @@
 package api
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+	"mini-bank/internal/telemetry"
+
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // responseRecorder wraps http.ResponseWriter to capture the status code.
@@ -27,10 +40,98 @@ func (rr *responseRecorder) WriteHeader(statusCode int) {
 	rr.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer to
+// flush streams and adjust deadlines.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rr.statusCode = http.StatusSwitchingProtocols
+	return http.NewResponseController(rr.ResponseWriter).Hijack()
+}
+
 
 type contextKey string
 
-const contextKeyUserID contextKey = "user_id"
+const (
+	contextKeyUserID    contextKey = "user_id"
+	contextKeyPrincipal contextKey = "principal"
+	contextKeyRoute     contextKey = "route"
+	// contextKeyAPIKey holds an API key the rate limiter already
+	// authenticated, so AuthMiddleware need not look it up again.
+	contextKeyAPIKey contextKey = "api_key"
+)
+
+// requestIDHeader carries the ID of a request in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	UserID int
+	// Scopes limits what a delegated caller may do. User sessions are not
+	// delegated and may do anything the user can.
+	Scopes []string
+	// APIKey is set when the caller authenticated with an API key.
+	APIKey *core.APIKey
+	// ClientID is set when the caller is an OAuth client acting for the user.
+	ClientID string
+}
+
+// delegated reports whether the caller acts for the user through an API
+// key or OAuth client rather than as the user themselves.
+func (p *principal) delegated() bool {
+	return p.APIKey != nil || p.ClientID != ""
+}
+
+// allowsAccount reports whether the caller may act on an account it owns.
+// Ownership itself is checked separately against UserID.
+func (p *principal) allowsAccount(accountID int) bool {
+	return p != nil && (p.APIKey == nil || p.APIKey.AllowsAccount(accountID))
+}
+
+// authorizeScopes checks that a delegated caller holds every scope a route
+// requires. Routes without scopes are reserved for user sessions.
+func authorizeScopes(p *principal, scopes []string) error {
+	if !p.delegated() {
+		return nil
+	}
+	if len(scopes) == 0 {
+		return &core.Error{Kind: core.KindForbidden, Code: core.CodeSessionRequired, Message: "this endpoint requires a user session"}
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return &core.Error{Kind: core.KindForbidden, Code: core.CodeMissingScope, Message: "missing scope " + scope}
+		}
+	}
+	return nil
+}
+
+func withPrincipal(ctx context.Context, p *principal) context.Context {
+	ctx = context.WithValue(ctx, contextKeyUserID, p.UserID)
+	ctx = audit.WithActor(ctx, p.actor())
+	return context.WithValue(ctx, contextKeyPrincipal, p)
+}
+
+// actor is how the caller appears in the audit log.
+func (p *principal) actor() core.Actor {
+	userID := p.UserID
+	switch {
+	case p.APIKey != nil:
+		return core.Actor{Type: core.ActorAPIKey, ID: strconv.Itoa(p.APIKey.ID), UserID: &userID}
+	case p.ClientID != "":
+		return core.Actor{Type: core.ActorOAuth, ID: p.ClientID, UserID: &userID}
+	default:
+		return audit.UserActor(userID)
+	}
+}
+
+// principalFrom returns the authenticated caller, or nil outside AuthMiddleware.
+func principalFrom(ctx context.Context) *principal {
+	p, _ := ctx.Value(contextKeyPrincipal).(*principal)
+	return p
+}
 
 // LoggingMiddleware logs details about each incoming request.
 func (a *API) LoggingMiddleware(next http.Handler) http.Handler {
@@ -43,7 +144,7 @@ func (a *API) LoggingMiddleware(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		a.logger.Info("processed request",
+		a.logger.InfoContext(r.Context(), "processed request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"duration", duration,
@@ -53,58 +154,199 @@ func (a *API) LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequestIDMiddleware tags each request with an ID, reusing the one in
+// X-Request-ID when the client or a proxy sent a usable one, and echoes it
+// in the response. The ID is added to the request's span and to every log
+// line written with its context.
+func (a *API) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", id))
+		ctx := telemetry.WithRequestID(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts short IDs made of visible ASCII characters, so a
+// client cannot inject arbitrary text into responses and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 func (a *API) TimeoutMiddleware(next http.Handler, timeout time.Duration) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Streams stay open for as long as the client listens.
+		if isStreamRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
 		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (a *API) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// AuthMiddleware authenticates the caller with either a Bearer JWT or an
+// API key. API keys and OAuth access tokens must hold every scope listed;
+// routes registered without scopes are reserved for user sessions.
+func (a *API) AuthMiddleware(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := apiKeyFromRequest(r); apiKey != "" {
+			a.apiKeyAuth(w, r, next, apiKey, scopes)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			a.writeError(w, r, core.ErrUnauthenticated.WithMessage("Authorization header required"))
 			return
 		}
 
 		tokenString := ""
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-				http.Error(w, "Invalid Authorization Header", http.StatusUnauthorized)
+				a.writeError(w, r, core.ErrUnauthenticated.WithMessage("invalid Authorization header"))
 				return
 			}
 		tokenString = authHeader[7:]
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		info, err := a.VerifyAccessToken(r.Context(), tokenString)
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) {
+				a.logger.WarnContext(r.Context(), "invalid token", "err", err)
+				a.writeError(w, r, core.ErrInvalidToken)
+				return
 			}
-			return []byte(a.jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			a.logger.Warn("invalid token", "err", err)
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			a.writeError(w, r, err)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		p := &principal{UserID: info.UserID, Scopes: info.Scopes, ClientID: info.ClientID}
+		if err := authorizeScopes(p, scopes); err != nil {
+			a.writeError(w, r, err)
 			return
 		}
 
-		userIDFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			http.Error(w, "Invalid user ID in token", http.StatusUnauthorized)
+		ctx := withPrincipal(r.Context(), p)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a *API) apiKeyAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, apiKey string, scopes []string) {
+	key, ok := r.Context().Value(contextKeyAPIKey).(*core.APIKey)
+	if !ok {
+		var err error
+		key, err = a.service.AuthenticateAPIKey(r.Context(), apiKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrInvalidAPIKey) {
+				a.logger.WarnContext(r.Context(), "invalid api key")
+			}
+			a.writeError(w, r, err)
 			return
 		}
+	}
 
-		ctx := context.WithValue(r.Context(), contextKeyUserID, int(userIDFloat))
+	p := &principal{UserID: key.UserID, Scopes: key.Scopes, APIKey: key}
+	if err := authorizeScopes(p, scopes); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+	ctx := withPrincipal(r.Context(), p)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// apiKeyFromRequest returns the API key sent in the X-API-Key header or
+// as "Authorization: ApiKey <key>", if any.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "ApiKey ") {
+		return authHeader[7:]
+	}
+	return ""
+}
+
+// ErrInvalidToken is returned for access tokens that are malformed,
+// expired or revoked.
+var ErrInvalidToken = core.ErrInvalidToken
+
+// TokenInfo describes the bearer of a verified access token.
+type TokenInfo struct {
+	UserID int
+	// ClientID and Scopes are set for tokens issued to OAuth clients, which
+	// only carry the scopes the user consented to.
+	ClientID string
+	Scopes   []string
+}
+
+// VerifyAccessToken checks a session or OAuth access token, including
+// whether an OAuth token has since been revoked.
+func (a *API) VerifyAccessToken(ctx context.Context, tokenString string) (*TokenInfo, error) {
+	claims, err := a.parseToken(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing user id", ErrInvalidToken)
+	}
+
+	info := &TokenInfo{UserID: int(userID)}
+	if clientID, ok := claims["client_id"].(string); ok {
+		revoked, err := a.oauthTokenRevoked(ctx, claims)
+		if err != nil {
+			return nil, fmt.Errorf("check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("%w: revoked", ErrInvalidToken)
+		}
+		scope, _ := claims["scope"].(string)
+		info.ClientID = clientID
+		info.Scopes = strings.Fields(scope)
+	}
+	return info, nil
+}
+
+// IssueAccessToken signs a session token for the user, as returned by login.
+func (a *API) IssueAccessToken(userID int) (string, error) {
+	return a.generateJWTToken(userID)
+}
+
+// parseToken verifies a signed JWT and returns its claims.
+func (a *API) parseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(a.jwtSecret), nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
 }
 
 func (a *API) AuthenticationMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -112,11 +354,11 @@ func (a *API) AuthenticationMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		authHeader := r.Header.Get("Authorization")
 
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			a.writeError(w, r, core.ErrUnauthenticated.WithMessage("Authorization header required"))
 			return
 		}
 		if !strings.HasPrefix(authHeader, "Bearer") {
-			http.Error(w, "Invalid Authorization Header", http.StatusUnauthorized)
+			a.writeError(w, r, core.ErrUnauthenticated.WithMessage("invalid Authorization header"))
 			return
 		}
 		tokenString := authHeader[7:]
@@ -125,7 +367,7 @@ func (a *API) AuthenticationMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return []byte(a.jwtSecret), nil
 		})
 		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token or expired token", http.StatusUnauthorized)
+			a.writeError(w, r, core.ErrInvalidToken)
 			return
 		}
 
@@ -0,0 +1,772 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	oauthCodeTTL         = 5 * time.Minute
+	oauthAccessTokenTTL  = 10 * time.Minute
+	oauthRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Redis keys used by the OAuth server. Authorization codes, refresh tokens
+// and revocations are short-lived, so they live next to session tokens.
+func oauthCodeKey(code string) string     { return "oauth:code:" + code }
+func oauthRefreshKey(token string) string { return "oauth:refresh:" + token }
+func oauthRevokedKey(jti string) string   { return "oauth:revoked:" + jti }
+func oauthGrantKey(userID int, clientID string) string {
+	return fmt.Sprintf("oauth:grant:%d:%s", userID, clientID)
+}
+
+// authorizationCode is what an issued code stands for until it is redeemed.
+type authorizationCode struct {
+	ClientID      string   `json:"client_id"`
+	UserID        int      `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+}
+
+// oauthGrant is what a refresh token stands for.
+type oauthGrant struct {
+	ClientID string   `json:"client_id"`
+	UserID   int      `json:"user_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+type registerOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	// ClientSecret is only returned on registration.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type authorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	// Approve is the user's decision when submitting the consent form.
+	Approve bool `json:"approve"`
+}
+
+type authorizeResponse struct {
+	// RedirectTo is where the user agent should be sent next.
+	RedirectTo      string               `json:"redirect_to,omitempty"`
+	ConsentRequired bool                 `json:"consent_required,omitempty"`
+	Client          *oauthClientResponse `json:"client,omitempty"`
+	Scopes          []string             `json:"scopes,omitempty"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	UserID    int    `json:"user_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
+
+type oauthConsentResponse struct {
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+func newOAuthClientResponse(c *core.OAuthClient, secret string) *oauthClientResponse {
+	return &oauthClientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       c.Scopes,
+		Confidential: c.Confidential(),
+		CreatedAt:    c.CreatedAt,
+		ClientSecret: secret,
+	}
+}
+
+// oauthProtocolPaths are the endpoints OAuth clients call directly. They
+// answer with RFC 6749 errors rather than problem details.
+var oauthProtocolPaths = map[string]bool{
+	"/api/v1/oauth/token":      true,
+	"/api/v1/oauth/introspect": true,
+	"/api/v1/oauth/revoke":     true,
+}
+
+// oauthError writes an RFC 6749 error response.
+func oauthError(w http.ResponseWriter, status int, code string, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mini-bank"`)
+	}
+	jsonResponse(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func validateRegisterOAuthClientRequest(req registerOAuthClientRequest) error {
+	if req.Name == "" {
+		return core.InvalidField("name", "name is required")
+	}
+	if len(req.RedirectURIs) == 0 {
+		return core.InvalidField("redirect_uris", "at least one redirect URI is required")
+	}
+	for _, raw := range req.RedirectURIs {
+		u, err := url.Parse(raw)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return core.InvalidField("redirect_uris", fmt.Sprintf("invalid redirect URI %q", raw))
+		}
+		if u.Scheme != "https" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+			return core.InvalidField("redirect_uris", fmt.Sprintf("redirect URI %q must use https", raw))
+		}
+	}
+	if len(req.Scopes) == 0 {
+		return core.InvalidField("scopes", "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !core.ValidScope(scope) {
+			return core.InvalidField("scopes", fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+	return nil
+}
+
+func (a *API) RegisterOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req registerOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if err := validateRegisterOAuthClientRequest(req); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	client, secret, err := a.service.RegisterOAuthClient(ctx, userID, req.Name, req.RedirectURIs, req.Scopes, req.Confidential)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newOAuthClientResponse(client, secret))
+}
+
+func (a *API) GetOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	clients, err := a.service.ListOAuthClients(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := make([]*oauthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		resp = append(resp, newOAuthClientResponse(c, ""))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// AuthorizeHandler starts the authorization code flow for the signed-in
+// user. If the user already consented to the requested scopes a code is
+// issued straight away; otherwise the client and scopes are returned so
+// the user can be asked for consent.
+func (a *API) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := authorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+	a.authorize(w, r, req, false)
+}
+
+// ConsentHandler records the signed-in user's decision on a consent
+// prompt returned by AuthorizeHandler.
+func (a *API) ConsentHandler(w http.ResponseWriter, r *http.Request) {
+	var req authorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	a.authorize(w, r, req, true)
+}
+
+func (a *API) authorize(w http.ResponseWriter, r *http.Request, req authorizeRequest, decided bool) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	// Until the client and redirect URI are known to be genuine, errors
+	// must not be sent to the redirect URI.
+	client, err := a.service.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOAuthClientNotFound) {
+			oauthError(w, http.StatusBadRequest, "invalid_request", "unknown client_id")
+			return
+		}
+		a.writeError(w, r, err)
+		return
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client")
+		return
+	}
+
+	redirect := func(params url.Values) {
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		jsonResponse(w, http.StatusOK, authorizeResponse{RedirectTo: withQuery(req.RedirectURI, params)})
+	}
+	redirectError := func(code, description string) {
+		redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	if req.ResponseType != "code" {
+		redirectError("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		redirectError("invalid_request", "PKCE with code_challenge_method S256 is required")
+		return
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		redirectError("invalid_scope", "scope is required")
+		return
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			redirectError("invalid_scope", "client may not request scope "+scope)
+			return
+		}
+	}
+
+	if decided {
+		if !req.Approve {
+			redirectError("access_denied", "the user denied the request")
+			return
+		}
+		if _, err := a.service.GrantOAuthConsent(ctx, userID, client.ID, scopes); err != nil {
+			a.writeError(w, r, err)
+			return
+		}
+	} else {
+		consent, err := a.service.GetOAuthConsent(ctx, userID, client.ID)
+		if err != nil && !errors.Is(err, storage.ErrConsentNotFound) {
+			a.writeError(w, r, err)
+			return
+		}
+		if consent == nil || !consent.Covers(scopes) {
+			jsonResponse(w, http.StatusOK, authorizeResponse{
+				ConsentRequired: true,
+				Client:          &oauthClientResponse{ClientID: client.ID, Name: client.Name},
+				Scopes:          scopes,
+			})
+			return
+		}
+	}
+
+	code := uuid.NewString()
+	payload, _ := json.Marshal(authorizationCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err := a.redis.Set(ctx, oauthCodeKey(code), payload, oauthCodeTTL).Err(); err != nil {
+		a.writeError(w, r, fmt.Errorf("store authorization code: %w", err))
+		return
+	}
+
+	redirect(url.Values{"code": {code}})
+}
+
+// TokenHandler implements the token endpoint for the authorization_code,
+// refresh_token and client_credentials grants.
+func (a *API) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+	client, ok := a.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		a.authorizationCodeGrant(w, r, client)
+	case "refresh_token":
+		a.refreshTokenGrant(w, r, client)
+	case "client_credentials":
+		a.clientCredentialsGrant(w, r, client)
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type")
+	}
+}
+
+func (a *API) authorizationCodeGrant(w http.ResponseWriter, r *http.Request, client *core.OAuthClient) {
+	ctx := r.Context()
+	raw, err := a.redis.GetDel(ctx, oauthCodeKey(r.PostForm.Get("code"))).Bytes()
+	if errors.Is(err, redis.Nil) {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired code")
+		return
+	} else if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to redeem authorization code", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to redeem code")
+		return
+	}
+
+	var code authorizationCode
+	if err := json.Unmarshal(raw, &code); err != nil {
+		a.logger.ErrorContext(r.Context(), "corrupt authorization code", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to redeem code")
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectURI != r.PostForm.Get("redirect_uri") {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "code was not issued to this client or redirect_uri")
+		return
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, r.PostForm.Get("code_verifier")) {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+		return
+	}
+
+	a.issueTokens(w, r, oauthGrant{ClientID: client.ID, UserID: code.UserID, Scopes: code.Scopes}, true)
+}
+
+func (a *API) refreshTokenGrant(w http.ResponseWriter, r *http.Request, client *core.OAuthClient) {
+	ctx := r.Context()
+	token := r.PostForm.Get("refresh_token")
+	// Refresh tokens are single use: redeeming one rotates it.
+	raw, err := a.redis.GetDel(ctx, oauthRefreshKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+		return
+	} else if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to redeem refresh token", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to redeem refresh token")
+		return
+	}
+
+	var grant oauthGrant
+	if err := json.Unmarshal(raw, &grant); err != nil {
+		a.logger.ErrorContext(r.Context(), "corrupt refresh token", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to redeem refresh token")
+		return
+	}
+	if grant.ClientID != client.ID {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh token was not issued to this client")
+		return
+	}
+	a.redis.SRem(ctx, oauthGrantKey(grant.UserID, grant.ClientID), token)
+
+	// A client may narrow, but never widen, the scopes of a refreshed token.
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(grant.Scopes, scope) {
+				oauthError(w, http.StatusBadRequest, "invalid_scope", "scope exceeds the original grant")
+				return
+			}
+		}
+		grant.Scopes = requested
+	}
+
+	a.issueTokens(w, r, grant, true)
+}
+
+// clientCredentialsGrant lets a confidential client act on its own
+// behalf, which is as the user who registered it.
+func (a *API) clientCredentialsGrant(w http.ResponseWriter, r *http.Request, client *core.OAuthClient) {
+	if !client.Confidential() {
+		oauthError(w, http.StatusUnauthorized, "unauthorized_client", "public clients cannot use client_credentials")
+		return
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(client.Scopes, scope) {
+				oauthError(w, http.StatusBadRequest, "invalid_scope", "client may not request scope "+scope)
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	a.issueTokens(w, r, oauthGrant{ClientID: client.ID, UserID: client.OwnerUserID, Scopes: scopes}, false)
+}
+
+// issueTokens issues an access token and, optionally, a refresh token for
+// a grant. Grants made on behalf of a user require an active consent.
+func (a *API) issueTokens(w http.ResponseWriter, r *http.Request, grant oauthGrant, withRefresh bool) {
+	ctx := r.Context()
+	if withRefresh {
+		consent, err := a.service.GetOAuthConsent(ctx, grant.UserID, grant.ClientID)
+		if err != nil && !errors.Is(err, storage.ErrConsentNotFound) {
+			a.logger.ErrorContext(r.Context(), "failed to get consent", "err", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "failed to issue token")
+			return
+		}
+		if consent == nil || !consent.Covers(grant.Scopes) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "consent has been revoked")
+			return
+		}
+	}
+
+	now := time.Now()
+	accessToken, err := a.signToken(jwt.MapClaims{
+		"user_id":   grant.UserID,
+		"client_id": grant.ClientID,
+		"scope":     strings.Join(grant.Scopes, " "),
+		"jti":       uuid.NewString(),
+		"iat":       now.Unix(),
+		"exp":       now.Add(oauthAccessTokenTTL).Unix(),
+		"app":       "mini-bank",
+	})
+	if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to sign access token", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to issue token")
+		return
+	}
+
+	resp := tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenTTL.Seconds()),
+		Scope:       strings.Join(grant.Scopes, " "),
+	}
+
+	if withRefresh {
+		refreshToken := uuid.NewString()
+		payload, _ := json.Marshal(grant)
+		grantKey := oauthGrantKey(grant.UserID, grant.ClientID)
+		pipe := a.redis.TxPipeline()
+		pipe.Set(ctx, oauthRefreshKey(refreshToken), payload, oauthRefreshTokenTTL)
+		pipe.SAdd(ctx, grantKey, refreshToken)
+		pipe.Expire(ctx, grantKey, oauthRefreshTokenTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			a.logger.ErrorContext(r.Context(), "failed to store refresh token", "err", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "failed to issue token")
+			return
+		}
+		resp.RefreshToken = refreshToken
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// IntrospectHandler reports whether a token issued to the calling client
+// is active (RFC 7662).
+func (a *API) IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+	client, ok := a.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	if claims, err := a.parseToken(token); err == nil {
+		clientID, _ := claims["client_id"].(string)
+		revoked, err := a.oauthTokenRevoked(ctx, claims)
+		if err != nil {
+			a.logger.ErrorContext(r.Context(), "failed to check token revocation", "err", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "failed to introspect token")
+			return
+		}
+		if clientID != client.ID || revoked {
+			jsonResponse(w, http.StatusOK, introspectionResponse{Active: false})
+			return
+		}
+		userID, _ := claims["user_id"].(float64)
+		scope, _ := claims["scope"].(string)
+		exp, _ := claims["exp"].(float64)
+		iat, _ := claims["iat"].(float64)
+		jsonResponse(w, http.StatusOK, introspectionResponse{
+			Active:    true,
+			Scope:     scope,
+			ClientID:  clientID,
+			UserID:    int(userID),
+			TokenType: "access_token",
+			Exp:       int64(exp),
+			Iat:       int64(iat),
+		})
+		return
+	}
+
+	raw, err := a.redis.Get(ctx, oauthRefreshKey(token)).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		a.logger.ErrorContext(r.Context(), "failed to look up refresh token", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to introspect token")
+		return
+	}
+	var grant oauthGrant
+	if err != nil || json.Unmarshal(raw, &grant) != nil || grant.ClientID != client.ID {
+		jsonResponse(w, http.StatusOK, introspectionResponse{Active: false})
+		return
+	}
+	jsonResponse(w, http.StatusOK, introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(grant.Scopes, " "),
+		ClientID:  grant.ClientID,
+		UserID:    grant.UserID,
+		TokenType: "refresh_token",
+	})
+}
+
+// RevokeHandler revokes an access or refresh token issued to the calling
+// client (RFC 7009). Unknown tokens are ignored.
+func (a *API) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+	client, ok := a.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	var err error
+	if claims, parseErr := a.parseToken(token); parseErr == nil {
+		err = a.revokeAccessToken(ctx, claims, client.ID)
+	} else {
+		err = a.revokeRefreshToken(ctx, token, client.ID)
+	}
+	if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to revoke token", "err", err)
+		oauthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "failed to revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *API) revokeAccessToken(ctx context.Context, claims jwt.MapClaims, clientID string) error {
+	if tokenClient, _ := claims["client_id"].(string); tokenClient != clientID {
+		return nil
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if jti == "" || err != nil || exp == nil {
+		return nil
+	}
+	// Remember the revocation until the token would have expired anyway.
+	return a.redis.Set(ctx, oauthRevokedKey(jti), 1, time.Until(exp.Time)).Err()
+}
+
+func (a *API) revokeRefreshToken(ctx context.Context, token string, clientID string) error {
+	raw, err := a.redis.Get(ctx, oauthRefreshKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var grant oauthGrant
+	if err := json.Unmarshal(raw, &grant); err != nil || grant.ClientID != clientID {
+		return nil
+	}
+	pipe := a.redis.TxPipeline()
+	pipe.Del(ctx, oauthRefreshKey(token))
+	pipe.SRem(ctx, oauthGrantKey(grant.UserID, grant.ClientID), token)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (a *API) GetOAuthConsentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	consents, err := a.service.ListOAuthConsents(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := make([]*oauthConsentResponse, 0, len(consents))
+	for _, c := range consents {
+		resp = append(resp, &oauthConsentResponse{ClientID: c.ClientID, Scopes: c.Scopes, GrantedAt: c.GrantedAt})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// RevokeOAuthConsentHandler withdraws a user's consent for a client and
+// invalidates every token the client holds for the user.
+func (a *API) RevokeOAuthConsentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientID := r.PathValue("client_id")
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if err := a.service.RevokeOAuthConsent(ctx, userID, clientID); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	if err := a.revokeGrant(ctx, userID, clientID); err != nil {
+		a.writeError(w, r, fmt.Errorf("revoke tokens for consent of client %s: %w", clientID, err))
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "consent revoked"})
+}
+
+// revokeGrant deletes a grant's refresh tokens and rejects access tokens
+// issued before now.
+func (a *API) revokeGrant(ctx context.Context, userID int, clientID string) error {
+	grantKey := oauthGrantKey(userID, clientID)
+	tokens, err := a.redis.SMembers(ctx, grantKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := a.redis.TxPipeline()
+	for _, token := range tokens {
+		pipe.Del(ctx, oauthRefreshKey(token))
+	}
+	pipe.Del(ctx, grantKey)
+	pipe.Set(ctx, grantKey+":revoked_at", time.Now().Unix(), oauthAccessTokenTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// oauthTokenRevoked reports whether an OAuth access token was revoked
+// directly or by withdrawing the consent it was issued under.
+func (a *API) oauthTokenRevoked(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	clientID, _ := claims["client_id"].(string)
+	userID, _ := claims["user_id"].(float64)
+	iat, _ := claims["iat"].(float64)
+
+	vals, err := a.redis.MGet(ctx, oauthRevokedKey(jti), oauthGrantKey(int(userID), clientID)+":revoked_at").Result()
+	if err != nil {
+		return false, err
+	}
+	if vals[0] != nil {
+		return true, nil
+	}
+	if revokedAt, ok := vals[1].(string); ok {
+		ts, _ := strconv.ParseInt(revokedAt, 10, 64)
+		return int64(iat) <= ts, nil
+	}
+	return false, nil
+}
+
+// authenticateClient authenticates the calling client with HTTP Basic
+// credentials or client_id/client_secret form fields.
+func (a *API) authenticateClient(w http.ResponseWriter, r *http.Request) (*core.OAuthClient, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, err := a.service.AuthenticateOAuthClient(r.Context(), clientID, secret)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidClient) {
+			oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+			return nil, false
+		}
+		a.logger.ErrorContext(r.Context(), "failed to authenticate oauth client", "err", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to authenticate client")
+		return nil, false
+	}
+	return client, true
+}
+
+// verifyCodeChallenge checks a PKCE code_verifier against its S256 challenge.
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+func withQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
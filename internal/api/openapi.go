@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"mini-bank/internal/core"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+//go:embed openapi.yaml
+var openAPIYAML []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+var emailPattern = regexp.MustCompile(openapi3.FormatOfStringForEmail)
+
+// openAPISpec is the API description, parsed and checked once.
+type openAPISpec struct {
+	doc  *openapi3.T
+	json []byte
+}
+
+var loadSpec = sync.OnceValues(func() (*openAPISpec, error) {
+	openapi3.DefineStringFormatCallback("email", func(v string) error {
+		if !emailPattern.MatchString(v) {
+			return errors.New("not an email address")
+		}
+		return nil
+	})
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(openAPIYAML)
+	if err != nil {
+		return nil, fmt.Errorf("parse openapi.yaml: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi.yaml: %w", err)
+	}
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &openAPISpec{doc: doc, json: data}, nil
+})
+
+// route finds the operation documented for a mux pattern such as
+// "GET /api/v1/accounts/{id}".
+func (s *openAPISpec) route(pattern string) (*routers.Route, error) {
+	method, path, ok := strings.Cut(strings.Join(strings.Fields(pattern), " "), " ")
+	if !ok {
+		return nil, fmt.Errorf("route %q has no method", pattern)
+	}
+	item := s.doc.Paths.Find(path)
+	if item == nil || item.GetOperation(method) == nil {
+		return nil, fmt.Errorf("route %q is missing from openapi.yaml", pattern)
+	}
+	return &routers.Route{
+		Spec:      s.doc,
+		Path:      path,
+		PathItem:  item,
+		Method:    method,
+		Operation: item.GetOperation(method),
+	}, nil
+}
+
+// checkCoverage reports routes missing from the spec and documented
+// operations that no route serves.
+func (s *openAPISpec) checkCoverage(routes []route) error {
+	var errs []error
+	served := make(map[string]bool)
+	for _, rt := range routes {
+		r, err := s.route(rt.pattern)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		served[r.Method+" "+r.Path] = true
+	}
+	for _, path := range s.doc.Paths.InMatchingOrder() {
+		for method := range s.doc.Paths.Value(path).Operations() {
+			if !served[method+" "+path] {
+				errs = append(errs, fmt.Errorf("openapi.yaml documents %s %s, which no route serves", method, path))
+			}
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// validateRequest rejects requests that do not match the route's
+// documented parameters and body. Authentication is left to AuthMiddleware.
+func (a *API) validateRequest(route *routers.Route, next http.HandlerFunc) http.HandlerFunc {
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         true,
+	}
+	jsonOnly := false
+	if body := route.Operation.RequestBody; body != nil {
+		content := body.Value.Content
+		jsonOnly = len(content) == 1 && content.Get("application/json") != nil
+		// Uploads would be read into memory whole to validate them; their
+		// handlers parse them under a size limit instead.
+		if len(content) == 1 && content.Get("multipart/form-data") != nil {
+			options.ExcludeRequestBody = true
+		}
+	}
+	oauthProtocol := oauthProtocolPaths[route.Path]
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Handlers have always decoded JSON bodies whatever the declared
+		// Content-Type, so keep accepting e.g. plain `curl -d` requests.
+		if jsonOnly {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+		}
+
+		pathParams := make(map[string]string)
+		for _, p := range append(route.PathItem.Parameters, route.Operation.Parameters...) {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+				pathParams[p.Value.Name] = r.PathValue(p.Value.Name)
+			}
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			verr := validationError(err)
+			if oauthProtocol {
+				oauthError(w, http.StatusBadRequest, "invalid_request", verr.Error())
+				return
+			}
+			a.writeError(w, r, verr)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validationError describes a validation failure field by field, without
+// echoing the offending values back.
+func validationError(err error) *core.Error {
+	var fields []core.FieldError
+	for _, reqErr := range requestErrors(err) {
+		if reqErr.RequestBody != nil {
+			var parseErr *openapi3filter.ParseError
+			if errors.As(reqErr.Err, &parseErr) {
+				return core.Invalid("invalid JSON body")
+			}
+		}
+
+		where := "body"
+		if reqErr.Parameter != nil {
+			where = reqErr.Parameter.Name
+		}
+		fields = append(fields, fieldErrors(reqErr, where)...)
+	}
+	if len(fields) == 0 {
+		return core.Invalid("invalid request")
+	}
+
+	e := core.InvalidFields(fields...)
+	if len(fields) == 1 {
+		e.Message = fmt.Sprintf("invalid field %q: %s", fields[0].Field, fields[0].Message)
+	}
+	return e
+}
+
+// requestErrors flattens the errors reported for a request.
+func requestErrors(err error) []*openapi3filter.RequestError {
+	switch err := err.(type) {
+	case *openapi3filter.RequestError:
+		return []*openapi3filter.RequestError{err}
+	case openapi3.MultiError:
+		var out []*openapi3filter.RequestError
+		for _, e := range err {
+			out = append(out, requestErrors(e)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// fieldErrors lists the problems found in one parameter or the body.
+// Schema errors inside the body name the offending field themselves.
+func fieldErrors(reqErr *openapi3filter.RequestError, where string) []core.FieldError {
+	var schemaErrs []*openapi3.SchemaError
+	var me openapi3.MultiError
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(reqErr.Err, &me):
+		for _, e := range me {
+			if errors.As(e, &schemaErr) {
+				schemaErrs = append(schemaErrs, schemaErr)
+			}
+		}
+	case errors.As(reqErr.Err, &schemaErr):
+		schemaErrs = append(schemaErrs, schemaErr)
+	}
+
+	var fields []core.FieldError
+	for _, se := range schemaErrs {
+		field := where
+		if reqErr.Parameter == nil {
+			if path := strings.Join(se.JSONPointer(), "."); path != "" {
+				field = path
+			}
+		}
+		fields = append(fields, core.FieldError{Field: field, Message: se.Reason})
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+
+	message := "is invalid"
+	var parseErr *openapi3filter.ParseError
+	switch {
+	case errors.Is(reqErr.Err, openapi3filter.ErrInvalidRequired):
+		message = "is required"
+	case errors.As(reqErr.Err, &parseErr) && parseErr.Reason != "":
+		message = parseErr.Reason
+	case reqErr.Reason != "":
+		message = reqErr.Reason
+	}
+	return []core.FieldError{{Field: where, Message: message}}
+}
+
+// OpenAPIHandler serves the API description as JSON.
+func (a *API) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	spec, err := loadSpec()
+	if err != nil {
+		a.writeError(w, r, fmt.Errorf("load API description: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec.json)
+}
+
+// DocsHandler serves a page rendering the API description.
+func (a *API) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsHTML)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+
+	"github.com/google/uuid"
+)
+
+type createPotRequest struct {
+	Name         string     `json:"name"`
+	TargetAmount *int64     `json:"target_amount"`
+	TargetDate   *time.Time `json:"target_date"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	RoundUp      bool       `json:"round_up"`
+}
+
+type updatePotRequest struct {
+	Name         *string    `json:"name"`
+	TargetAmount *int64     `json:"target_amount"`
+	TargetDate   *time.Time `json:"target_date"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	RoundUp      *bool      `json:"round_up"`
+}
+
+type movePotRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+type potResponse struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Balance      int64      `json:"balance"`
+	TargetAmount *int64     `json:"target_amount,omitempty"`
+	TargetDate   *time.Time `json:"target_date,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	RoundUp      bool       `json:"round_up"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func newPotResponse(p *core.Pot) *potResponse {
+	return &potResponse{
+		ID:           p.ID,
+		Name:         p.Name,
+		Balance:      p.Balance,
+		TargetAmount: p.TargetAmount,
+		TargetDate:   p.TargetDate,
+		LockedUntil:  p.LockedUntil,
+		RoundUp:      p.RoundUp,
+		CreatedAt:    p.CreatedAt,
+	}
+}
+
+type movePotResponse struct {
+	Pot       *potResponse     `json:"pot"`
+	Account   *accountResponse `json:"account"`
+	Reference string           `json:"reference"`
+}
+
+// getAuthorizedPot loads the pot named in the path. It writes an error
+// and returns nil unless the pot is in the account named in the path and
+// the caller may use that account.
+func (a *API) getAuthorizedPot(w http.ResponseWriter, r *http.Request) *core.Pot {
+	potID, err := strconv.Atoi(r.PathValue("pot_id"))
+	if err != nil || potID <= 0 {
+		a.writeError(w, r, core.InvalidField("pot_id", "invalid pot id"))
+		return nil
+	}
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return nil
+	}
+
+	p, err := a.service.GetPot(r.Context(), potID)
+	if err == nil && p.AccountID != acc.ID {
+		err = core.ErrPotNotFound
+	}
+	if err != nil {
+		a.writeError(w, r, err)
+		return nil
+	}
+	return p
+}
+
+func (a *API) CreatePotHandler(w http.ResponseWriter, r *http.Request) {
+	var req createPotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return
+	}
+
+	p, err := a.service.CreatePot(r.Context(), &core.Pot{
+		AccountID:    acc.ID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		TargetDate:   req.TargetDate,
+		LockedUntil:  req.LockedUntil,
+		RoundUp:      req.RoundUp,
+	})
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newPotResponse(p))
+}
+
+func (a *API) GetPotsHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return
+	}
+
+	ps, err := a.service.ListPots(r.Context(), acc.ID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	res := make([]*potResponse, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, newPotResponse(p))
+	}
+	jsonResponse(w, http.StatusOK, res)
+}
+
+func (a *API) UpdatePotHandler(w http.ResponseWriter, r *http.Request) {
+	var req updatePotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	upd := core.PotUpdate(req)
+	if upd == (core.PotUpdate{}) {
+		a.writeError(w, r, core.Invalid("give a field to update"))
+		return
+	}
+
+	p := a.getAuthorizedPot(w, r)
+	if p == nil {
+		return
+	}
+
+	p, err := a.service.UpdatePot(r.Context(), p.ID, upd)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newPotResponse(p))
+}
+
+func (a *API) DeletePotHandler(w http.ResponseWriter, r *http.Request) {
+	p := a.getAuthorizedPot(w, r)
+	if p == nil {
+		return
+	}
+	if err := a.service.DeletePot(r.Context(), p.ID); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "pot deleted"})
+}
+
+// DepositPotHandler moves money from an account's balance into one of its
+// pots.
+func (a *API) DepositPotHandler(w http.ResponseWriter, r *http.Request) {
+	a.movePot(w, r, 1)
+}
+
+// WithdrawPotHandler moves money from a pot back to its account's
+// balance.
+func (a *API) WithdrawPotHandler(w http.ResponseWriter, r *http.Request) {
+	a.movePot(w, r, -1)
+}
+
+// movePot moves the amount in the request into the pot in the path, or
+// out of it if sign is negative.
+func (a *API) movePot(w http.ResponseWriter, r *http.Request, sign int64) {
+	var req movePotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if req.Amount <= 0 {
+		a.writeError(w, r, core.InvalidField("amount", "amount must be positive"))
+		return
+	}
+
+	p := a.getAuthorizedPot(w, r)
+	if p == nil {
+		return
+	}
+
+	reference := uuid.NewString()
+	p, acc, err := a.service.MovePot(r.Context(), p.ID, sign*req.Amount, reference)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := movePotResponse{
+		Pot:       newPotResponse(p),
+		Account:   newAccountResponse(acc),
+		Reference: reference,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/telemetry"
+)
+
+// problemTypePrefix namespaces problem types: a problem's type is this
+// prefix followed by its code, e.g. urn:mini-bank:problem:account_not_found.
+const problemTypePrefix = "urn:mini-bank:problem:"
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []core.FieldError `json:"errors,omitempty"`
+}
+
+// kindStatus maps each kind of domain error to its HTTP status.
+var kindStatus = map[core.Kind]int{
+	core.KindInternal:        http.StatusInternalServerError,
+	core.KindInvalid:         http.StatusBadRequest,
+	core.KindUnauthenticated: http.StatusUnauthorized,
+	core.KindForbidden:       http.StatusForbidden,
+	core.KindNotFound:        http.StatusNotFound,
+	core.KindConflict:        http.StatusConflict,
+	core.KindRejected:        http.StatusUnprocessableEntity,
+	core.KindRateLimited:     http.StatusTooManyRequests,
+}
+
+// writeError responds with err as application/problem+json. Domain errors
+// keep their code and message; anything else is logged and reported as an
+// internal error without its details.
+func (a *API) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *core.Error
+	if !errors.As(err, &e) {
+		a.logger.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+		e = core.Internal("internal error")
+	}
+
+	status, ok := kindStatus[e.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	p := problem{
+		Type:      problemTypePrefix + e.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    e.Message,
+		Instance:  r.URL.Path,
+		Code:      e.Code,
+		RequestID: telemetry.RequestID(r.Context()),
+		Errors:    e.Fields,
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
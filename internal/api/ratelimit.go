@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/config"
+	"mini-bank/internal/core"
+	"mini-bank/internal/ratelimit"
+)
+
+// RateLimitPolicy configures request limits for the API.
+type RateLimitPolicy struct {
+	// Default applies to every route without a more specific limit.
+	Default ratelimit.Limit
+	// Routes holds per-route limits keyed by "METHOD /path".
+	Routes map[string]ratelimit.Limit
+	// TrustForwardedFor uses the first X-Forwarded-For address as the
+	// client IP. Only enable it behind a proxy that sets the header.
+	TrustForwardedFor bool
+}
+
+// NewRateLimitPolicy builds the policy from configured limits. Login,
+// transfers and token issuance get limits of their own, by default
+// stricter than the rest of the API. Payee name checks get the transfer
+// limit too, so they cannot be used to guess who owns accounts.
+func NewRateLimitPolicy(limits config.Limits) RateLimitPolicy {
+	return RateLimitPolicy{
+		Default: limits.Default,
+		Routes: map[string]ratelimit.Limit{
+			"POST /api/v1/login":                       limits.Login,
+			"POST /api/v1/transactions/transfer":       limits.Transfer,
+			"POST /api/v1/beneficiaries":               limits.Transfer,
+			"POST /api/v1/beneficiaries/confirm-payee": limits.Transfer,
+			"POST /api/v1/oauth/token":                 limits.Token,
+		},
+		TrustForwardedFor: limits.TrustForwardedFor,
+	}
+}
+
+// Limit returns the limit for route, "METHOD /path", and the name of the
+// bucket it is counted in: the route itself if it has a limit of its own,
+// or "default".
+func (p RateLimitPolicy) Limit(route string) (string, ratelimit.Limit) {
+	if limit, ok := p.Routes[route]; ok {
+		return route, limit
+	}
+	return "default", p.Default
+}
+
+// RateLimitMiddleware rejects requests exceeding the policy's limits with
+// 429 Too Many Requests. Clients are identified by user ID, authenticated
+// API key or IP address, in that order of preference.
+func (a *API) RateLimitMiddleware(next http.Handler, limiter ratelimit.Limiter, policy RateLimitPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, limit := policy.Limit(r.Method + " " + r.URL.Path)
+		subject, r := a.rateLimitSubject(r, policy.TrustForwardedFor)
+		key := route + "|" + subject
+		res, err := limiter.Allow(r.Context(), key, limit)
+		if err != nil {
+			// Fail open: an unavailable limiter should not take the API down.
+			a.logger.ErrorContext(r.Context(), "rate limiter failed", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+
+		if !res.Allowed {
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			a.writeError(w, r, core.ErrRateLimited)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitSubject identifies the client a request is counted against. An
+// API key only identifies the client once it has been authenticated, so
+// made-up keys cannot each get a bucket of their own; the authenticated
+// key is carried on the returned request for AuthMiddleware.
+func (a *API) rateLimitSubject(r *http.Request, trustForwardedFor bool) (string, *http.Request) {
+	if userID, ok := r.Context().Value(contextKeyUserID).(int); ok {
+		return "user:" + strconv.Itoa(userID), r
+	}
+
+	// The limiter runs ahead of AuthMiddleware, so resolve the user from
+	// the bearer token ourselves. Invalid tokens fall through to the IP.
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		if claims, err := a.parseToken(authHeader[7:]); err == nil {
+			if userID, ok := claims["user_id"].(float64); ok {
+				return "user:" + strconv.Itoa(int(userID)), r
+			}
+		}
+	}
+
+	if secret := apiKeyFromRequest(r); secret != "" {
+		if key, err := a.service.AuthenticateAPIKey(r.Context(), secret); err == nil {
+			ctx := context.WithValue(r.Context(), contextKeyAPIKey, key)
+			return "key:" + strconv.Itoa(key.ID), r.WithContext(ctx)
+		}
+	}
+
+	return "ip:" + clientIP(r, trustForwardedFor), r
+}
+
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
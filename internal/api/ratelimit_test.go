@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/ratelimit"
+	"mini-bank/internal/service"
+)
+
+// keyService knows a single API key.
+type keyService struct {
+	service.Service
+	secret string
+	key    *core.APIKey
+}
+
+func (s *keyService) AuthenticateAPIKey(ctx context.Context, secret string) (*core.APIKey, error) {
+	if secret != s.secret {
+		return nil, core.ErrInvalidAPIKey
+	}
+	return s.key, nil
+}
+
+func TestRateLimitKeysOnlyAuthenticatedAPIKeys(t *testing.T) {
+	a := &API{service: &keyService{secret: "mb_real_secret", key: &core.APIKey{ID: 1, UserID: 7}}}
+	var sawKey *core.APIKey
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawKey, _ = r.Context().Value(contextKeyAPIKey).(*core.APIKey)
+	})
+	policy := RateLimitPolicy{Default: ratelimit.Limit{Requests: 1, Window: time.Minute}}
+	h := a.RateLimitMiddleware(next, ratelimit.NewMemoryLimiter(), policy)
+
+	send := func(key string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+		r.RemoteAddr = "203.0.113.9:4000"
+		r.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("mb_bogus_one"); code != http.StatusOK {
+		t.Fatalf("first bogus key: status %d, want 200", code)
+	}
+	if code := send("mb_bogus_two"); code != http.StatusTooManyRequests {
+		t.Errorf("second bogus key: status %d, want 429 from the IP's bucket", code)
+	}
+	if code := send("mb_real_secret"); code != http.StatusOK {
+		t.Errorf("authenticated key: status %d, want 200 from its own bucket", code)
+	}
+	if sawKey == nil || sawKey.ID != 1 {
+		t.Errorf("authenticated key was not passed on to the handler")
+	}
+}
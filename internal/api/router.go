@@ -1,31 +1,157 @@
 package api
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"strings"
 
-func (a *API) Router() http.Handler {
-	mux := http.NewServeMux()
+	"mini-bank/internal/core"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// route is a mux pattern and the handler serving it.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// routes lists every route the API serves. Each one must be documented in
+// openapi.yaml.
+func (a *API) routes() []route {
+	return []route{
+		// Account routes
+		{"POST /api/v1/accounts", a.AuthMiddleware(a.CreateAccountHandler, core.ScopeWriteAccounts)},
+		{"GET /api/v1/accounts", a.AuthMiddleware(a.GetAccountsHandler, core.ScopeReadAccounts)},
+		{"GET /api/v1/accounts/lookup", a.AuthMiddleware(a.LookupAccountHandler, core.ScopeReadAccounts)},
+		{"GET /api/v1/accounts/{number}", a.AuthMiddleware(a.GetAccountHandler, core.ScopeReadAccounts)},
+		{"PATCH /api/v1/accounts/{number}", a.AuthMiddleware(a.UpdateAccountHandler, core.ScopeWriteAccounts)},
+		{"GET /api/v1/accounts/{number}/limits", a.AuthMiddleware(a.GetAccountLimitsHandler, core.ScopeReadAccounts)},
+
+		// Pot routes
+		{"POST /api/v1/accounts/{number}/pots", a.AuthMiddleware(a.CreatePotHandler, core.ScopeWriteAccounts)},
+		{"GET /api/v1/accounts/{number}/pots", a.AuthMiddleware(a.GetPotsHandler, core.ScopeReadAccounts)},
+		{"PATCH /api/v1/accounts/{number}/pots/{pot_id}", a.AuthMiddleware(a.UpdatePotHandler, core.ScopeWriteAccounts)},
+		{"DELETE /api/v1/accounts/{number}/pots/{pot_id}", a.AuthMiddleware(a.DeletePotHandler, core.ScopeWriteAccounts)},
+		{"POST /api/v1/accounts/{number}/pots/{pot_id}/deposit", a.AuthMiddleware(a.DepositPotHandler, core.ScopeWriteAccounts)},
+		{"POST /api/v1/accounts/{number}/pots/{pot_id}/withdraw", a.AuthMiddleware(a.WithdrawPotHandler, core.ScopeWriteAccounts)},
+
+		// Transaction routes
+		{"POST /api/v1/transactions/transfer", a.AuthMiddleware(a.TransferHandler, core.ScopeWriteTransfers)},
+		{"POST /api/v1/transactions/payment", a.AuthMiddleware(a.PaymentHandler, core.ScopeWritePayments)},
+		{"GET /api/v1/accounts/{number}/transactions", a.AuthMiddleware(a.GetTransactionsHandler, core.ScopeReadTransactions)},
+		{"GET /api/v1/accounts/{number}/stream", a.AuthMiddleware(a.StreamAccountHandler, core.ScopeReadAccounts, core.ScopeReadTransactions)},
+		{"GET /api/v1/transactions/{ref}", a.AuthMiddleware(a.GetTransactionHandler, core.ScopeReadTransactions)},
+
+		// Beneficiary routes
+		{"POST /api/v1/beneficiaries", a.AuthMiddleware(a.CreateBeneficiaryHandler, core.ScopeWriteTransfers)},
+		{"GET /api/v1/beneficiaries", a.AuthMiddleware(a.GetBeneficiariesHandler, core.ScopeWriteTransfers)},
+		{"DELETE /api/v1/beneficiaries/{id}", a.AuthMiddleware(a.DeleteBeneficiaryHandler, core.ScopeWriteTransfers)},
+		{"POST /api/v1/beneficiaries/confirm-payee", a.AuthMiddleware(a.ConfirmPayeeHandler, core.ScopeWriteTransfers)},
+
+		// Term deposit routes
+		{"GET /api/v1/term-deposits/rates", a.AuthMiddleware(a.GetTermDepositRatesHandler, core.ScopeReadAccounts)},
+		{"POST /api/v1/term-deposits", a.AuthMiddleware(a.OpenTermDepositHandler, core.ScopeWriteTransfers)},
+		{"GET /api/v1/term-deposits", a.AuthMiddleware(a.GetTermDepositsHandler, core.ScopeReadAccounts)},
+		{"GET /api/v1/term-deposits/{id}", a.AuthMiddleware(a.GetTermDepositHandler, core.ScopeReadAccounts)},
+		{"PATCH /api/v1/term-deposits/{id}", a.AuthMiddleware(a.UpdateTermDepositHandler, core.ScopeWriteAccounts)},
+		{"POST /api/v1/term-deposits/{id}/break", a.AuthMiddleware(a.BreakTermDepositHandler, core.ScopeWriteTransfers)},
 
-	// Account routes
-	mux.HandleFunc("POST /api/v1/accounts", a.AuthMiddleware(a.CreateAccountHandler))
-	mux.HandleFunc("GET /api/v1/accounts", a.AuthMiddleware(a.GetAccountsHandler))
-	mux.HandleFunc("GET /api/v1/accounts/{id}", a.AuthMiddleware(a.GetAccountHandler))
+		// User routes
+		{"POST  /api/v1/users/create", a.CreateUserHandler},
+		{"GET /api/v1/users", a.AuthMiddleware(a.GetUsersHandler, core.ScopeReadUsers)},
+		{"GET /api/v1/users/{id}", a.AuthMiddleware(a.GetUserHandler, core.ScopeReadUsers)},
+		{"PUT /api/v1/users/{id}", a.AuthMiddleware(a.UpdateUserHandler, core.ScopeWriteUsers)},
+		{"GET /api/v1/users/{id}/balances", a.AuthMiddleware(a.GetUserBalancesHandler, core.ScopeReadAccounts)},
+		{"DELETE /api/v1/users/{id}", a.AuthMiddleware(a.DeleteUserHandler)},
+		{"GET /api/v1/users/{id}/kyc", a.AuthMiddleware(a.GetKYCStatusHandler, core.ScopeReadUsers)},
+		{"POST /api/v1/users/{id}/kyc", a.AuthMiddleware(a.SubmitKYCHandler, core.ScopeWriteUsers)},
 
-	// Transaction routes
-	mux.HandleFunc("POST /api/v1/transactions/transfer", a.AuthMiddleware(a.TransferHandler))
-	mux.HandleFunc("POST /api/v1/transactions/payment", a.AuthMiddleware(a.PaymentHandler))
-	mux.HandleFunc("GET /api/v1/accounts/{id}/transactions", a.AuthMiddleware(a.GetTransactionsHandler))
-	mux.HandleFunc("GET /api/v1/transactions/{ref}", a.AuthMiddleware(a.GetTransactionHandler))
+		// Authentication routes
+		{"POST /api/v1/login", a.LoginHandler},
+		{"POST /api/v1/refresh", a.AuthMiddleware(a.RefreshTokenHandler)},
 
-	// User routes
-	mux.HandleFunc("POST  /api/v1/users/create", a.CreateUserHandler)
-	mux.HandleFunc("GET /api/v1/users", a.AuthMiddleware(a.GetUsersHandler))
-	mux.HandleFunc("GET /api/v1/users/{id}", a.AuthMiddleware(a.GetUserHandler))
-	mux.HandleFunc("PUT /api/v1/users/{id}", a.AuthMiddleware(a.UpdateUserHandler))
-	mux.HandleFunc("DELETE /api/v1/users/{id}", a.AuthMiddleware(a.DeleteUserHandler))
+		// API key routes (session only: keys cannot manage other keys)
+		{"POST /api/v1/api-keys", a.AuthMiddleware(a.CreateAPIKeyHandler)},
+		{"GET /api/v1/api-keys", a.AuthMiddleware(a.GetAPIKeysHandler)},
+		{"POST /api/v1/api-keys/{id}/rotate", a.AuthMiddleware(a.RotateAPIKeyHandler)},
+		{"DELETE /api/v1/api-keys/{id}", a.AuthMiddleware(a.RevokeAPIKeyHandler)},
 
-	// Authentication routes
-	mux.HandleFunc("POST /api/v1/login", a.LoginHandler)
-	mux.HandleFunc("POST /api/v1/refresh", a.AuthMiddleware(a.RefreshTokenHandler))
+		// OAuth2 routes. The token, introspection and revocation endpoints
+		// authenticate the client rather than a user.
+		{"POST /api/v1/oauth/clients", a.AuthMiddleware(a.RegisterOAuthClientHandler)},
+		{"GET /api/v1/oauth/clients", a.AuthMiddleware(a.GetOAuthClientsHandler)},
+		{"GET /api/v1/oauth/authorize", a.AuthMiddleware(a.AuthorizeHandler)},
+		{"POST /api/v1/oauth/authorize", a.AuthMiddleware(a.ConsentHandler)},
+		{"POST /api/v1/oauth/token", a.TokenHandler},
+		{"POST /api/v1/oauth/introspect", a.IntrospectHandler},
+		{"POST /api/v1/oauth/revoke", a.RevokeHandler},
+		{"GET /api/v1/oauth/consents", a.AuthMiddleware(a.GetOAuthConsentsHandler)},
+		{"DELETE /api/v1/oauth/consents/{client_id}", a.AuthMiddleware(a.RevokeOAuthConsentHandler)},
 
+		// Webhook routes
+		{"POST /api/v1/webhooks", a.AuthMiddleware(a.CreateWebhookHandler, core.ScopeManageWebhooks)},
+		{"GET /api/v1/webhooks", a.AuthMiddleware(a.GetWebhooksHandler, core.ScopeManageWebhooks)},
+		{"DELETE /api/v1/webhooks/{id}", a.AuthMiddleware(a.DeleteWebhookHandler, core.ScopeManageWebhooks)},
+		{"GET /api/v1/webhooks/{id}/deliveries", a.AuthMiddleware(a.GetWebhookDeliveriesHandler, core.ScopeManageWebhooks)},
+		{"POST /api/v1/webhooks/{id}/deliveries/{delivery_id}/replay", a.AuthMiddleware(a.ReplayWebhookDeliveryHandler, core.ScopeManageWebhooks)},
+
+		// Audit routes (session only, for users with the auditor role)
+		{"GET /api/v1/audit", a.AuthMiddleware(a.RequireRole(core.RoleAuditor, a.GetAuditLogHandler))},
+
+		// Monitoring case, screening and KYC review routes (session only, for
+		// users with the compliance role)
+		{"GET /api/v1/cases", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetCasesHandler))},
+		{"GET /api/v1/cases/{id}", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetCaseHandler))},
+		{"POST /api/v1/cases/{id}/resolve", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.ResolveCaseHandler))},
+		{"GET /api/v1/screening/hits", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetScreeningHitsHandler))},
+		{"GET /api/v1/screening/hits/{id}", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetScreeningHitHandler))},
+		{"POST /api/v1/screening/hits/{id}/resolve", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.ResolveScreeningHitHandler))},
+		{"GET /api/v1/kyc/submissions", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetKYCSubmissionsHandler))},
+		{"GET /api/v1/kyc/submissions/{id}", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetKYCSubmissionHandler))},
+		{"GET /api/v1/kyc/submissions/{id}/documents/{document_id}", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.GetKYCDocumentHandler))},
+		{"POST /api/v1/kyc/submissions/{id}/review", a.AuthMiddleware(a.RequireRole(core.RoleCompliance, a.ReviewKYCSubmissionHandler))},
+
+		// API description
+		{"GET /api/v1/openapi.json", a.OpenAPIHandler},
+		{"GET /api/v1/docs", a.DocsHandler},
+	}
+}
+
+// Router registers every route behind request validation against
+// openapi.yaml. TestRoutesMatchSpec catches a route and the spec
+// disagreeing; Router also panics on it, so an undocumented route cannot
+// ship even if the tests were skipped.
+func (a *API) Router() http.Handler {
+	spec, err := loadSpec()
+	if err != nil {
+		panic(err)
+	}
+	routes := a.routes()
+	if err := spec.checkCoverage(routes); err != nil {
+		panic(fmt.Sprintf("openapi.yaml is out of date:\n%v", err))
+	}
+
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		r, _ := spec.route(rt.pattern)
+		mux.HandleFunc(rt.pattern, instrumentRoute(rt.pattern, a.validateRequest(r, rt.handler)))
+	}
 	return mux
 }
+
+// instrumentRoute names the request's span and metrics after the route it
+// matched rather than its raw path, so requests for different accounts
+// group together.
+func instrumentRoute(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	name := strings.Join(strings.Fields(pattern), " ")
+	_, path, _ := strings.Cut(name, " ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(name)
+		span.SetAttributes(semconv.HTTPRoute(path))
+		setRoute(r.Context(), path)
+		next(w, r)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutesMatchSpec(t *testing.T) {
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &API{}
+	if err := spec.checkCoverage(a.routes()); err != nil {
+		t.Fatalf("openapi.yaml is out of date:\n%v", err)
+	}
+}
+
+func TestCheckCoverageReportsUndocumentedRoutes(t *testing.T) {
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &API{}
+	routes := append(a.routes(), route{"GET /api/v1/undocumented", func(http.ResponseWriter, *http.Request) {}})
+	err = spec.checkCoverage(routes)
+	if err == nil || !strings.Contains(err.Error(), "GET /api/v1/undocumented") {
+		t.Fatalf("checkCoverage() = %v, want the undocumented route reported", err)
+	}
+}
+
+func TestCheckCoverageReportsUnservedOperations(t *testing.T) {
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &API{}
+	var routes []route
+	for _, rt := range a.routes() {
+		if rt.pattern != "GET /api/v1/openapi.json" {
+			routes = append(routes, rt)
+		}
+	}
+	err = spec.checkCoverage(routes)
+	if err == nil || !strings.Contains(err.Error(), "GET /api/v1/openapi.json") {
+		t.Fatalf("checkCoverage() = %v, want the unserved operation reported", err)
+	}
+}
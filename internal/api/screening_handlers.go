@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type screeningHitResponse struct {
+	ID          int        `json:"id"`
+	SubjectType string     `json:"subject_type"`
+	SubjectID   *int       `json:"subject_id,omitempty"`
+	Name        string     `json:"name"`
+	EntryID     string     `json:"entry_id"`
+	EntryName   string     `json:"entry_name"`
+	Program     string     `json:"program,omitempty"`
+	Score       float64    `json:"score"`
+	Decision    string     `json:"decision"`
+	ListVersion string     `json:"list_version"`
+	Status      string     `json:"status"`
+	Note        string     `json:"note,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func newScreeningHitResponse(h *core.ScreeningHit) *screeningHitResponse {
+	return &screeningHitResponse{
+		ID:          h.ID,
+		SubjectType: h.SubjectType,
+		SubjectID:   h.SubjectID,
+		Name:        h.Name,
+		EntryID:     h.EntryID,
+		EntryName:   h.EntryName,
+		Program:     h.Program,
+		Score:       h.Score,
+		Decision:    h.Decision,
+		ListVersion: h.ListVersion,
+		Status:      h.Status,
+		Note:        h.Note,
+		ResolvedBy:  h.ResolvedBy,
+		ResolvedAt:  h.ResolvedAt,
+		CreatedAt:   h.CreatedAt,
+	}
+}
+
+type screeningHitListResponse struct {
+	Hits []*screeningHitResponse `json:"hits"`
+	// NextAfterID is passed as after_id to fetch the next page. It is
+	// omitted when no hits matched.
+	NextAfterID *int `json:"next_after_id,omitempty"`
+}
+
+// GetScreeningHitsHandler returns watchlist screening hits matching the
+// query, oldest first, a page at a time.
+func (a *API) GetScreeningHitsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := core.ScreeningHitFilter{Status: q.Get("status"), SubjectType: q.Get("subject_type")}
+
+	var fields []core.FieldError
+	parseInt := func(name string, min int) int {
+		v := q.Get(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min {
+			fields = append(fields, core.FieldError{Field: name, Message: name + " must be an integer of at least " + strconv.Itoa(min)})
+		}
+		return n
+	}
+	filter.SubjectID = parseInt("subject_id", 1)
+	filter.AfterID = parseInt("after_id", 0)
+	filter.Limit = parseInt("limit", 1)
+	if len(fields) > 0 {
+		a.writeError(w, r, core.InvalidFields(fields...))
+		return
+	}
+
+	hits, err := a.service.ListScreeningHits(r.Context(), filter)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := screeningHitListResponse{Hits: make([]*screeningHitResponse, len(hits))}
+	for i, h := range hits {
+		resp.Hits[i] = newScreeningHitResponse(h)
+	}
+	if n := len(hits); n > 0 {
+		next := hits[n-1].ID
+		resp.NextAfterID = &next
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) GetScreeningHitHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid screening hit id"))
+		return
+	}
+
+	h, err := a.service.GetScreeningHit(r.Context(), id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newScreeningHitResponse(h))
+}
+
+// ResolveScreeningHitHandler closes an open hit as cleared or confirmed.
+// It takes the same body as ResolveCaseHandler.
+func (a *API) ResolveScreeningHitHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid screening hit id"))
+		return
+	}
+
+	var req resolveCaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+
+	h, err := a.service.ResolveScreeningHit(r.Context(), id, req.Status, req.Note)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newScreeningHitResponse(h))
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/stream"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	streamWriteWait   = 10 * time.Second
+	// pongWait is how long a WebSocket client may stay silent before it
+	// is considered gone.
+	pongWait = 3 * heartbeatInterval
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// isStreamRequest reports whether r opens a long-lived account stream.
+func isStreamRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/v1/accounts/") && strings.HasSuffix(r.URL.Path, "/stream")
+}
+
+// streamSender writes updates and heartbeats to one client.
+type streamSender interface {
+	send(u stream.Update) error
+	heartbeat() error
+}
+
+// StreamAccountHandler pushes updates to an account in real time, over a
+// WebSocket when the client asks for an upgrade and Server-Sent Events
+// otherwise. Clients resume after a disconnect by passing the last
+// sequence they saw in Last-Event-ID or ?last_event_id=.
+func (a *API) StreamAccountHandler(w http.ResponseWriter, r *http.Request) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if lastID != "" {
+		var err error
+		after, err = strconv.ParseInt(lastID, 10, 64)
+		if err != nil || after < 0 {
+			a.writeError(w, r, core.InvalidField("last_event_id", "invalid last event id"))
+			return
+		}
+	}
+
+	acc := a.accountFromPath(w, r)
+	if acc == nil {
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		a.streamWebSocket(w, r, acc, after)
+		return
+	}
+	a.streamSSE(w, r, acc, after)
+}
+
+func (a *API) streamSSE(w http.ResponseWriter, r *http.Request, acc *core.Account, after int64) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sse := &sseSender{w: w, rc: http.NewResponseController(w)}
+	if err := sse.write("retry: 3000\n\n"); err != nil {
+		return
+	}
+	a.streamUpdates(r, sse, acc, after)
+}
+
+func (a *API) streamWebSocket(w http.ResponseWriter, r *http.Request, acc *core.Account, after int64) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		a.logger.WarnContext(r.Context(), "websocket upgrade failed", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	// Read in the background so pongs and close frames are processed.
+	// Clients are not expected to send anything else.
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	a.streamUpdates(r.WithContext(ctx), &wsSender{conn: conn}, acc, after)
+
+	// Tell the client to reconnect, e.g. after it was dropped for falling
+	// behind or the server is shutting down.
+	msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "stream ended")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+// streamUpdates replays missed updates and then forwards live ones until
+// the client goes away or falls too far behind.
+func (a *API) streamUpdates(r *http.Request, sender streamSender, acc *core.Account, after int64) {
+	ctx := r.Context()
+
+	// Subscribe before replaying so nothing is missed in between; updates
+	// seen in the replay are skipped when they arrive live.
+	sub := a.hub.Subscribe(acc.Number)
+	defer sub.Close()
+
+	last := after
+	for after > 0 {
+		missed, err := a.service.ListAccountEvents(ctx, acc.ID, last)
+		if err != nil {
+			a.logger.ErrorContext(r.Context(), "failed to replay account events", "account_id", acc.ID, "err", err)
+			return
+		}
+		if len(missed) == 0 {
+			break
+		}
+		for _, e := range missed {
+			if err := sender.send(stream.NewUpdate(e)); err != nil {
+				return
+			}
+			last = e.Sequence
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Dropped():
+			return
+		case u := <-sub.Updates():
+			if u.Sequence <= last {
+				continue
+			}
+			if err := sender.send(u); err != nil {
+				return
+			}
+			last = u.Sequence
+		case <-heartbeat.C:
+			if err := sender.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type sseSender struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseSender) send(u stream.Update) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", u.Sequence, u.Type, data))
+}
+
+func (s *sseSender) heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+// write sends one chunk with its own deadline, replacing the server's
+// write timeout which would otherwise end the stream.
+func (s *sseSender) write(chunk string) error {
+	if err := s.rc.SetWriteDeadline(time.Now().Add(streamWriteWait)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(s.w, chunk); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+type wsSender struct {
+	conn *websocket.Conn
+}
+
+func (s *wsSender) send(u stream.Update) error {
+	s.conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+	return s.conn.WriteJSON(u)
+}
+
+func (s *wsSender) heartbeat() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait))
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+
+	"github.com/google/uuid"
+)
+
+type openTermDepositRequest struct {
+	FromAccountNumber string `json:"from_account_number"`
+	Amount            int64  `json:"amount"`
+	TermMonths        int    `json:"term_months"`
+	Rollover          bool   `json:"rollover"`
+}
+
+type updateTermDepositRequest struct {
+	Rollover *bool `json:"rollover"`
+}
+
+type termDepositRateResponse struct {
+	TermMonths   int `json:"term_months"`
+	RateBPS      int `json:"rate_bps"`
+	BreakRateBPS int `json:"break_rate_bps"`
+}
+
+type termDepositResponse struct {
+	ID                  int        `json:"id"`
+	AccountNumber       string     `json:"account_number"`
+	PayoutAccountNumber string     `json:"payout_account_number"`
+	Principal           int64      `json:"principal"`
+	TermMonths          int        `json:"term_months"`
+	RateBPS             int        `json:"rate_bps"`
+	BreakRateBPS        int        `json:"break_rate_bps"`
+	Rollover            bool       `json:"rollover"`
+	Status              string     `json:"status"`
+	StartedAt           time.Time  `json:"started_at"`
+	MaturesAt           time.Time  `json:"matures_at"`
+	InterestPaid        int64      `json:"interest_paid"`
+	ClosedAt            *time.Time `json:"closed_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func newTermDepositResponse(d *core.TermDeposit) *termDepositResponse {
+	return &termDepositResponse{
+		ID:                  d.ID,
+		AccountNumber:       d.AccountNumber,
+		PayoutAccountNumber: d.PayoutAccountNumber,
+		Principal:           d.Principal,
+		TermMonths:          d.TermMonths,
+		RateBPS:             d.RateBPS,
+		BreakRateBPS:        d.BreakRateBPS,
+		Rollover:            d.Rollover,
+		Status:              d.Status,
+		StartedAt:           d.StartedAt,
+		MaturesAt:           d.MaturesAt,
+		InterestPaid:        d.InterestPaid,
+		ClosedAt:            d.ClosedAt,
+		CreatedAt:           d.CreatedAt,
+	}
+}
+
+// getAuthorizedTermDeposit loads the caller's term deposit named in the
+// path. It writes an error and returns nil unless the caller may use the
+// account the deposit pays out to.
+func (a *API) getAuthorizedTermDeposit(w http.ResponseWriter, r *http.Request) *core.TermDeposit {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid term deposit id"))
+		return nil
+	}
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return nil
+	}
+
+	d, err := a.service.GetTermDeposit(ctx, userID, id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return nil
+	}
+	if !principalFrom(ctx).allowsAccount(d.PayoutAccountID) {
+		a.writeError(w, r, core.ErrForbidden)
+		return nil
+	}
+	return d
+}
+
+func (a *API) GetTermDepositRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := a.service.TermDepositRates(r.Context())
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	res := make([]termDepositRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		res = append(res, termDepositRateResponse{TermMonths: rate.TermMonths, RateBPS: rate.RateBPS, BreakRateBPS: rate.BreakRateBPS})
+	}
+	jsonResponse(w, http.StatusOK, res)
+}
+
+// OpenTermDepositHandler moves money from one of the caller's accounts
+// into a new term deposit.
+func (a *API) OpenTermDepositHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req openTermDepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if req.Amount <= 0 {
+		a.writeError(w, r, core.InvalidField("amount", "amount must be positive"))
+		return
+	}
+
+	from := a.getAuthorizedAccount(w, r, "from_account_number", req.FromAccountNumber)
+	if from == nil {
+		return
+	}
+
+	d, err := a.service.OpenTermDeposit(ctx, from.UserID, from.ID, req.Amount, req.TermMonths, req.Rollover, uuid.NewString())
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newTermDepositResponse(d))
+}
+
+func (a *API) GetTermDepositsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	ds, err := a.service.ListTermDeposits(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	caller := principalFrom(ctx)
+	res := []*termDepositResponse{}
+	for _, d := range ds {
+		if caller.allowsAccount(d.PayoutAccountID) {
+			res = append(res, newTermDepositResponse(d))
+		}
+	}
+	jsonResponse(w, http.StatusOK, res)
+}
+
+func (a *API) GetTermDepositHandler(w http.ResponseWriter, r *http.Request) {
+	d := a.getAuthorizedTermDeposit(w, r)
+	if d == nil {
+		return
+	}
+	jsonResponse(w, http.StatusOK, newTermDepositResponse(d))
+}
+
+// UpdateTermDepositHandler turns rollover at maturity on or off.
+func (a *API) UpdateTermDepositHandler(w http.ResponseWriter, r *http.Request) {
+	var req updateTermDepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if req.Rollover == nil {
+		a.writeError(w, r, core.InvalidField("rollover", "rollover is required"))
+		return
+	}
+
+	d := a.getAuthorizedTermDeposit(w, r)
+	if d == nil {
+		return
+	}
+
+	d, err := a.service.SetTermDepositRollover(r.Context(), d.UserID, d.ID, *req.Rollover)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newTermDepositResponse(d))
+}
+
+// BreakTermDepositHandler pays a term deposit out before it matures, with
+// the lower interest of an early break.
+func (a *API) BreakTermDepositHandler(w http.ResponseWriter, r *http.Request) {
+	d := a.getAuthorizedTermDeposit(w, r)
+	if d == nil {
+		return
+	}
+
+	d, err := a.service.BreakTermDeposit(r.Context(), d.UserID, d.ID, uuid.NewString())
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, newTermDepositResponse(d))
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+type createWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is optional; one is generated when omitted.
+	Secret string `json:"secret"`
+}
+
+type webhookResponse struct {
+	ID             int       `json:"id"`
+	URL            string    `json:"url"`
+	EventTypes     []string  `json:"event_types"`
+	AccountNumbers []string  `json:"account_numbers"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Secret is only returned on creation.
+	Secret string `json:"secret,omitempty"`
+}
+
+// newWebhookResponse shows sub with the numbers of the accounts it is
+// restricted to, looked up in numbers.
+func newWebhookResponse(sub *core.WebhookSubscription, numbers map[int]string, secret string) *webhookResponse {
+	return &webhookResponse{
+		ID:             sub.ID,
+		URL:            sub.URL,
+		EventTypes:     sub.EventTypes,
+		AccountNumbers: accountNumbersOf(sub.AccountIDs, numbers),
+		CreatedAt:      sub.CreatedAt,
+		Secret:         secret,
+	}
+}
+
+// allowsWebhook reports whether the caller may see and manage a
+// subscription. An API key restricted to some accounts only sees
+// subscriptions restricted to accounts among them, so it cannot read the
+// delivery log of events on other accounts.
+func (p *principal) allowsWebhook(sub *core.WebhookSubscription) bool {
+	if p == nil {
+		return false
+	}
+	if p.APIKey == nil || len(p.APIKey.AccountIDs) == 0 {
+		return true
+	}
+	if len(sub.AccountIDs) == 0 {
+		return false
+	}
+	for _, id := range sub.AccountIDs {
+		if !p.APIKey.AllowsAccount(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeWebhook writes an error and returns false unless the caller
+// owns the subscription and may manage it.
+func (a *API) authorizeWebhook(w http.ResponseWriter, r *http.Request, userID int, id int) bool {
+	ctx := r.Context()
+	subs, err := a.service.ListWebhooks(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return false
+	}
+	for _, sub := range subs {
+		if sub.ID == id && principalFrom(ctx).allowsWebhook(sub) {
+			return true
+		}
+	}
+	a.writeError(w, r, core.ErrWebhookNotFound)
+	return false
+}
+
+type webhookDeliveryResponse struct {
+	ID             int             `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func newWebhookDeliveryResponse(d *core.WebhookDelivery) *webhookDeliveryResponse {
+	resp := &webhookDeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	if d.Status == core.DeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
+
+func validateCreateWebhookRequest(req createWebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "https" && u.Scheme != "http") {
+		return core.InvalidField("url", "url must be an absolute http(s) URL")
+	}
+	if len(req.EventTypes) == 0 {
+		return core.InvalidField("event_types", "at least one event type is required")
+	}
+	for _, t := range req.EventTypes {
+		if !core.ValidEventType(t) {
+			return core.InvalidField("event_types", fmt.Sprintf("unknown event type %q", t))
+		}
+	}
+	return nil
+}
+
+func (a *API) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, r, core.Invalid("invalid JSON body"))
+		return
+	}
+	if err := validateCreateWebhookRequest(req); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	// A key restricted to some accounts only subscribes to events on them.
+	var accountIDs []int
+	if p := principalFrom(ctx); p.APIKey != nil {
+		accountIDs = p.APIKey.AccountIDs
+	}
+	sub, err := a.service.CreateWebhook(ctx, userID, req.URL, req.EventTypes, req.Secret, accountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	numbers, err := a.service.AccountNumbers(ctx, sub.AccountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newWebhookResponse(sub, numbers, sub.Secret))
+}
+
+func (a *API) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	subs, err := a.service.ListWebhooks(ctx, userID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	caller := principalFrom(ctx)
+	var visible []*core.WebhookSubscription
+	var accountIDs []int
+	for _, sub := range subs {
+		if caller.allowsWebhook(sub) {
+			visible = append(visible, sub)
+			accountIDs = append(accountIDs, sub.AccountIDs...)
+		}
+	}
+	numbers, err := a.service.AccountNumbers(ctx, accountIDs)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := make([]*webhookResponse, 0, len(visible))
+	for _, sub := range visible {
+		resp = append(resp, newWebhookResponse(sub, numbers, ""))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid webhook id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if !a.authorizeWebhook(w, r, userID, id) {
+		return
+	}
+	if err := a.service.DeleteWebhook(ctx, userID, id); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "webhook deleted"})
+}
+
+func (a *API) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid webhook id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if !a.authorizeWebhook(w, r, userID, id) {
+		return
+	}
+	deliveries, err := a.service.ListWebhookDeliveries(ctx, userID, id)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	resp := make([]*webhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(d))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) ReplayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		a.writeError(w, r, core.InvalidField("id", "invalid webhook id"))
+		return
+	}
+	deliveryID, err := strconv.Atoi(r.PathValue("delivery_id"))
+	if err != nil || deliveryID <= 0 {
+		a.writeError(w, r, core.InvalidField("delivery_id", "invalid delivery id"))
+		return
+	}
+
+	userID, ok := ctx.Value(contextKeyUserID).(int)
+	if !ok {
+		a.writeError(w, r, core.ErrUnauthenticated)
+		return
+	}
+
+	if !a.authorizeWebhook(w, r, userID, id) {
+		return
+	}
+	d, err := a.service.ReplayWebhookDelivery(ctx, userID, id, deliveryID)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusAccepted, newWebhookDeliveryResponse(d))
+}
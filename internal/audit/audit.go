@@ -0,0 +1,399 @@
+// Package audit builds and verifies entries of the hash-chained audit log.
+// Storage appends entries in the same transaction as the change they
+// record; this package decides what an entry contains and how it is
+// hashed, so the writer and the verifier cannot disagree.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/telemetry"
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming who is acting, for the entries
+// written while serving it.
+func WithActor(ctx context.Context, actor core.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, or an anonymous one.
+func ActorFrom(ctx context.Context) core.Actor {
+	if actor, ok := ctx.Value(actorKey{}).(core.Actor); ok {
+		return actor
+	}
+	return core.Actor{Type: core.ActorAnonymous}
+}
+
+// UserActor is a user acting through their own session.
+func UserActor(userID int) core.Actor {
+	return core.Actor{Type: core.ActorUser, ID: strconv.Itoa(userID), UserID: &userID}
+}
+
+// New returns an entry for a change to a target made by the actor in ctx,
+// with before and after snapshots of the target. Either snapshot may be
+// nil, for creations and deletions. The entry is chained and timestamped
+// when it is appended.
+func New(ctx context.Context, action, targetType string, targetID any, before, after any) core.AuditEntry {
+	return core.AuditEntry{
+		Actor:      ActorFrom(ctx),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   formatID(targetID),
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		RequestID:  telemetry.RequestID(ctx),
+	}
+}
+
+func formatID(id any) string {
+	switch v := id.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// snapshot encodes v as JSON. Snapshots are of plain domain values, which
+// always encode.
+func snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return b
+}
+
+type userState struct {
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// UserState is the audited state of a user. Credentials are left out.
+func UserState(u *core.User) any {
+	if u == nil {
+		return nil
+	}
+	return userState{ID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName}
+}
+
+type accountState struct {
+	ID      int    `json:"id"`
+	UserID  int    `json:"user_id"`
+	Balance int64  `json:"balance"`
+	Status  string `json:"status"`
+	Product string `json:"product"`
+	Name    string `json:"name"`
+	Primary bool   `json:"primary"`
+}
+
+// AccountState is the audited state of an account.
+func AccountState(a *core.Account) any {
+	if a == nil {
+		return nil
+	}
+	return accountState{ID: a.ID, UserID: a.UserID, Balance: a.Balance, Status: a.Status, Product: a.Product, Name: a.Name, Primary: a.Primary}
+}
+
+// AccountBefore is the audited state of an account before its balance
+// changed by delta to reach a.
+func AccountBefore(a *core.Account, delta int64) any {
+	prev := *a
+	prev.Balance -= delta
+	return AccountState(&prev)
+}
+
+type transactionState struct {
+	ID            int    `json:"id"`
+	AccountID     int    `json:"account_id"`
+	Type          string `json:"type"`
+	Amount        int64  `json:"amount"`
+	Reference     string `json:"reference,omitempty"`
+	FromAccountID *int   `json:"from_account_id,omitempty"`
+	ToAccountID   *int   `json:"to_account_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	ReversesID    *int   `json:"reverses_id,omitempty"`
+	PotID         *int   `json:"pot_id,omitempty"`
+}
+
+// TransactionStates are the audited transaction rows a change booked.
+func TransactionStates(txns ...*core.Transaction) any {
+	res := make([]transactionState, 0, len(txns))
+	for _, t := range txns {
+		res = append(res, transactionState{
+			ID:            t.ID,
+			AccountID:     t.AccountID,
+			Type:          t.Type,
+			Amount:        t.Amount,
+			Reference:     t.Reference,
+			FromAccountID: t.FromAccountID,
+			ToAccountID:   t.ToAccountID,
+			Reason:        t.Reason,
+			ReversesID:    t.ReversesID,
+			PotID:         t.PotID,
+		})
+	}
+	return res
+}
+
+type limitsState struct {
+	MaxSingle       *int64 `json:"max_single"`
+	DailyOutbound   *int64 `json:"daily_outbound"`
+	MonthlyOutbound *int64 `json:"monthly_outbound"`
+	HourlyCount     *int64 `json:"hourly_count"`
+}
+
+// LimitsState is the audited state of a set of limits; nil if none were
+// set.
+func LimitsState(l *core.Limits) any {
+	if l == nil {
+		return nil
+	}
+	return limitsState{MaxSingle: l.MaxSingle, DailyOutbound: l.DailyOutbound, MonthlyOutbound: l.MonthlyOutbound, HourlyCount: l.HourlyCount}
+}
+
+type caseState struct {
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// CaseState is the audited state of a monitoring case: what a reviewer
+// can change. The flagged activity is in the case itself.
+func CaseState(c *core.Case) any {
+	if c == nil {
+		return nil
+	}
+	return caseState{Status: c.Status, Note: c.Note}
+}
+
+type kycState struct {
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// KYCState is the audited state of a KYC submission: what a reviewer can
+// change. The identity data is left out of the log.
+func KYCState(sub *core.KYCSubmission) any {
+	if sub == nil {
+		return nil
+	}
+	return kycState{Status: sub.Status, Note: sub.Note}
+}
+
+type tierState struct {
+	Tier string `json:"kyc_tier"`
+}
+
+// TierState is the audited KYC tier of a user.
+func TierState(tier string) any {
+	return tierState{Tier: tier}
+}
+
+// HitState is the audited state of a screening hit, like CaseState.
+func HitState(h *core.ScreeningHit) any {
+	if h == nil {
+		return nil
+	}
+	return caseState{Status: h.Status, Note: h.Note}
+}
+
+type beneficiaryState struct {
+	Nickname  string `json:"nickname"`
+	AccountID int    `json:"account_id"`
+	PayeeName string `json:"payee_name"`
+	NameMatch string `json:"name_match"`
+}
+
+// BeneficiaryState is the audited state of a saved payee.
+func BeneficiaryState(b *core.Beneficiary) any {
+	if b == nil {
+		return nil
+	}
+	return beneficiaryState{Nickname: b.Nickname, AccountID: b.AccountID, PayeeName: b.PayeeName, NameMatch: b.NameMatch}
+}
+
+type potState struct {
+	Name         string     `json:"name"`
+	Balance      int64      `json:"balance"`
+	TargetAmount *int64     `json:"target_amount,omitempty"`
+	TargetDate   *time.Time `json:"target_date,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	RoundUp      bool       `json:"round_up"`
+}
+
+// PotState is the audited state of a pot.
+func PotState(p *core.Pot) any {
+	if p == nil {
+		return nil
+	}
+	return potState{Name: p.Name, Balance: p.Balance, TargetAmount: p.TargetAmount, TargetDate: p.TargetDate, LockedUntil: p.LockedUntil, RoundUp: p.RoundUp}
+}
+
+type termDepositState struct {
+	AccountID       int        `json:"account_id"`
+	PayoutAccountID int        `json:"payout_account_id"`
+	Principal       int64      `json:"principal"`
+	TermMonths      int        `json:"term_months"`
+	RateBPS         int        `json:"rate_bps"`
+	BreakRateBPS    int        `json:"break_rate_bps"`
+	Rollover        bool       `json:"rollover"`
+	Status          string     `json:"status"`
+	MaturesAt       time.Time  `json:"matures_at"`
+	InterestPaid    int64      `json:"interest_paid"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+}
+
+// TermDepositState is the audited state of a term deposit.
+func TermDepositState(d *core.TermDeposit) any {
+	if d == nil {
+		return nil
+	}
+	return termDepositState{
+		AccountID:       d.AccountID,
+		PayoutAccountID: d.PayoutAccountID,
+		Principal:       d.Principal,
+		TermMonths:      d.TermMonths,
+		RateBPS:         d.RateBPS,
+		BreakRateBPS:    d.BreakRateBPS,
+		Rollover:        d.Rollover,
+		Status:          d.Status,
+		MaturesAt:       d.MaturesAt,
+		InterestPaid:    d.InterestPaid,
+		ClosedAt:        d.ClosedAt,
+	}
+}
+
+type rateState struct {
+	RateBPS      int `json:"rate_bps"`
+	BreakRateBPS int `json:"break_rate_bps"`
+}
+
+// RateState is the audited state of the rates offered on a term; nil if
+// the term was not offered.
+func RateState(r *core.TermDepositRate) any {
+	if r == nil {
+		return nil
+	}
+	return rateState{RateBPS: r.RateBPS, BreakRateBPS: r.BreakRateBPS}
+}
+
+// Timestamp returns the time to record for an entry appended now, at the
+// precision the database keeps, so the hash survives a round trip.
+func Timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// hashed is what an entry's hash covers, in a fixed order.
+type hashed struct {
+	PrevHash    string          `json:"prev_hash"`
+	OccurredAt  string          `json:"occurred_at"`
+	ActorType   string          `json:"actor_type"`
+	ActorID     string          `json:"actor_id"`
+	ActorUserID *int            `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"target_type"`
+	TargetID    string          `json:"target_id"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	RequestID   string          `json:"request_id"`
+}
+
+// Hash returns the hex SHA-256 of an entry's content and its PrevHash.
+// The ID is not covered: gaps in IDs are normal, and the chain itself
+// fixes the order.
+func Hash(e *core.AuditEntry) string {
+	b, _ := json.Marshal(hashed{
+		PrevHash:    e.PrevHash,
+		OccurredAt:  e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorType:   e.Actor.Type,
+		ActorID:     e.Actor.ID,
+		ActorUserID: e.Actor.UserID,
+		Action:      e.Action,
+		TargetType:  e.TargetType,
+		TargetID:    e.TargetID,
+		Before:      nullIfEmpty(e.Before),
+		After:       nullIfEmpty(e.After),
+		RequestID:   e.RequestID,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func nullIfEmpty(m json.RawMessage) json.RawMessage {
+	if len(m) == 0 {
+		return json.RawMessage("null")
+	}
+	return m
+}
+
+// Chain links entries to the one before them as they are appended.
+func Chain(prevHash string, entries []core.AuditEntry) {
+	for i := range entries {
+		entries[i].PrevHash = prevHash
+		entries[i].Hash = Hash(&entries[i])
+		prevHash = entries[i].Hash
+	}
+}
+
+// Verifier checks the chain one entry at a time, in ID order, so the log
+// can be verified in batches.
+type Verifier struct {
+	result core.AuditVerification
+	// head is a hash an earlier verification ended on, if known. The
+	// chain alone cannot show entries deleted from its end; the earlier
+	// head going missing can.
+	head     string
+	headSeen bool
+}
+
+// NewVerifier starts a verification at the beginning of the chain. head
+// is the Head of an earlier verification, or empty.
+func NewVerifier(head string) *Verifier {
+	return &Verifier{result: core.AuditVerification{CheckedAt: time.Now().UTC()}, head: head}
+}
+
+// Add checks the next entry: that it links to the previous one and that
+// its content still matches its hash.
+func (v *Verifier) Add(e *core.AuditEntry) {
+	r := &v.result
+	if e.PrevHash != r.Head {
+		r.Breaks = append(r.Breaks, core.AuditBreak{EntryID: e.ID, Reason: "previous hash does not match the entry before it"})
+	}
+	if Hash(e) != e.Hash {
+		r.Breaks = append(r.Breaks, core.AuditBreak{EntryID: e.ID, Reason: "content does not match its hash"})
+	}
+	if e.Hash == v.head {
+		v.headSeen = true
+	}
+	r.Entries++
+	r.Head = e.Hash
+}
+
+// Result returns what the entries added so far showed.
+func (v *Verifier) Result() *core.AuditVerification {
+	res := v.result
+	if v.head != "" && !v.headSeen {
+		res.Breaks = append(res.Breaks[:len(res.Breaks):len(res.Breaks)], core.AuditBreak{
+			Reason: "the earlier head " + v.head + " is no longer in the chain",
+		})
+	}
+	return &res
+}
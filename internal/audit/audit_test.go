@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+func chain(n int) []core.AuditEntry {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	entries := make([]core.AuditEntry, n)
+	for i := range entries {
+		entries[i] = core.AuditEntry{
+			ID:         int64(i + 1),
+			OccurredAt: start.Add(time.Duration(i) * time.Minute),
+			Actor:      UserActor(7),
+			Action:     "account.update",
+			TargetType: "account",
+			TargetID:   formatID(i + 1),
+			Before:     snapshot(map[string]int{"balance": i}),
+			After:      snapshot(map[string]int{"balance": i + 1}),
+			RequestID:  "req",
+		}
+	}
+	Chain("", entries)
+	return entries
+}
+
+func verify(head string, entries []core.AuditEntry) *core.AuditVerification {
+	v := NewVerifier(head)
+	for i := range entries {
+		v.Add(&entries[i])
+	}
+	return v.Result()
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	entries := chain(5)
+	res := verify("", entries)
+	if !res.OK() {
+		t.Fatalf("Breaks = %v, want none", res.Breaks)
+	}
+	if res.Entries != 5 {
+		t.Errorf("Entries = %d, want 5", res.Entries)
+	}
+	if res.Head != entries[4].Hash {
+		t.Errorf("Head = %q, want the last entry's hash %q", res.Head, entries[4].Hash)
+	}
+	if again := verify(res.Head, entries); !again.OK() {
+		t.Errorf("verifying against its own head: Breaks = %v, want none", again.Breaks)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]core.AuditEntry) []core.AuditEntry
+		want   []int64
+	}{
+		{"changed snapshot", func(e []core.AuditEntry) []core.AuditEntry {
+			e[2].After = json.RawMessage(`{"balance":1000}`)
+			return e
+		}, []int64{3}},
+		{"changed actor", func(e []core.AuditEntry) []core.AuditEntry {
+			e[1].Actor = UserActor(8)
+			return e
+		}, []int64{2}},
+		{"changed time", func(e []core.AuditEntry) []core.AuditEntry {
+			e[0].OccurredAt = e[0].OccurredAt.Add(time.Second)
+			return e
+		}, []int64{1}},
+		{"rehashed after a change", func(e []core.AuditEntry) []core.AuditEntry {
+			e[2].Action = "account.delete"
+			e[2].Hash = Hash(&e[2])
+			return e
+		}, []int64{4}},
+		{"deleted entry", func(e []core.AuditEntry) []core.AuditEntry {
+			return append(e[:2], e[3:]...)
+		}, []int64{4}},
+		{"swapped entries", func(e []core.AuditEntry) []core.AuditEntry {
+			e[1], e[2] = e[2], e[1]
+			return e
+		}, []int64{3, 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := verify("", tt.tamper(chain(5)))
+			var got []int64
+			for _, b := range res.Breaks {
+				if len(got) == 0 || got[len(got)-1] != b.EntryID {
+					got = append(got, b.EntryID)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("broken entries = %v, want %v (%v)", got, tt.want, res.Breaks)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("broken entries = %v, want %v (%v)", got, tt.want, res.Breaks)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsEntriesDeletedFromTheEnd(t *testing.T) {
+	entries := chain(5)
+	head := verify("", entries).Head
+
+	res := verify(head, entries[:3])
+	if res.OK() {
+		t.Fatal("chain missing its last entries verified, want a break for the lost head")
+	}
+	if len(res.Breaks) != 1 || res.Breaks[0].EntryID != 0 {
+		t.Errorf("Breaks = %v, want one break not tied to an entry", res.Breaks)
+	}
+}
+
+func TestHashSurvivesJSONRoundTrip(t *testing.T) {
+	e := New(WithActor(context.Background(), UserActor(3)), "user.update", "user", 3, nil, map[string]string{"name": "Ada"})
+	e.OccurredAt = Timestamp()
+	e.Hash = Hash(&e)
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got core.AuditEntry
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if h := Hash(&got); h != e.Hash {
+		t.Errorf("Hash after round trip = %q, want %q", h, e.Hash)
+	}
+}
@@ -0,0 +1,342 @@
+// Package config defines the settings of the bank server and loads them
+// from defaults, a YAML or TOML file, environment variables and command
+// line flags, each overriding the one before.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/monitor"
+	"mini-bank/internal/ratelimit"
+)
+
+// Config holds every setting of the bank server.
+type Config struct {
+	Server        Server        `yaml:"server" toml:"server"`
+	Storage       Storage       `yaml:"storage" toml:"storage"`
+	Auth          Auth          `yaml:"auth" toml:"auth"`
+	Limits        Limits        `yaml:"limits" toml:"limits"`
+	Webhooks      Webhooks      `yaml:"webhooks" toml:"webhooks"`
+	Telemetry     Telemetry     `yaml:"telemetry" toml:"telemetry"`
+	Reconcile     Reconcile     `yaml:"reconcile" toml:"reconcile"`
+	Monitoring    Monitoring    `yaml:"monitoring" toml:"monitoring"`
+	Screening     Screening     `yaml:"screening" toml:"screening"`
+	KYC           KYC           `yaml:"kyc" toml:"kyc"`
+	Beneficiaries Beneficiaries `yaml:"beneficiaries" toml:"beneficiaries"`
+	TermDeposits  TermDeposits  `yaml:"term_deposits" toml:"term_deposits"`
+}
+
+// Server configures the HTTP and gRPC listeners.
+type Server struct {
+	Addr         string        `yaml:"addr" toml:"addr"`
+	GRPCAddr     string        `yaml:"grpc_addr" toml:"grpc_addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// RequestTimeout bounds each HTTP request and unary RPC. Streams are
+	// exempt.
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	// ShutdownDelay is how long readiness fails before the server stops
+	// accepting requests, giving load balancers time to drain it.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	// ShutdownTimeout is how long in-flight requests get to finish once
+	// the server stops accepting new ones.
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout" toml:"health_check_timeout"`
+}
+
+// Storage configures the Postgres pool and the Redis session store.
+type Storage struct {
+	DatabaseURL     Secret        `yaml:"database_url" toml:"database_url"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	RedisAddr       string        `yaml:"redis_addr" toml:"redis_addr"`
+	RedisPassword   Secret        `yaml:"redis_password" toml:"redis_password"`
+}
+
+// Auth configures session tokens.
+type Auth struct {
+	JWTSecret       Secret        `yaml:"jwt_secret" toml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+}
+
+// Limits configures request rate limits.
+type Limits struct {
+	// RateLimitBackend is "memory" for per-instance limits or "redis" to
+	// share limits across instances.
+	RateLimitBackend string `yaml:"rate_limit_backend" toml:"rate_limit_backend"`
+	// TrustForwardedFor uses the first X-Forwarded-For address as the
+	// client IP. Only enable it behind a proxy that sets the header.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" toml:"trust_forwarded_for"`
+	// Default applies to every route without a stricter limit below.
+	Default  ratelimit.Limit `yaml:"default" toml:"default"`
+	Login    ratelimit.Limit `yaml:"login" toml:"login"`
+	Transfer ratelimit.Limit `yaml:"transfer" toml:"transfer"`
+	Token    ratelimit.Limit `yaml:"token" toml:"token"`
+}
+
+// Webhooks configures where webhooks may be delivered.
+type Webhooks struct {
+	// AllowPrivateTargets lets webhooks be delivered to loopback, private
+	// and link-local addresses, such as a local test receiver. Leave it
+	// off in production: it lets any customer make the server send
+	// requests into its own network.
+	AllowPrivateTargets bool `yaml:"allow_private_targets" toml:"allow_private_targets"`
+}
+
+// Telemetry configures tracing.
+type Telemetry struct {
+	// TracesExporter is where spans go: "otlp", "stdout" or "none".
+	TracesExporter string `yaml:"traces_exporter" toml:"traces_exporter"`
+}
+
+// Reconcile schedules the job that checks balances against transaction
+// history. Every instance runs it; it only reads.
+type Reconcile struct {
+	// Interval is the time between runs. Zero turns the job off.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	// At anchors the runs to a time of day in UTC, as HH:MM, so a daily
+	// job runs at night. Empty counts the interval from startup.
+	At string `yaml:"at" toml:"at"`
+}
+
+// Monitoring configures the fraud and AML rules. The rules can only be
+// set in the config file.
+type Monitoring struct {
+	// Rules screen every transfer and payment as it is made and again in
+	// the scan. A file that sets rules replaces the defaults; an empty
+	// list turns monitoring off.
+	Rules []monitor.Rule `yaml:"rules" toml:"rules"`
+	// ScanInterval is the time between scans of recent history. Zero
+	// turns the scan off.
+	ScanInterval time.Duration `yaml:"scan_interval" toml:"scan_interval"`
+	// ScanWindow is how far back each scan looks. It should exceed the
+	// interval so consecutive scans overlap.
+	ScanWindow time.Duration `yaml:"scan_window" toml:"scan_window"`
+}
+
+// Screening configures sanctions and watchlist screening of names.
+type Screening struct {
+	// ListFile is the watchlist, a CSV file with id, name, aliases and
+	// program columns. Empty turns screening off.
+	ListFile string `yaml:"list_file" toml:"list_file"`
+	// ReviewThreshold is the similarity, from 0 to 1, at which a name
+	// matches a listed one and is recorded for review.
+	ReviewThreshold float64 `yaml:"review_threshold" toml:"review_threshold"`
+	// BlockThreshold is the similarity at which a match is refused
+	// outright and the customer's accounts frozen.
+	BlockThreshold float64 `yaml:"block_threshold" toml:"block_threshold"`
+	// CheckInterval is the time between checks of the list file for
+	// changes, which rescreen every user. Zero turns rescreening off.
+	CheckInterval time.Duration `yaml:"check_interval" toml:"check_interval"`
+}
+
+// KYC configures where identity documents are kept. Tier limits are kept
+// in the database and set with bankctl.
+type KYC struct {
+	// DocumentDir is the directory uploaded documents are stored under.
+	DocumentDir string `yaml:"document_dir" toml:"document_dir"`
+	// MaxDocumentSize caps each uploaded document, in bytes.
+	MaxDocumentSize int `yaml:"max_document_size" toml:"max_document_size"`
+}
+
+// Beneficiaries configures the cooling-off period of new payees, which
+// limits the harm of a payee added by someone who took over an account.
+type Beneficiaries struct {
+	// CoolingOff is how long after a payee is saved as a beneficiary or
+	// first paid transfers to it are capped at CoolingOffAmount. Zero
+	// turns the cap off.
+	CoolingOff time.Duration `yaml:"cooling_off" toml:"cooling_off"`
+	// CoolingOffAmount is the largest transfer, in minor units, to a
+	// payee in its cooling-off period.
+	CoolingOffAmount int `yaml:"cooling_off_amount" toml:"cooling_off_amount"`
+}
+
+// TermDeposits schedules the job that pays out or rolls over matured term
+// deposits. Every instance runs it. Rates are kept in the database and
+// set with bankctl.
+type TermDeposits struct {
+	// MaturityInterval is the time between runs, and so how late after
+	// maturing a deposit may be paid. Zero turns the job off.
+	MaturityInterval time.Duration `yaml:"maturity_interval" toml:"maturity_interval"`
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:               ":8080",
+			GRPCAddr:           ":9000",
+			ReadTimeout:        5 * time.Second,
+			WriteTimeout:       10 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			RequestTimeout:     15 * time.Second,
+			ShutdownDelay:      5 * time.Second,
+			ShutdownTimeout:    5 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		Storage: Storage{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 10 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			RedisAddr:       "localhost:6379",
+		},
+		Auth: Auth{
+			AccessTokenTTL:  10 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		Limits: Limits{
+			RateLimitBackend: "memory",
+			Default:          ratelimit.Limit{Requests: 100, Window: time.Minute},
+			Login:            ratelimit.Limit{Requests: 5, Window: time.Minute},
+			Transfer:         ratelimit.Limit{Requests: 10, Window: time.Minute},
+			Token:            ratelimit.Limit{Requests: 20, Window: time.Minute},
+		},
+		Telemetry: Telemetry{
+			TracesExporter: "none",
+		},
+		Reconcile: Reconcile{
+			Interval: 24 * time.Hour,
+			At:       "02:00",
+		},
+		Monitoring: Monitoring{
+			Rules: []monitor.Rule{
+				{Name: "large-amount", Type: monitor.LargeAmount, Decision: core.DecisionReview, Amount: 1000000},
+				{Name: "structuring", Type: monitor.Structuring, Decision: core.DecisionReview, Amount: 1000000, Margin: 100000, Count: 3, Window: 24 * time.Hour},
+				{Name: "rapid-in-out", Type: monitor.RapidMovement, Decision: core.DecisionReview, Amount: 500000, Ratio: 0.9, Window: 24 * time.Hour},
+				{Name: "new-account-large-withdrawal", Type: monitor.NewAccount, Decision: core.DecisionBlock, Amount: 500000, MaxAge: 48 * time.Hour},
+			},
+			ScanInterval: time.Hour,
+			ScanWindow:   2 * time.Hour,
+		},
+		Screening: Screening{
+			ReviewThreshold: 0.88,
+			BlockThreshold:  0.97,
+			CheckInterval:   5 * time.Minute,
+		},
+		KYC: KYC{
+			DocumentDir:     "data/kyc",
+			MaxDocumentSize: 10 << 20,
+		},
+		Beneficiaries: Beneficiaries{
+			CoolingOff:       24 * time.Hour,
+			CoolingOffAmount: 100000,
+		},
+		TermDeposits: TermDeposits{
+			MaturityInterval: time.Hour,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var p problems
+	p.check(c.Server.Addr != "", "server.addr is required")
+	p.check(c.Server.GRPCAddr != "", "server.grpc_addr is required")
+	p.positive("server.read_timeout", c.Server.ReadTimeout)
+	p.positive("server.write_timeout", c.Server.WriteTimeout)
+	p.positive("server.idle_timeout", c.Server.IdleTimeout)
+	p.positive("server.request_timeout", c.Server.RequestTimeout)
+	p.check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative, got %s", c.Server.ShutdownDelay)
+	p.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	p.positive("server.health_check_timeout", c.Server.HealthCheckTimeout)
+
+	c.Storage.validate(&p)
+
+	p.check(c.Auth.JWTSecret != "", "auth.jwt_secret is required (JWT_SECRET)")
+	p.positive("auth.access_token_ttl", c.Auth.AccessTokenTTL)
+	p.positive("auth.refresh_token_ttl", c.Auth.RefreshTokenTTL)
+
+	p.check(c.Limits.RateLimitBackend == "memory" || c.Limits.RateLimitBackend == "redis",
+		"limits.rate_limit_backend must be memory or redis, got %q", c.Limits.RateLimitBackend)
+	for _, l := range []struct {
+		name  string
+		limit ratelimit.Limit
+	}{
+		{"default", c.Limits.Default},
+		{"login", c.Limits.Login},
+		{"transfer", c.Limits.Transfer},
+		{"token", c.Limits.Token},
+	} {
+		p.check(l.limit.Requests > 0 && l.limit.Window > 0,
+			"limits.%s needs positive requests and window, got %d per %s", l.name, l.limit.Requests, l.limit.Window)
+	}
+
+	switch c.Telemetry.TracesExporter {
+	case "", "none", "stdout", "console", "otlp":
+	default:
+		p = append(p, fmt.Errorf("telemetry.traces_exporter must be none, stdout or otlp, got %q", c.Telemetry.TracesExporter))
+	}
+
+	p.check(c.Reconcile.Interval >= 0, "reconcile.interval must not be negative, got %s", c.Reconcile.Interval)
+	if c.Reconcile.At != "" {
+		_, err := time.Parse("15:04", c.Reconcile.At)
+		p.check(err == nil, "reconcile.at must be a time of day as HH:MM, got %q", c.Reconcile.At)
+	}
+
+	if err := monitor.Validate(c.Monitoring.Rules); err != nil {
+		p = append(p, fmt.Errorf("monitoring.rules: %w", err))
+	}
+	p.check(c.Monitoring.ScanInterval >= 0, "monitoring.scan_interval must not be negative, got %s", c.Monitoring.ScanInterval)
+	if c.Monitoring.ScanInterval > 0 {
+		p.check(c.Monitoring.ScanWindow >= c.Monitoring.ScanInterval,
+			"monitoring.scan_window must be at least monitoring.scan_interval (%s), got %s", c.Monitoring.ScanInterval, c.Monitoring.ScanWindow)
+	}
+
+	sc := c.Screening
+	p.check(sc.ReviewThreshold > 0 && sc.ReviewThreshold <= 1, "screening.review_threshold must be above 0 and at most 1, got %g", sc.ReviewThreshold)
+	p.check(sc.BlockThreshold >= sc.ReviewThreshold && sc.BlockThreshold <= 1,
+		"screening.block_threshold must be between screening.review_threshold (%g) and 1, got %g", sc.ReviewThreshold, sc.BlockThreshold)
+	p.check(sc.CheckInterval >= 0, "screening.check_interval must not be negative, got %s", sc.CheckInterval)
+
+	p.check(c.KYC.DocumentDir != "", "kyc.document_dir is required (KYC_DOCUMENT_DIR)")
+	p.check(c.KYC.MaxDocumentSize > 0, "kyc.max_document_size must be positive, got %d", c.KYC.MaxDocumentSize)
+
+	p.check(c.Beneficiaries.CoolingOff >= 0, "beneficiaries.cooling_off must not be negative, got %s", c.Beneficiaries.CoolingOff)
+	p.check(c.Beneficiaries.CoolingOffAmount >= 0, "beneficiaries.cooling_off_amount must not be negative, got %d", c.Beneficiaries.CoolingOffAmount)
+
+	p.check(c.TermDeposits.MaturityInterval >= 0, "term_deposits.maturity_interval must not be negative, got %s", c.TermDeposits.MaturityInterval)
+
+	return errors.Join(p...)
+}
+
+// Validate reports every invalid storage setting at once, for commands
+// that use nothing else.
+func (s *Storage) Validate() error {
+	var p problems
+	s.validate(&p)
+	return errors.Join(p...)
+}
+
+func (s *Storage) validate(p *problems) {
+	p.check(s.DatabaseURL != "", "storage.database_url is required (DATABASE_URL)")
+	p.check(s.MaxOpenConns > 0, "storage.max_open_conns must be positive, got %d", s.MaxOpenConns)
+	p.check(s.MaxIdleConns >= 0 && s.MaxIdleConns <= s.MaxOpenConns,
+		"storage.max_idle_conns must be between 0 and max_open_conns (%d), got %d", s.MaxOpenConns, s.MaxIdleConns)
+	p.positive("storage.conn_max_lifetime", s.ConnMaxLifetime)
+	p.positive("storage.conn_max_idle_time", s.ConnMaxIdleTime)
+	p.positive("storage.connect_timeout", s.ConnectTimeout)
+	p.check(s.RedisAddr != "", "storage.redis_addr is required (REDIS_ADDR)")
+}
+
+// problems collects invalid settings.
+type problems []error
+
+func (p *problems) check(ok bool, format string, args ...any) {
+	if !ok {
+		*p = append(*p, fmt.Errorf(format, args...))
+	}
+}
+
+func (p *problems) positive(name string, d time.Duration) {
+	p.check(d > 0, "%s must be positive, got %s", name, d)
+}
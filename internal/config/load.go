@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/monitor"
+	"mini-bank/internal/ratelimit"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the YAML or TOML file named by -config or CONFIG_FILE,
+// environment variables and the remaining flags in args. It returns
+// flag.ErrHelp if args ask for usage, which has then been printed.
+func Load(name string, args []string, getenv func(string) string) (*Config, error) {
+	cfg, _, err := load(name, args, getenv)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// LoadCommand loads the configuration like Load for a command whose flags
+// are followed by positional arguments, which it returns. Only the storage
+// settings are validated, since such commands serve no requests.
+func LoadCommand(name string, args []string, getenv func(string) string) (*Config, []string, error) {
+	cfg, rest, err := load(name, args, getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Storage.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, rest, nil
+}
+
+func load(name string, args []string, getenv func(string) string) (*Config, []string, error) {
+	// The first pass only finds the config file. Flags are applied for real
+	// once the file and environment have been read, so they win over both.
+	var path string
+	probe := Default()
+	fs, _ := flagSet(name, &probe, &path)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	fs, env := flagSet(name, &cfg, &path)
+	fs.SetOutput(io.Discard)
+	for _, e := range env {
+		if v := getenv(e.name); v != "" {
+			if err := fs.Set(e.flag, e.prefix+v); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", e.name, err)
+			}
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+// loadFile reads settings from a YAML or TOML file, chosen by extension.
+// Unknown keys are errors so that typos do not silently keep a default.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("failed to parse %s: unknown keys %v", path, undecoded)
+		}
+		// TOML decodes arrays of tables into the existing elements, which
+		// would mix the default rules into the file's. Rules given in the
+		// file replace the defaults instead.
+		if md.IsDefined("monitoring", "rules") {
+			var file struct {
+				Monitoring struct {
+					Rules []monitor.Rule `toml:"rules"`
+				} `toml:"monitoring"`
+			}
+			if _, err := toml.Decode(string(data), &file); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			cfg.Monitoring.Rules = file.Monitoring.Rules
+		}
+	default:
+		return fmt.Errorf("unsupported config file type %q: use .yaml, .yml or .toml", ext)
+	}
+	return nil
+}
+
+// envVar sets a flag from an environment variable, prefixing its value.
+type envVar struct {
+	name   string
+	flag   string
+	prefix string
+}
+
+// flagSet defines a flag for every setting, bound to cfg, and returns the
+// environment variables that set them.
+func flagSet(name string, cfg *Config, path *string) (*flag.FlagSet, []envVar) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var env []envVar
+	bind := func(flagName, envName, prefix string) {
+		env = append(env, envVar{name: envName, flag: flagName, prefix: prefix})
+	}
+	str := func(p *string, flagName, envName, usage string) {
+		fs.StringVar(p, flagName, *p, fmt.Sprintf("%s ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+	dur := func(p *time.Duration, flagName, envName, usage string) {
+		fs.DurationVar(p, flagName, *p, fmt.Sprintf("%s ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+	num := func(p *int, flagName, envName, usage string) {
+		fs.IntVar(p, flagName, *p, fmt.Sprintf("%s ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+	float := func(p *float64, flagName, envName, usage string) {
+		fs.Float64Var(p, flagName, *p, fmt.Sprintf("%s ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+	secret := func(p *Secret, flagName, envName, usage string) {
+		fs.Var(secretFlag{p}, flagName, fmt.Sprintf("%s ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+	limit := func(p *ratelimit.Limit, flagName, envName, usage string) {
+		fs.Var(limitFlag{p}, flagName, fmt.Sprintf("%s, as requests/window ($%s)", usage, envName))
+		bind(flagName, envName, "")
+	}
+
+	fs.StringVar(path, "config", *path, "YAML or TOML config file ($CONFIG_FILE)")
+
+	s := &cfg.Server
+	fs.StringVar(&s.Addr, "addr", s.Addr, "HTTP listen address ($PORT sets the port)")
+	bind("addr", "PORT", ":")
+	fs.StringVar(&s.GRPCAddr, "grpc-addr", s.GRPCAddr, "gRPC listen address ($GRPC_PORT sets the port)")
+	bind("grpc-addr", "GRPC_PORT", ":")
+	dur(&s.ReadTimeout, "read-timeout", "READ_TIMEOUT", "HTTP read timeout")
+	dur(&s.WriteTimeout, "write-timeout", "WRITE_TIMEOUT", "HTTP write timeout")
+	dur(&s.IdleTimeout, "idle-timeout", "IDLE_TIMEOUT", "HTTP keep-alive idle timeout")
+	dur(&s.RequestTimeout, "request-timeout", "REQUEST_TIMEOUT", "time limit for each request")
+	dur(&s.ShutdownDelay, "shutdown-delay", "SHUTDOWN_DELAY", "how long readiness fails before shutting down")
+	dur(&s.ShutdownTimeout, "shutdown-timeout", "SHUTDOWN_TIMEOUT", "time for in-flight requests to finish on shutdown")
+	dur(&s.HealthCheckTimeout, "health-check-timeout", "HEALTH_CHECK_TIMEOUT", "time limit for each readiness check")
+
+	st := &cfg.Storage
+	secret(&st.DatabaseURL, "database-url", "DATABASE_URL", "Postgres connection URL")
+	num(&st.MaxOpenConns, "db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections")
+	num(&st.MaxIdleConns, "db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections")
+	dur(&st.ConnMaxLifetime, "db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection")
+	dur(&st.ConnMaxIdleTime, "db-conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "maximum idle time of a database connection")
+	dur(&st.ConnectTimeout, "db-connect-timeout", "DB_CONNECT_TIMEOUT", "time limit for the initial database ping")
+	str(&st.RedisAddr, "redis-addr", "REDIS_ADDR", "Redis address")
+	secret(&st.RedisPassword, "redis-password", "REDIS_PASSWORD", "Redis password")
+
+	a := &cfg.Auth
+	secret(&a.JWTSecret, "jwt-secret", "JWT_SECRET", "secret signing session tokens")
+	dur(&a.AccessTokenTTL, "access-token-ttl", "ACCESS_TOKEN_TTL", "lifetime of session access tokens")
+	dur(&a.RefreshTokenTTL, "refresh-token-ttl", "REFRESH_TOKEN_TTL", "lifetime of session refresh tokens")
+
+	l := &cfg.Limits
+	str(&l.RateLimitBackend, "rate-limit-backend", "RATE_LIMIT_BACKEND", "rate limit store: memory or redis")
+	fs.BoolVar(&l.TrustForwardedFor, "trust-forwarded-for", l.TrustForwardedFor, "identify clients by X-Forwarded-For ($TRUST_FORWARDED_FOR)")
+	bind("trust-forwarded-for", "TRUST_FORWARDED_FOR", "")
+	limit(&l.Default, "rate-limit-default", "RATE_LIMIT_DEFAULT", "default rate limit")
+	limit(&l.Login, "rate-limit-login", "RATE_LIMIT_LOGIN", "login rate limit")
+	limit(&l.Transfer, "rate-limit-transfer", "RATE_LIMIT_TRANSFER", "transfer rate limit")
+	limit(&l.Token, "rate-limit-token", "RATE_LIMIT_TOKEN", "OAuth token rate limit")
+
+	fs.BoolVar(&cfg.Webhooks.AllowPrivateTargets, "webhook-allow-private-targets", cfg.Webhooks.AllowPrivateTargets, "allow webhooks to non-public addresses, for local testing ($WEBHOOK_ALLOW_PRIVATE_TARGETS)")
+	bind("webhook-allow-private-targets", "WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+
+	str(&cfg.Telemetry.TracesExporter, "traces-exporter", "OTEL_TRACES_EXPORTER", "trace exporter: otlp, stdout or none")
+
+	dur(&cfg.Reconcile.Interval, "reconcile-interval", "RECONCILE_INTERVAL", "time between ledger reconciliations, 0 to disable")
+	str(&cfg.Reconcile.At, "reconcile-at", "RECONCILE_AT", "UTC time of day (HH:MM) reconciliations are anchored to")
+
+	dur(&cfg.Monitoring.ScanInterval, "monitoring-scan-interval", "MONITORING_SCAN_INTERVAL", "time between monitoring scans of recent history, 0 to disable")
+	dur(&cfg.Monitoring.ScanWindow, "monitoring-scan-window", "MONITORING_SCAN_WINDOW", "how far back each monitoring scan looks")
+
+	sc := &cfg.Screening
+	str(&sc.ListFile, "watchlist-file", "WATCHLIST_FILE", "sanctions watchlist CSV file, empty to disable screening")
+	float(&sc.ReviewThreshold, "screening-review-threshold", "SCREENING_REVIEW_THRESHOLD", "name similarity from 0 to 1 recorded for review")
+	float(&sc.BlockThreshold, "screening-block-threshold", "SCREENING_BLOCK_THRESHOLD", "name similarity from 0 to 1 refused outright")
+	dur(&sc.CheckInterval, "watchlist-check-interval", "WATCHLIST_CHECK_INTERVAL", "time between checks of the watchlist file for changes, 0 to disable")
+
+	str(&cfg.KYC.DocumentDir, "kyc-document-dir", "KYC_DOCUMENT_DIR", "directory KYC documents are stored under")
+	num(&cfg.KYC.MaxDocumentSize, "kyc-max-document-size", "KYC_MAX_DOCUMENT_SIZE", "maximum size of each KYC document in bytes")
+
+	dur(&cfg.Beneficiaries.CoolingOff, "beneficiary-cooling-off", "BENEFICIARY_COOLING_OFF", "time after a payee is saved or first paid that large transfers to it are refused, 0 to disable")
+	num(&cfg.Beneficiaries.CoolingOffAmount, "beneficiary-cooling-off-amount", "BENEFICIARY_COOLING_OFF_AMOUNT", "largest transfer to a payee in its cooling-off period")
+
+	dur(&cfg.TermDeposits.MaturityInterval, "term-deposit-maturity-interval", "TERM_DEPOSIT_MATURITY_INTERVAL", "time between payouts of matured term deposits, 0 to disable")
+
+	return fs, env
+}
+
+// limitFlag parses a rate limit written as requests/window, e.g. 100/1m.
+type limitFlag struct {
+	l *ratelimit.Limit
+}
+
+func (f limitFlag) String() string {
+	if f.l == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", f.l.Requests, f.l.Window)
+}
+
+func (f limitFlag) Set(v string) error {
+	requests, window, ok := strings.Cut(v, "/")
+	if !ok {
+		return fmt.Errorf("want requests/window, got %q", v)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return fmt.Errorf("invalid request count %q", requests)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return err
+	}
+	*f.l = ratelimit.Limit{Requests: n, Window: d}
+	return nil
+}
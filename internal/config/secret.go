@@ -0,0 +1,56 @@
+package config
+
+import "log/slog"
+
+const redacted = "[REDACTED]"
+
+// Secret is a setting that must not appear in logs or dumps of the
+// configuration. Printing, logging or marshalling it yields a placeholder;
+// call Reveal where the actual value is needed.
+type Secret string
+
+// Reveal returns the secret itself.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// LogValue keeps the secret out of slog output.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText keeps the secret out of JSON, YAML and TOML output.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the secret from a config file.
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
+
+// secretFlag sets a Secret from the command line without echoing it in
+// usage output.
+type secretFlag struct {
+	s *Secret
+}
+
+func (f secretFlag) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return f.s.String()
+}
+
+func (f secretFlag) Set(v string) error {
+	*f.s = Secret(v)
+	return nil
+}
@@ -1,10 +1,127 @@
 package core
 
-import "time"
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// Account statuses. Customers cannot move money in or out of a frozen
+// account; support can still adjust it or reverse its transactions.
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+)
+
+// Account products. Each has its own default limits and rules on how
+// money leaves its accounts: current accounts are unrestricted, savings
+// accounts only pay out to their owner's other accounts, and fixed
+// deposits pay out nothing at all.
+const (
+	ProductCurrent      = "current"
+	ProductSavings      = "savings"
+	ProductFixedDeposit = "fixed_deposit"
+)
+
+// DefaultProduct is the product accounts are opened on unless another is
+// chosen.
+const DefaultProduct = ProductCurrent
+
+// Products lists every product an account can be opened on.
+var Products = []string{ProductCurrent, ProductSavings, ProductFixedDeposit}
+
+// ValidProduct reports whether p is one of Products.
+func ValidProduct(p string) bool {
+	return slices.Contains(Products, p)
+}
 
 type Account struct {
-	ID        int
-	UserID    int
-	Balance   int64
+	ID int
+	// Number identifies the account outside the bank; ID is internal.
+	Number  string
+	UserID  int
+	Balance int64
+	Status  string
+	// Product decides the account's default limits and what it can pay.
+	Product string
+	// Name is the owner's label for the account; it may be empty.
+	Name string
+	// Primary marks the one current account a user keeps as their main
+	// account.
+	Primary   bool
 	CreatedAt time.Time
 }
+
+// CheckWithdrawal returns ErrProductRestricted if the account's product
+// does not allow withdrawals.
+func (a *Account) CheckWithdrawal() error {
+	if a.Product != ProductCurrent {
+		return ErrProductRestricted.WithMessage("withdrawals are only allowed from current accounts")
+	}
+	return nil
+}
+
+// CheckDeposit returns ErrProductRestricted if the account's product does
+// not take deposits.
+func (a *Account) CheckDeposit() error {
+	if a.Product == ProductFixedDeposit {
+		return ErrProductRestricted.WithMessage("fixed deposit accounts only receive money when their term deposit is opened")
+	}
+	return nil
+}
+
+// CheckTransferTo returns ErrProductRestricted if the account's product
+// does not allow it to send money to the account to, or to is a fixed
+// deposit: those are only funded when their term deposit is opened.
+func (a *Account) CheckTransferTo(to *Account) error {
+	if to.Product == ProductFixedDeposit {
+		return ErrProductRestricted.WithMessage("fixed deposit accounts only receive money when their term deposit is opened")
+	}
+	return a.CheckPayTo(to.UserID)
+}
+
+// CheckPayTo returns ErrProductRestricted if the account's product does
+// not allow it to send money to an account of the user ownerID.
+func (a *Account) CheckPayTo(ownerID int) error {
+	switch {
+	case a.Product == ProductFixedDeposit:
+		return ErrProductRestricted.WithMessage("transfers are not allowed from fixed deposit accounts")
+	case a.Product == ProductSavings && ownerID != a.UserID:
+		return ErrProductRestricted.WithMessage("savings accounts can only transfer to their owner's other accounts")
+	}
+	return nil
+}
+
+// AccountNumberLength is the number of digits in an account number.
+const AccountNumberLength = 10
+
+// NewAccountNumber returns a random account number: eight digits, the
+// first not zero, followed by two check digits computed as for IBANs (ISO
+// 7064 MOD 97-10), so that the whole number leaves 1 when divided by 97.
+func NewAccountNumber() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(90000000))
+	if err != nil {
+		panic(err)
+	}
+	base := n.Int64() + 10000000
+	return fmt.Sprintf("%d%02d", base, 98-base*100%97)
+}
+
+// ValidAccountNumber reports whether s is an account number with valid
+// check digits. They catch any single mistyped digit and most swaps of
+// two digits.
+func ValidAccountNumber(s string) bool {
+	if len(s) != AccountNumberLength {
+		return false
+	}
+	rem := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+		rem = (rem*10 + int(c-'0')) % 97
+	}
+	return rem == 1
+}
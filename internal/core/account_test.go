@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccountCheckTransferTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Account
+		allowed  bool
+	}{
+		{"current to another user", Account{UserID: 1, Product: ProductCurrent}, Account{UserID: 2, Product: ProductCurrent}, true},
+		{"savings to own current", Account{UserID: 1, Product: ProductSavings}, Account{UserID: 1, Product: ProductCurrent}, true},
+		{"savings to another user", Account{UserID: 1, Product: ProductSavings}, Account{UserID: 2, Product: ProductCurrent}, false},
+		{"from fixed deposit", Account{UserID: 1, Product: ProductFixedDeposit}, Account{UserID: 1, Product: ProductCurrent}, false},
+		{"current to own fixed deposit", Account{UserID: 1, Product: ProductCurrent}, Account{UserID: 1, Product: ProductFixedDeposit}, false},
+		{"current to another's fixed deposit", Account{UserID: 1, Product: ProductCurrent}, Account{UserID: 2, Product: ProductFixedDeposit}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.from.CheckTransferTo(&tt.to)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckTransferTo = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrProductRestricted) {
+				t.Errorf("CheckTransferTo = %v, want ErrProductRestricted", err)
+			}
+		})
+	}
+}
+
+func TestAccountCheckPayToAllowsFundingOwnFixedDeposit(t *testing.T) {
+	from := Account{UserID: 1, Product: ProductSavings}
+	if err := from.CheckPayTo(1); err != nil {
+		t.Errorf("CheckPayTo(owner) = %v, want nil", err)
+	}
+	if err := from.CheckPayTo(2); !errors.Is(err, ErrProductRestricted) {
+		t.Errorf("CheckPayTo(other) = %v, want ErrProductRestricted", err)
+	}
+}
+
+func TestAccountCheckPayments(t *testing.T) {
+	for _, product := range Products {
+		acc := Account{Product: product}
+		if err := acc.CheckWithdrawal(); (err == nil) != (product == ProductCurrent) {
+			t.Errorf("%s: CheckWithdrawal = %v", product, err)
+		}
+		if err := acc.CheckDeposit(); (err == nil) != (product != ProductFixedDeposit) {
+			t.Errorf("%s: CheckDeposit = %v", product, err)
+		}
+	}
+}
+
+func TestValidAccountNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"1234567889", true},
+		{"1000000064", true},
+		{"9999999952", true},
+		{"1234567888", false},  // wrong check digits
+		{"1234567989", false},  // one digit mistyped
+		{"2134567889", false},  // two digits swapped
+		{"123456789", false},   // too short
+		{"12345678890", false}, // too long
+		{"12345678a9", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidAccountNumber(tt.number); got != tt.valid {
+			t.Errorf("ValidAccountNumber(%q) = %v, want %v", tt.number, got, tt.valid)
+		}
+	}
+}
+
+func TestValidAccountNumberCatchesEverySingleDigitError(t *testing.T) {
+	const number = "1234567889"
+	for i := range number {
+		for d := byte('0'); d <= '9'; d++ {
+			if d == number[i] {
+				continue
+			}
+			typo := number[:i] + string(d) + number[i+1:]
+			if ValidAccountNumber(typo) {
+				t.Errorf("ValidAccountNumber(%q) = true, want false", typo)
+			}
+		}
+	}
+}
+
+func TestNewAccountNumberIsValid(t *testing.T) {
+	for range 1000 {
+		n := NewAccountNumber()
+		if !ValidAccountNumber(n) {
+			t.Fatalf("NewAccountNumber() = %q, which is not valid", n)
+		}
+		if n[0] == '0' {
+			t.Fatalf("NewAccountNumber() = %q, which has a leading zero", n)
+		}
+	}
+}
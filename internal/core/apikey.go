@@ -0,0 +1,63 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes that can be granted to API keys.
+const (
+	ScopeReadAccounts     = "read:accounts"
+	ScopeWriteAccounts    = "write:accounts"
+	ScopeReadTransactions = "read:transactions"
+	ScopeWritePayments    = "write:payments"
+	ScopeWriteTransfers   = "write:transfers"
+	ScopeReadUsers        = "read:users"
+	ScopeWriteUsers       = "write:users"
+	ScopeManageWebhooks   = "manage:webhooks"
+)
+
+// Scopes lists every scope known to the API.
+var Scopes = []string{
+	ScopeReadAccounts,
+	ScopeWriteAccounts,
+	ScopeReadTransactions,
+	ScopeWritePayments,
+	ScopeWriteTransfers,
+	ScopeReadUsers,
+	ScopeWriteUsers,
+	ScopeManageWebhooks,
+}
+
+// ValidScope reports whether scope is a known scope.
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// APIKey is a long-lived credential used by server-to-server integrations
+// to act on behalf of a user. Only a hash of the secret is stored.
+type APIKey struct {
+	ID     int
+	UserID int
+	Name   string
+	// Prefix is the public part of the key used to look it up.
+	Prefix string
+	Hash   string
+	Scopes []string
+	// AccountIDs restricts the key to the given accounts. An empty list
+	// allows every account owned by the user.
+	AccountIDs []int
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// AllowsAccount reports whether the key may act on the account.
+func (k *APIKey) AllowsAccount(accountID int) bool {
+	return len(k.AccountIDs) == 0 || slices.Contains(k.AccountIDs, accountID)
+}
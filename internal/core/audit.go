@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Roles grant staff access beyond a customer's own data. They are assigned
+// with bankctl and only take effect for user sessions.
+const (
+	// RoleAuditor may read the audit log.
+	RoleAuditor = "auditor"
+	// RoleCompliance may review and resolve monitoring cases, screening
+	// hits and KYC submissions.
+	RoleCompliance = "compliance"
+)
+
+// Roles lists every known role.
+var Roles = []string{RoleAuditor, RoleCompliance}
+
+// Kinds of actor that appear in the audit log.
+const (
+	// ActorAnonymous is an unauthenticated caller, such as someone logging
+	// in or signing up.
+	ActorAnonymous = "anonymous"
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorOAuth     = "oauth_client"
+	// ActorAdmin is an operator using bankctl.
+	ActorAdmin = "admin"
+	// ActorSystem is a background job.
+	ActorSystem = "system"
+)
+
+// Actor is whoever caused an audited change.
+type Actor struct {
+	Type string `json:"type"`
+	// ID identifies the actor within its type: a user or API key ID, an
+	// OAuth client ID, an operator name or a job name.
+	ID string `json:"id,omitempty"`
+	// UserID is the user an API key or OAuth client acts for, or the user
+	// themselves for sessions.
+	UserID *int `json:"user_id,omitempty"`
+}
+
+// String names the actor as type:id, or just the type if it has no ID.
+func (a Actor) String() string {
+	if a.ID == "" {
+		return a.Type
+	}
+	return a.Type + ":" + a.ID
+}
+
+// Audited actions.
+const (
+	AuditLogin           = "auth.login"
+	AuditLoginFailed     = "auth.login_failed"
+	AuditUserCreate      = "user.create"
+	AuditUserUpdate      = "user.update"
+	AuditUserDelete      = "user.delete"
+	AuditRoleGrant       = "user.role_grant"
+	AuditRoleRevoke      = "user.role_revoke"
+	AuditAccountCreate   = "account.create"
+	AuditAccountUpdate   = "account.update"
+	AuditAccountFreeze   = "account.freeze"
+	AuditAccountUnfreeze = "account.unfreeze"
+	AuditAdjustment      = "account.adjust"
+	AuditDeposit         = "payment.deposit"
+	AuditWithdrawal      = "payment.withdraw"
+	AuditTransfer        = "transfer"
+	AuditReversal        = "transaction.reverse"
+	AuditLimitsUpdate    = "limits.update"
+	AuditCaseOpen        = "case.open"
+	AuditCaseResolve     = "case.resolve"
+	AuditScreeningHit    = "screening.hit"
+	AuditHitResolve      = "screening.resolve"
+	AuditKYCSubmit       = "kyc.submit"
+	AuditKYCReview       = "kyc.review"
+	AuditTierChange      = "user.kyc_tier"
+	AuditPayeeCreate     = "beneficiary.create"
+	AuditPayeeDelete     = "beneficiary.delete"
+	AuditPotCreate       = "pot.create"
+	AuditPotUpdate       = "pot.update"
+	AuditPotDelete       = "pot.delete"
+	AuditPotMove         = "pot.move"
+	AuditInterest        = "account.interest"
+	AuditDepositOpen     = "term_deposit.open"
+	AuditDepositUpdate   = "term_deposit.update"
+	AuditDepositMature   = "term_deposit.mature"
+	AuditDepositBreak    = "term_deposit.break"
+	AuditRateUpdate      = "term_deposit.rate_update"
+)
+
+// Kinds of audit target.
+const (
+	TargetUser        = "user"
+	TargetAccount     = "account"
+	TargetTransaction = "transaction"
+	TargetProduct     = "product"
+	TargetCase        = "case"
+	TargetScreening   = "screening_hit"
+	TargetKYC         = "kyc_submission"
+	TargetTier        = "kyc_tier"
+	TargetBeneficiary = "beneficiary"
+	TargetPot         = "pot"
+	TargetTermDeposit = "term_deposit"
+	TargetTermRate    = "term_deposit_rate"
+)
+
+// AuditEntry records one change and who made it. Entries form a chain:
+// each one's hash covers its own content and the previous entry's hash,
+// so editing, inserting or deleting an entry breaks every later link.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      Actor           `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	ActorType  string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	// AfterID pages through entries in chain order.
+	AfterID int64
+	Limit   int
+}
+
+// AuditBreak is a point where the audit chain does not verify. EntryID
+// is zero for problems with the chain as a whole.
+type AuditBreak struct {
+	EntryID int64
+	Reason  string
+}
+
+// AuditVerification is the result of walking the whole audit chain.
+type AuditVerification struct {
+	CheckedAt time.Time
+	Entries   int64
+	// Head is the hash of the last entry. Keeping a copy elsewhere lets a
+	// later run detect entries deleted from the end of the chain.
+	Head   string
+	Breaks []AuditBreak
+}
+
+// OK reports whether every link verified.
+func (v *AuditVerification) OK() bool {
+	return len(v.Breaks) == 0
+}
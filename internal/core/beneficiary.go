@@ -0,0 +1,40 @@
+package core
+
+import "time"
+
+// Confirmation-of-payee results, comparing the name a customer gives for
+// a payee with the name of the account's owner.
+const (
+	PayeeMatch      = "match"
+	PayeeCloseMatch = "close_match"
+	PayeeNoMatch    = "no_match"
+)
+
+// Beneficiary is a payee a user has saved, so transfers can name it
+// instead of a raw account ID.
+type Beneficiary struct {
+	ID            int
+	UserID        int
+	Nickname      string
+	AccountID     int
+	AccountNumber string
+	// PayeeName is the name of the account's owner, as confirmed when the
+	// beneficiary was saved.
+	PayeeName string
+	// NameMatch is how closely the name the user gave matched PayeeName.
+	NameMatch string
+	CreatedAt time.Time
+	// CoolingOffUntil is when large transfers to the beneficiary become
+	// allowed, while that is still in the future.
+	CoolingOffUntil *time.Time
+}
+
+// PayeeCheck is the result of a confirmation-of-payee lookup.
+type PayeeCheck struct {
+	AccountID     int
+	AccountNumber string
+	Result        string
+	// PayeeName is the owner's name. It is only given for a match or close
+	// match, so lookups cannot reveal who owns an account.
+	PayeeName string
+}
@@ -0,0 +1,178 @@
+package core
+
+import "fmt"
+
+// Kind classifies errors by how a caller should react to them.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthenticated
+	KindForbidden
+	KindNotFound
+	KindConflict
+	// KindRejected is for well-formed requests the bank refuses to carry
+	// out, such as a payment without enough funds.
+	KindRejected
+	KindRateLimited
+)
+
+// Error codes are stable, machine-readable identifiers clients can rely on.
+// Messages may change; codes may not.
+const (
+	CodeInternal            = "internal_error"
+	CodeInvalidRequest      = "invalid_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeUnauthenticated     = "unauthenticated"
+	CodeInvalidToken        = "invalid_token"
+	CodeInvalidAPIKey       = "invalid_api_key"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeInvalidClient       = "invalid_client"
+	CodeForbidden           = "forbidden"
+	CodeMissingScope        = "missing_scope"
+	CodeSessionRequired     = "session_required"
+	CodeAccountNotFound     = "account_not_found"
+	CodeTransactionNotFound = "transaction_not_found"
+	CodeUserNotFound        = "user_not_found"
+	CodeAPIKeyNotFound      = "api_key_not_found"
+	CodeOAuthClientNotFound = "oauth_client_not_found"
+	CodeConsentNotFound     = "consent_not_found"
+	CodeWebhookNotFound     = "webhook_not_found"
+	CodeDeliveryNotFound    = "webhook_delivery_not_found"
+	CodeDuplicateEmail      = "duplicate_email"
+	CodeInsufficientFunds   = "insufficient_funds"
+	CodeAccountFrozen       = "account_frozen"
+	CodeAlreadyReversed     = "transaction_already_reversed"
+	CodeNotReversible       = "transaction_not_reversible"
+	CodeLimitExceeded       = "limit_exceeded"
+	CodeProductNotFound     = "product_not_found"
+	CodeProductRestricted   = "product_restricted"
+	CodeTransactionBlocked  = "transaction_blocked"
+	CodeCaseNotFound        = "case_not_found"
+	CodeCaseResolved        = "case_already_resolved"
+	CodeScreeningBlocked    = "screening_blocked"
+	CodeHitNotFound         = "screening_hit_not_found"
+	CodeSubmissionNotFound  = "kyc_submission_not_found"
+	CodeSubmissionPending   = "kyc_submission_pending"
+	CodeSubmissionReviewed  = "kyc_submission_reviewed"
+	CodeDocumentNotFound    = "kyc_document_not_found"
+	CodeBeneficiaryNotFound = "beneficiary_not_found"
+	CodeBeneficiaryExists   = "beneficiary_exists"
+	CodePayeeMismatch       = "payee_name_mismatch"
+	CodeCoolingOff          = "beneficiary_cooling_off"
+	CodePotNotFound         = "pot_not_found"
+	CodePotLocked           = "pot_locked"
+	CodePotNotEmpty         = "pot_not_empty"
+	CodeTermDepositNotFound = "term_deposit_not_found"
+	CodeTermDepositClosed   = "term_deposit_closed"
+	CodeTermNotOffered      = "term_not_offered"
+	CodeRateLimited         = "rate_limited"
+)
+
+// Domain errors returned by storage and services.
+var (
+	ErrAccountNotFound     = &Error{Kind: KindNotFound, Code: CodeAccountNotFound, Message: "account not found"}
+	ErrInsufficientFunds   = &Error{Kind: KindRejected, Code: CodeInsufficientFunds, Message: "insufficient funds"}
+	ErrAccountFrozen       = &Error{Kind: KindRejected, Code: CodeAccountFrozen, Message: "account is frozen"}
+	ErrAlreadyReversed     = &Error{Kind: KindConflict, Code: CodeAlreadyReversed, Message: "transaction has already been reversed"}
+	ErrNotReversible       = &Error{Kind: KindRejected, Code: CodeNotReversible, Message: "transaction cannot be reversed"}
+	ErrLimitExceeded       = &Error{Kind: KindRejected, Code: CodeLimitExceeded, Message: "transaction limit exceeded"}
+	ErrProductNotFound     = &Error{Kind: KindNotFound, Code: CodeProductNotFound, Message: "product not found"}
+	ErrProductRestricted   = &Error{Kind: KindRejected, Code: CodeProductRestricted, Message: "the account's product does not allow this"}
+	ErrTransactionBlocked  = &Error{Kind: KindRejected, Code: CodeTransactionBlocked, Message: "transaction blocked for review"}
+	ErrCaseNotFound        = &Error{Kind: KindNotFound, Code: CodeCaseNotFound, Message: "case not found"}
+	ErrCaseResolved        = &Error{Kind: KindConflict, Code: CodeCaseResolved, Message: "case has already been resolved"}
+	ErrScreeningBlocked    = &Error{Kind: KindRejected, Code: CodeScreeningBlocked, Message: "request cannot be completed"}
+	ErrHitNotFound         = &Error{Kind: KindNotFound, Code: CodeHitNotFound, Message: "screening hit not found"}
+	ErrSubmissionNotFound  = &Error{Kind: KindNotFound, Code: CodeSubmissionNotFound, Message: "kyc submission not found"}
+	ErrSubmissionPending   = &Error{Kind: KindConflict, Code: CodeSubmissionPending, Message: "a kyc submission is already awaiting review"}
+	ErrSubmissionReviewed  = &Error{Kind: KindConflict, Code: CodeSubmissionReviewed, Message: "kyc submission has already been reviewed"}
+	ErrDocumentNotFound    = &Error{Kind: KindNotFound, Code: CodeDocumentNotFound, Message: "kyc document not found"}
+	ErrBeneficiaryNotFound = &Error{Kind: KindNotFound, Code: CodeBeneficiaryNotFound, Message: "beneficiary not found"}
+	ErrBeneficiaryExists   = &Error{Kind: KindConflict, Code: CodeBeneficiaryExists, Message: "account is already a beneficiary"}
+	ErrPayeeMismatch       = &Error{Kind: KindRejected, Code: CodePayeeMismatch, Message: "name does not match the account's owner"}
+	ErrCoolingOff          = &Error{Kind: KindRejected, Code: CodeCoolingOff, Message: "beneficiary is too new for a transfer this large"}
+	ErrPotNotFound         = &Error{Kind: KindNotFound, Code: CodePotNotFound, Message: "pot not found"}
+	ErrPotLocked           = &Error{Kind: KindRejected, Code: CodePotLocked, Message: "pot is locked"}
+	ErrPotNotEmpty         = &Error{Kind: KindRejected, Code: CodePotNotEmpty, Message: "pot still holds money"}
+	ErrTermDepositNotFound = &Error{Kind: KindNotFound, Code: CodeTermDepositNotFound, Message: "term deposit not found"}
+	ErrTermDepositClosed   = &Error{Kind: KindConflict, Code: CodeTermDepositClosed, Message: "term deposit has already been paid out"}
+	ErrTermNotOffered      = &Error{Kind: KindRejected, Code: CodeTermNotOffered, Message: "no term deposit is offered for this term"}
+	ErrTransactionNotFound = &Error{Kind: KindNotFound, Code: CodeTransactionNotFound, Message: "transaction not found"}
+	ErrUserNotFound        = &Error{Kind: KindNotFound, Code: CodeUserNotFound, Message: "user not found"}
+	ErrDuplicateEmail      = &Error{Kind: KindConflict, Code: CodeDuplicateEmail, Message: "a user with this email already exists"}
+	ErrInvalidCredentials  = &Error{Kind: KindUnauthenticated, Code: CodeInvalidCredentials, Message: "invalid email or password"}
+	ErrAPIKeyNotFound      = &Error{Kind: KindNotFound, Code: CodeAPIKeyNotFound, Message: "api key not found"}
+	ErrInvalidAPIKey       = &Error{Kind: KindUnauthenticated, Code: CodeInvalidAPIKey, Message: "invalid or revoked api key"}
+	ErrOAuthClientNotFound = &Error{Kind: KindNotFound, Code: CodeOAuthClientNotFound, Message: "oauth client not found"}
+	ErrInvalidClient       = &Error{Kind: KindUnauthenticated, Code: CodeInvalidClient, Message: "invalid client credentials"}
+	ErrConsentNotFound     = &Error{Kind: KindNotFound, Code: CodeConsentNotFound, Message: "consent not found"}
+	ErrWebhookNotFound     = &Error{Kind: KindNotFound, Code: CodeWebhookNotFound, Message: "webhook not found"}
+	ErrDeliveryNotFound    = &Error{Kind: KindNotFound, Code: CodeDeliveryNotFound, Message: "webhook delivery not found"}
+
+	ErrUnauthenticated = &Error{Kind: KindUnauthenticated, Code: CodeUnauthenticated, Message: "authentication required"}
+	ErrInvalidToken    = &Error{Kind: KindUnauthenticated, Code: CodeInvalidToken, Message: "invalid or expired token"}
+	ErrForbidden       = &Error{Kind: KindForbidden, Code: CodeForbidden, Message: "forbidden"}
+	ErrRateLimited     = &Error{Kind: KindRateLimited, Code: CodeRateLimited, Message: "rate limit exceeded"}
+)
+
+// Error is a domain error with a stable code. Its message is safe to show
+// to clients.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields lists the offending fields of an invalid request.
+	Fields []FieldError
+}
+
+// FieldError describes a problem with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors by code, so errors.Is(err, ErrAccountNotFound) also
+// holds for copies made with WithMessage.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a more specific message.
+func (e *Error) WithMessage(format string, args ...any) *Error {
+	c := *e
+	c.Message = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// Invalid reports a malformed request.
+func Invalid(format string, args ...any) *Error {
+	return &Error{Kind: KindInvalid, Code: CodeInvalidRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidFields reports a request whose fields failed validation.
+func InvalidFields(fields ...FieldError) *Error {
+	return &Error{Kind: KindInvalid, Code: CodeValidationFailed, Message: "request validation failed", Fields: fields}
+}
+
+// InvalidField reports a request with one invalid field.
+func InvalidField(field, message string) *Error {
+	return InvalidFields(FieldError{Field: field, Message: message})
+}
+
+// Forbidden reports that the caller may not do something.
+func Forbidden(format string, args ...any) *Error {
+	return ErrForbidden.WithMessage(format, args...)
+}
+
+// Internal reports an unexpected failure. The message should say what
+// failed without exposing why.
+func Internal(format string, args ...any) *Error {
+	return &Error{Kind: KindInternal, Code: CodeInternal, Message: fmt.Sprintf(format, args...)}
+}
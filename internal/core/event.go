@@ -0,0 +1,66 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// Event types emitted for account activity.
+const (
+	EventAccountCreated   = "account.created"
+	EventTransferSent     = "transfer.sent"
+	EventTransferReceived = "transfer.received"
+	EventDeposit          = "payment.deposit"
+	EventWithdrawal       = "payment.withdraw"
+	EventAccountFrozen    = "account.frozen"
+	EventAccountUnfrozen  = "account.unfrozen"
+	EventAdjustment       = "account.adjusted"
+	EventReversal         = "transaction.reversed"
+	// EventPotMoved carries the signed change a pot move made to the
+	// account's balance.
+	EventPotMoved = "pot.moved"
+	// EventInterestPaid is interest credited to a term deposit's account.
+	EventInterestPaid = "interest.paid"
+)
+
+// EventTypes lists every event type that can be subscribed to.
+var EventTypes = []string{
+	EventAccountCreated,
+	EventTransferSent,
+	EventTransferReceived,
+	EventDeposit,
+	EventWithdrawal,
+	EventAccountFrozen,
+	EventAccountUnfrozen,
+	EventAdjustment,
+	EventReversal,
+	EventPotMoved,
+	EventInterestPaid,
+}
+
+// ValidEventType reports whether t is a known event type.
+func ValidEventType(t string) bool {
+	return slices.Contains(EventTypes, t)
+}
+
+// Event describes something that happened to an account.
+type Event struct {
+	ID string
+	// Sequence orders events; it increases with every event written.
+	Sequence int64
+	Type     string
+	// UserID owns the account the event concerns.
+	UserID    int
+	AccountID int
+	Amount    int64
+	// Balance is the account balance after the event.
+	Balance               int64
+	Reference             string
+	CounterpartyAccountID *int
+	OccurredAt            time.Time
+	// The account numbers of AccountID and CounterpartyAccountID, which
+	// are what leaves the bank. Storage fills them in when it reads
+	// events back.
+	AccountNumber             string
+	CounterpartyAccountNumber string
+}
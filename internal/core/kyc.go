@@ -0,0 +1,105 @@
+package core
+
+import (
+	"io"
+	"time"
+)
+
+// KYC tiers, from least to most verified. A user's tier caps the money
+// leaving their accounts on top of the account's own limits.
+const (
+	TierUnverified = "unverified"
+	// TierBasic needs identity data and an identity document.
+	TierBasic = "basic"
+	// TierFull also needs a proof of address.
+	TierFull = "full"
+)
+
+// Tiers lists every tier, from least to most verified.
+var Tiers = []string{TierUnverified, TierBasic, TierFull}
+
+// TierRank orders tiers, returning -1 for unknown ones.
+func TierRank(tier string) int {
+	for i, t := range Tiers {
+		if t == tier {
+			return i
+		}
+	}
+	return -1
+}
+
+// Kinds of KYC document.
+const (
+	DocumentIdentity       = "identity"
+	DocumentProofOfAddress = "proof_of_address"
+)
+
+// Identity documents a customer can verify with.
+var IdentityDocumentTypes = []string{"passport", "national_id", "driving_licence"}
+
+// KYC submission statuses. Pending submissions wait in the review queue.
+const (
+	KYCPending  = "pending"
+	KYCApproved = "approved"
+	KYCRejected = "rejected"
+)
+
+// KYCSubmission is a customer's request to be verified to a tier, with
+// the identity data they gave.
+type KYCSubmission struct {
+	ID     int
+	UserID int
+	Tier   string
+	// LegalName is the name on the identity document, which may differ
+	// from the name the customer signed up with.
+	LegalName      string
+	DateOfBirth    time.Time
+	Address        string
+	Nationality    string
+	DocumentType   string
+	DocumentNumber string
+	Status         string
+	// Note is what the reviewer wrote; it is shown to the customer.
+	Note       string
+	ReviewedBy string
+	ReviewedAt *time.Time
+	CreatedAt  time.Time
+	Documents  []*KYCDocument
+}
+
+// KYCDocument is an uploaded document. Its content is kept in the blob
+// store under BlobKey.
+type KYCDocument struct {
+	ID           int
+	SubmissionID int
+	Kind         string
+	Filename     string
+	ContentType  string
+	Size         int64
+	SHA256       string
+	BlobKey      string
+	CreatedAt    time.Time
+}
+
+// KYCUpload is a document being submitted.
+type KYCUpload struct {
+	Kind     string
+	Filename string
+	Body     io.Reader
+}
+
+// KYCStatus is a user's tier and their latest submission, if any.
+type KYCStatus struct {
+	UserID int
+	Tier   string
+	Latest *KYCSubmission
+}
+
+// KYCFilter selects KYC submissions. Zero fields match everything.
+type KYCFilter struct {
+	Status string
+	UserID int
+	// AfterID pages through submissions in ID order.
+	AfterID int
+	Limit   int
+}
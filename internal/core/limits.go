@@ -0,0 +1,134 @@
+package core
+
+import "time"
+
+// Limits cap the money leaving an account through withdrawals and sent
+// transfers. A nil limit is no limit. Support adjustments and reversals
+// are not limited and do not count.
+type Limits struct {
+	// MaxSingle caps the amount of one withdrawal or transfer.
+	MaxSingle *int64
+	// DailyOutbound caps the total sent since midnight UTC.
+	DailyOutbound *int64
+	// MonthlyOutbound caps the total sent since the start of the month, UTC.
+	MonthlyOutbound *int64
+	// HourlyCount caps how many withdrawals and transfers can be made in
+	// any 60 minutes.
+	HourlyCount *int64
+}
+
+// LimitUsage is how much of a limit has been used in its current window.
+type LimitUsage struct {
+	Limit *int64
+	Used  int64
+	// ResetsAt is when usage next falls, if anything is used.
+	ResetsAt *time.Time
+}
+
+// Remaining is what can still be used before the limit is reached, or nil
+// if there is no limit.
+func (u LimitUsage) Remaining() *int64 {
+	if u.Limit == nil {
+		return nil
+	}
+	r := max(*u.Limit-u.Used, 0)
+	return &r
+}
+
+// AccountLimits are the limits in force on an account and how much of
+// each has been used.
+type AccountLimits struct {
+	AccountID int
+	Product   string
+	// Tier is the owner's KYC tier, whose limits apply where they are
+	// lower than the account's. It is empty if they were not applied.
+	Tier      string
+	MaxSingle *int64
+	Daily     LimitUsage
+	Monthly   LimitUsage
+	Hourly    LimitUsage
+}
+
+// Cap lowers each of the account's limits to the matching one of limits,
+// such as those of its owner's KYC tier, where that is lower.
+func (l *AccountLimits) Cap(limits Limits) {
+	l.MaxSingle = lower(l.MaxSingle, limits.MaxSingle)
+	l.Daily.Limit = lower(l.Daily.Limit, limits.DailyOutbound)
+	l.Monthly.Limit = lower(l.Monthly.Limit, limits.MonthlyOutbound)
+	l.Hourly.Limit = lower(l.Hourly.Limit, limits.HourlyCount)
+}
+
+// lower returns the lower of two limits, where nil is no limit.
+func lower(a, b *int64) *int64 {
+	if a == nil || (b != nil && *b < *a) {
+		return b
+	}
+	return a
+}
+
+// Check returns ErrLimitExceeded if sending amount would break a limit.
+func (l *AccountLimits) Check(amount int64) error {
+	if l.MaxSingle != nil && amount > *l.MaxSingle {
+		return ErrLimitExceeded.WithMessage("amount exceeds the single transaction limit of %d", *l.MaxSingle)
+	}
+	if r := l.Daily.Remaining(); r != nil && amount > *r {
+		return ErrLimitExceeded.WithMessage("amount exceeds the remaining daily limit of %d", *r)
+	}
+	if r := l.Monthly.Remaining(); r != nil && amount > *r {
+		return ErrLimitExceeded.WithMessage("amount exceeds the remaining monthly limit of %d", *r)
+	}
+	if r := l.Hourly.Remaining(); r != nil && *r == 0 {
+		return ErrLimitExceeded.WithMessage("at most %d withdrawals and transfers are allowed per hour", *l.Hourly.Limit)
+	}
+	return nil
+}
+
+// Outbound reports whether a transaction took money out of its account on
+// a customer's behalf, and so counts toward the account's limits.
+func (t *Transaction) Outbound() bool {
+	return t.Type == TransactionWithdraw || (t.Type == TransactionTransfer && t.ToAccountID != nil)
+}
+
+// Use counts the outbound transactions among txns, all booked on the
+// account, toward the usage of its limits in the windows current at now:
+// the UTC day, the UTC month and the last hour. Stores that cannot
+// compute usage themselves use it.
+func (l *AccountLimits) Use(txns []*Transaction, now time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hour := now.Add(-time.Hour)
+
+	var oldest time.Time
+	for _, t := range txns {
+		if !t.Outbound() {
+			continue
+		}
+		at := t.Timestamp.UTC()
+		if !at.Before(day) {
+			l.Daily.Used += t.Amount
+		}
+		if !at.Before(month) {
+			l.Monthly.Used += t.Amount
+		}
+		if at.After(hour) {
+			l.Hourly.Used++
+			if oldest.IsZero() || at.Before(oldest) {
+				oldest = at
+			}
+		}
+	}
+
+	if l.Daily.Used > 0 {
+		t := day.AddDate(0, 0, 1)
+		l.Daily.ResetsAt = &t
+	}
+	if l.Monthly.Used > 0 {
+		t := month.AddDate(0, 1, 0)
+		l.Monthly.ResetsAt = &t
+	}
+	if !oldest.IsZero() {
+		t := oldest.Add(time.Hour)
+		l.Hourly.ResetsAt = &t
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func limit(n int64) *int64 { return &n }
+
+func TestAccountLimitsCap(t *testing.T) {
+	tests := []struct {
+		name    string
+		account *int64
+		tier    *int64
+		want    *int64
+	}{
+		{"neither", nil, nil, nil},
+		{"account only", limit(500), nil, limit(500)},
+		{"tier only", nil, limit(300), limit(300)},
+		{"tier lower", limit(500), limit(300), limit(300)},
+		{"account lower", limit(200), limit(300), limit(200)},
+		{"equal", limit(300), limit(300), limit(300)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &AccountLimits{MaxSingle: tt.account}
+			l.Daily.Limit = tt.account
+			l.Monthly.Limit = tt.account
+			l.Hourly.Limit = tt.account
+			l.Cap(Limits{MaxSingle: tt.tier, DailyOutbound: tt.tier, MonthlyOutbound: tt.tier, HourlyCount: tt.tier})
+			for name, got := range map[string]*int64{
+				"max single": l.MaxSingle,
+				"daily":      l.Daily.Limit,
+				"monthly":    l.Monthly.Limit,
+				"hourly":     l.Hourly.Limit,
+			} {
+				if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+					t.Errorf("%s limit = %v, want %v", name, deref(got), deref(tt.want))
+				}
+			}
+		})
+	}
+}
+
+func TestAccountLimitsCheckAfterCap(t *testing.T) {
+	l := &AccountLimits{MaxSingle: limit(1000)}
+	l.Cap(Limits{MaxSingle: limit(100)})
+	if err := l.Check(100); err != nil {
+		t.Errorf("Check(100) = %v, want nil", err)
+	}
+	if err := l.Check(101); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Check(101) = %v, want ErrLimitExceeded", err)
+	}
+}
+
+func deref(p *int64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestAccountLimitsUse(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+	to := 2
+	txns := []*Transaction{
+		{Type: TransactionWithdraw, Amount: 1, Timestamp: now.Add(-10 * time.Minute)},
+		{Type: TransactionTransfer, Amount: 10, ToAccountID: &to, Timestamp: now.Add(-2 * time.Hour)},
+		{Type: TransactionWithdraw, Amount: 100, Timestamp: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: TransactionWithdraw, Amount: 1000, Timestamp: time.Date(2025, 2, 28, 23, 59, 0, 0, time.UTC)},
+		// Money coming in, moved to pots and booked by support is not
+		// counted.
+		{Type: TransactionDeposit, Amount: 10000, Timestamp: now},
+		{Type: TransactionTransfer, Amount: 10000, Timestamp: now},
+		{Type: TransactionPotIn, Amount: 10000, Timestamp: now},
+		{Type: TransactionAdjustment, Amount: -10000, Timestamp: now},
+	}
+	var l AccountLimits
+	l.Use(txns, now)
+
+	if l.Daily.Used != 11 || l.Monthly.Used != 111 || l.Hourly.Used != 1 {
+		t.Errorf("daily, monthly, hourly used = %d, %d, %d, want 11, 111, 1", l.Daily.Used, l.Monthly.Used, l.Hourly.Used)
+	}
+	for name, tt := range map[string]struct {
+		got  *time.Time
+		want time.Time
+	}{
+		"daily":   {l.Daily.ResetsAt, time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)},
+		"monthly": {l.Monthly.ResetsAt, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		"hourly":  {l.Hourly.ResetsAt, now.Add(50 * time.Minute)},
+	} {
+		if tt.got == nil || !tt.got.Equal(tt.want) {
+			t.Errorf("%s resets at %v, want %v", name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import "time"
+
+// Monitoring decisions, from least to most severe. Review lets a movement
+// through and opens a case; block refuses it and opens a case.
+const (
+	DecisionAllow  = "allow"
+	DecisionReview = "review"
+	DecisionBlock  = "block"
+)
+
+// Where a case came from: a movement screened as it was made, or the
+// scheduled scan of recent history.
+const (
+	CaseSourceRealtime = "realtime"
+	CaseSourceScan     = "scan"
+)
+
+// Case statuses. Open cases await compliance staff, who clear false
+// alarms or confirm suspicious activity.
+const (
+	CaseOpen      = "open"
+	CaseCleared   = "cleared"
+	CaseConfirmed = "confirmed"
+)
+
+// Case is activity a monitoring rule flagged for review.
+type Case struct {
+	ID        int
+	AccountID int
+	Rule      string
+	Decision  string
+	Reason    string
+	// Reference is the flagged transaction's, or the attempt's if it was
+	// blocked.
+	Reference       string
+	TransactionType string
+	Amount          int64
+	Source          string
+	Status          string
+	// Note is what the reviewer wrote when resolving the case.
+	Note       string
+	ResolvedBy string
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// CaseFilter selects cases. Zero fields match everything.
+type CaseFilter struct {
+	Status    string
+	AccountID int
+	Rule      string
+	// AfterID pages through cases in ID order.
+	AfterID int
+	Limit   int
+}
+
+// AccountActivity is an account and its transactions since some time,
+// oldest first: the history monitoring rules judge a movement against.
+type AccountActivity struct {
+	Account      *Account
+	Transactions []*Transaction
+}
+
+// MonitoringScan is the result of screening recent history again.
+type MonitoringScan struct {
+	ScannedAt time.Time
+	Since     time.Time
+	// Transactions is how many transactions were screened.
+	Transactions int
+	// Cases are the cases opened by this scan. Activity already flagged
+	// does not open a second case.
+	Cases []*Case
+}
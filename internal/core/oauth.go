@@ -0,0 +1,53 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// OAuthClient is a third-party application registered to access customer
+// accounts through OAuth2.
+type OAuthClient struct {
+	ID          string
+	OwnerUserID int
+	Name        string
+	// SecretHash is empty for public clients, which authenticate with PKCE only.
+	SecretHash   string
+	RedirectURIs []string
+	// Scopes are the scopes the client may request.
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+// Confidential reports whether the client authenticates with a secret.
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirect reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// OAuthConsent records the scopes a user granted to a client.
+type OAuthConsent struct {
+	ID        int
+	UserID    int
+	ClientID  string
+	Scopes    []string
+	GrantedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Covers reports whether the consent is active and includes every scope.
+func (c *OAuthConsent) Covers(scopes []string) bool {
+	if c.RevokedAt != nil {
+		return false
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package core
+
+import "time"
+
+// RoundUpUnit is what withdrawals are rounded up to, in minor units, when
+// an account saves its spare change into a pot.
+const RoundUpUnit = 100
+
+// Pot sets money aside inside an account. Its balance is kept apart from
+// the account's, which is what the owner can spend, and moves between the
+// two are booked as pot_in and pot_out transactions on the account.
+type Pot struct {
+	ID        int
+	AccountID int
+	Name      string
+	Balance   int64
+	// TargetAmount and TargetDate are the owner's savings goal, if any.
+	TargetAmount *int64
+	TargetDate   *time.Time
+	// LockedUntil keeps money in the pot until then.
+	LockedUntil *time.Time
+	// RoundUp marks the one pot of an account that receives the change
+	// from rounding up its withdrawals.
+	RoundUp   bool
+	CreatedAt time.Time
+}
+
+// Locked reports whether money cannot leave the pot at now.
+func (p *Pot) Locked(now time.Time) bool {
+	return p.LockedUntil != nil && now.Before(*p.LockedUntil)
+}
+
+// RoundUp returns what rounds amount up to the next RoundUpUnit; zero if
+// it is already a whole number of them.
+func RoundUp(amount int64) int64 {
+	return (RoundUpUnit - amount%RoundUpUnit) % RoundUpUnit
+}
+
+// PotUpdate changes the fields of a pot that are not nil.
+type PotUpdate struct {
+	Name         *string
+	TargetAmount *int64
+	TargetDate   *time.Time
+	LockedUntil  *time.Time
+	RoundUp      *bool
+}
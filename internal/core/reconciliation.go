@@ -0,0 +1,60 @@
+package core
+
+import "time"
+
+// LedgerMismatch is an account whose stored balance differs from the sum
+// of its transactions, or a pot whose balance differs from the sum of its
+// moves.
+type LedgerMismatch struct {
+	AccountID int
+	// PotID is set if the mismatch is in one of the account's pots.
+	PotID         *int
+	Balance       int64
+	LedgerBalance int64
+}
+
+// Delta is how far the stored balance is off: positive if the account
+// holds more than its transactions explain.
+func (m LedgerMismatch) Delta() int64 {
+	return m.Balance - m.LedgerBalance
+}
+
+// TransferImbalance is a transfer, a reversal of one or a pot move whose
+// legs do not cancel out, so moving the money created or destroyed some.
+type TransferImbalance struct {
+	Reference  string
+	AccountIDs []int
+	Legs       int
+	// Net is what the legs add up to; it should be zero.
+	Net int64
+}
+
+// Reconciliation is the result of checking the whole ledger in a single
+// snapshot.
+type Reconciliation struct {
+	CheckedAt    time.Time
+	Accounts     int
+	Transactions int
+	// TotalBalance is the sum of every account and pot balance.
+	TotalBalance int64
+	// ExternalNet is the money that came in or went out through deposits,
+	// withdrawals, adjustments and their reversals.
+	ExternalNet int64
+	// InternalNet is what transfers, their reversals and pot moves add up
+	// to. Money moved between accounts and pots is neither created nor
+	// destroyed, so it should be zero.
+	InternalNet         int64
+	Mismatches          []LedgerMismatch
+	ImbalancedTransfers []TransferImbalance
+}
+
+// Conserved reports whether the bank holds exactly the money that came in
+// net of what went out.
+func (r *Reconciliation) Conserved() bool {
+	return r.InternalNet == 0 && r.TotalBalance == r.ExternalNet
+}
+
+// OK reports whether the ledger is consistent.
+func (r *Reconciliation) OK() bool {
+	return r.Conserved() && len(r.Mismatches) == 0 && len(r.ImbalancedTransfers) == 0
+}
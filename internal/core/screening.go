@@ -0,0 +1,54 @@
+package core
+
+import "time"
+
+// Kinds of party screened against the watchlist.
+const (
+	ScreeningSubjectUser        = "user"
+	ScreeningSubjectBeneficiary = "beneficiary"
+)
+
+// ScreeningHit is a name that resembled a watchlist entry closely enough
+// to need review or to be blocked. Hits reuse the case statuses: open
+// until compliance staff clear or confirm them.
+type ScreeningHit struct {
+	ID          int
+	SubjectType string
+	// SubjectID is nil when the party was blocked before it was created,
+	// such as a refused signup.
+	SubjectID *int
+	Name      string
+	EntryID   string
+	EntryName string
+	Program   string
+	// Score is how alike the names are, from 0 to 1.
+	Score    float64
+	Decision string
+	// ListVersion identifies the watchlist the hit was found in.
+	ListVersion string
+	Status      string
+	Note        string
+	ResolvedBy  string
+	ResolvedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+// ScreeningHitFilter selects screening hits. Zero fields match everything.
+type ScreeningHitFilter struct {
+	Status      string
+	SubjectType string
+	SubjectID   int
+	// AfterID pages through hits in ID order.
+	AfterID int
+	Limit   int
+}
+
+// Rescreening is the result of screening every user again.
+type Rescreening struct {
+	ScreenedAt  time.Time
+	ListVersion string
+	Users       int
+	// Hits are the hits recorded by this run. Names already matched to an
+	// entry are not recorded again.
+	Hits []*ScreeningHit
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"math/big"
+	"time"
+)
+
+// Term deposit statuses. An active deposit holds its money in a
+// fixed_deposit account of its own until it matures or is broken early;
+// the account then pays everything in it out to the payout account.
+const (
+	TermDepositActive  = "active"
+	TermDepositMatured = "matured"
+	TermDepositBroken  = "broken"
+)
+
+// DaysPerYear is the year interest rates are quoted over. Interest is
+// simple, for the UTC calendar days held.
+const DaysPerYear = 365
+
+// TermDepositRate is what the bank pays on deposits of one term. Rates
+// are in basis points a year.
+type TermDepositRate struct {
+	TermMonths int
+	RateBPS    int
+	// BreakRateBPS is paid instead of RateBPS, for the days held, on a
+	// deposit broken before it matures. The interest lost is the penalty.
+	BreakRateBPS int
+}
+
+// TermDeposit locks money away for a term at a fixed rate.
+type TermDeposit struct {
+	ID     int
+	UserID int
+	// AccountID is the fixed_deposit account holding the money.
+	AccountID int
+	// PayoutAccountID funded the deposit and receives it back with its
+	// interest.
+	PayoutAccountID int
+	// AccountNumber and PayoutAccountNumber are the accounts' numbers,
+	// filled in by the service.
+	AccountNumber       string
+	PayoutAccountNumber string
+	Principal           int64
+	TermMonths          int
+	// The rates are those offered when the current term started.
+	RateBPS      int
+	BreakRateBPS int
+	// Rollover starts another term at maturity instead of paying out, at
+	// the rate then offered, with the interest added to the principal.
+	Rollover bool
+	Status   string
+	// StartedAt and MaturesAt bound the current term.
+	StartedAt time.Time
+	MaturesAt time.Time
+	// InterestPaid totals the interest credited over every term.
+	InterestPaid int64
+	ClosedAt     *time.Time
+	CreatedAt    time.Time
+}
+
+// MaturityDate returns when a term of months started at start ends.
+func MaturityDate(start time.Time, months int) time.Time {
+	return start.AddDate(0, months, 0)
+}
+
+// Interest returns the interest the deposit earns by maturity.
+func (d *TermDeposit) Interest() int64 {
+	return Interest(d.Principal, d.RateBPS, days(d.StartedAt, d.MaturesAt))
+}
+
+// BreakInterest returns the interest paid if the deposit is broken at now,
+// at the break rate for the UTC calendar days held.
+func (d *TermDeposit) BreakInterest(now time.Time) int64 {
+	return Interest(d.Principal, d.BreakRateBPS, days(d.StartedAt, now))
+}
+
+// Interest returns the simple interest on principal at rateBPS a year for
+// days, rounded down to a minor unit. It is worked out in arbitrary
+// precision, so large deposits cannot overflow.
+func Interest(principal int64, rateBPS int, days int) int64 {
+	if principal <= 0 || rateBPS <= 0 || days <= 0 {
+		return 0
+	}
+	n := new(big.Int).Mul(big.NewInt(principal), big.NewInt(int64(rateBPS)))
+	n.Mul(n, big.NewInt(int64(days)))
+	return n.Quo(n, big.NewInt(10000*DaysPerYear)).Int64()
+}
+
+// days counts the UTC calendar days from from to to. Going by dates
+// rather than elapsed time means the zone the times are in, and any
+// daylight saving change between them, cannot lose a day.
+func days(from, to time.Time) int {
+	from, to = utcDate(from), utcDate(to)
+	if !to.After(from) {
+		return 0
+	}
+	return int(to.Sub(from) / (24 * time.Hour))
+}
+
+// utcDate returns midnight UTC of t's UTC date.
+func utcDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// MaturityRun is the result of processing the term deposits that have
+// matured.
+type MaturityRun struct {
+	RanAt time.Time
+	// Due counts the deposits found matured.
+	Due        int
+	PaidOut    int
+	RolledOver int
+	// Failures are the deposits left to the next run.
+	Failures []MaturityFailure
+}
+
+// MaturityFailure is a matured deposit that could not be processed, such
+// as one whose payout account is frozen.
+type MaturityFailure struct {
+	TermDepositID int
+	Err           string
+}
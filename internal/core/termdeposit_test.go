@@ -0,0 +1,104 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestInterest(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal int64
+		rateBPS   int
+		days      int
+		want      int64
+	}{
+		{"one year", 100000, 500, 365, 5000},
+		{"half a year", 100000, 500, 182, 2493},
+		{"rounds down", 10001, 500, 365, 500},
+		{"less than a minor unit", 1000, 1, 365, 0},
+		{"no days", 100000, 500, 0, 0},
+		{"negative days", 100000, 500, -1, 0},
+		{"no rate", 100000, 0, 365, 0},
+		{"no principal", 0, 500, 365, 0},
+		{"does not overflow", math.MaxInt64, 100, 365, math.MaxInt64 / 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Interest(tt.principal, tt.rateBPS, tt.days); got != tt.want {
+				t.Errorf("Interest(%d, %d, %d) = %d, want %d", tt.principal, tt.rateBPS, tt.days, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermDepositInterestCountsDaysOfTheTerm(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		want  int64
+	}{
+		// 2024 is a leap year, so its term is a day longer.
+		{"leap year", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 5013},
+		{"common year", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := TermDeposit{Principal: 100000, RateBPS: 500, StartedAt: tt.start, MaturesAt: MaturityDate(tt.start, 12)}
+			if got := d.Interest(); got != tt.want {
+				t.Errorf("Interest() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermDepositBreakInterestCountsDaysHeld(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := TermDeposit{Principal: 365000, RateBPS: 500, BreakRateBPS: 100, StartedAt: start, MaturesAt: MaturityDate(start, 12)}
+	tests := []struct {
+		name string
+		now  time.Time
+		want int64
+	}{
+		{"before it started", start.Add(-time.Hour), 0},
+		{"same day", start.Add(11 * time.Hour), 0},
+		{"next day", start.Add(13 * time.Hour), 10},
+		{"time of day not counted", start.Add(30*24*time.Hour + 11*time.Hour), 300},
+		{"at the break rate", start.AddDate(0, 0, 100), 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.BreakInterest(tt.now); got != tt.want {
+				t.Errorf("BreakInterest(%v) = %d, want %d", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysAcrossDaylightSaving(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		// Clocks go forward on 30 March 2025, so noon to noon is 23 hours.
+		{"clocks go forward", time.Date(2025, 3, 29, 12, 0, 0, 0, london), time.Date(2025, 3, 30, 12, 0, 0, 0, london), 1},
+		{"week over clocks going forward", time.Date(2025, 3, 27, 12, 0, 0, 0, london), time.Date(2025, 4, 3, 12, 0, 0, 0, london), 7},
+		// Clocks go back on 26 October 2025, so noon to noon is 25 hours.
+		{"clocks go back", time.Date(2025, 10, 25, 12, 0, 0, 0, london), time.Date(2025, 10, 26, 12, 0, 0, 0, london), 1},
+		// Half past midnight on 31 March in London is still 30 March in UTC.
+		{"counts UTC dates", time.Date(2025, 3, 30, 0, 30, 0, 0, time.UTC), time.Date(2025, 3, 31, 0, 30, 0, 0, london), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := days(tt.from, tt.to); got != tt.want {
+				t.Errorf("days(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,22 @@ package core
 
 import "time"
 
+// Transaction types. Deposits, withdrawals, transfers, pot moves and
+// interest carry positive amounts and their type gives the direction;
+// adjustments and reversals carry the signed change to the account's
+// balance. pot_in moves money from the account into one of its pots and
+// pot_out moves it back. Interest is credited to term deposits.
+const (
+	TransactionDeposit    = "deposit"
+	TransactionWithdraw   = "withdraw"
+	TransactionTransfer   = "transfer"
+	TransactionAdjustment = "adjustment"
+	TransactionReversal   = "reversal"
+	TransactionPotIn      = "pot_in"
+	TransactionPotOut     = "pot_out"
+	TransactionInterest   = "interest"
+)
+
 type Transaction struct {
 	ID            int
 	AccountID     int
@@ -11,4 +27,15 @@ type Transaction struct {
 	Reference     string
 	FromAccountID *int
 	ToAccountID   *int
+	// The account numbers of AccountID, FromAccountID and ToAccountID, as
+	// shown to customers. Storage leaves them empty.
+	AccountNumber     string
+	FromAccountNumber string
+	ToAccountNumber   string
+	// Reason says why support made an adjustment or reversal.
+	Reason string
+	// ReversesID is the transaction a reversal undoes.
+	ReversesID *int
+	// PotID is the pot a pot_in or pot_out moved money to or from.
+	PotID *int
 }
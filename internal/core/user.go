@@ -8,3 +8,22 @@ type User struct {
 	Balance   *int
 	Password  *string
 }
+
+// ProductBalance totals a user's accounts on one product.
+type ProductBalance struct {
+	Product  string
+	Accounts int
+	Balance  int64
+	// PotBalance is what the accounts hold in pots, on top of Balance.
+	PotBalance int64
+}
+
+// UserBalances totals the balances of every account a user has and of
+// their pots.
+type UserBalances struct {
+	UserID int
+	Total  int64
+	// PrimaryAccountNumber is empty if the user has no primary account.
+	PrimaryAccountNumber string
+	Products             []ProductBalance
+}
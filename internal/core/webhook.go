@@ -0,0 +1,56 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription asks for events on a user's accounts to be POSTed
+// to a URL, signed with Secret.
+type WebhookSubscription struct {
+	ID         int
+	UserID     int
+	URL        string
+	EventTypes []string
+	Secret     string
+	// AccountIDs restricts the subscription to events on the given
+	// accounts, those of the API key that created it. An empty list
+	// covers every account owned by the user.
+	AccountIDs []int
+	CreatedAt  time.Time
+}
+
+// Wants reports whether the subscription covers an event type.
+func (s *WebhookSubscription) Wants(eventType string) bool {
+	return slices.Contains(s.EventTypes, eventType)
+}
+
+// AllowsAccount reports whether the subscription covers events on the
+// account.
+func (s *WebhookSubscription) AllowsAccount(accountID int) bool {
+	return len(s.AccountIDs) == 0 || slices.Contains(s.AccountIDs, accountID)
+}
+
+// WebhookDelivery is one event queued for, or sent to, a subscription.
+type WebhookDelivery struct {
+	ID             int
+	SubscriptionID int
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus *int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
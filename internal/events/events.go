@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/core"
+
+	"github.com/google/uuid"
+)
+
+// Publisher delivers account events to interested parties. Events are
+// delivered at least once, so publishers should tolerate duplicates.
+type Publisher interface {
+	Publish(ctx context.Context, event core.Event) error
+}
+
+// New returns an event with a fresh ID and timestamp.
+func New(eventType string, acc *core.Account, amount int64, reference string, counterparty *int) core.Event {
+	return core.Event{
+		ID:                    uuid.NewString(),
+		Type:                  eventType,
+		UserID:                acc.UserID,
+		AccountID:             acc.ID,
+		Amount:                amount,
+		Balance:               acc.Balance,
+		Reference:             reference,
+		CounterpartyAccountID: counterparty,
+		OccurredAt:            time.Now().UTC(),
+	}
+}
+
+// LogPublisher writes events to a structured logger.
+type LogPublisher struct {
+	logger *slog.Logger
+}
+
+// NewLogPublisher creates a publisher that logs every event.
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Publish logs the event.
+func (p *LogPublisher) Publish(ctx context.Context, event core.Event) error {
+	p.logger.InfoContext(ctx, "event published",
+		"event_id", event.ID,
+		"type", event.Type,
+		"account_id", event.AccountID,
+		"amount", event.Amount,
+		"reference", event.Reference,
+	)
+	return nil
+}
+
+// Multi publishes each event to every publisher in turn and returns the
+// first error encountered.
+type Multi []Publisher
+
+// Publish sends the event to every publisher.
+func (m Multi) Publish(ctx context.Context, event core.Event) error {
+	var firstErr error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Channel publishes events to an in-process channel for consumers in the
+// same binary. Publish blocks until the event is received or ctx is done.
+type Channel chan core.Event
+
+// Publish sends the event on the channel.
+func (c Channel) Publish(ctx context.Context, event core.Event) error {
+	select {
+	case c <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,40 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"mini-bank/internal/core"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// kindCode maps each kind of domain error to its status code, as
+// kindStatus does for HTTP.
+var kindCode = map[core.Kind]codes.Code{
+	core.KindInvalid:         codes.InvalidArgument,
+	core.KindUnauthenticated: codes.Unauthenticated,
+	core.KindForbidden:       codes.PermissionDenied,
+	core.KindNotFound:        codes.NotFound,
+	core.KindConflict:        codes.FailedPrecondition,
+	core.KindRejected:        codes.FailedPrecondition,
+	core.KindRateLimited:     codes.ResourceExhausted,
+}
+
+// statusError converts an error from the service to a status. Domain
+// errors keep their message; anything else is logged and reported as
+// Internal with message, without its details.
+func (s *Server) statusError(ctx context.Context, err error, message string) error {
+	var e *core.Error
+	if errors.As(err, &e) {
+		if errors.Is(err, core.ErrDuplicateEmail) {
+			return status.Error(codes.AlreadyExists, e.Message)
+		}
+		if code, ok := kindCode[e.Kind]; ok {
+			return status.Error(code, e.Message)
+		}
+	}
+	s.logger.ErrorContext(ctx, message, "err", err)
+	return status.Error(codes.Internal, message)
+}
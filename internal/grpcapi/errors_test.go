@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"mini-bank/internal/core"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	s := &Server{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{core.InvalidField("amount", "must be positive"), codes.InvalidArgument},
+		{core.ErrInvalidCredentials, codes.Unauthenticated},
+		{core.ErrForbidden, codes.PermissionDenied},
+		{core.ErrAccountNotFound, codes.NotFound},
+		{fmt.Errorf("transfer: %w", core.ErrAccountNotFound), codes.NotFound},
+		{core.ErrAlreadyReversed, codes.FailedPrecondition},
+		{core.ErrProductRestricted, codes.FailedPrecondition},
+		{core.ErrCoolingOff, codes.FailedPrecondition},
+		{core.ErrRateLimited, codes.ResourceExhausted},
+		{core.ErrDuplicateEmail, codes.AlreadyExists},
+		{errors.New("connection reset"), codes.Internal},
+	}
+	for _, tt := range tests {
+		err := s.statusError(context.Background(), tt.err, "failed")
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("statusError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestStatusErrorHidesInternalDetails(t *testing.T) {
+	s := &Server{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	err := s.statusError(context.Background(), errors.New("pq: password authentication failed"), "transfer failed")
+	if msg := status.Convert(err).Message(); msg != "transfer failed" {
+		t.Errorf("message = %q, want %q", msg, "transfer failed")
+	}
+}
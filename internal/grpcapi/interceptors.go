@@ -0,0 +1,177 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"mini-bank/internal/api"
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	pb "mini-bank/internal/grpcapi/minibankv1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods can be called without a token.
+var publicMethods = map[string]bool{
+	pb.BankService_CreateUser_FullMethodName: true,
+	pb.BankService_Login_FullMethodName:      true,
+}
+
+// methodScopes lists the scopes an OAuth access token needs for each
+// method, matching the equivalent HTTP routes. Methods not listed are
+// reserved for user sessions.
+var methodScopes = map[string][]string{
+	pb.BankService_CreateAccount_FullMethodName:       {core.ScopeWriteAccounts},
+	pb.BankService_GetAccount_FullMethodName:          {core.ScopeReadAccounts},
+	pb.BankService_ListAccounts_FullMethodName:        {core.ScopeReadAccounts},
+	pb.BankService_Transfer_FullMethodName:            {core.ScopeWriteTransfers},
+	pb.BankService_Payment_FullMethodName:             {core.ScopeWritePayments},
+	pb.BankService_ListTransactions_FullMethodName:    {core.ScopeReadTransactions},
+	pb.BankService_GetTransaction_FullMethodName:      {core.ScopeReadTransactions},
+	pb.BankService_StreamAccountEvents_FullMethodName: {core.ScopeReadAccounts, core.ScopeReadTransactions},
+	pb.BankService_ListUsers_FullMethodName:           {core.ScopeReadUsers},
+	pb.BankService_GetUser_FullMethodName:             {core.ScopeReadUsers},
+	pb.BankService_UpdateUser_FullMethodName:          {core.ScopeWriteUsers},
+}
+
+type callerKey struct{}
+
+// callerFrom returns the authenticated caller set by the auth interceptors.
+func callerFrom(ctx context.Context) *api.TokenInfo {
+	info, _ := ctx.Value(callerKey{}).(*api.TokenInfo)
+	if info == nil {
+		return &api.TokenInfo{}
+	}
+	return info
+}
+
+// authenticate verifies the bearer token in the call's metadata and checks
+// it may call method.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if publicMethods[method] {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+	}
+
+	info, err := s.tokens.VerifyAccessToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, api.ErrInvalidToken) {
+			s.logger.WarnContext(ctx, "invalid token", "method", method, "err", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+		s.logger.ErrorContext(ctx, "failed to verify token", "err", err)
+		return nil, status.Error(codes.Internal, "failed to authenticate")
+	}
+
+	// OAuth tokens only carry consented scopes; sessions may do anything
+	// the user can.
+	if info.ClientID != "" {
+		scopes, ok := methodScopes[method]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "this method requires a user session")
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(info.Scopes, scope) {
+				return nil, status.Error(codes.PermissionDenied, "missing scope "+scope)
+			}
+		}
+	}
+	ctx = audit.WithActor(ctx, callerActor(info))
+	return context.WithValue(ctx, callerKey{}, info), nil
+}
+
+// callerActor is how the caller appears in the audit log.
+func callerActor(info *api.TokenInfo) core.Actor {
+	if info.ClientID != "" {
+		userID := info.UserID
+		return core.Actor{Type: core.ActorOAuth, ID: info.ClientID, UserID: &userID}
+	}
+	return audit.UserActor(info.UserID)
+}
+
+// AuthUnaryInterceptor authenticates unary calls.
+func (s *Server) AuthUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// AuthStreamInterceptor authenticates streaming calls.
+func (s *Server) AuthStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// LoggingUnaryInterceptor logs details about each unary call.
+func (s *Server) LoggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// LoggingStreamInterceptor logs details about each streaming call once it
+// ends.
+func (s *Server) LoggingStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+func (s *Server) logCall(ctx context.Context, method string, start time.Time, err error) {
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			userAgent = ua[0]
+		}
+	}
+
+	s.logger.InfoContext(ctx, "processed rpc",
+		"method", method,
+		"duration", time.Since(start),
+		"code", status.Code(err).String(),
+		"user_agent", userAgent,
+	)
+}
+
+// TimeoutUnaryInterceptor bounds each unary call. Streams stay open for as
+// long as the client listens, so there is no stream equivalent.
+func TimeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// serverStream overrides the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,88 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/api"
+	pb "mini-bank/internal/grpcapi/minibankv1"
+	"mini-bank/internal/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoutes names the HTTP route whose limit each method shares, so
+// switching between the APIs does not get a client a second allowance.
+// Other methods count against the default limit.
+var methodRoutes = map[string]string{
+	pb.BankService_Login_FullMethodName:      "POST /api/v1/login",
+	pb.BankService_CreateUser_FullMethodName: "POST /api/v1/users/create",
+	pb.BankService_Transfer_FullMethodName:   "POST /api/v1/transactions/transfer",
+	pb.BankService_Payment_FullMethodName:    "POST /api/v1/transactions/payment",
+}
+
+// RateLimitUnaryInterceptor rejects calls exceeding the policy's limits
+// with ResourceExhausted, counting them in the same buckets as the HTTP
+// API. It runs after AuthUnaryInterceptor: callers are identified by user
+// ID once authenticated, and by IP address otherwise.
+func (s *Server) RateLimitUnaryInterceptor(limiter ratelimit.Limiter, policy api.RateLimitPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		route, limit := policy.Limit(methodRoutes[info.FullMethod])
+		key := route + "|" + rateLimitSubject(ctx, policy.TrustForwardedFor)
+		res, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			// Fail open, as the HTTP API does.
+			s.logger.ErrorContext(ctx, "rate limiter failed", "err", err)
+			return handler(ctx, req)
+		}
+
+		md := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(res.Limit),
+			"ratelimit-remaining", strconv.Itoa(res.Remaining),
+			"ratelimit-reset", ceilSeconds(res.Reset),
+		)
+		if !res.Allowed {
+			md.Set("retry-after", ceilSeconds(res.RetryAfter))
+			grpc.SetHeader(ctx, md)
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+		grpc.SetHeader(ctx, md)
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitSubject identifies the client a call is counted against, in
+// the same form as the HTTP API so both share a bucket.
+func rateLimitSubject(ctx context.Context, trustForwardedFor bool) string {
+	if userID := callerFrom(ctx).UserID; userID != 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	if trustForwardedFor {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if fwd := md.Get("x-forwarded-for"); len(fwd) > 0 && fwd[0] != "" {
+			first, _, _ := strings.Cut(fwd[0], ",")
+			return "ip:" + strings.TrimSpace(first)
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:unknown"
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "ip:" + addr
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
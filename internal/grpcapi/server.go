@@ -0,0 +1,368 @@
+// Package grpcapi serves the bank over gRPC for internal services. It
+// exposes the same operations and authorization rules as the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"mini-bank/internal/api"
+	"mini-bank/internal/core"
+	pb "mini-bank/internal/grpcapi/minibankv1"
+	"mini-bank/internal/service"
+	"mini-bank/internal/storage"
+	"mini-bank/internal/stream"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Tokens issues and verifies the JWTs shared with the HTTP API.
+type Tokens interface {
+	IssueAccessToken(userID int) (string, error)
+	VerifyAccessToken(ctx context.Context, token string) (*api.TokenInfo, error)
+}
+
+// Server implements minibankv1.BankServiceServer.
+type Server struct {
+	pb.UnimplementedBankServiceServer
+
+	service service.Service
+	hub     *stream.Hub
+	tokens  Tokens
+	logger  *slog.Logger
+}
+
+func NewServer(s service.Service, hub *stream.Hub, tokens Tokens, logger *slog.Logger) *Server {
+	return &Server{service: s, hub: hub, tokens: tokens, logger: logger}
+}
+
+func (s *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	if req.InitialBalance < 0 {
+		return nil, status.Error(codes.InvalidArgument, "initial balance must be positive")
+	}
+
+	acc, err := s.service.CreateAccount(ctx, callerFrom(ctx).UserID, req.InitialBalance, core.DefaultProduct, "")
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to create account")
+	}
+	return &pb.CreateAccountResponse{Account: toAccount(acc)}, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	acc, err := s.authorizedAccount(ctx, req.AccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetAccountResponse{Account: toAccount(acc)}, nil
+}
+
+func (s *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	accounts, err := s.service.ListAccounts(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to get accounts")
+	}
+
+	userID := callerFrom(ctx).UserID
+	resp := &pb.ListAccountsResponse{}
+	for _, acc := range accounts {
+		if acc.UserID == userID {
+			resp.Accounts = append(resp.Accounts, toAccount(acc))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) Transfer(ctx context.Context, req *pb.TransferRequest) (*pb.TransferResponse, error) {
+	switch {
+	case req.Amount <= 0:
+		return nil, status.Error(codes.InvalidArgument, "amount must be greater than zero")
+	case req.FromAccountNumber == req.ToAccountNumber:
+		return nil, status.Error(codes.InvalidArgument, "sender and receiver accounts cannot be the same")
+	case !core.ValidAccountNumber(req.FromAccountNumber):
+		return nil, status.Error(codes.InvalidArgument, "invalid sender account number")
+	case !core.ValidAccountNumber(req.ToAccountNumber):
+		return nil, status.Error(codes.InvalidArgument, "invalid receiver account number")
+	}
+
+	from, err := s.authorizedAccount(ctx, req.FromAccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.service.GetAccountByNumber(ctx, req.ToAccountNumber)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil, status.Error(codes.NotFound, "receiver account not found")
+		}
+		return nil, s.statusError(ctx, err, "failed to retrieve account")
+	}
+
+	reference := uuid.NewString()
+	from, to, err = s.service.Transfer(ctx, from.ID, to.ID, req.Amount, reference)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "transfer failed")
+	}
+	return &pb.TransferResponse{
+		FromAccount:     toAccount(from),
+		ToAccountNumber: to.Number,
+		Reference:       reference,
+	}, nil
+}
+
+func (s *Server) Payment(ctx context.Context, req *pb.PaymentRequest) (*pb.PaymentResponse, error) {
+	var pType storage.PaymentType
+	switch req.Type {
+	case pb.PaymentType_PAYMENT_TYPE_DEPOSIT:
+		pType = storage.Deposit
+	case pb.PaymentType_PAYMENT_TYPE_WITHDRAW:
+		pType = storage.Withdraw
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid payment type")
+	}
+	if req.Amount <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be greater than zero")
+	}
+	acc, err := s.authorizedAccount(ctx, req.AccountNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := uuid.NewString()
+	acc, err = s.service.Payment(ctx, acc.ID, req.Amount, pType, reference)
+	if err != nil {
+		return nil, s.statusError(ctx, err, string(pType)+" failed")
+	}
+	return &pb.PaymentResponse{Account: toAccount(acc), Reference: reference}, nil
+}
+
+func (s *Server) ListTransactions(ctx context.Context, req *pb.ListTransactionsRequest) (*pb.ListTransactionsResponse, error) {
+	acc, err := s.authorizedAccount(ctx, req.AccountNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.service.ListTransactions(ctx, acc.ID)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "could not retrieve transactions")
+	}
+
+	resp := &pb.ListTransactionsResponse{}
+	for _, tx := range txs {
+		resp.Transactions = append(resp.Transactions, toTransaction(tx))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+	if req.Reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid transaction reference")
+	}
+
+	tx, err := s.service.GetTransaction(ctx, req.Reference)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to retrieve transaction")
+	}
+
+	// Transactions are only shown to the owner of the account they were
+	// booked on or of its counterparty; to anyone else they do not exist.
+	allowed, err := s.canSeeTransaction(ctx, tx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to retrieve transaction")
+	}
+	if !allowed {
+		return nil, s.statusError(ctx, core.ErrTransactionNotFound, "")
+	}
+	return &pb.GetTransactionResponse{Transaction: toTransaction(tx)}, nil
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if req.FirstName == "" || req.LastName == "" || req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "first name, last name, email and password are required")
+	}
+
+	user, err := s.service.CreateUser(ctx, req.FirstName, req.LastName, req.Email, req.Password)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to create user")
+	}
+
+	token, err := s.tokens.IssueAccessToken(user.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to generate token", "err", err)
+		return nil, status.Error(codes.Internal, "failed to generate token")
+	}
+	return &pb.CreateUserResponse{User: toUser(user), Token: token}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	user, err := s.service.Login(ctx, req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidCredentials) {
+			s.logger.WarnContext(ctx, "login failed", "email", req.Email)
+		}
+		return nil, s.statusError(ctx, err, "login failed")
+	}
+
+	token, err := s.tokens.IssueAccessToken(user.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to generate token", "err", err)
+		return nil, status.Error(codes.Internal, "failed to generate token")
+	}
+	return &pb.LoginResponse{Token: token}, nil
+}
+
+func (s *Server) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users, err := s.service.GetUsers(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to retrieve users")
+	}
+
+	resp := &pb.ListUsersResponse{}
+	for _, user := range users {
+		u := toUser(user)
+		u.Balance = nil
+		resp.Users = append(resp.Users, u)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	if err := checkSelf(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.GetUser(ctx, int(req.Id))
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to retrieve user")
+	}
+	return &pb.GetUserResponse{User: toUser(user)}, nil
+}
+
+func (s *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
+	if err := checkSelf(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.UpdateUser(ctx, int(req.Id), req.FirstName, req.LastName, req.Email)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to update user")
+	}
+	return &pb.UpdateUserResponse{User: toUser(user)}, nil
+}
+
+func (s *Server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	if err := checkSelf(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	if err := s.service.DeleteUser(ctx, int(req.Id)); err != nil {
+		return nil, s.statusError(ctx, err, "failed to delete user")
+	}
+	return &pb.DeleteUserResponse{}, nil
+}
+
+// authorizedAccount returns the account with the given number if the
+// caller owns it. Other users' accounts are reported as not found.
+func (s *Server) authorizedAccount(ctx context.Context, number string) (*core.Account, error) {
+	if !core.ValidAccountNumber(number) {
+		return nil, status.Error(codes.InvalidArgument, "invalid account number")
+	}
+	acc, err := s.service.GetAccountByNumber(ctx, number)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "failed to retrieve account")
+	}
+	if acc.UserID != callerFrom(ctx).UserID {
+		return nil, s.statusError(ctx, core.ErrAccountNotFound, "")
+	}
+	return acc, nil
+}
+
+// canSeeTransaction reports whether the caller owns the account a
+// transaction was booked on or the counterparty's account.
+func (s *Server) canSeeTransaction(ctx context.Context, tx *core.Transaction) (bool, error) {
+	ids := []int{tx.AccountID}
+	for _, id := range []*int{tx.FromAccountID, tx.ToAccountID} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	for _, id := range ids {
+		acc, err := s.service.GetAccount(ctx, id)
+		if errors.Is(err, core.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if acc.UserID == callerFrom(ctx).UserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkSelf rejects requests about a user other than the caller.
+func checkSelf(ctx context.Context, userID int64) error {
+	if userID <= 0 {
+		return status.Error(codes.InvalidArgument, "invalid user id")
+	}
+	if int(userID) != callerFrom(ctx).UserID {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return nil
+}
+
+func toAccount(acc *core.Account) *pb.Account {
+	return &pb.Account{
+		AccountNumber: acc.Number,
+		Balance:       acc.Balance,
+		CreatedAt:     timestamppb.New(acc.CreatedAt),
+	}
+}
+
+func toTransaction(tx *core.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		Id:                int64(tx.ID),
+		AccountNumber:     tx.AccountNumber,
+		Type:              tx.Type,
+		Amount:            tx.Amount,
+		Timestamp:         timestamppb.New(tx.Timestamp),
+		Reference:         tx.Reference,
+		FromAccountNumber: tx.FromAccountNumber,
+		ToAccountNumber:   tx.ToAccountNumber,
+	}
+}
+
+func toUser(user *core.User) *pb.User {
+	u := &pb.User{
+		Id:        int64(user.ID),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+	if user.Balance != nil {
+		balance := int64(*user.Balance)
+		u.Balance = &balance
+	}
+	return u
+}
+
+func toAccountEvent(u stream.Update) *pb.AccountEvent {
+	return &pb.AccountEvent{
+		Sequence:                  u.Sequence,
+		EventId:                   u.EventID,
+		Type:                      u.Type,
+		AccountNumber:             u.AccountNumber,
+		Amount:                    u.Amount,
+		Balance:                   u.Balance,
+		Reference:                 u.Reference,
+		CounterpartyAccountNumber: u.CounterpartyAccountNumber,
+		OccurredAt:                timestamppb.New(u.OccurredAt),
+	}
+}
@@ -0,0 +1,68 @@
+package grpcapi
+
+import (
+	pb "mini-bank/internal/grpcapi/minibankv1"
+	"mini-bank/internal/stream"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamAccountEvents replays missed events and then forwards live ones
+// until the client goes away, falls too far behind or the server shuts
+// down. Dead connections are detected by the server's keepalive pings.
+func (s *Server) StreamAccountEvents(req *pb.StreamAccountEventsRequest, ss grpc.ServerStreamingServer[pb.StreamAccountEventsResponse]) error {
+	ctx := ss.Context()
+	if req.AfterSequence < 0 {
+		return status.Error(codes.InvalidArgument, "invalid after sequence")
+	}
+
+	acc, err := s.authorizedAccount(ctx, req.AccountNumber)
+	if err != nil {
+		return err
+	}
+
+	send := func(u stream.Update) error {
+		return ss.Send(&pb.StreamAccountEventsResponse{Event: toAccountEvent(u)})
+	}
+
+	// Subscribe before replaying so nothing is missed in between; events
+	// seen in the replay are skipped when they arrive live.
+	sub := s.hub.Subscribe(acc.Number)
+	defer sub.Close()
+
+	last := req.AfterSequence
+	for req.AfterSequence > 0 {
+		missed, err := s.service.ListAccountEvents(ctx, acc.ID, last)
+		if err != nil {
+			return s.statusError(ctx, err, "failed to replay account events")
+		}
+		if len(missed) == 0 {
+			break
+		}
+		for _, e := range missed {
+			if err := send(stream.NewUpdate(e)); err != nil {
+				return err
+			}
+			last = e.Sequence
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-sub.Dropped():
+			return status.Error(codes.Unavailable, "stream ended, resume from the last sequence received")
+		case u := <-sub.Updates():
+			if u.Sequence <= last {
+				continue
+			}
+			if err := send(u); err != nil {
+				return err
+			}
+			last = u.Sequence
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package health serves liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency is usable. It must return once ctx
+// is done.
+type Check func(ctx context.Context) error
+
+// Statuses reported by the probes.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Checker runs dependency checks for the readiness probe.
+type Checker struct {
+	timeout  time.Duration
+	logger   *slog.Logger
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// New returns a Checker that gives each check up to timeout.
+func New(timeout time.Duration, logger *slog.Logger) *Checker {
+	return &Checker{timeout: timeout, logger: logger, checks: map[string]Check{}}
+}
+
+// Add registers a dependency check under name.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// Drain makes the readiness probe fail from now on, so load balancers stop
+// sending traffic before the server shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Report is the body of a probe response.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of one dependency check. Error only says
+// whether the check failed or timed out; the cause is logged, since probe
+// responses are visible to anyone who can reach the server.
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Run checks every dependency concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.names))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := c.run(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, name string) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.checks[name](ctx)
+	res := CheckResult{Status: StatusOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		c.logger.WarnContext(ctx, "health check failed", "check", name, "err", err)
+		res.Status = StatusUnavailable
+		res.Error = "check failed"
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			res.Error = "timed out"
+		}
+	}
+	return res
+}
+
+// LivenessHandler reports that the process is up and serving. It checks
+// no dependencies: restarting the process would not fix them.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler reports whether the process should receive traffic:
+// every dependency check passed and it is not draining. It responds 503
+// otherwise, with the status of each dependency either way.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
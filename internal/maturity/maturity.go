@@ -0,0 +1,88 @@
+// Package maturity pays out or rolls over term deposits as they mature,
+// on a schedule, and reports what it did in the logs and metrics.
+package maturity
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/metrics"
+)
+
+// Processor matures the term deposits that are due.
+type Processor interface {
+	ProcessMaturities(ctx context.Context) (*core.MaturityRun, error)
+}
+
+// Job processes matured term deposits every interval. Every instance may
+// run it: each deposit is locked while it matures, so it is only paid out
+// once.
+type Job struct {
+	processor Processor
+	interval  time.Duration
+	logger    *slog.Logger
+	metrics   *metrics.Metrics
+}
+
+// NewJob creates a job processing matured deposits every interval. m may
+// be nil.
+func NewJob(processor Processor, interval time.Duration, logger *slog.Logger, m *metrics.Metrics) *Job {
+	return &Job{processor: processor, interval: interval, logger: logger, metrics: m}
+}
+
+// Run processes matured deposits once at startup, since some may have
+// matured while the service was down, then every interval until ctx is
+// cancelled. It does nothing if the interval is zero.
+func (j *Job) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		return
+	}
+	j.RunOnce(ctx)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce processes the deposits matured by now and reports the result.
+// Payouts and rollovers are audited as made by the system.
+func (j *Job) RunOnce(ctx context.Context) (*core.MaturityRun, error) {
+	ctx = audit.WithActor(ctx, core.Actor{Type: core.ActorSystem, ID: "maturity"})
+	start := time.Now()
+	run, err := j.processor.ProcessMaturities(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "term deposit maturity run failed", "err", err)
+		if j.metrics != nil {
+			j.metrics.MaturityRunFailed()
+		}
+		return nil, err
+	}
+	if j.metrics != nil {
+		j.metrics.MaturityRunCompleted(run)
+	}
+
+	j.logger.InfoContext(ctx, "term deposit maturity run finished",
+		"due", run.Due,
+		"paid_out", run.PaidOut,
+		"rolled_over", run.RolledOver,
+		"failed", len(run.Failures),
+		"duration", time.Since(start),
+	)
+	for _, f := range run.Failures {
+		j.logger.ErrorContext(ctx, "term deposit could not mature",
+			"term_deposit_id", f.TermDepositID,
+			"err", f.Err,
+		)
+	}
+	return run, nil
+}
@@ -0,0 +1,332 @@
+// Package metrics collects Prometheus metrics and serves them for
+// scraping. Nothing here talks to a Prometheus server; it only keeps
+// counters in memory until something scrapes /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/core"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "minibank"
+
+// Metrics holds every collector the service exports.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	transfers         prometheus.Counter
+	payments          *prometheus.CounterVec
+	volume            *prometheus.CounterVec
+	insufficientFunds *prometheus.CounterVec
+	limitExceeded     *prometheus.CounterVec
+	blocked           *prometheus.CounterVec
+
+	redisDuration *prometheus.HistogramVec
+	redisErrors   *prometheus.CounterVec
+
+	reconciliations     *prometheus.CounterVec
+	lastReconciliation  prometheus.Gauge
+	ledgerMismatches    prometheus.Gauge
+	imbalancedTransfers prometheus.Gauge
+	ledgerConserved     prometheus.Gauge
+
+	monitoringScans *prometheus.CounterVec
+	monitoringCases *prometheus.CounterVec
+
+	screeningBlocked *prometheus.CounterVec
+	rescreens        *prometheus.CounterVec
+	rescreenHits     *prometheus.CounterVec
+	watchlistEntries prometheus.Gauge
+
+	maturityRuns *prometheus.CounterVec
+	maturities   *prometheus.CounterVec
+}
+
+// New creates the collectors, along with the standard Go runtime and
+// process metrics, in a registry of their own.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		transfers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_total",
+			Help:      "Transfers completed.",
+		}),
+		payments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payments_total",
+			Help:      "Payments completed, by type.",
+		}, []string{"type"}),
+		volume: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transaction_volume_total",
+			Help:      "Amount moved by completed transactions in minor units, by type.",
+		}, []string{"type"}),
+		insufficientFunds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "insufficient_funds_total",
+			Help:      "Transactions rejected for insufficient funds, by type.",
+		}, []string{"type"}),
+		limitExceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "limit_exceeded_total",
+			Help:      "Transactions rejected for exceeding an account limit, by type.",
+		}, []string{"type"}),
+		blocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_blocked_total",
+			Help:      "Transactions refused by a monitoring rule, by type.",
+		}, []string{"type"}),
+		redisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Time taken by Redis commands, by command. Pipelines count as one command named pipeline.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command"}),
+		redisErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redis_command_errors_total",
+			Help:      "Redis commands that failed, by command. Missing keys are not failures.",
+		}, []string{"command"}),
+		reconciliations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconciliations_total",
+			Help:      "Ledger reconciliations run, by result: ok, discrepancies or error.",
+		}, []string{"result"}),
+		lastReconciliation: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "reconciliation_last_run_timestamp_seconds",
+			Help:      "When the ledger was last reconciled, as a Unix time.",
+		}),
+		ledgerMismatches: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ledger_mismatched_accounts",
+			Help:      "Accounts whose balance differed from their transactions at the last reconciliation.",
+		}),
+		imbalancedTransfers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ledger_imbalanced_transfers",
+			Help:      "Transfers whose legs did not cancel out at the last reconciliation.",
+		}),
+		ledgerConserved: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ledger_conserved",
+			Help:      "1 if the money held matched the money that came in and went out at the last reconciliation, else 0.",
+		}),
+		monitoringScans: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "monitoring_scans_total",
+			Help:      "Monitoring scans of recent history run, by result: ok or error.",
+		}, []string{"result"}),
+		monitoringCases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "monitoring_scan_cases_total",
+			Help:      "Cases opened by monitoring scans, by rule.",
+		}, []string{"rule"}),
+		screeningBlocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "screening_blocked_total",
+			Help:      "Requests refused because a name matched the watchlist, by operation.",
+		}, []string{"operation"}),
+		rescreens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rescreens_total",
+			Help:      "Rescreenings of every user against the watchlist, by result: ok or error.",
+		}, []string{"result"}),
+		rescreenHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rescreen_hits_total",
+			Help:      "Screening hits recorded by rescreenings, by decision.",
+		}, []string{"decision"}),
+		watchlistEntries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchlist_entries",
+			Help:      "Entries in the watchlist in use.",
+		}),
+		maturityRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "maturity_runs_total",
+			Help:      "Runs over matured term deposits, by result: ok or error.",
+		}, []string{"result"}),
+		maturities: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "term_deposit_maturities_total",
+			Help:      "Matured term deposits processed, by result: paid_out, rolled_over or failed.",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.transfers,
+		m.payments,
+		m.volume,
+		m.insufficientFunds,
+		m.limitExceeded,
+		m.blocked,
+		m.redisDuration,
+		m.redisErrors,
+		m.reconciliations,
+		m.lastReconciliation,
+		m.ledgerMismatches,
+		m.imbalancedTransfers,
+		m.ledgerConserved,
+		m.monitoringScans,
+		m.monitoringCases,
+		m.screeningBlocked,
+		m.rescreens,
+		m.rescreenHits,
+		m.watchlistEntries,
+		m.maturityRuns,
+		m.maturities,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exports the connection pool statistics of db under the given
+// name.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveHTTPRequest records one served request. Route is the pattern the
+// request matched, never its raw path, so the number of series stays
+// bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// TransferCompleted records a transfer of amount minor units.
+func (m *Metrics) TransferCompleted(amount int64) {
+	m.transfers.Inc()
+	m.volume.WithLabelValues("transfer").Add(float64(amount))
+}
+
+// PaymentCompleted records a deposit or withdrawal of amount minor units.
+func (m *Metrics) PaymentCompleted(paymentType string, amount int64) {
+	m.payments.WithLabelValues(paymentType).Inc()
+	m.volume.WithLabelValues(paymentType).Add(float64(amount))
+}
+
+// InsufficientFunds records a transfer or withdrawal rejected because the
+// account could not cover it.
+func (m *Metrics) InsufficientFunds(transactionType string) {
+	m.insufficientFunds.WithLabelValues(transactionType).Inc()
+}
+
+// LimitExceeded records a transfer or withdrawal rejected by one of the
+// account's limits.
+func (m *Metrics) LimitExceeded(transactionType string) {
+	m.limitExceeded.WithLabelValues(transactionType).Inc()
+}
+
+// TransactionBlocked records a transfer or payment refused by a
+// monitoring rule.
+func (m *Metrics) TransactionBlocked(transactionType string) {
+	m.blocked.WithLabelValues(transactionType).Inc()
+}
+
+// ReconciliationCompleted records the outcome of a ledger reconciliation.
+func (m *Metrics) ReconciliationCompleted(rec *core.Reconciliation) {
+	result := "ok"
+	if !rec.OK() {
+		result = "discrepancies"
+	}
+	m.reconciliations.WithLabelValues(result).Inc()
+	m.lastReconciliation.Set(float64(rec.CheckedAt.Unix()))
+	m.ledgerMismatches.Set(float64(len(rec.Mismatches)))
+	m.imbalancedTransfers.Set(float64(len(rec.ImbalancedTransfers)))
+	conserved := 0.0
+	if rec.Conserved() {
+		conserved = 1
+	}
+	m.ledgerConserved.Set(conserved)
+}
+
+// ReconciliationFailed records a reconciliation that could not finish.
+func (m *Metrics) ReconciliationFailed() {
+	m.reconciliations.WithLabelValues("error").Inc()
+}
+
+// MonitoringScanCompleted records a monitoring scan and the cases it
+// opened.
+func (m *Metrics) MonitoringScanCompleted(scan *core.MonitoringScan) {
+	m.monitoringScans.WithLabelValues("ok").Inc()
+	for _, c := range scan.Cases {
+		m.monitoringCases.WithLabelValues(c.Rule).Inc()
+	}
+}
+
+// MonitoringScanFailed records a monitoring scan that could not finish.
+func (m *Metrics) MonitoringScanFailed() {
+	m.monitoringScans.WithLabelValues("error").Inc()
+}
+
+// ScreeningBlocked records a signup, profile update or new beneficiary
+// refused because the name matched the watchlist.
+func (m *Metrics) ScreeningBlocked(operation string) {
+	m.screeningBlocked.WithLabelValues(operation).Inc()
+}
+
+// RescreenCompleted records a rescreening and the hits it recorded.
+func (m *Metrics) RescreenCompleted(res *core.Rescreening) {
+	m.rescreens.WithLabelValues("ok").Inc()
+	for _, h := range res.Hits {
+		m.rescreenHits.WithLabelValues(h.Decision).Inc()
+	}
+}
+
+// RescreenFailed records a rescreening that could not finish.
+func (m *Metrics) RescreenFailed() {
+	m.rescreens.WithLabelValues("error").Inc()
+}
+
+// WatchlistLoaded records the size of the watchlist now in use.
+func (m *Metrics) WatchlistLoaded(entries int) {
+	m.watchlistEntries.Set(float64(entries))
+}
+
+// MaturityRunCompleted records a run over matured term deposits and what
+// it did with them.
+func (m *Metrics) MaturityRunCompleted(run *core.MaturityRun) {
+	m.maturityRuns.WithLabelValues("ok").Inc()
+	m.maturities.WithLabelValues("paid_out").Add(float64(run.PaidOut))
+	m.maturities.WithLabelValues("rolled_over").Add(float64(run.RolledOver))
+	m.maturities.WithLabelValues("failed").Add(float64(len(run.Failures)))
+}
+
+// MaturityRunFailed records a run over matured term deposits that could
+// not finish.
+func (m *Metrics) MaturityRunFailed() {
+	m.maturityRuns.WithLabelValues("error").Inc()
+}
@@ -0,0 +1,213 @@
+// Package monitor screens money movements for signs of fraud and money
+// laundering. The same rules judge each transfer and payment as it is
+// made and, in a scheduled scan, the recent history of every active
+// account, so rules added later still catch earlier activity.
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/metrics"
+)
+
+// Movement is money about to move into or out of an account, or that
+// moved, as the rules see it.
+type Movement struct {
+	// Type is deposit, withdraw or transfer; transfers are screened on
+	// the sender's side.
+	Type   string
+	Amount int64
+	At     time.Time
+}
+
+// MovementOf returns the movement a transaction records, or false for
+// rows the rules do not screen: received transfers, adjustments and
+// reversals.
+func MovementOf(t *core.Transaction) (Movement, bool) {
+	if t.Type != core.TransactionDeposit && !outbound(t) {
+		return Movement{}, false
+	}
+	return Movement{Type: t.Type, Amount: t.Amount, At: t.Timestamp}, true
+}
+
+// Hit is a rule flagging a movement.
+type Hit struct {
+	Rule     string
+	Decision string
+	Reason   string
+}
+
+// Decide returns the most severe decision among hits: block, review or,
+// with no hits, allow.
+func Decide(hits []Hit) string {
+	decision := core.DecisionAllow
+	for _, h := range hits {
+		if h.Decision == core.DecisionBlock {
+			return core.DecisionBlock
+		}
+		decision = core.DecisionReview
+	}
+	return decision
+}
+
+// Engine evaluates a fixed set of rules. A nil Engine flags nothing.
+type Engine struct {
+	rules    []Rule
+	lookback time.Duration
+}
+
+// New returns an engine for rules, or an error if any is invalid.
+func New(rules []Rule) (*Engine, error) {
+	if err := Validate(rules); err != nil {
+		return nil, err
+	}
+	e := &Engine{rules: rules}
+	for _, r := range rules {
+		e.lookback = max(e.lookback, r.lookback())
+	}
+	return e, nil
+}
+
+// Lookback is how much of an account's history the rules need to judge a
+// movement.
+func (e *Engine) Lookback() time.Duration {
+	if e == nil {
+		return 0
+	}
+	return e.lookback
+}
+
+// Evaluate runs every rule over a movement on acc. history holds the
+// account's transactions before the movement, oldest first, going back
+// at least Lookback.
+func (e *Engine) Evaluate(acc *core.Account, history []*core.Transaction, m Movement) []Hit {
+	if e == nil {
+		return nil
+	}
+	var hits []Hit
+	for i := range e.rules {
+		r := &e.rules[i]
+		if reason, ok := r.evaluate(acc, history, m); ok {
+			hits = append(hits, Hit{Rule: r.Name, Decision: r.Decision, Reason: reason})
+		}
+	}
+	return hits
+}
+
+// Scan screens every movement made since the given time, each against
+// the history before it, and returns how many it screened and the cases
+// the hits call for. activity must reach back Lookback before since.
+func (e *Engine) Scan(activity []*core.AccountActivity, since time.Time) (int, []*core.Case) {
+	var n int
+	var cases []*core.Case
+	for _, a := range activity {
+		for i, t := range a.Transactions {
+			m, ok := MovementOf(t)
+			if !ok || t.Timestamp.Before(since) {
+				continue
+			}
+			n++
+			hits := e.Evaluate(a.Account, a.Transactions[:i], m)
+			cases = append(cases, Cases(a.Account.ID, t.Reference, m, core.CaseSourceScan, hits)...)
+		}
+	}
+	return n, cases
+}
+
+// Cases returns the cases to open for the hits on a movement.
+func Cases(accountID int, reference string, m Movement, source string, hits []Hit) []*core.Case {
+	cases := make([]*core.Case, 0, len(hits))
+	for _, h := range hits {
+		cases = append(cases, &core.Case{
+			AccountID:       accountID,
+			Rule:            h.Rule,
+			Decision:        h.Decision,
+			Reason:          h.Reason,
+			Reference:       reference,
+			TransactionType: m.Type,
+			Amount:          m.Amount,
+			Source:          source,
+			Status:          core.CaseOpen,
+		})
+	}
+	return cases
+}
+
+// Scanner screens recent history.
+type Scanner interface {
+	ScanActivity(ctx context.Context, since time.Time) (*core.MonitoringScan, error)
+}
+
+// Job scans the history of the last window every interval. Windows
+// overlap so nothing is missed between runs or across restarts; activity
+// flagged before does not open a second case.
+type Job struct {
+	scanner  Scanner
+	interval time.Duration
+	window   time.Duration
+	logger   *slog.Logger
+	metrics  *metrics.Metrics
+}
+
+// NewJob creates a job scanning the last window every interval. m may be
+// nil.
+func NewJob(scanner Scanner, interval, window time.Duration, logger *slog.Logger, m *metrics.Metrics) *Job {
+	return &Job{scanner: scanner, interval: interval, window: window, logger: logger, metrics: m}
+}
+
+// Run scans every interval until ctx is cancelled. It does nothing if the
+// interval is zero.
+func (j *Job) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce scans the last window now and reports the result. Cases it
+// opens are audited as made by the system.
+func (j *Job) RunOnce(ctx context.Context) (*core.MonitoringScan, error) {
+	ctx = audit.WithActor(ctx, core.Actor{Type: core.ActorSystem, ID: "monitoring"})
+	start := time.Now()
+	scan, err := j.scanner.ScanActivity(ctx, start.Add(-j.window))
+	if err != nil {
+		j.logger.ErrorContext(ctx, "monitoring scan failed", "err", err)
+		if j.metrics != nil {
+			j.metrics.MonitoringScanFailed()
+		}
+		return nil, err
+	}
+	if j.metrics != nil {
+		j.metrics.MonitoringScanCompleted(scan)
+	}
+
+	j.logger.InfoContext(ctx, "monitoring scan finished",
+		"since", scan.Since,
+		"transactions", scan.Transactions,
+		"cases_opened", len(scan.Cases),
+		"duration", time.Since(start),
+	)
+	for _, c := range scan.Cases {
+		j.logger.WarnContext(ctx, "monitoring case opened",
+			"case_id", c.ID,
+			"account_id", c.AccountID,
+			"rule", c.Rule,
+			"decision", c.Decision,
+			"reference", c.Reference,
+		)
+	}
+	return scan, nil
+}
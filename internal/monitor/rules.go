@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+// Rule types.
+const (
+	// LargeAmount flags any movement of at least Amount.
+	LargeAmount = "large_amount"
+	// Structuring flags Count or more movements within Window that each
+	// stay just under the threshold Amount, as if to avoid it.
+	Structuring = "structuring"
+	// RapidMovement flags money leaving an account soon after it came in:
+	// at least Amount received within Window, of which Ratio or more is
+	// sent out again in the same Window.
+	RapidMovement = "rapid_movement"
+	// NewAccount flags an account younger than MaxAge sending Amount or
+	// more.
+	NewAccount = "new_account"
+)
+
+// Rule is one check of a movement against the account's history. Which
+// fields apply depends on the type.
+type Rule struct {
+	// Name identifies the rule in cases and logs.
+	Name string `yaml:"name" toml:"name"`
+	Type string `yaml:"type" toml:"type"`
+	// Decision is what a hit means for the movement: review or block.
+	Decision string `yaml:"decision" toml:"decision"`
+	// Types are the transaction types screened: deposit, withdraw or
+	// transfer. Empty screens every type the rule supports.
+	Types  []string `yaml:"types" toml:"types"`
+	Amount int64    `yaml:"amount" toml:"amount"`
+	// Margin is how far below Amount counts as just under it.
+	Margin int64         `yaml:"margin" toml:"margin"`
+	Count  int           `yaml:"count" toml:"count"`
+	Window time.Duration `yaml:"window" toml:"window"`
+	Ratio  float64       `yaml:"ratio" toml:"ratio"`
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// supported lists the transaction types each rule type can screen. Rapid
+// movement and new accounts are about money leaving an account.
+var supported = map[string][]string{
+	LargeAmount:   {core.TransactionDeposit, core.TransactionWithdraw, core.TransactionTransfer},
+	Structuring:   {core.TransactionDeposit, core.TransactionWithdraw, core.TransactionTransfer},
+	RapidMovement: {core.TransactionWithdraw, core.TransactionTransfer},
+	NewAccount:    {core.TransactionWithdraw, core.TransactionTransfer},
+}
+
+// Validate reports every problem with a set of rules at once.
+func Validate(rules []Rule) error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, r := range rules {
+		bad := func(format string, args ...any) {
+			name := r.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			errs = append(errs, fmt.Errorf("rule %s: "+format, append([]any{name}, args...)...))
+		}
+		switch {
+		case r.Name == "":
+			bad("name is required")
+		case seen[r.Name]:
+			bad("name is used by another rule")
+		}
+		seen[r.Name] = true
+
+		types, ok := supported[r.Type]
+		if !ok {
+			bad("type must be %s, %s, %s or %s, got %q", LargeAmount, Structuring, RapidMovement, NewAccount, r.Type)
+			continue
+		}
+		if r.Decision != core.DecisionReview && r.Decision != core.DecisionBlock {
+			bad("decision must be review or block, got %q", r.Decision)
+		}
+		for _, t := range r.Types {
+			if !slices.Contains(types, t) {
+				bad("%s rules cannot screen %q", r.Type, t)
+			}
+		}
+		if r.Amount <= 0 {
+			bad("amount must be positive, got %d", r.Amount)
+		}
+		switch r.Type {
+		case Structuring:
+			if r.Margin <= 0 || r.Margin > r.Amount {
+				bad("margin must be positive and at most the amount, got %d", r.Margin)
+			}
+			if r.Count < 2 {
+				bad("count must be at least 2, got %d", r.Count)
+			}
+			if r.Window <= 0 {
+				bad("window must be positive, got %s", r.Window)
+			}
+		case RapidMovement:
+			if r.Ratio <= 0 || r.Ratio > 1 {
+				bad("ratio must be above 0 and at most 1, got %g", r.Ratio)
+			}
+			if r.Window <= 0 {
+				bad("window must be positive, got %s", r.Window)
+			}
+		case NewAccount:
+			if r.MaxAge <= 0 {
+				bad("max_age must be positive, got %s", r.MaxAge)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// screens reports whether the rule looks at movements of a type.
+func (r *Rule) screens(typ string) bool {
+	if len(r.Types) == 0 {
+		return slices.Contains(supported[r.Type], typ)
+	}
+	return slices.Contains(r.Types, typ)
+}
+
+// lookback is how much history the rule needs.
+func (r *Rule) lookback() time.Duration {
+	switch r.Type {
+	case Structuring, RapidMovement:
+		return r.Window
+	default:
+		return 0
+	}
+}
+
+// evaluate returns why the rule flags m, or false if it does not.
+// history holds the account's transactions before m, oldest first.
+func (r *Rule) evaluate(acc *core.Account, history []*core.Transaction, m Movement) (string, bool) {
+	if !r.screens(m.Type) {
+		return "", false
+	}
+	from := m.At.Add(-r.lookback())
+	recent := func(t *core.Transaction) bool {
+		return t.Timestamp.After(from)
+	}
+
+	switch r.Type {
+	case LargeAmount:
+		if m.Amount >= r.Amount {
+			return fmt.Sprintf("%s of %d is at least %d", m.Type, m.Amount, r.Amount), true
+		}
+
+	case Structuring:
+		if !r.justUnder(m.Amount) {
+			return "", false
+		}
+		n := 1
+		for _, t := range history {
+			if prev, ok := MovementOf(t); ok && recent(t) && r.screens(prev.Type) && r.justUnder(prev.Amount) {
+				n++
+			}
+		}
+		if n >= r.Count {
+			return fmt.Sprintf("%d movements between %d and %d within %s", n, r.Amount-r.Margin, r.Amount-1, r.Window), true
+		}
+
+	case RapidMovement:
+		var in, out int64
+		for _, t := range history {
+			switch {
+			case !recent(t):
+			case inbound(t):
+				in += t.Amount
+			case outbound(t):
+				out += t.Amount
+			}
+		}
+		out += m.Amount
+		if in >= r.Amount && float64(out) >= r.Ratio*float64(in) {
+			return fmt.Sprintf("%d of %d received within %s is going out again", out, in, r.Window), true
+		}
+
+	case NewAccount:
+		if age := m.At.Sub(acc.CreatedAt); age < r.MaxAge && m.Amount >= r.Amount {
+			return fmt.Sprintf("account opened %s ago is sending %d", age.Round(time.Minute), m.Amount), true
+		}
+	}
+	return "", false
+}
+
+func (r *Rule) justUnder(amount int64) bool {
+	return amount < r.Amount && amount >= r.Amount-r.Margin
+}
+
+// inbound reports whether t brought money into its account.
+func inbound(t *core.Transaction) bool {
+	return t.Type == core.TransactionDeposit || (t.Type == core.TransactionTransfer && t.FromAccountID != nil)
+}
+
+// outbound reports whether t took money out of its account on the
+// customer's behalf.
+func outbound(t *core.Transaction) bool {
+	return t.Type == core.TransactionWithdraw || (t.Type == core.TransactionTransfer && t.ToAccountID != nil)
+}
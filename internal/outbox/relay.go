@@ -0,0 +1,106 @@
+// Package outbox relays events written by the transactional outbox to a
+// publisher.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/events"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 100
+	// retention is how long published events are kept before pruning.
+	retention     = 7 * 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+// Store is the persistence the relay needs.
+type Store interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(context.Context, core.Event) error) (int, error)
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Relay publishes outbox events with at-least-once semantics. Events for
+// the same account are published in the order they were written: once one
+// fails, later events for that account wait until it succeeds.
+type Relay struct {
+	store     Store
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+// NewRelay creates a relay that publishes events from store.
+func NewRelay(store Store, publisher events.Publisher, logger *slog.Logger) *Relay {
+	return &Relay{store: store, publisher: publisher, logger: logger}
+}
+
+// Run relays events until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		r.drain(ctx)
+		if time.Since(lastPrune) > pruneInterval {
+			r.prune(ctx)
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain relays batches until the outbox is empty or a batch fails.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		failed := false
+		n, err := r.store.RelayOutbox(ctx, batchSize, r.publishInOrder(&failed))
+		if err != nil {
+			r.logger.Error("failed to relay outbox", "err", err)
+			return
+		}
+		// Retry failures on the next tick rather than spinning on them.
+		if n < batchSize || failed {
+			return
+		}
+	}
+}
+
+// publishInOrder returns a publish func for one batch that holds back
+// every event for an account after one of its events fails.
+func (r *Relay) publishInOrder(failed *bool) func(context.Context, core.Event) error {
+	blocked := make(map[int]bool)
+	return func(ctx context.Context, e core.Event) error {
+		if blocked[e.AccountID] {
+			return fmt.Errorf("account %d has an earlier unpublished event", e.AccountID)
+		}
+		if err := r.publisher.Publish(ctx, e); err != nil {
+			r.logger.Warn("failed to publish event", "event_id", e.ID, "sequence", e.Sequence, "err", err)
+			blocked[e.AccountID] = true
+			*failed = true
+			return err
+		}
+		return nil
+	}
+}
+
+func (r *Relay) prune(ctx context.Context) {
+	n, err := r.store.PruneOutbox(ctx, time.Now().Add(-retention))
+	if err != nil {
+		r.logger.Error("failed to prune outbox", "err", err)
+		return
+	}
+	if n > 0 {
+		r.logger.Info("pruned outbox", "events", n)
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery controls how often idle buckets are evicted.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	capacity float64
+	window   time.Duration
+}
+
+// MemoryLimiter is a token bucket limiter local to a single process.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+// NewMemoryLimiter creates a new in-memory token bucket limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket for key, refilling it at
+// limit.Requests tokens per limit.Window.
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds() // tokens per second
+
+	b, ok := l.buckets[key]
+	if !ok || b.capacity != capacity || b.window != limit.Window {
+		b = &bucket{tokens: capacity, last: now, capacity: capacity, window: limit.Window}
+		l.buckets[key] = b
+	}
+
+	// Refill tokens for the time elapsed since the last request.
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	b.last = now
+
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+	return res, nil
+}
+
+// sweep removes buckets that have been idle long enough to be full again.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > b.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func approx(got, want time.Duration) bool {
+	d := got - want
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+func TestMemoryLimiterRefillsAtTheLimitRate(t *testing.T) {
+	c := &clock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryLimiter()
+	l.now = c.now
+	limit := Limit{Requests: 2, Window: 10 * time.Second}
+
+	steps := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{0, true, 1, 0, 5 * time.Second},
+		{0, true, 0, 0, 10 * time.Second},
+		{0, false, 0, 5 * time.Second, 10 * time.Second},
+		// Half a token has come back, not enough for a request.
+		{2500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 7500 * time.Millisecond},
+		{2500 * time.Millisecond, true, 0, 0, 10 * time.Second},
+		// A long idle period refills only up to the limit.
+		{time.Hour, true, 1, 0, 5 * time.Second},
+	}
+	for i, s := range steps {
+		c.advance(s.after)
+		res, err := l.Allow(context.Background(), "k", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != s.allowed || res.Remaining != s.remaining || res.Limit != limit.Requests {
+			t.Errorf("step %d: Allowed, Remaining, Limit = %v, %d, %d, want %v, %d, %d", i, res.Allowed, res.Remaining, res.Limit, s.allowed, s.remaining, limit.Requests)
+		}
+		if !approx(res.RetryAfter, s.retryAfter) || !approx(res.Reset, s.reset) {
+			t.Errorf("step %d: RetryAfter, Reset = %v, %v, want %v, %v", i, res.RetryAfter, res.Reset, s.retryAfter, s.reset)
+		}
+	}
+}
+
+func TestMemoryLimiterKeepsKeysApart(t *testing.T) {
+	l := NewMemoryLimiter()
+	limit := Limit{Requests: 1, Window: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := l.Allow(ctx, "a", limit); !res.Allowed {
+		t.Fatal("first request for a was refused")
+	}
+	if res, _ := l.Allow(ctx, "a", limit); res.Allowed {
+		t.Error("second request for a was allowed, want refused")
+	}
+	if res, _ := l.Allow(ctx, "b", limit); !res.Allowed {
+		t.Error("first request for b was refused after a ran out")
+	}
+}
+
+func TestMemoryLimiterStartsAgainWhenTheLimitChanges(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+
+	l.Allow(ctx, "k", Limit{Requests: 1, Window: time.Minute})
+	res, _ := l.Allow(ctx, "k", Limit{Requests: 5, Window: time.Minute})
+	if !res.Allowed || res.Remaining != 4 {
+		t.Errorf("Allowed, Remaining = %v, %d, want true, 4", res.Allowed, res.Remaining)
+	}
+}
+
+func TestMemoryLimiterSweepsIdleBuckets(t *testing.T) {
+	c := &clock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryLimiter()
+	l.now = c.now
+	ctx := context.Background()
+
+	l.Allow(ctx, "idle", Limit{Requests: 1, Window: time.Second})
+	c.advance(2 * time.Second)
+	for range sweepEvery {
+		l.Allow(ctx, "busy", Limit{Requests: 1, Window: time.Minute})
+	}
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("busy bucket was swept")
+	}
+}
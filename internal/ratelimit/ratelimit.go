@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit describes how many requests are allowed within a window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result reports the outcome of a rate limit check.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully replenished.
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed.
+	// It is zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key may proceed.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow keeps a log of request timestamps in a sorted set and
+// admits a request only if fewer than the limit fall inside the window.
+// It uses the Redis server clock so that all instances agree on time.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = 0
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// RedisLimiter is a sliding window limiter shared by every instance
+// connected to the same Redis.
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisLimiter creates a distributed limiter storing its state under
+// keys prefixed with "ratelimit:".
+func NewRedisLimiter(client redis.Scripter) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: "ratelimit:"}
+}
+
+// Allow records a request for key and reports whether it fits the limit.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	window := limit.Window.Milliseconds()
+	vals, err := slidingWindow.Run(ctx, l.client, []string{l.prefix + key}, window, limit.Requests, uuid.NewString()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(vals) != 3 {
+		return Result{}, fmt.Errorf("rate limit script: unexpected reply %v", vals)
+	}
+
+	res := Result{
+		Allowed:   vals[0] == 1,
+		Limit:     limit.Requests,
+		Remaining: max(limit.Requests-int(vals[1]), 0),
+		Reset:     time.Duration(vals[2]) * time.Millisecond,
+	}
+	if !res.Allowed {
+		res.RetryAfter = res.Reset
+	}
+	return res, nil
+}
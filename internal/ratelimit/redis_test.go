@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// script answers the sliding window script with a canned reply.
+type script struct {
+	redis.Scripter
+	reply []interface{}
+	err   error
+
+	keys []string
+	args []interface{}
+}
+
+func (s *script) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	s.keys, s.args = keys, args
+	return redis.NewCmdResult(s.reply, s.err)
+}
+
+func TestRedisLimiter(t *testing.T) {
+	limit := Limit{Requests: 3, Window: time.Minute}
+	tests := []struct {
+		name  string
+		reply []interface{}
+		want  Result
+	}{
+		{"allowed", []interface{}{int64(1), int64(1), int64(60000)},
+			Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Minute}},
+		{"last allowed", []interface{}{int64(1), int64(3), int64(45000)},
+			Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 45 * time.Second}},
+		{"refused", []interface{}{int64(0), int64(3), int64(1500)},
+			Result{Limit: 3, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 1500 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &script{reply: tt.reply}
+			got, err := NewRedisLimiter(s).Allow(context.Background(), "ip:1.2.3.4", limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Allow() = %+v, want %+v", got, tt.want)
+			}
+			if len(s.keys) != 1 || s.keys[0] != "ratelimit:ip:1.2.3.4" {
+				t.Errorf("keys = %v, want [ratelimit:ip:1.2.3.4]", s.keys)
+			}
+			if len(s.args) < 2 || s.args[0] != int64(60000) || s.args[1] != 3 {
+				t.Errorf("args = %v, want window 60000 ms and limit 3 first", s.args)
+			}
+		})
+	}
+}
+
+func TestRedisLimiterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *script
+	}{
+		{"script failed", &script{err: errors.New("connection refused")}},
+		{"short reply", &script{reply: []interface{}{int64(1)}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewRedisLimiter(tt.s).Allow(context.Background(), "k", Limit{Requests: 1, Window: time.Second}); err == nil {
+			t.Errorf("%s: Allow() returned no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package reconcile runs the ledger reconciliation on a schedule and
+// reports what it finds in the logs and metrics.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/metrics"
+)
+
+// maxLogged caps how many discrepancies of each kind one run logs; the
+// counts are always logged in full.
+const maxLogged = 100
+
+// Ledger is what the job checks.
+type Ledger interface {
+	Reconcile(ctx context.Context) (*core.Reconciliation, error)
+}
+
+// Job reconciles the ledger every interval.
+type Job struct {
+	ledger   Ledger
+	interval time.Duration
+	// at is the time of day runs are aligned to, if anchored.
+	at       time.Duration
+	anchored bool
+	logger   *slog.Logger
+	metrics  *metrics.Metrics
+}
+
+// New creates a job that runs every interval, aligned to the UTC time of
+// day at (HH:MM) unless at is empty. m may be nil.
+func New(ledger Ledger, interval time.Duration, at string, logger *slog.Logger, m *metrics.Metrics) (*Job, error) {
+	j := &Job{ledger: ledger, interval: interval, logger: logger, metrics: m}
+	if at != "" {
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day %q: %w", at, err)
+		}
+		j.at = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		j.anchored = true
+	}
+	return j, nil
+}
+
+// Run reconciles at every scheduled time until ctx is cancelled. It does
+// nothing if the interval is zero.
+func (j *Job) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		return
+	}
+	for {
+		next := j.next(time.Now())
+		j.logger.Info("next ledger reconciliation", "at", next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		j.RunOnce(ctx)
+	}
+}
+
+// next returns the first scheduled time after now.
+func (j *Job) next(now time.Time) time.Time {
+	if !j.anchored {
+		return now.Add(j.interval)
+	}
+	now = now.UTC()
+	anchor := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(j.at)
+	if anchor.After(now) {
+		anchor = anchor.Add(-24 * time.Hour)
+	}
+	return anchor.Add((now.Sub(anchor)/j.interval + 1) * j.interval)
+}
+
+// RunOnce reconciles the ledger now and reports the result.
+func (j *Job) RunOnce(ctx context.Context) (*core.Reconciliation, error) {
+	start := time.Now()
+	rec, err := j.ledger.Reconcile(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "ledger reconciliation failed", "err", err)
+		if j.metrics != nil {
+			j.metrics.ReconciliationFailed()
+		}
+		return nil, err
+	}
+	if j.metrics != nil {
+		j.metrics.ReconciliationCompleted(rec)
+	}
+
+	attrs := []any{
+		"accounts", rec.Accounts,
+		"transactions", rec.Transactions,
+		"total_balance", rec.TotalBalance,
+		"external_net", rec.ExternalNet,
+		"internal_net", rec.InternalNet,
+		"duration", time.Since(start),
+	}
+	if rec.OK() {
+		j.logger.InfoContext(ctx, "ledger reconciled", attrs...)
+		return rec, nil
+	}
+
+	attrs = append(attrs,
+		"conserved", rec.Conserved(),
+		"mismatched_accounts", len(rec.Mismatches),
+		"imbalanced_transfers", len(rec.ImbalancedTransfers),
+	)
+	j.logger.ErrorContext(ctx, "ledger discrepancies found", attrs...)
+	for i, m := range rec.Mismatches {
+		if i == maxLogged {
+			break
+		}
+		mismatch := []any{
+			"account_id", m.AccountID,
+			"balance", m.Balance,
+			"ledger_balance", m.LedgerBalance,
+			"delta", m.Delta(),
+		}
+		if m.PotID != nil {
+			mismatch = append(mismatch, "pot_id", *m.PotID)
+		}
+		j.logger.ErrorContext(ctx, "balance does not match transactions", mismatch...)
+	}
+	for i, t := range rec.ImbalancedTransfers {
+		if i == maxLogged {
+			break
+		}
+		j.logger.ErrorContext(ctx, "transfer legs do not cancel out",
+			"reference", t.Reference,
+			"account_ids", t.AccountIDs,
+			"legs", t.Legs,
+			"net", t.Net,
+		)
+	}
+	return rec, nil
+}
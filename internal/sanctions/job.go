@@ -0,0 +1,107 @@
+package sanctions
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/metrics"
+)
+
+// Rescreener screens every user again against the current list.
+type Rescreener interface {
+	RescreenUsers(ctx context.Context) (*core.Rescreening, error)
+}
+
+// Job watches the list file and rescreens every user when it changes, so
+// customers who join a list after signing up are caught.
+type Job struct {
+	watchlist  *Watchlist
+	rescreener Rescreener
+	interval   time.Duration
+	logger     *slog.Logger
+	metrics    *metrics.Metrics
+}
+
+// NewJob creates a job checking the list file every interval. m may be
+// nil.
+func NewJob(watchlist *Watchlist, rescreener Rescreener, interval time.Duration, logger *slog.Logger, m *metrics.Metrics) *Job {
+	return &Job{watchlist: watchlist, rescreener: rescreener, interval: interval, logger: logger, metrics: m}
+}
+
+// Run rescreens once at startup, since the list may have changed while
+// the service was down, then checks the list file every interval until
+// ctx is cancelled. It does nothing if there is no watchlist or the
+// interval is zero.
+func (j *Job) Run(ctx context.Context) {
+	if j.watchlist == nil || j.interval <= 0 {
+		return
+	}
+	if j.metrics != nil {
+		j.metrics.WatchlistLoaded(len(j.watchlist.Current().Entries))
+	}
+	j.RunOnce(ctx)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.check(ctx)
+		}
+	}
+}
+
+// check reloads the list file and rescreens if it changed. A file that
+// fails to load leaves the previous list in use.
+func (j *Job) check(ctx context.Context) {
+	changed, err := j.watchlist.Reload()
+	if err != nil {
+		j.logger.ErrorContext(ctx, "watchlist reload failed", "err", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	list := j.watchlist.Current()
+	j.logger.InfoContext(ctx, "watchlist updated", "version", list.Version, "entries", len(list.Entries))
+	if j.metrics != nil {
+		j.metrics.WatchlistLoaded(len(list.Entries))
+	}
+	j.RunOnce(ctx)
+}
+
+// RunOnce rescreens every user now and reports the result. Hits it
+// records are audited as made by the system.
+func (j *Job) RunOnce(ctx context.Context) (*core.Rescreening, error) {
+	ctx = audit.WithActor(ctx, core.Actor{Type: core.ActorSystem, ID: "screening"})
+	res, err := j.rescreener.RescreenUsers(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "rescreening failed", "err", err)
+		if j.metrics != nil {
+			j.metrics.RescreenFailed()
+		}
+		return nil, err
+	}
+	if j.metrics != nil {
+		j.metrics.RescreenCompleted(res)
+	}
+
+	blocked := 0
+	for _, h := range res.Hits {
+		if h.Decision == core.DecisionBlock {
+			blocked++
+		}
+	}
+	j.logger.InfoContext(ctx, "rescreening finished",
+		"list_version", res.ListVersion,
+		"users", res.Users,
+		"hits", len(res.Hits),
+		"blocked", blocked,
+	)
+	return res, nil
+}
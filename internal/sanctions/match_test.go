@@ -0,0 +1,77 @@
+package sanctions
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"  José  María-López ", "jose maria lopez"},
+		{"Straße", "strasse"},
+		{"Łukasz Søren", "lukasz soren"},
+		{"Владимир Путин", "vladimir putin"},
+		{"Ελένη", "eleni"},
+		{"ＡＢＣ Trading 24", "abc trading 24"},
+		{"O'Brien, Jr.", "o brien jr"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"same", "same", 1},
+		{"abc", "xyz", 0},
+		{"", "", 1},
+		{"a", "", 0},
+	}
+	for _, tt := range tests {
+		got := JaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if back := JaroWinkler(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f, but %.4f the other way round", tt.b, tt.a, back, got)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		atLeast float64
+		below   float64
+	}{
+		{"equal", "ivan petrov", "ivan petrov", 1, 1.01},
+		{"word order", "petrov ivan", "ivan petrov", 1, 1.01},
+		{"spelling variant", "ivan petrof", "ivan petrov", 0.95, 1},
+		{"words of a longer name", "ivan petrov", "ivan sergeyevich petrov", 1, 1.01},
+		{"one shared word", "ivan smith", "ivan petrov", 0, 0.88},
+		{"unrelated", "maria garcia", "ivan petrov", 0, 0.7},
+		{"empty", "", "ivan petrov", 0, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(tt.a, tt.b)
+			if got < tt.atLeast || got >= tt.below {
+				t.Errorf("Similarity(%q, %q) = %.4f, want in [%.2f, %.2f)", tt.a, tt.b, got, tt.atLeast, tt.below)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Package sanctions screens names against a sanctions or watchlist file
+// kept on local disk. Names are normalized and transliterated before they
+// are compared, and compared fuzzily, so spelling variants and names
+// written in another script still match.
+package sanctions
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"mini-bank/internal/core"
+)
+
+// Entry is one listed party.
+type Entry struct {
+	ID      string
+	Name    string
+	Aliases []string
+	// Program is the sanctions program or list the party is on.
+	Program string
+}
+
+// listedName is a name or alias of an entry, normalized once at load.
+type listedName struct {
+	entry      *Entry
+	name       string
+	normalized string
+}
+
+// List is a loaded watchlist.
+type List struct {
+	// Version identifies the file contents, so hits record which version
+	// of the list they were found with.
+	Version string
+	Entries []Entry
+	names   []listedName
+}
+
+// Load reads a watchlist from a CSV file with a header row naming the
+// columns id, name, aliases and program, in any order. Aliases are
+// separated by semicolons; aliases and program may be left out.
+func Load(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watchlist: %w", err)
+	}
+	list, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse watchlist %s: %w", path, err)
+	}
+	return list, nil
+}
+
+func parse(data []byte) (*List, error) {
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range []string{"id", "name"} {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("header has no %s column", c)
+		}
+	}
+	field := func(rec []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	sum := sha256.Sum256(data)
+	list := &List{Version: hex.EncodeToString(sum[:8])}
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := Entry{ID: field(rec, "id"), Name: field(rec, "name"), Program: field(rec, "program")}
+		if e.ID == "" || e.Name == "" {
+			return nil, fmt.Errorf("line %d: id and name are required", line)
+		}
+		for _, alias := range strings.Split(field(rec, "aliases"), ";") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				e.Aliases = append(e.Aliases, alias)
+			}
+		}
+		list.Entries = append(list.Entries, e)
+	}
+
+	for i := range list.Entries {
+		e := &list.Entries[i]
+		for _, name := range append([]string{e.Name}, e.Aliases...) {
+			if n := Normalize(name); n != "" {
+				list.names = append(list.names, listedName{entry: e, name: name, normalized: n})
+			}
+		}
+	}
+	return list, nil
+}
+
+// Match is a listed party whose name or alias resembles a screened name.
+type Match struct {
+	Entry *Entry
+	// Name is the listed name or alias that matched best.
+	Name     string
+	Score    float64
+	Decision string
+}
+
+// Watchlist screens names against the current version of a list file,
+// which Reload picks up without a restart. A nil Watchlist matches
+// nothing.
+type Watchlist struct {
+	path   string
+	review float64
+	block  float64
+
+	mu   sync.RWMutex
+	list *List
+}
+
+// Open loads the list at path. Names scoring at least review against a
+// listed name are matches needing review; those scoring at least block
+// are blocked.
+func Open(path string, review, block float64) (*Watchlist, error) {
+	list, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Watchlist{path: path, review: review, block: block, list: list}, nil
+}
+
+// Current returns the list in use.
+func (w *Watchlist) Current() *List {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.list
+}
+
+// Reload reads the list file again and reports whether it changed. On
+// error the previous list stays in use.
+func (w *Watchlist) Reload() (bool, error) {
+	list, err := Load(w.path)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if list.Version == w.list.Version {
+		return false, nil
+	}
+	w.list = list
+	return true, nil
+}
+
+// Screen returns the listed parties whose names resemble name closely
+// enough to need review, best first, with one match per party, and the
+// version of the list they were found in.
+func (w *Watchlist) Screen(name string) ([]Match, string) {
+	if w == nil {
+		return nil, ""
+	}
+	list := w.Current()
+	normalized := Normalize(name)
+	best := map[*Entry]Match{}
+	for _, n := range list.names {
+		score := Similarity(normalized, n.normalized)
+		if score < w.review || score <= best[n.entry].Score {
+			continue
+		}
+		decision := core.DecisionReview
+		if score >= w.block {
+			decision = core.DecisionBlock
+		}
+		best[n.entry] = Match{Entry: n.entry, Name: n.name, Score: score, Decision: decision}
+	}
+
+	matches := make([]Match, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Entry.ID, b.Entry.ID))
+	})
+	return matches, list.Version
+}
+
+// Blocked reports whether any match blocks.
+func Blocked(matches []Match) bool {
+	return slices.ContainsFunc(matches, func(m Match) bool {
+		return m.Decision == core.DecisionBlock
+	})
+}
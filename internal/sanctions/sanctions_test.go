@@ -0,0 +1,59 @@
+package sanctions
+
+import (
+	"testing"
+
+	"mini-bank/internal/core"
+)
+
+const testList = `id,name,aliases,program
+1,Ivan Sergeyevich Petrov,Иван Петров;I. Petrov,SDN
+2,Maria Garcia,,EU
+`
+
+func TestScreen(t *testing.T) {
+	list, err := parse([]byte(testList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Watchlist{review: 0.88, block: 0.97, list: list}
+
+	tests := []struct {
+		name     string
+		wantID   string
+		decision string
+	}{
+		{"Ivan Petrov", "1", core.DecisionBlock},
+		{"IVAN PETROV", "1", core.DecisionBlock},
+		{"Иван Петров", "1", core.DecisionBlock},
+		{"Ivan Petrof", "1", core.DecisionReview},
+		{"Garcia, Maria", "2", core.DecisionBlock},
+		{"John Smith", "", ""},
+	}
+	for _, tt := range tests {
+		matches, version := w.Screen(tt.name)
+		if version != list.Version {
+			t.Errorf("Screen(%q) version = %q, want %q", tt.name, version, list.Version)
+		}
+		if tt.wantID == "" {
+			if len(matches) != 0 {
+				t.Errorf("Screen(%q) = %v, want no matches", tt.name, matches)
+			}
+			continue
+		}
+		if len(matches) != 1 {
+			t.Errorf("Screen(%q) returned %d matches, want 1", tt.name, len(matches))
+			continue
+		}
+		if m := matches[0]; m.Entry.ID != tt.wantID || m.Decision != tt.decision {
+			t.Errorf("Screen(%q) = entry %s %s (%.4f), want entry %s %s", tt.name, m.Entry.ID, m.Decision, m.Score, tt.wantID, tt.decision)
+		}
+	}
+}
+
+func TestNilWatchlistMatchesNothing(t *testing.T) {
+	var w *Watchlist
+	if matches, _ := w.Screen("Ivan Petrov"); matches != nil {
+		t.Errorf("Screen on nil watchlist = %v, want nil", matches)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"mini-bank/internal/core"
+
+	"github.com/google/uuid"
+)
+
+// FreezeAccount stops customers moving money in or out of an account.
+func (s *service) FreezeAccount(ctx context.Context, id int) (*core.Account, error) {
+	return s.store.SetAccountStatus(ctx, id, core.AccountFrozen)
+}
+
+// UnfreezeAccount lifts a freeze.
+func (s *service) UnfreezeAccount(ctx context.Context, id int) (*core.Account, error) {
+	return s.store.SetAccountStatus(ctx, id, core.AccountActive)
+}
+
+// AdjustBalance credits a positive amount to an account or debits a
+// negative one, recording the reason with the transaction.
+func (s *service) AdjustBalance(ctx context.Context, accountID int, amount int64, reason string) (*core.Transaction, error) {
+	var fields []core.FieldError
+	if amount == 0 {
+		fields = append(fields, core.FieldError{Field: "amount", Message: "amount must not be zero"})
+	}
+	if strings.TrimSpace(reason) == "" {
+		fields = append(fields, core.FieldError{Field: "reason", Message: "reason is required"})
+	}
+	if len(fields) > 0 {
+		return nil, core.InvalidFields(fields...)
+	}
+	return s.store.AdjustBalance(ctx, accountID, amount, strings.TrimSpace(reason), uuid.NewString())
+}
+
+// ReverseTransaction undoes a transaction, recording the reason with the
+// reversal.
+func (s *service) ReverseTransaction(ctx context.Context, id int, reason string) ([]*core.Transaction, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, core.InvalidField("reason", "reason is required")
+	}
+	return s.store.ReverseTransaction(ctx, id, strings.TrimSpace(reason), uuid.NewString())
+}
+
+// Reconcile checks every balance against its transaction history and
+// that transfers neither create nor destroy money.
+func (s *service) Reconcile(ctx context.Context) (*core.Reconciliation, error) {
+	return s.store.Reconcile(ctx)
+}
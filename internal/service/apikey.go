@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// apiKeyTag starts every API key so leaked keys are easy to recognise.
+const apiKeyTag = "mbk"
+
+func (s *service) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, accountIDs []int) (*core.APIKey, string, error) {
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := s.store.CreateAPIKey(ctx, &core.APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		Hash:       hashSecret(secret),
+		Scopes:     scopes,
+		AccountIDs: accountIDs,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+func (s *service) ListAPIKeys(ctx context.Context, userID int) ([]*core.APIKey, error) {
+	return s.store.ListAPIKeys(ctx, userID)
+}
+
+// RotateAPIKey issues a new secret for a key; the old secret stops working immediately.
+func (s *service) RotateAPIKey(ctx context.Context, userID int, id int) (*core.APIKey, string, error) {
+	if _, err := s.ownAPIKey(ctx, userID, id); err != nil {
+		return nil, "", err
+	}
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := s.store.RotateAPIKey(ctx, id, prefix, hashSecret(secret))
+	if err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+func (s *service) RevokeAPIKey(ctx context.Context, userID int, id int) error {
+	if _, err := s.ownAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.store.RevokeAPIKey(ctx, id)
+}
+
+// AuthenticateAPIKey resolves an API key secret to an active key and
+// records its use.
+func (s *service) AuthenticateAPIKey(ctx context.Context, secret string) (*core.APIKey, error) {
+	parts := strings.SplitN(secret, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag {
+		return nil, storage.ErrInvalidAPIKey
+	}
+
+	key, err := s.store.GetAPIKeyByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return nil, storage.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, storage.ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil {
+		return nil, storage.ErrInvalidAPIKey
+	}
+
+	if err := s.store.TouchAPIKey(ctx, key.ID); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ownAPIKey loads a key and hides keys belonging to other users.
+func (s *service) ownAPIKey(ctx context.Context, userID int, id int) (*core.APIKey, error) {
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserID != userID {
+		return nil, storage.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// generateAPIKey returns a lookup prefix and the full secret, formatted as
+// mbk_<prefix>_<random>.
+func generateAPIKey() (string, string, error) {
+	buf := make([]byte, 30)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(buf[:6])
+	secret := apiKeyTag + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(buf[6:])
+	return prefix, secret, nil
+}
+
+// hashSecret hashes a generated credential for storage. API keys and
+// client secrets carry enough entropy that a fast hash is sufficient,
+// unlike passwords.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+)
+
+// Page sizes for reading the audit log.
+const (
+	auditPageDefault = 100
+	auditPageMax     = 1000
+)
+
+// ListAuditEntries returns one page of audit entries matching the filter.
+func (s *service) ListAuditEntries(ctx context.Context, filter core.AuditFilter) ([]core.AuditEntry, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = auditPageDefault
+	case filter.Limit > auditPageMax:
+		filter.Limit = auditPageMax
+	}
+	return s.store.ListAuditEntries(ctx, filter)
+}
+
+// VerifyAudit walks the whole audit chain, checking every link and hash.
+// head, if given, is the Head of an earlier verification, which must
+// still be in the chain.
+func (s *service) VerifyAudit(ctx context.Context, head string) (*core.AuditVerification, error) {
+	v := audit.NewVerifier(head)
+	var after int64
+	for {
+		entries, err := s.store.ListAuditEntries(ctx, core.AuditFilter{AfterID: after, Limit: auditPageMax})
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			v.Add(&entries[i])
+		}
+		if len(entries) < auditPageMax {
+			return v.Result(), nil
+		}
+		after = entries[len(entries)-1].ID
+	}
+}
+
+// HasRole reports whether a user holds a staff role.
+func (s *service) HasRole(ctx context.Context, userID int, role string) (bool, error) {
+	roles, err := s.store.GetUserRoles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(roles, role), nil
+}
+
+// GrantRole gives a user a staff role.
+func (s *service) GrantRole(ctx context.Context, userID int, role string) ([]string, error) {
+	if !slices.Contains(core.Roles, role) {
+		return nil, core.InvalidField("role", "unknown role "+role)
+	}
+	return s.store.SetUserRole(ctx, userID, role, true)
+}
+
+// RevokeRole takes a staff role away from a user.
+func (s *service) RevokeRole(ctx context.Context, userID int, role string) ([]string, error) {
+	if !slices.Contains(core.Roles, role) {
+		return nil, core.InvalidField("role", "unknown role "+role)
+	}
+	return s.store.SetUserRole(ctx, userID, role, false)
+}
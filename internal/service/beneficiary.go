@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/sanctions"
+	"mini-bank/internal/storage"
+)
+
+// closeMatchScore is the name similarity, from 0 to 1, at which a payee
+// name that is not an exact match is a close match.
+const closeMatchScore = 0.9
+
+// maxNicknameLength caps beneficiary nicknames, in characters.
+const maxNicknameLength = 50
+
+// CheckPayee compares the name a customer gives for a payee with the name
+// of the account's owner, ignoring case, accents, punctuation and word
+// order. The owner's name is only given back for a match or close match.
+func (s *service) CheckPayee(ctx context.Context, accountID int, name string) (*core.PayeeCheck, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, core.InvalidField("name", "name is required")
+	}
+	acc, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := s.store.GetUser(ctx, acc.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &core.PayeeCheck{AccountID: accountID, AccountNumber: acc.Number, Result: core.PayeeNoMatch}
+	payee := fullName(owner.FirstName, owner.LastName)
+	given, want := sortedWords(sanctions.Normalize(name)), sortedWords(sanctions.Normalize(payee))
+	switch {
+	case given == want:
+		check.Result = core.PayeeMatch
+	case sanctions.JaroWinkler(given, want) >= closeMatchScore:
+		check.Result = core.PayeeCloseMatch
+	default:
+		return check, nil
+	}
+	check.PayeeName = payee
+	return check, nil
+}
+
+// sortedWords puts the words of a normalized name in order. Unlike
+// screening, the whole name is compared: a relative sharing a surname must
+// not be a close match.
+func sortedWords(name string) string {
+	words := strings.Fields(name)
+	slices.Sort(words)
+	return strings.Join(words, " ")
+}
+
+// CreateBeneficiary saves a payee for the user once the name they gave
+// confirms who owns the account. A name that does not match is refused
+// with ErrPayeeMismatch. The name is screened against the watchlist like
+// a signup: a blocked name is refused, and one needing review is saved
+// and the hits recorded against the beneficiary.
+func (s *service) CreateBeneficiary(ctx context.Context, userID int, nickname string, accountID int, name string) (*core.Beneficiary, error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" || utf8.RuneCountInString(nickname) > maxNicknameLength {
+		return nil, core.InvalidField("nickname", fmt.Sprintf("nickname is required and at most %d characters", maxNicknameLength))
+	}
+	check, err := s.CheckPayee(ctx, accountID, name)
+	if err != nil {
+		return nil, err
+	}
+	if check.Result == core.PayeeNoMatch {
+		return nil, storage.ErrPayeeMismatch
+	}
+	hits, err := s.screenName(ctx, core.ScreeningSubjectBeneficiary, nil, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.store.CreateBeneficiary(ctx, &core.Beneficiary{
+		UserID:    userID,
+		Nickname:  nickname,
+		AccountID: accountID,
+		PayeeName: check.PayeeName,
+		NameMatch: check.Result,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordReviewHits(ctx, hits, b.ID)
+	s.setCoolingOff(b)
+	return b, nil
+}
+
+func (s *service) ListBeneficiaries(ctx context.Context, userID int) ([]*core.Beneficiary, error) {
+	bs, err := s.store.ListBeneficiaries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bs {
+		s.setCoolingOff(b)
+	}
+	return bs, nil
+}
+
+func (s *service) DeleteBeneficiary(ctx context.Context, userID int, id int) error {
+	if _, err := s.ownBeneficiary(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.store.DeleteBeneficiary(ctx, id)
+}
+
+// BeneficiaryAccount returns the account of one of the user's
+// beneficiaries.
+func (s *service) BeneficiaryAccount(ctx context.Context, userID int, id int) (int, error) {
+	b, err := s.ownBeneficiary(ctx, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	return b.AccountID, nil
+}
+
+// checkCoolingOff refuses with ErrCoolingOff a transfer over the
+// cooling-off amount to a payee the sender's owner has known for less than
+// the cooling-off period. A payee is known from when it was saved as a
+// beneficiary or first paid, whichever came first, so deleting a
+// beneficiary or paying an account without saving it does not get round
+// the cap. Transfers between the owner's own accounts pass, and so does a
+// missing account, as in checkTransfer.
+func (s *service) checkCoolingOff(ctx context.Context, fromID, toID int, amount int64) error {
+	if s.coolingOff <= 0 || amount <= s.coolingOffAmount {
+		return nil
+	}
+	from, err := s.store.GetAccount(ctx, fromID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	to, err := s.store.GetAccount(ctx, toID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	if to.UserID == from.UserID {
+		return nil
+	}
+
+	since, err := s.payeeKnownSince(ctx, from.UserID, toID)
+	if err != nil {
+		return err
+	}
+	if since == nil {
+		return storage.ErrCoolingOff.WithMessage(fmt.Sprintf("transfers over %d to a new payee are allowed %s after it is saved or first paid",
+			s.coolingOffAmount, s.coolingOff))
+	}
+	if until := since.Add(s.coolingOff); time.Now().Before(until) {
+		return storage.ErrCoolingOff.WithMessage(fmt.Sprintf("transfers over %d to this payee are allowed from %s",
+			s.coolingOffAmount, until.UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// payeeKnownSince returns the earlier of when the user saved an account as
+// a beneficiary and when they first paid it, or nil if they have done
+// neither.
+func (s *service) payeeKnownSince(ctx context.Context, userID, accountID int) (*time.Time, error) {
+	since, err := s.store.FirstTransferTo(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.store.FindBeneficiary(ctx, userID, accountID)
+	if err != nil {
+		if errors.Is(err, storage.ErrBeneficiaryNotFound) {
+			return since, nil
+		}
+		return nil, err
+	}
+	if since == nil || b.CreatedAt.Before(*since) {
+		return &b.CreatedAt, nil
+	}
+	return since, nil
+}
+
+// setCoolingOff fills in when a beneficiary's cooling-off period ends, if
+// it has not yet.
+func (s *service) setCoolingOff(b *core.Beneficiary) {
+	if s.coolingOff <= 0 {
+		return
+	}
+	if until := b.CreatedAt.Add(s.coolingOff); time.Now().Before(until) {
+		b.CoolingOffUntil = &until
+	}
+}
+
+// ownBeneficiary loads a beneficiary and hides those of other users.
+func (s *service) ownBeneficiary(ctx context.Context, userID int, id int) (*core.Beneficiary, error) {
+	b, err := s.store.GetBeneficiary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.UserID != userID {
+		return nil, storage.ErrBeneficiaryNotFound
+	}
+	return b, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// payeeStore knows a few accounts, the beneficiaries saved for them and
+// when each user first paid them.
+type payeeStore struct {
+	storage.Storage
+	accounts      map[int]*core.Account
+	beneficiaries map[int]*core.Beneficiary
+	firstPaid     map[[2]int]time.Time
+}
+
+func (s *payeeStore) GetAccount(ctx context.Context, id int) (*core.Account, error) {
+	acc, ok := s.accounts[id]
+	if !ok {
+		return nil, storage.ErrAccountNotFound
+	}
+	return acc, nil
+}
+
+func (s *payeeStore) GetBeneficiary(ctx context.Context, id int) (*core.Beneficiary, error) {
+	b, ok := s.beneficiaries[id]
+	if !ok {
+		return nil, storage.ErrBeneficiaryNotFound
+	}
+	return b, nil
+}
+
+func (s *payeeStore) FindBeneficiary(ctx context.Context, userID int, accountID int) (*core.Beneficiary, error) {
+	for _, b := range s.beneficiaries {
+		if b.UserID == userID && b.AccountID == accountID {
+			return b, nil
+		}
+	}
+	return nil, storage.ErrBeneficiaryNotFound
+}
+
+func (s *payeeStore) DeleteBeneficiary(ctx context.Context, id int) error {
+	delete(s.beneficiaries, id)
+	return nil
+}
+
+func (s *payeeStore) FirstTransferTo(ctx context.Context, userID int, accountID int) (*time.Time, error) {
+	at, ok := s.firstPaid[[2]int{userID, accountID}]
+	if !ok {
+		return nil, nil
+	}
+	return &at, nil
+}
+
+func TestCheckCoolingOff(t *testing.T) {
+	now := time.Now()
+	store := &payeeStore{
+		accounts: map[int]*core.Account{
+			1: {ID: 1, UserID: 1},
+			2: {ID: 2, UserID: 1},
+			3: {ID: 3, UserID: 2},
+			4: {ID: 4, UserID: 3},
+			5: {ID: 5, UserID: 4},
+			6: {ID: 6, UserID: 5},
+		},
+		beneficiaries: map[int]*core.Beneficiary{
+			1: {ID: 1, UserID: 1, AccountID: 3, CreatedAt: now.Add(-time.Hour)},
+			2: {ID: 2, UserID: 1, AccountID: 6, CreatedAt: now.Add(-time.Hour)},
+		},
+		firstPaid: map[[2]int]time.Time{
+			{1, 4}: now.Add(-48 * time.Hour),
+			{1, 6}: now.Add(-48 * time.Hour),
+		},
+	}
+	s := New(store, Options{CoolingOff: 24 * time.Hour, CoolingOffAmount: 1000}).(*service)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		toID    int
+		amount  int64
+		wantErr bool
+	}{
+		{"within the cap", 5, 1000, false},
+		{"own account", 2, 5000, false},
+		{"missing account", 99, 5000, false},
+		{"never paid or saved", 5, 5000, true},
+		{"new beneficiary", 3, 5000, true},
+		{"paid two days ago", 4, 5000, false},
+		{"new beneficiary paid two days ago", 6, 5000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkCoolingOff(ctx, 1, tt.toID, tt.amount)
+			if tt.wantErr != errors.Is(err, storage.ErrCoolingOff) {
+				t.Fatalf("checkCoolingOff = %v, want cooling off %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("deleted beneficiary", func(t *testing.T) {
+		if err := s.DeleteBeneficiary(ctx, 1, 1); err != nil {
+			t.Fatalf("DeleteBeneficiary: %v", err)
+		}
+		if err := s.checkCoolingOff(ctx, 1, 3, 5000); !errors.Is(err, storage.ErrCoolingOff) {
+			t.Fatalf("checkCoolingOff after delete = %v, want ErrCoolingOff", err)
+		}
+	})
+}
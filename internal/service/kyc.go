@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/blob"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// Page sizes for listing KYC submissions.
+const (
+	kycPageDefault = 100
+	kycPageMax     = 1000
+)
+
+// maxUploads caps the documents in one submission.
+const maxUploads = 4
+
+// documentTypes are the content types accepted for documents, detected
+// from their content rather than trusted from the upload.
+var documentTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+func (s *service) GetKYCStatus(ctx context.Context, userID int) (*core.KYCStatus, error) {
+	return s.store.GetKYCStatus(ctx, userID)
+}
+
+// SubmitKYC puts a user's identity data and documents in the review
+// queue. The legal name is screened against the watchlist like a signup.
+// Documents are written to the blob store before the submission is saved
+// and removed again if saving fails.
+func (s *service) SubmitKYC(ctx context.Context, userID int, sub core.KYCSubmission, uploads []core.KYCUpload) (*core.KYCSubmission, error) {
+	sub.UserID = userID
+	sub.LegalName = strings.TrimSpace(sub.LegalName)
+	sub.Address = strings.TrimSpace(sub.Address)
+	sub.Nationality = strings.ToUpper(strings.TrimSpace(sub.Nationality))
+	sub.DocumentNumber = strings.TrimSpace(sub.DocumentNumber)
+	if err := validateSubmission(&sub, uploads); err != nil {
+		return nil, err
+	}
+	if s.documents == nil {
+		return nil, errors.New("no document store configured")
+	}
+	// Fail before uploading anything if a submission is already waiting;
+	// the store checks again when saving.
+	status, err := s.store.GetKYCStatus(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if status.Latest != nil && status.Latest.Status == core.KYCPending {
+		return nil, storage.ErrSubmissionPending
+	}
+
+	hits, err := s.screenName(ctx, core.ScreeningSubjectUser, &userID, sub.LegalName)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	cleanup := func() {
+		for _, key := range keys {
+			s.documents.Delete(context.WithoutCancel(ctx), key)
+		}
+	}
+	for _, u := range uploads {
+		doc, err := s.storeDocument(ctx, userID, u)
+		if doc != nil {
+			keys = append(keys, doc.BlobKey)
+		}
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		sub.Documents = append(sub.Documents, doc)
+	}
+
+	created, err := s.store.CreateKYCSubmission(ctx, &sub)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	s.recordReviewHits(ctx, hits, userID)
+	return created, nil
+}
+
+func validateSubmission(sub *core.KYCSubmission, uploads []core.KYCUpload) error {
+	var fields []core.FieldError
+	add := func(field, message string) {
+		fields = append(fields, core.FieldError{Field: field, Message: message})
+	}
+	if sub.Tier != core.TierBasic && sub.Tier != core.TierFull {
+		add("tier", "tier must be basic or full")
+	}
+	if sub.LegalName == "" {
+		add("legal_name", "legal_name is required")
+	}
+	if sub.DateOfBirth.IsZero() {
+		add("date_of_birth", "date_of_birth is required")
+	} else if sub.DateOfBirth.AddDate(18, 0, 0).After(time.Now()) {
+		add("date_of_birth", "customers must be at least 18 years old")
+	}
+	if sub.Address == "" {
+		add("address", "address is required")
+	}
+	if len(sub.Nationality) != 2 || strings.Trim(sub.Nationality, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		add("nationality", "nationality must be a two-letter ISO 3166 country code")
+	}
+	if !slices.Contains(core.IdentityDocumentTypes, sub.DocumentType) {
+		add("document_type", "document_type must be one of "+strings.Join(core.IdentityDocumentTypes, ", "))
+	}
+	if sub.DocumentNumber == "" || len(sub.DocumentNumber) > 50 {
+		add("document_number", "document_number is required and at most 50 characters")
+	}
+
+	if len(uploads) > maxUploads {
+		add("documents", "at most "+strconv.Itoa(maxUploads)+" documents can be uploaded")
+	}
+	kinds := map[string]bool{}
+	for _, u := range uploads {
+		kinds[u.Kind] = true
+	}
+	if !kinds[core.DocumentIdentity] {
+		add(core.DocumentIdentity, "an identity document is required")
+	}
+	if sub.Tier == core.TierFull && !kinds[core.DocumentProofOfAddress] {
+		add(core.DocumentProofOfAddress, "a proof of address is required for the full tier")
+	}
+	if len(fields) > 0 {
+		return core.InvalidFields(fields...)
+	}
+	return nil
+}
+
+// storeDocument writes an upload to the blob store, hashing and measuring
+// it on the way. It returns the document written so far even on error,
+// so the caller can remove it.
+func (s *service) storeDocument(ctx context.Context, userID int, u core.KYCUpload) (*core.KYCDocument, error) {
+	br := bufio.NewReader(u.Body)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if len(head) == 0 {
+		return nil, core.InvalidField(u.Kind, "document is empty")
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if !slices.Contains(documentTypes, contentType) {
+		return nil, core.InvalidField(u.Kind, "document must be a JPEG, PNG or PDF file")
+	}
+
+	doc := &core.KYCDocument{
+		Kind:        u.Kind,
+		Filename:    filepath.Base(u.Filename),
+		ContentType: contentType,
+		BlobKey:     blob.NewKey("kyc/" + strconv.Itoa(userID)),
+	}
+	h := sha256.New()
+	counter := &countingWriter{}
+	body := io.TeeReader(io.LimitReader(br, s.maxDocumentSize+1), io.MultiWriter(h, counter))
+	if err := s.documents.Put(ctx, doc.BlobKey, body); err != nil {
+		return doc, fmt.Errorf("failed to store document: %w", err)
+	}
+	if counter.n > s.maxDocumentSize {
+		return doc, core.InvalidField(u.Kind, "document must be at most "+strconv.FormatInt(s.maxDocumentSize, 10)+" bytes")
+	}
+	doc.Size = counter.n
+	doc.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return doc, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// ListKYCSubmissions returns one page of submissions matching the filter.
+func (s *service) ListKYCSubmissions(ctx context.Context, filter core.KYCFilter) ([]*core.KYCSubmission, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = kycPageDefault
+	case filter.Limit > kycPageMax:
+		filter.Limit = kycPageMax
+	}
+	return s.store.ListKYCSubmissions(ctx, filter)
+}
+
+func (s *service) GetKYCSubmission(ctx context.Context, id int) (*core.KYCSubmission, error) {
+	return s.store.GetKYCSubmission(ctx, id)
+}
+
+// OpenKYCDocument returns a document of a submission and its content,
+// which the caller must close.
+func (s *service) OpenKYCDocument(ctx context.Context, submissionID int, documentID int) (*core.KYCDocument, io.ReadCloser, error) {
+	sub, err := s.store.GetKYCSubmission(ctx, submissionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := slices.IndexFunc(sub.Documents, func(d *core.KYCDocument) bool { return d.ID == documentID })
+	if i < 0 || s.documents == nil {
+		return nil, nil, storage.ErrDocumentNotFound
+	}
+	doc := sub.Documents[i]
+	body, err := s.documents.Get(ctx, doc.BlobKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, storage.ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, body, nil
+}
+
+// ReviewKYCSubmission approves or rejects a pending submission. A
+// rejection needs a note telling the customer what to fix.
+func (s *service) ReviewKYCSubmission(ctx context.Context, id int, status string, note string) (*core.KYCSubmission, error) {
+	note = strings.TrimSpace(note)
+	var fields []core.FieldError
+	if status != core.KYCApproved && status != core.KYCRejected {
+		fields = append(fields, core.FieldError{Field: "status", Message: "status must be approved or rejected"})
+	}
+	if status == core.KYCRejected && note == "" {
+		fields = append(fields, core.FieldError{Field: "note", Message: "note is required when rejecting"})
+	}
+	if len(fields) > 0 {
+		return nil, core.InvalidFields(fields...)
+	}
+	return s.store.ReviewKYCSubmission(ctx, id, status, note)
+}
+
+// tierLimits returns a user's KYC tier and the limits it puts on money
+// leaving each of their accounts. Tier rules are decided here, so every
+// debit is held to the same tier whichever way it is made; storage only
+// applies the limits under the debit's lock, so that concurrent debits
+// cannot each pass alone.
+func (s *service) tierLimits(ctx context.Context, userID int) (string, core.Limits, error) {
+	tier, err := s.store.GetUserTier(ctx, userID)
+	if err != nil {
+		return "", core.Limits{}, err
+	}
+	limits, err := s.store.GetTierLimits(ctx, tier)
+	if err != nil {
+		return "", core.Limits{}, err
+	}
+	return tier, *limits, nil
+}
+
+// debitTierLimits returns the tier limits on money leaving an account. A
+// missing account has none, so the debit itself reports it.
+func (s *service) debitTierLimits(ctx context.Context, accountID int) (core.Limits, error) {
+	acc, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return core.Limits{}, nil
+		}
+		return core.Limits{}, err
+	}
+	_, limits, err := s.tierLimits(ctx, acc.UserID)
+	return limits, err
+}
+
+// SetTierLimits changes the limits of a KYC tier.
+func (s *service) SetTierLimits(ctx context.Context, tier string, limits core.Limits) error {
+	if core.TierRank(tier) < 0 {
+		return core.InvalidField("tier", "tier must be one of "+strings.Join(core.Tiers, ", "))
+	}
+	if err := validateLimits(limits); err != nil {
+		return err
+	}
+	return s.store.SetTierLimits(ctx, tier, limits)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	"mini-bank/internal/core"
+)
+
+// GetAccountLimits returns the limits in force on an account, capped at
+// its owner's KYC tier's, and how much of each is used.
+func (s *service) GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error) {
+	lim, err := s.store.GetAccountLimits(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return lim, s.applyTier(ctx, lim)
+}
+
+// SetAccountLimits overrides some or all of an account's product limits.
+func (s *service) SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error) {
+	if err := validateLimits(limits); err != nil {
+		return nil, err
+	}
+	lim, err := s.store.SetAccountLimits(ctx, accountID, limits)
+	if err != nil {
+		return nil, err
+	}
+	return lim, s.applyTier(ctx, lim)
+}
+
+// applyTier caps an account's limits at those of its owner's KYC tier.
+func (s *service) applyTier(ctx context.Context, lim *core.AccountLimits) error {
+	acc, err := s.store.GetAccount(ctx, lim.AccountID)
+	if err != nil {
+		return err
+	}
+	tier, limits, err := s.tierLimits(ctx, acc.UserID)
+	if err != nil {
+		return err
+	}
+	lim.Tier = tier
+	lim.Cap(limits)
+	return nil
+}
+
+// SetProductLimits changes the default limits of every account on a
+// product that does not override them.
+func (s *service) SetProductLimits(ctx context.Context, product string, limits core.Limits) error {
+	if err := validateLimits(limits); err != nil {
+		return err
+	}
+	return s.store.SetProductLimits(ctx, product, limits)
+}
+
+// validateLimits rejects negative limits. A zero limit blocks every
+// withdrawal and transfer, which is how an account is capped at nothing.
+func validateLimits(l core.Limits) error {
+	var fields []core.FieldError
+	check := func(field string, v *int64) {
+		if v != nil && *v < 0 {
+			fields = append(fields, core.FieldError{Field: field, Message: field + " must not be negative"})
+		}
+	}
+	check("max_single", l.MaxSingle)
+	check("daily_outbound", l.DailyOutbound)
+	check("monthly_outbound", l.MonthlyOutbound)
+	check("hourly_count", l.HourlyCount)
+	if len(fields) > 0 {
+		return core.InvalidFields(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/metrics"
+	"mini-bank/internal/storage"
+)
+
+// metricsService counts completed and rejected money movements and
+// requests refused by screening. Other calls pass straight through to
+// the wrapped Service.
+type metricsService struct {
+	Service
+	m *metrics.Metrics
+}
+
+// WithMetrics returns a Service that records transfers and payments made
+// through next in m.
+func WithMetrics(next Service, m *metrics.Metrics) Service {
+	return &metricsService{Service: next, m: m}
+}
+
+func (s *metricsService) Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error) {
+	from, to, err := s.Service.Transfer(ctx, fromID, toID, amount, reference)
+	switch {
+	case err == nil:
+		s.m.TransferCompleted(amount)
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		s.m.InsufficientFunds("transfer")
+	case errors.Is(err, storage.ErrLimitExceeded):
+		s.m.LimitExceeded("transfer")
+	case errors.Is(err, storage.ErrTransactionBlocked):
+		s.m.TransactionBlocked("transfer")
+	}
+	return from, to, err
+}
+
+func (s *metricsService) Payment(ctx context.Context, accountID int, amount int64, pType storage.PaymentType, reference string) (*core.Account, error) {
+	acc, err := s.Service.Payment(ctx, accountID, amount, pType, reference)
+	switch {
+	case err == nil:
+		s.m.PaymentCompleted(string(pType), amount)
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		s.m.InsufficientFunds(string(pType))
+	case errors.Is(err, storage.ErrLimitExceeded):
+		s.m.LimitExceeded(string(pType))
+	case errors.Is(err, storage.ErrTransactionBlocked):
+		s.m.TransactionBlocked(string(pType))
+	}
+	return acc, err
+}
+
+func (s *metricsService) CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error) {
+	u, err := s.Service.CreateUser(ctx, firstName, lastName, email, password)
+	if errors.Is(err, storage.ErrScreeningBlocked) {
+		s.m.ScreeningBlocked("create_user")
+	}
+	return u, err
+}
+
+func (s *metricsService) CreateBeneficiary(ctx context.Context, userID int, nickname string, accountID int, name string) (*core.Beneficiary, error) {
+	b, err := s.Service.CreateBeneficiary(ctx, userID, nickname, accountID, name)
+	if errors.Is(err, storage.ErrScreeningBlocked) {
+		s.m.ScreeningBlocked("create_beneficiary")
+	}
+	return b, err
+}
+
+func (s *metricsService) UpdateUser(ctx context.Context, id int, firstName string, lastName string, email string) (*core.User, error) {
+	u, err := s.Service.UpdateUser(ctx, id, firstName, lastName, email)
+	if errors.Is(err, storage.ErrScreeningBlocked) {
+		s.m.ScreeningBlocked("update_user")
+	}
+	return u, err
+}
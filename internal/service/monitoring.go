@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/monitor"
+	"mini-bank/internal/storage"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Page sizes for listing monitoring cases.
+const (
+	casePageDefault = 100
+	casePageMax     = 1000
+)
+
+// screen runs the monitoring rules over a movement about to be made on an
+// account. If a rule blocks it, screen opens the cases and returns
+// ErrTransactionBlocked without saying which rule, so customers cannot
+// learn to avoid them. Otherwise it returns the review cases to open once
+// the movement is made.
+func (s *service) screen(ctx context.Context, accountID int, typ string, amount int64, reference string) ([]*core.Case, error) {
+	if s.rules == nil {
+		return nil, nil
+	}
+	m := monitor.Movement{Type: typ, Amount: amount, At: time.Now().UTC()}
+	activity, err := s.store.GetAccountActivity(ctx, accountID, m.At.Add(-s.rules.Lookback()))
+	if err != nil {
+		// A missing account is reported by the movement itself.
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	hits := s.rules.Evaluate(activity.Account, activity.Transactions, m)
+	cases := monitor.Cases(accountID, reference, m, core.CaseSourceRealtime, hits)
+	if monitor.Decide(hits) == core.DecisionBlock {
+		if _, err := s.store.OpenCases(ctx, cases...); err != nil {
+			return nil, err
+		}
+		return nil, storage.ErrTransactionBlocked
+	}
+	return cases, nil
+}
+
+// openReviewCases opens the cases for a movement that has been made. The
+// movement stands even if this fails, so the failure is only recorded on
+// the trace; the next scan opens the cases instead.
+func (s *service) openReviewCases(ctx context.Context, cases []*core.Case) {
+	if _, err := s.store.OpenCases(ctx, cases...); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+// ScanActivity screens every movement made since the given time again,
+// each against the history before it, and opens cases for what the rules
+// flag that was not flagged already.
+func (s *service) ScanActivity(ctx context.Context, since time.Time) (*core.MonitoringScan, error) {
+	scan := &core.MonitoringScan{ScannedAt: time.Now().UTC(), Since: since}
+	if s.rules == nil {
+		return scan, nil
+	}
+	activity, err := s.store.ListAccountActivity(ctx, since.Add(-s.rules.Lookback()), since)
+	if err != nil {
+		return nil, err
+	}
+	n, cases := s.rules.Scan(activity, since)
+	scan.Transactions = n
+	if scan.Cases, err = s.store.OpenCases(ctx, cases...); err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
+// ListCases returns one page of monitoring cases matching the filter.
+func (s *service) ListCases(ctx context.Context, filter core.CaseFilter) ([]*core.Case, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = casePageDefault
+	case filter.Limit > casePageMax:
+		filter.Limit = casePageMax
+	}
+	return s.store.ListCases(ctx, filter)
+}
+
+func (s *service) GetCase(ctx context.Context, id int) (*core.Case, error) {
+	return s.store.GetCase(ctx, id)
+}
+
+// ResolveCase closes an open case as cleared, a false alarm, or
+// confirmed, suspicious activity. A note explaining the decision is
+// required.
+func (s *service) ResolveCase(ctx context.Context, id int, status string, note string) (*core.Case, error) {
+	note, err := resolution(status, note)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.ResolveCase(ctx, id, status, note)
+}
+
+// resolution checks how a case or screening hit is being resolved and
+// returns the trimmed note.
+func resolution(status string, note string) (string, error) {
+	var fields []core.FieldError
+	if !slices.Contains([]string{core.CaseCleared, core.CaseConfirmed}, status) {
+		fields = append(fields, core.FieldError{Field: "status", Message: "status must be cleared or confirmed"})
+	}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		fields = append(fields, core.FieldError{Field: "note", Message: "note is required"})
+	}
+	if len(fields) > 0 {
+		return "", core.InvalidFields(fields...)
+	}
+	return note, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// RegisterOAuthClient registers a third-party client owned by a user.
+// Confidential clients receive a secret, which is only returned here.
+func (s *service) RegisterOAuthClient(ctx context.Context, ownerUserID int, name string, redirectURIs []string, scopes []string, confidential bool) (*core.OAuthClient, string, error) {
+	buf := make([]byte, 40)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+
+	client := &core.OAuthClient{
+		ID:           "mbc_" + hex.EncodeToString(buf[:8]),
+		OwnerUserID:  ownerUserID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+	}
+	var secret string
+	if confidential {
+		secret = base64.RawURLEncoding.EncodeToString(buf[8:])
+		client.SecretHash = hashSecret(secret)
+	}
+
+	client, err := s.store.CreateOAuthClient(ctx, client)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+func (s *service) GetOAuthClient(ctx context.Context, id string) (*core.OAuthClient, error) {
+	return s.store.GetOAuthClient(ctx, id)
+}
+
+func (s *service) ListOAuthClients(ctx context.Context, ownerUserID int) ([]*core.OAuthClient, error) {
+	return s.store.ListOAuthClients(ctx, ownerUserID)
+}
+
+// AuthenticateOAuthClient verifies client credentials. Public clients
+// have no secret and must present none.
+func (s *service) AuthenticateOAuthClient(ctx context.Context, clientID string, secret string) (*core.OAuthClient, error) {
+	client, err := s.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOAuthClientNotFound) {
+			return nil, storage.ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if !client.Confidential() {
+		if secret != "" {
+			return nil, storage.ErrInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, storage.ErrInvalidClient
+	}
+	return client, nil
+}
+
+// GrantOAuthConsent adds scopes to the consent a user has given a client.
+func (s *service) GrantOAuthConsent(ctx context.Context, userID int, clientID string, scopes []string) (*core.OAuthConsent, error) {
+	existing, err := s.store.GetOAuthConsent(ctx, userID, clientID)
+	if err != nil && !errors.Is(err, storage.ErrConsentNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.RevokedAt == nil {
+		for _, scope := range existing.Scopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return s.store.SaveOAuthConsent(ctx, userID, clientID, scopes)
+}
+
+func (s *service) GetOAuthConsent(ctx context.Context, userID int, clientID string) (*core.OAuthConsent, error) {
+	return s.store.GetOAuthConsent(ctx, userID, clientID)
+}
+
+func (s *service) ListOAuthConsents(ctx context.Context, userID int) ([]*core.OAuthConsent, error) {
+	return s.store.ListOAuthConsents(ctx, userID)
+}
+
+func (s *service) RevokeOAuthConsent(ctx context.Context, userID int, clientID string) error {
+	return s.store.RevokeOAuthConsent(ctx, userID, clientID)
+}
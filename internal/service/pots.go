@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mini-bank/internal/core"
+)
+
+// maxPotNameLength caps pot names, in characters.
+const maxPotNameLength = 50
+
+// CreatePot opens an empty pot in an account.
+func (s *service) CreatePot(ctx context.Context, p *core.Pot) (*core.Pot, error) {
+	name, err := potName(p.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPotUpdate(core.PotUpdate{TargetAmount: p.TargetAmount, TargetDate: p.TargetDate, LockedUntil: p.LockedUntil}); err != nil {
+		return nil, err
+	}
+	return s.store.CreatePot(ctx, &core.Pot{
+		AccountID:    p.AccountID,
+		Name:         name,
+		TargetAmount: p.TargetAmount,
+		TargetDate:   p.TargetDate,
+		LockedUntil:  p.LockedUntil,
+		RoundUp:      p.RoundUp,
+	})
+}
+
+func (s *service) GetPot(ctx context.Context, id int) (*core.Pot, error) {
+	return s.store.GetPot(ctx, id)
+}
+
+func (s *service) ListPots(ctx context.Context, accountID int) ([]*core.Pot, error) {
+	return s.store.ListPots(ctx, accountID)
+}
+
+// UpdatePot renames a pot, sets its goal, locks it or makes it the
+// account's round-up pot. A locked pot's lock can only be extended.
+func (s *service) UpdatePot(ctx context.Context, id int, upd core.PotUpdate) (*core.Pot, error) {
+	if upd.Name != nil {
+		name, err := potName(*upd.Name)
+		if err != nil {
+			return nil, err
+		}
+		upd.Name = &name
+	}
+	if err := checkPotUpdate(upd); err != nil {
+		return nil, err
+	}
+	return s.store.UpdatePot(ctx, id, upd)
+}
+
+// DeletePot removes a pot once it is empty.
+func (s *service) DeletePot(ctx context.Context, id int) error {
+	return s.store.DeletePot(ctx, id)
+}
+
+// MovePot moves money between a pot and its account: into the pot if
+// amount is positive and back out if it is negative.
+func (s *service) MovePot(ctx context.Context, id int, amount int64, reference string) (*core.Pot, *core.Account, error) {
+	if amount == 0 {
+		return nil, nil, core.InvalidField("amount", "amount must not be zero")
+	}
+	return s.store.MovePot(ctx, id, amount, reference)
+}
+
+// potName trims a pot's name and checks it is given and not too long.
+func potName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxPotNameLength {
+		return "", core.InvalidField("name", fmt.Sprintf("name is required and at most %d characters", maxPotNameLength))
+	}
+	return name, nil
+}
+
+// checkPotUpdate checks a pot's goal and lock: the target amount must be
+// positive and the dates in the future.
+func checkPotUpdate(upd core.PotUpdate) error {
+	now := time.Now()
+	var fields []core.FieldError
+	if upd.TargetAmount != nil && *upd.TargetAmount <= 0 {
+		fields = append(fields, core.FieldError{Field: "target_amount", Message: "target_amount must be positive"})
+	}
+	if upd.TargetDate != nil && !upd.TargetDate.After(now) {
+		fields = append(fields, core.FieldError{Field: "target_date", Message: "target_date must be in the future"})
+	}
+	if upd.LockedUntil != nil && !upd.LockedUntil.After(now) {
+		fields = append(fields, core.FieldError{Field: "locked_until", Message: "locked_until must be in the future"})
+	}
+	if len(fields) > 0 {
+		return core.InvalidFields(fields...)
+	}
+	return nil
+}
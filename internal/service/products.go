@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// maxAccountNameLength caps account names, in characters.
+const maxAccountNameLength = 50
+
+// accountName trims a name for an account and checks its length.
+func accountName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxAccountNameLength {
+		return "", core.InvalidField("name", fmt.Sprintf("name must be at most %d characters", maxAccountNameLength))
+	}
+	return name, nil
+}
+
+// UpdateAccount renames an account if name is not nil and makes it its
+// owner's primary account if primary is set. Only current accounts can be
+// primary.
+func (s *service) UpdateAccount(ctx context.Context, id int, name *string, primary bool) (*core.Account, error) {
+	if name != nil {
+		n, err := accountName(*name)
+		if err != nil {
+			return nil, err
+		}
+		name = &n
+	}
+	if primary {
+		acc, err := s.store.GetAccount(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if acc.Product != core.ProductCurrent {
+			return nil, core.ErrProductRestricted.WithMessage("only current accounts can be primary")
+		}
+	}
+	return s.store.UpdateAccount(ctx, id, name, primary)
+}
+
+// GetUserBalances totals a user's account balances by product.
+func (s *service) GetUserBalances(ctx context.Context, userID int) (*core.UserBalances, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.store.UserBalances(ctx, userID)
+}
+
+// checkTransfer refuses a transfer the sender's product does not allow,
+// or to a fixed deposit account, which only OpenTermDeposit pays into. A
+// missing account passes, so the transfer itself reports it. Products
+// never change, so the check cannot go stale before the transfer runs.
+func (s *service) checkTransfer(ctx context.Context, fromID, toID int) error {
+	from, err := s.store.GetAccount(ctx, fromID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	to, err := s.store.GetAccount(ctx, toID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	return from.CheckTransferTo(to)
+}
+
+// checkPayment refuses a deposit or withdrawal the account's product does
+// not allow, passing a missing account as checkTransfer does.
+func (s *service) checkPayment(ctx context.Context, accountID int, pType storage.PaymentType) error {
+	acc, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	if pType == storage.Withdraw {
+		return acc.CheckWithdrawal()
+	}
+	return acc.CheckDeposit()
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/sanctions"
+	"mini-bank/internal/storage"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Page sizes for listing screening hits.
+const (
+	hitPageDefault = 100
+	hitPageMax     = 1000
+)
+
+func fullName(firstName, lastName string) string {
+	return strings.TrimSpace(firstName + " " + lastName)
+}
+
+// screenName screens a name against the watchlist. If a match blocks it,
+// screenName records the hits against the subject, which may be nil, and
+// returns ErrScreeningBlocked without saying why, so the customer is not
+// tipped off. Otherwise it returns the hits to record once the subject is
+// saved.
+func (s *service) screenName(ctx context.Context, subjectType string, subjectID *int, name string) ([]*core.ScreeningHit, error) {
+	matches, version := s.watchlist.Screen(name)
+	hits := screeningHits(subjectType, subjectID, name, version, matches)
+	if sanctions.Blocked(matches) {
+		if _, err := s.store.RecordScreeningHits(ctx, hits...); err != nil {
+			return nil, err
+		}
+		return nil, storage.ErrScreeningBlocked
+	}
+	return hits, nil
+}
+
+// recordReviewHits records the hits for a subject that has been saved.
+// The subject stands even if this fails, so the failure is only recorded
+// on the trace; the next rescreening records the hits instead.
+func (s *service) recordReviewHits(ctx context.Context, hits []*core.ScreeningHit, subjectID int) {
+	for _, h := range hits {
+		h.SubjectID = &subjectID
+	}
+	if _, err := s.store.RecordScreeningHits(ctx, hits...); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+func screeningHits(subjectType string, subjectID *int, name, version string, matches []sanctions.Match) []*core.ScreeningHit {
+	var hits []*core.ScreeningHit
+	for _, m := range matches {
+		hits = append(hits, &core.ScreeningHit{
+			SubjectType: subjectType,
+			SubjectID:   subjectID,
+			Name:        name,
+			EntryID:     m.Entry.ID,
+			EntryName:   m.Name,
+			Program:     m.Entry.Program,
+			Score:       m.Score,
+			Decision:    m.Decision,
+			ListVersion: version,
+		})
+	}
+	return hits
+}
+
+// RescreenUsers screens every user's name against the current watchlist
+// and records the hits not already recorded. Blocking hits freeze the
+// user's accounts.
+func (s *service) RescreenUsers(ctx context.Context) (*core.Rescreening, error) {
+	res := &core.Rescreening{ScreenedAt: time.Now().UTC()}
+	if s.watchlist == nil {
+		return res, nil
+	}
+	res.ListVersion = s.watchlist.Current().Version
+	users, err := s.store.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []*core.ScreeningHit
+	for _, u := range users {
+		matches, version := s.watchlist.Screen(fullName(u.FirstName, u.LastName))
+		hits = append(hits, screeningHits(core.ScreeningSubjectUser, &u.ID, fullName(u.FirstName, u.LastName), version, matches)...)
+	}
+	res.Users = len(users)
+	if res.Hits, err = s.store.RecordScreeningHits(ctx, hits...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListScreeningHits returns one page of screening hits matching the
+// filter.
+func (s *service) ListScreeningHits(ctx context.Context, filter core.ScreeningHitFilter) ([]*core.ScreeningHit, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = hitPageDefault
+	case filter.Limit > hitPageMax:
+		filter.Limit = hitPageMax
+	}
+	return s.store.ListScreeningHits(ctx, filter)
+}
+
+func (s *service) GetScreeningHit(ctx context.Context, id int) (*core.ScreeningHit, error) {
+	return s.store.GetScreeningHit(ctx, id)
+}
+
+// ResolveScreeningHit closes an open hit as cleared, a false match, or
+// confirmed, a listed party. A note explaining the decision is required.
+func (s *service) ResolveScreeningHit(ctx context.Context, id int, status string, note string) (*core.ScreeningHit, error) {
+	note, err := resolution(status, note)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.ResolveScreeningHit(ctx, id, status, note)
+}
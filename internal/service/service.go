@@ -3,66 +3,330 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"mini-bank/internal/audit"
+	"mini-bank/internal/blob"
 	"mini-bank/internal/core"
+	"mini-bank/internal/monitor"
+	"mini-bank/internal/sanctions"
 	"mini-bank/internal/storage"
+	"mini-bank/internal/webhook"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service interface {
-	CreateAccount(ctx context.Context, userID int, balance int64) (*core.Account, error)
+	CreateAccount(ctx context.Context, userID int, balance int64, product, name string) (*core.Account, error)
+	UpdateAccount(ctx context.Context, id int, name *string, primary bool) (*core.Account, error)
 	GetAccount(ctx context.Context, id int) (*core.Account, error)
+	GetAccountByNumber(ctx context.Context, number string) (*core.Account, error)
+	// AccountNumbers maps account ids to their account numbers.
+	AccountNumbers(ctx context.Context, ids []int) (map[int]string, error)
 	ListAccounts(ctx context.Context) ([]*core.Account, error)
 	Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error)
 	Payment(ctx context.Context, accountID int, amount int64, pType storage.PaymentType, reference string) (*core.Account, error)
 	ListTransactions(ctx context.Context, accountID int) ([]*core.Transaction, error)
+	ListAccountEvents(ctx context.Context, accountID int, afterSequence int64) ([]core.Event, error)
 	GetTransaction(ctx context.Context, reference string) (*core.Transaction, error)
 	CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error)
 	GetUsers(ctx context.Context) ([]*core.User, error)
 	GetUser(ctx context.Context, id int) (*core.User, error)
+	GetUserBalances(ctx context.Context, userID int) (*core.UserBalances, error)
 	UpdateUser(ctx context.Context, id int, firstName string, lastName string, email string) (*core.User, error)
 	DeleteUser(ctx context.Context, id int) error
 	Login(ctx context.Context, email string, password string) (*core.User, error)
+
+	CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, accountIDs []int) (*core.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID int) ([]*core.APIKey, error)
+	RotateAPIKey(ctx context.Context, userID int, id int) (*core.APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, userID int, id int) error
+	AuthenticateAPIKey(ctx context.Context, secret string) (*core.APIKey, error)
+
+	RegisterOAuthClient(ctx context.Context, ownerUserID int, name string, redirectURIs []string, scopes []string, confidential bool) (*core.OAuthClient, string, error)
+	GetOAuthClient(ctx context.Context, id string) (*core.OAuthClient, error)
+	ListOAuthClients(ctx context.Context, ownerUserID int) ([]*core.OAuthClient, error)
+	AuthenticateOAuthClient(ctx context.Context, clientID string, secret string) (*core.OAuthClient, error)
+	GrantOAuthConsent(ctx context.Context, userID int, clientID string, scopes []string) (*core.OAuthConsent, error)
+	GetOAuthConsent(ctx context.Context, userID int, clientID string) (*core.OAuthConsent, error)
+	ListOAuthConsents(ctx context.Context, userID int) ([]*core.OAuthConsent, error)
+	RevokeOAuthConsent(ctx context.Context, userID int, clientID string) error
+
+	CreateWebhook(ctx context.Context, userID int, url string, eventTypes []string, secret string, accountIDs []int) (*core.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context, userID int) ([]*core.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, userID int, id int) error
+	ListWebhookDeliveries(ctx context.Context, userID int, subscriptionID int) ([]*core.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, userID int, subscriptionID int, deliveryID int) (*core.WebhookDelivery, error)
+
+	FreezeAccount(ctx context.Context, id int) (*core.Account, error)
+	UnfreezeAccount(ctx context.Context, id int) (*core.Account, error)
+	AdjustBalance(ctx context.Context, accountID int, amount int64, reason string) (*core.Transaction, error)
+	ReverseTransaction(ctx context.Context, id int, reason string) ([]*core.Transaction, error)
+	Reconcile(ctx context.Context) (*core.Reconciliation, error)
+
+	ListAuditEntries(ctx context.Context, filter core.AuditFilter) ([]core.AuditEntry, error)
+	VerifyAudit(ctx context.Context, head string) (*core.AuditVerification, error)
+	HasRole(ctx context.Context, userID int, role string) (bool, error)
+	GrantRole(ctx context.Context, userID int, role string) ([]string, error)
+	RevokeRole(ctx context.Context, userID int, role string) ([]string, error)
+
+	GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error)
+	SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error)
+	SetProductLimits(ctx context.Context, product string, limits core.Limits) error
+
+	ScanActivity(ctx context.Context, since time.Time) (*core.MonitoringScan, error)
+	ListCases(ctx context.Context, filter core.CaseFilter) ([]*core.Case, error)
+	GetCase(ctx context.Context, id int) (*core.Case, error)
+	ResolveCase(ctx context.Context, id int, status string, note string) (*core.Case, error)
+
+	RescreenUsers(ctx context.Context) (*core.Rescreening, error)
+	ListScreeningHits(ctx context.Context, filter core.ScreeningHitFilter) ([]*core.ScreeningHit, error)
+	GetScreeningHit(ctx context.Context, id int) (*core.ScreeningHit, error)
+	ResolveScreeningHit(ctx context.Context, id int, status string, note string) (*core.ScreeningHit, error)
+
+	GetKYCStatus(ctx context.Context, userID int) (*core.KYCStatus, error)
+	SubmitKYC(ctx context.Context, userID int, sub core.KYCSubmission, uploads []core.KYCUpload) (*core.KYCSubmission, error)
+	ListKYCSubmissions(ctx context.Context, filter core.KYCFilter) ([]*core.KYCSubmission, error)
+	GetKYCSubmission(ctx context.Context, id int) (*core.KYCSubmission, error)
+	OpenKYCDocument(ctx context.Context, submissionID int, documentID int) (*core.KYCDocument, io.ReadCloser, error)
+	ReviewKYCSubmission(ctx context.Context, id int, status string, note string) (*core.KYCSubmission, error)
+	SetTierLimits(ctx context.Context, tier string, limits core.Limits) error
+
+	CheckPayee(ctx context.Context, accountID int, name string) (*core.PayeeCheck, error)
+	CreateBeneficiary(ctx context.Context, userID int, nickname string, accountID int, name string) (*core.Beneficiary, error)
+	ListBeneficiaries(ctx context.Context, userID int) ([]*core.Beneficiary, error)
+	DeleteBeneficiary(ctx context.Context, userID int, id int) error
+	BeneficiaryAccount(ctx context.Context, userID int, id int) (int, error)
+
+	CreatePot(ctx context.Context, p *core.Pot) (*core.Pot, error)
+	GetPot(ctx context.Context, id int) (*core.Pot, error)
+	ListPots(ctx context.Context, accountID int) ([]*core.Pot, error)
+	UpdatePot(ctx context.Context, id int, upd core.PotUpdate) (*core.Pot, error)
+	DeletePot(ctx context.Context, id int) error
+	MovePot(ctx context.Context, id int, amount int64, reference string) (*core.Pot, *core.Account, error)
+
+	TermDepositRates(ctx context.Context) ([]*core.TermDepositRate, error)
+	SetTermDepositRate(ctx context.Context, rate core.TermDepositRate) error
+	OpenTermDeposit(ctx context.Context, userID int, fromID int, amount int64, termMonths int, rollover bool, reference string) (*core.TermDeposit, error)
+	GetTermDeposit(ctx context.Context, userID int, id int) (*core.TermDeposit, error)
+	ListTermDeposits(ctx context.Context, userID int) ([]*core.TermDeposit, error)
+	SetTermDepositRollover(ctx context.Context, userID int, id int, rollover bool) (*core.TermDeposit, error)
+	BreakTermDeposit(ctx context.Context, userID int, id int, reference string) (*core.TermDeposit, error)
+	ProcessMaturities(ctx context.Context) (*core.MaturityRun, error)
+}
+
+// eventReplayLimit caps how many missed events a client can catch up on.
+const eventReplayLimit = 1000
+
+// Options configure the screening a Service does, where it keeps
+// documents and how it treats new beneficiaries. The zero value screens
+// nothing, accepts no documents and lets new beneficiaries receive any
+// amount.
+type Options struct {
+	// Rules screen transfers and payments.
+	Rules *monitor.Engine
+	// Watchlist screens the names of users.
+	Watchlist *sanctions.Watchlist
+	// Documents keeps KYC documents.
+	Documents blob.Store
+	// MaxDocumentSize caps each KYC document, in bytes.
+	MaxDocumentSize int64
+	// CoolingOff is how long after a payee is saved as a beneficiary or
+	// first paid transfers to it are capped at CoolingOffAmount. A payee
+	// that is neither is capped too. Zero turns the cap off.
+	CoolingOff       time.Duration
+	CoolingOffAmount int64
+	// WebhookTargets decides which addresses webhooks may be registered
+	// for.
+	WebhookTargets webhook.Targets
 }
 
 type service struct {
-	store storage.Storage
+	store            storage.Storage
+	rules            *monitor.Engine
+	watchlist        *sanctions.Watchlist
+	documents        blob.Store
+	maxDocumentSize  int64
+	coolingOff       time.Duration
+	coolingOffAmount int64
+	webhookTargets   webhook.Targets
 }
 
-func New(store storage.Storage) Service {
-	return &service{store: store}
+// New returns a Service backed by store.
+func New(store storage.Storage, opts Options) Service {
+	return &service{
+		store:            store,
+		rules:            opts.Rules,
+		watchlist:        opts.Watchlist,
+		documents:        opts.Documents,
+		maxDocumentSize:  opts.MaxDocumentSize,
+		coolingOff:       opts.CoolingOff,
+		coolingOffAmount: opts.CoolingOffAmount,
+		webhookTargets:   opts.WebhookTargets,
+	}
 }
 
-func (s *service) CreateAccount(ctx context.Context, userID int, balance int64) (*core.Account, error) {
-	return s.store.CreateAccount(ctx, userID, balance)
+// CreateAccount opens an account on a product, the default one if product
+// is empty.
+func (s *service) CreateAccount(ctx context.Context, userID int, balance int64, product, name string) (*core.Account, error) {
+	if product == "" {
+		product = core.DefaultProduct
+	}
+	if !core.ValidProduct(product) {
+		return nil, core.InvalidField("product", "product must be one of "+strings.Join(core.Products, ", "))
+	}
+	if product == core.ProductFixedDeposit {
+		return nil, core.InvalidField("product", "fixed deposit accounts are opened with a term deposit")
+	}
+	name, err := accountName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.CreateAccount(ctx, &core.Account{UserID: userID, Balance: balance, Product: product, Name: name})
 }
 
 func (s *service) GetAccount(ctx context.Context, id int) (*core.Account, error) {
 	return s.store.GetAccount(ctx, id)
 }
 
+func (s *service) GetAccountByNumber(ctx context.Context, number string) (*core.Account, error) {
+	if !core.ValidAccountNumber(number) {
+		return nil, storage.ErrAccountNotFound
+	}
+	return s.store.GetAccountByNumber(ctx, number)
+}
+
+func (s *service) AccountNumbers(ctx context.Context, ids []int) (map[int]string, error) {
+	return s.store.AccountNumbers(ctx, ids)
+}
+
 func (s *service) ListAccounts(ctx context.Context) ([]*core.Account, error) {
 	return s.store.ListAccounts(ctx)
 }
 
+// Transfer moves money between accounts once the sender's product has
+// allowed it and the monitoring rules have screened it on the sender's
+// side. The sender is held to its owner's KYC tier limits, and to the
+// cooling-off cap while the receiver is a new payee of the owner's.
 func (s *service) Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error) {
-	return s.store.Transfer(ctx, fromID, toID, amount, reference)
+	if err := s.checkTransfer(ctx, fromID, toID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkCoolingOff(ctx, fromID, toID, amount); err != nil {
+		return nil, nil, err
+	}
+	tierLimits, err := s.debitTierLimits(ctx, fromID)
+	if err != nil {
+		return nil, nil, err
+	}
+	cases, err := s.screen(ctx, fromID, core.TransactionTransfer, amount, reference)
+	if err != nil {
+		return nil, nil, err
+	}
+	from, to, err := s.store.Transfer(ctx, fromID, toID, amount, reference, tierLimits)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.openReviewCases(ctx, cases)
+	return from, to, nil
 }
 
+// Payment makes a deposit or withdrawal once the monitoring rules have
+// screened it. Only current accounts allow withdrawals, which are held to
+// the owner's KYC tier limits, and fixed deposits take no deposits.
 func (s *service) Payment(ctx context.Context, accountID int, amount int64, pType storage.PaymentType, reference string) (*core.Account, error) {
-	return s.store.Payment(ctx, accountID, amount, pType, reference)
+	if err := s.checkPayment(ctx, accountID, pType); err != nil {
+		return nil, err
+	}
+	var tierLimits core.Limits
+	if pType == storage.Withdraw {
+		var err error
+		if tierLimits, err = s.debitTierLimits(ctx, accountID); err != nil {
+			return nil, err
+		}
+	}
+	cases, err := s.screen(ctx, accountID, string(pType), amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := s.store.Payment(ctx, accountID, amount, pType, reference, tierLimits)
+	if err != nil {
+		return nil, err
+	}
+	s.openReviewCases(ctx, cases)
+	return acc, nil
 }
 
 func (s *service) ListTransactions(ctx context.Context, accountID int) ([]*core.Transaction, error) {
-	return s.store.ListTransactions(ctx, accountID)
+	txns, err := s.store.ListTransactions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setAccountNumbers(ctx, txns...); err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// ListAccountEvents returns the account's events after the given
+// sequence, for clients catching up on missed updates.
+func (s *service) ListAccountEvents(ctx context.Context, accountID int, afterSequence int64) ([]core.Event, error) {
+	return s.store.ListAccountEvents(ctx, accountID, afterSequence, eventReplayLimit)
 }
 
 func (s *service) GetTransaction(ctx context.Context, reference string) (*core.Transaction, error) {
-	return s.store.GetTransaction(ctx, reference)
+	txn, err := s.store.GetTransaction(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setAccountNumbers(ctx, txn); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// setAccountNumbers fills in the account numbers of the accounts the
+// transactions name.
+func (s *service) setAccountNumbers(ctx context.Context, txns ...*core.Transaction) error {
+	var ids []int
+	for _, t := range txns {
+		ids = append(ids, t.AccountID)
+		if t.FromAccountID != nil {
+			ids = append(ids, *t.FromAccountID)
+		}
+		if t.ToAccountID != nil {
+			ids = append(ids, *t.ToAccountID)
+		}
+	}
+	slices.Sort(ids)
+	numbers, err := s.store.AccountNumbers(ctx, slices.Compact(ids))
+	if err != nil {
+		return err
+	}
+	for _, t := range txns {
+		t.AccountNumber = numbers[t.AccountID]
+		if t.FromAccountID != nil {
+			t.FromAccountNumber = numbers[*t.FromAccountID]
+		}
+		if t.ToAccountID != nil {
+			t.ToAccountNumber = numbers[*t.ToAccountID]
+		}
+	}
+	return nil
 }
 
+// CreateUser signs up a user once their name has been screened against
+// the watchlist. A blocked name is refused; a name needing review is
+// signed up and the hits recorded.
 func (s *service) CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error) {
+	hits, err := s.screenName(ctx, core.ScreeningSubjectUser, nil, fullName(firstName, lastName))
+	if err != nil {
+		return nil, err
+	}
 	hashedPassword, err := hashPassword(password)
 	if err != nil {
 		return nil, err
@@ -71,9 +335,10 @@ func (s *service) CreateUser(ctx context.Context, firstName string, lastName str
 	if err != nil {
 		return nil, err
 	}
-	if _, err := s.store.CreateAccount(ctx, res.ID, 0); err != nil {
+	if _, err := s.store.CreateAccount(ctx, &core.Account{UserID: res.ID, Product: core.DefaultProduct}); err != nil {
 		return nil, err
 	}
+	s.recordReviewHits(ctx, hits, res.ID)
 	return res, nil
 }
 
@@ -85,31 +350,58 @@ func (s *service) GetUser(ctx context.Context, id int) (*core.User, error) {
 	return s.store.GetUser(ctx, id)
 }
 
+// UpdateUser screens the new name like CreateUser. A blocked name is not
+// saved, and the hits recorded against the user freeze their accounts.
 func (s *service) UpdateUser(ctx context.Context, id int, firstName string, lastName string, email string) (*core.User, error) {
-	return s.store.UpdateUser(ctx, id, firstName, lastName, email)
+	hits, err := s.screenName(ctx, core.ScreeningSubjectUser, &id, fullName(firstName, lastName))
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.store.UpdateUser(ctx, id, firstName, lastName, email)
+	if err != nil {
+		return nil, err
+	}
+	s.recordReviewHits(ctx, hits, id)
+	return u, nil
 }
 
 func (s *service) DeleteUser(ctx context.Context, id int) error {
 	return s.store.DeleteUser(ctx, id)
 }
 
+// Login checks a user's credentials. Every attempt, successful or not, is
+// recorded in the audit log.
 func (s *service) Login(ctx context.Context, email string, password string) (*core.User, error) {
 	user, err := s.store.GetUserByEmail(ctx, email)
 	if err != nil {
 		// If user not found, we return InvalidCredentials to avoid enumeration
 		if errors.Is(err, storage.ErrUserNotFound) {
-			return nil, storage.ErrInvalidCredentials
+			return nil, s.loginFailed(ctx, email, "")
 		}
 		return nil, err
 	}
 
 	if err := verifyPassword(*user.Password, password); err != nil {
-		return nil, storage.ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, email, strconv.Itoa(user.ID))
 	}
 
+	ctx = audit.WithActor(ctx, audit.UserActor(user.ID))
+	if err := s.store.AppendAudit(ctx, audit.New(ctx, core.AuditLogin, core.TargetUser, user.ID, nil, nil)); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
+// loginFailed audits a failed login and returns the error to report. The
+// target is empty when no user has the email.
+func (s *service) loginFailed(ctx context.Context, email string, userID string) error {
+	attempt := map[string]string{"email": email}
+	if err := s.store.AppendAudit(ctx, audit.New(ctx, core.AuditLoginFailed, core.TargetUser, userID, nil, attempt)); err != nil {
+		return err
+	}
+	return storage.ErrInvalidCredentials
+}
+
 func hashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
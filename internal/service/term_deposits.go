@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// TermDepositRates returns the terms deposits can be opened for and their
+// rates, shortest first.
+func (s *service) TermDepositRates(ctx context.Context) ([]*core.TermDepositRate, error) {
+	return s.store.TermDepositRates(ctx)
+}
+
+// SetTermDepositRate offers a term at new rates. The break rate may not
+// exceed the rate.
+func (s *service) SetTermDepositRate(ctx context.Context, rate core.TermDepositRate) error {
+	var fields []core.FieldError
+	if rate.TermMonths <= 0 {
+		fields = append(fields, core.FieldError{Field: "term_months", Message: "term_months must be positive"})
+	}
+	if rate.RateBPS < 0 {
+		fields = append(fields, core.FieldError{Field: "rate_bps", Message: "rate_bps must not be negative"})
+	}
+	if rate.BreakRateBPS < 0 || rate.BreakRateBPS > rate.RateBPS {
+		fields = append(fields, core.FieldError{Field: "break_rate_bps", Message: "break_rate_bps must be between 0 and rate_bps"})
+	}
+	if len(fields) > 0 {
+		return core.InvalidFields(fields...)
+	}
+	return s.store.SetTermDepositRate(ctx, rate)
+}
+
+// OpenTermDeposit locks amount from one of the user's accounts away for a
+// term, at the rates offered for it. The money moves like a transfer, so
+// the account's product, limits and the monitoring rules apply.
+func (s *service) OpenTermDeposit(ctx context.Context, userID int, fromID int, amount int64, termMonths int, rollover bool, reference string) (*core.TermDeposit, error) {
+	if amount <= 0 {
+		return nil, core.InvalidField("amount", "amount must be positive")
+	}
+	if err := s.checkTerm(ctx, termMonths); err != nil {
+		return nil, err
+	}
+	from, err := s.store.GetAccount(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	if from.UserID != userID {
+		return nil, storage.ErrAccountNotFound
+	}
+	if err := from.CheckPayTo(userID); err != nil {
+		return nil, err
+	}
+
+	_, tierLimits, err := s.tierLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := s.screen(ctx, fromID, core.TransactionTransfer, amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.store.OpenTermDeposit(ctx, &core.TermDeposit{
+		UserID:          userID,
+		PayoutAccountID: fromID,
+		Principal:       amount,
+		TermMonths:      termMonths,
+		Rollover:        rollover,
+	}, reference, tierLimits)
+	if err != nil {
+		return nil, err
+	}
+	s.openReviewCases(ctx, cases)
+	return d, s.setDepositAccountNumbers(ctx, d)
+}
+
+// checkTerm returns ErrTermNotOffered, naming the terms that are, unless
+// deposits can be opened for months.
+func (s *service) checkTerm(ctx context.Context, months int) error {
+	rates, err := s.store.TermDepositRates(ctx)
+	if err != nil {
+		return err
+	}
+	terms := make([]string, 0, len(rates))
+	for _, r := range rates {
+		if r.TermMonths == months {
+			return nil
+		}
+		terms = append(terms, strconv.Itoa(r.TermMonths))
+	}
+	if len(terms) == 0 {
+		return storage.ErrTermNotOffered.WithMessage("no term deposits are offered")
+	}
+	return storage.ErrTermNotOffered.WithMessage("term_months must be one of %s", strings.Join(terms, ", "))
+}
+
+func (s *service) GetTermDeposit(ctx context.Context, userID int, id int) (*core.TermDeposit, error) {
+	d, err := s.ownTermDeposit(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return d, s.setDepositAccountNumbers(ctx, d)
+}
+
+func (s *service) ListTermDeposits(ctx context.Context, userID int) ([]*core.TermDeposit, error) {
+	ds, err := s.store.ListTermDeposits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ds, s.setDepositAccountNumbers(ctx, ds...)
+}
+
+// SetTermDepositRollover decides whether one of the user's active
+// deposits starts another term when it matures.
+func (s *service) SetTermDepositRollover(ctx context.Context, userID int, id int, rollover bool) (*core.TermDeposit, error) {
+	if _, err := s.ownTermDeposit(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	d, err := s.store.SetTermDepositRollover(ctx, id, rollover)
+	if err != nil {
+		return nil, err
+	}
+	return d, s.setDepositAccountNumbers(ctx, d)
+}
+
+// BreakTermDeposit pays one of the user's deposits out before it matures.
+// The interest is worked out at the deposit's break rate, so breaking
+// costs the difference.
+func (s *service) BreakTermDeposit(ctx context.Context, userID int, id int, reference string) (*core.TermDeposit, error) {
+	if _, err := s.ownTermDeposit(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	d, err := s.store.BreakTermDeposit(ctx, id, reference)
+	if err != nil {
+		return nil, err
+	}
+	return d, s.setDepositAccountNumbers(ctx, d)
+}
+
+// ProcessMaturities matures every deposit due by now, each on its own so
+// that one that fails, such as one whose payout account is frozen, does
+// not hold up the rest; it is tried again on the next run.
+func (s *service) ProcessMaturities(ctx context.Context) (*core.MaturityRun, error) {
+	run := &core.MaturityRun{RanAt: time.Now().UTC()}
+	ids, err := s.store.DueTermDeposits(ctx, run.RanAt)
+	if err != nil {
+		return nil, err
+	}
+	run.Due = len(ids)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		d, err := s.store.MatureTermDeposit(ctx, id, uuid.NewString())
+		switch {
+		case errors.Is(err, storage.ErrTermDepositClosed):
+			// Another instance matured it first.
+		case err != nil:
+			run.Failures = append(run.Failures, core.MaturityFailure{TermDepositID: id, Err: err.Error()})
+		case d.Status == core.TermDepositActive:
+			run.RolledOver++
+		default:
+			run.PaidOut++
+		}
+	}
+	return run, nil
+}
+
+// setDepositAccountNumbers fills in the numbers of the deposits' accounts.
+func (s *service) setDepositAccountNumbers(ctx context.Context, ds ...*core.TermDeposit) error {
+	var ids []int
+	for _, d := range ds {
+		ids = append(ids, d.AccountID, d.PayoutAccountID)
+	}
+	slices.Sort(ids)
+	numbers, err := s.store.AccountNumbers(ctx, slices.Compact(ids))
+	if err != nil {
+		return err
+	}
+	for _, d := range ds {
+		d.AccountNumber = numbers[d.AccountID]
+		d.PayoutAccountNumber = numbers[d.PayoutAccountID]
+	}
+	return nil
+}
+
+// ownTermDeposit loads a term deposit and hides those of other users.
+func (s *service) ownTermDeposit(ctx context.Context, userID int, id int) (*core.TermDeposit, error) {
+	d, err := s.store.GetTermDeposit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.UserID != userID {
+		return nil, storage.ErrTermDepositNotFound
+	}
+	return d, nil
+}
@@ -0,0 +1,702 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "mini-bank/internal/service"
+
+// tracingService wraps a Service with a span per call. Attributes carry
+// IDs and amounts only; names, emails and secrets stay out of traces.
+type tracingService struct {
+	next   Service
+	tracer trace.Tracer
+}
+
+// WithTracing returns a Service that traces every call to next with the
+// global tracer provider.
+func WithTracing(next Service) Service {
+	return &tracingService{next: next, tracer: otel.Tracer(tracerName)}
+}
+
+func (t *tracingService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "service."+method, trace.WithAttributes(attrs...))
+}
+
+// end records err on span and ends it. Domain errors such as a missing
+// account are the caller's problem, so only other failures mark the span
+// as failed.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		var e *core.Error
+		if !errors.As(err, &e) || e.Kind == core.KindInternal {
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.String("error.code", e.Code))
+		}
+	}
+	span.End()
+}
+
+func (t *tracingService) CreateAccount(ctx context.Context, userID int, balance int64, product, name string) (*core.Account, error) {
+	ctx, span := t.start(ctx, "CreateAccount", attribute.Int("user.id", userID), attribute.String("account.product", product))
+	acc, err := t.next.CreateAccount(ctx, userID, balance, product, name)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) UpdateAccount(ctx context.Context, id int, name *string, primary bool) (*core.Account, error) {
+	ctx, span := t.start(ctx, "UpdateAccount", attribute.Int("account.id", id))
+	acc, err := t.next.UpdateAccount(ctx, id, name, primary)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) GetAccount(ctx context.Context, id int) (*core.Account, error) {
+	ctx, span := t.start(ctx, "GetAccount", attribute.Int("account.id", id))
+	acc, err := t.next.GetAccount(ctx, id)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) GetAccountByNumber(ctx context.Context, number string) (*core.Account, error) {
+	ctx, span := t.start(ctx, "GetAccountByNumber")
+	acc, err := t.next.GetAccountByNumber(ctx, number)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) AccountNumbers(ctx context.Context, ids []int) (map[int]string, error) {
+	ctx, span := t.start(ctx, "AccountNumbers", attribute.Int("account.count", len(ids)))
+	numbers, err := t.next.AccountNumbers(ctx, ids)
+	end(span, err)
+	return numbers, err
+}
+
+func (t *tracingService) ListAccounts(ctx context.Context) ([]*core.Account, error) {
+	ctx, span := t.start(ctx, "ListAccounts")
+	accs, err := t.next.ListAccounts(ctx)
+	end(span, err)
+	return accs, err
+}
+
+func (t *tracingService) Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error) {
+	ctx, span := t.start(ctx, "Transfer",
+		attribute.Int("account.from_id", fromID),
+		attribute.Int("account.to_id", toID),
+		attribute.Int64("amount", amount),
+	)
+	from, to, err := t.next.Transfer(ctx, fromID, toID, amount, reference)
+	end(span, err)
+	return from, to, err
+}
+
+func (t *tracingService) Payment(ctx context.Context, accountID int, amount int64, pType storage.PaymentType, reference string) (*core.Account, error) {
+	ctx, span := t.start(ctx, "Payment",
+		attribute.Int("account.id", accountID),
+		attribute.Int64("amount", amount),
+		attribute.String("payment.type", string(pType)),
+	)
+	acc, err := t.next.Payment(ctx, accountID, amount, pType, reference)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) ListTransactions(ctx context.Context, accountID int) ([]*core.Transaction, error) {
+	ctx, span := t.start(ctx, "ListTransactions", attribute.Int("account.id", accountID))
+	txs, err := t.next.ListTransactions(ctx, accountID)
+	end(span, err)
+	return txs, err
+}
+
+func (t *tracingService) ListAccountEvents(ctx context.Context, accountID int, afterSequence int64) ([]core.Event, error) {
+	ctx, span := t.start(ctx, "ListAccountEvents",
+		attribute.Int("account.id", accountID),
+		attribute.Int64("event.after_sequence", afterSequence),
+	)
+	events, err := t.next.ListAccountEvents(ctx, accountID, afterSequence)
+	end(span, err)
+	return events, err
+}
+
+func (t *tracingService) GetTransaction(ctx context.Context, reference string) (*core.Transaction, error) {
+	ctx, span := t.start(ctx, "GetTransaction")
+	tx, err := t.next.GetTransaction(ctx, reference)
+	end(span, err)
+	return tx, err
+}
+
+func (t *tracingService) CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error) {
+	ctx, span := t.start(ctx, "CreateUser")
+	user, err := t.next.CreateUser(ctx, firstName, lastName, email, password)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracingService) GetUsers(ctx context.Context) ([]*core.User, error) {
+	ctx, span := t.start(ctx, "GetUsers")
+	users, err := t.next.GetUsers(ctx)
+	end(span, err)
+	return users, err
+}
+
+func (t *tracingService) GetUser(ctx context.Context, id int) (*core.User, error) {
+	ctx, span := t.start(ctx, "GetUser", attribute.Int("user.id", id))
+	user, err := t.next.GetUser(ctx, id)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracingService) GetUserBalances(ctx context.Context, userID int) (*core.UserBalances, error) {
+	ctx, span := t.start(ctx, "GetUserBalances", attribute.Int("user.id", userID))
+	b, err := t.next.GetUserBalances(ctx, userID)
+	end(span, err)
+	return b, err
+}
+
+func (t *tracingService) UpdateUser(ctx context.Context, id int, firstName string, lastName string, email string) (*core.User, error) {
+	ctx, span := t.start(ctx, "UpdateUser", attribute.Int("user.id", id))
+	user, err := t.next.UpdateUser(ctx, id, firstName, lastName, email)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracingService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := t.start(ctx, "DeleteUser", attribute.Int("user.id", id))
+	err := t.next.DeleteUser(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) Login(ctx context.Context, email string, password string) (*core.User, error) {
+	ctx, span := t.start(ctx, "Login")
+	user, err := t.next.Login(ctx, email, password)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracingService) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, accountIDs []int) (*core.APIKey, string, error) {
+	ctx, span := t.start(ctx, "CreateAPIKey", attribute.Int("user.id", userID))
+	key, secret, err := t.next.CreateAPIKey(ctx, userID, name, scopes, accountIDs)
+	end(span, err)
+	return key, secret, err
+}
+
+func (t *tracingService) ListAPIKeys(ctx context.Context, userID int) ([]*core.APIKey, error) {
+	ctx, span := t.start(ctx, "ListAPIKeys", attribute.Int("user.id", userID))
+	keys, err := t.next.ListAPIKeys(ctx, userID)
+	end(span, err)
+	return keys, err
+}
+
+func (t *tracingService) RotateAPIKey(ctx context.Context, userID int, id int) (*core.APIKey, string, error) {
+	ctx, span := t.start(ctx, "RotateAPIKey", attribute.Int("user.id", userID), attribute.Int("api_key.id", id))
+	key, secret, err := t.next.RotateAPIKey(ctx, userID, id)
+	end(span, err)
+	return key, secret, err
+}
+
+func (t *tracingService) RevokeAPIKey(ctx context.Context, userID int, id int) error {
+	ctx, span := t.start(ctx, "RevokeAPIKey", attribute.Int("user.id", userID), attribute.Int("api_key.id", id))
+	err := t.next.RevokeAPIKey(ctx, userID, id)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) AuthenticateAPIKey(ctx context.Context, secret string) (*core.APIKey, error) {
+	ctx, span := t.start(ctx, "AuthenticateAPIKey")
+	key, err := t.next.AuthenticateAPIKey(ctx, secret)
+	end(span, err)
+	return key, err
+}
+
+func (t *tracingService) RegisterOAuthClient(ctx context.Context, ownerUserID int, name string, redirectURIs []string, scopes []string, confidential bool) (*core.OAuthClient, string, error) {
+	ctx, span := t.start(ctx, "RegisterOAuthClient", attribute.Int("user.id", ownerUserID))
+	client, secret, err := t.next.RegisterOAuthClient(ctx, ownerUserID, name, redirectURIs, scopes, confidential)
+	end(span, err)
+	return client, secret, err
+}
+
+func (t *tracingService) GetOAuthClient(ctx context.Context, id string) (*core.OAuthClient, error) {
+	ctx, span := t.start(ctx, "GetOAuthClient", attribute.String("oauth.client_id", id))
+	client, err := t.next.GetOAuthClient(ctx, id)
+	end(span, err)
+	return client, err
+}
+
+func (t *tracingService) ListOAuthClients(ctx context.Context, ownerUserID int) ([]*core.OAuthClient, error) {
+	ctx, span := t.start(ctx, "ListOAuthClients", attribute.Int("user.id", ownerUserID))
+	clients, err := t.next.ListOAuthClients(ctx, ownerUserID)
+	end(span, err)
+	return clients, err
+}
+
+func (t *tracingService) AuthenticateOAuthClient(ctx context.Context, clientID string, secret string) (*core.OAuthClient, error) {
+	ctx, span := t.start(ctx, "AuthenticateOAuthClient", attribute.String("oauth.client_id", clientID))
+	client, err := t.next.AuthenticateOAuthClient(ctx, clientID, secret)
+	end(span, err)
+	return client, err
+}
+
+func (t *tracingService) GrantOAuthConsent(ctx context.Context, userID int, clientID string, scopes []string) (*core.OAuthConsent, error) {
+	ctx, span := t.start(ctx, "GrantOAuthConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	consent, err := t.next.GrantOAuthConsent(ctx, userID, clientID, scopes)
+	end(span, err)
+	return consent, err
+}
+
+func (t *tracingService) GetOAuthConsent(ctx context.Context, userID int, clientID string) (*core.OAuthConsent, error) {
+	ctx, span := t.start(ctx, "GetOAuthConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	consent, err := t.next.GetOAuthConsent(ctx, userID, clientID)
+	end(span, err)
+	return consent, err
+}
+
+func (t *tracingService) ListOAuthConsents(ctx context.Context, userID int) ([]*core.OAuthConsent, error) {
+	ctx, span := t.start(ctx, "ListOAuthConsents", attribute.Int("user.id", userID))
+	consents, err := t.next.ListOAuthConsents(ctx, userID)
+	end(span, err)
+	return consents, err
+}
+
+func (t *tracingService) RevokeOAuthConsent(ctx context.Context, userID int, clientID string) error {
+	ctx, span := t.start(ctx, "RevokeOAuthConsent", attribute.Int("user.id", userID), attribute.String("oauth.client_id", clientID))
+	err := t.next.RevokeOAuthConsent(ctx, userID, clientID)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) CreateWebhook(ctx context.Context, userID int, url string, eventTypes []string, secret string, accountIDs []int) (*core.WebhookSubscription, error) {
+	ctx, span := t.start(ctx, "CreateWebhook", attribute.Int("user.id", userID))
+	sub, err := t.next.CreateWebhook(ctx, userID, url, eventTypes, secret, accountIDs)
+	end(span, err)
+	return sub, err
+}
+
+func (t *tracingService) ListWebhooks(ctx context.Context, userID int) ([]*core.WebhookSubscription, error) {
+	ctx, span := t.start(ctx, "ListWebhooks", attribute.Int("user.id", userID))
+	subs, err := t.next.ListWebhooks(ctx, userID)
+	end(span, err)
+	return subs, err
+}
+
+func (t *tracingService) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	ctx, span := t.start(ctx, "DeleteWebhook", attribute.Int("user.id", userID), attribute.Int("webhook.id", id))
+	err := t.next.DeleteWebhook(ctx, userID, id)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) ListWebhookDeliveries(ctx context.Context, userID int, subscriptionID int) ([]*core.WebhookDelivery, error) {
+	ctx, span := t.start(ctx, "ListWebhookDeliveries", attribute.Int("user.id", userID), attribute.Int("webhook.id", subscriptionID))
+	deliveries, err := t.next.ListWebhookDeliveries(ctx, userID, subscriptionID)
+	end(span, err)
+	return deliveries, err
+}
+
+func (t *tracingService) ReplayWebhookDelivery(ctx context.Context, userID int, subscriptionID int, deliveryID int) (*core.WebhookDelivery, error) {
+	ctx, span := t.start(ctx, "ReplayWebhookDelivery",
+		attribute.Int("user.id", userID),
+		attribute.Int("webhook.id", subscriptionID),
+		attribute.Int("webhook.delivery_id", deliveryID),
+	)
+	delivery, err := t.next.ReplayWebhookDelivery(ctx, userID, subscriptionID, deliveryID)
+	end(span, err)
+	return delivery, err
+}
+
+func (t *tracingService) FreezeAccount(ctx context.Context, id int) (*core.Account, error) {
+	ctx, span := t.start(ctx, "FreezeAccount", attribute.Int("account.id", id))
+	acc, err := t.next.FreezeAccount(ctx, id)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) UnfreezeAccount(ctx context.Context, id int) (*core.Account, error) {
+	ctx, span := t.start(ctx, "UnfreezeAccount", attribute.Int("account.id", id))
+	acc, err := t.next.UnfreezeAccount(ctx, id)
+	end(span, err)
+	return acc, err
+}
+
+func (t *tracingService) AdjustBalance(ctx context.Context, accountID int, amount int64, reason string) (*core.Transaction, error) {
+	ctx, span := t.start(ctx, "AdjustBalance", attribute.Int("account.id", accountID), attribute.Int64("amount", amount))
+	txn, err := t.next.AdjustBalance(ctx, accountID, amount, reason)
+	end(span, err)
+	return txn, err
+}
+
+func (t *tracingService) ReverseTransaction(ctx context.Context, id int, reason string) ([]*core.Transaction, error) {
+	ctx, span := t.start(ctx, "ReverseTransaction", attribute.Int("transaction.id", id))
+	txns, err := t.next.ReverseTransaction(ctx, id, reason)
+	end(span, err)
+	return txns, err
+}
+
+func (t *tracingService) Reconcile(ctx context.Context) (*core.Reconciliation, error) {
+	ctx, span := t.start(ctx, "Reconcile")
+	rec, err := t.next.Reconcile(ctx)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Int("ledger.accounts", rec.Accounts),
+			attribute.Int("ledger.mismatches", len(rec.Mismatches)),
+			attribute.Int("ledger.imbalanced_transfers", len(rec.ImbalancedTransfers)),
+		)
+	}
+	end(span, err)
+	return rec, err
+}
+
+func (t *tracingService) ListAuditEntries(ctx context.Context, filter core.AuditFilter) ([]core.AuditEntry, error) {
+	ctx, span := t.start(ctx, "ListAuditEntries")
+	entries, err := t.next.ListAuditEntries(ctx, filter)
+	end(span, err)
+	return entries, err
+}
+
+func (t *tracingService) VerifyAudit(ctx context.Context, head string) (*core.AuditVerification, error) {
+	ctx, span := t.start(ctx, "VerifyAudit")
+	res, err := t.next.VerifyAudit(ctx, head)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Int64("audit.entries", res.Entries),
+			attribute.Int("audit.breaks", len(res.Breaks)),
+		)
+	}
+	end(span, err)
+	return res, err
+}
+
+func (t *tracingService) HasRole(ctx context.Context, userID int, role string) (bool, error) {
+	ctx, span := t.start(ctx, "HasRole", attribute.Int("user.id", userID), attribute.String("user.role", role))
+	ok, err := t.next.HasRole(ctx, userID, role)
+	end(span, err)
+	return ok, err
+}
+
+func (t *tracingService) GrantRole(ctx context.Context, userID int, role string) ([]string, error) {
+	ctx, span := t.start(ctx, "GrantRole", attribute.Int("user.id", userID), attribute.String("user.role", role))
+	roles, err := t.next.GrantRole(ctx, userID, role)
+	end(span, err)
+	return roles, err
+}
+
+func (t *tracingService) RevokeRole(ctx context.Context, userID int, role string) ([]string, error) {
+	ctx, span := t.start(ctx, "RevokeRole", attribute.Int("user.id", userID), attribute.String("user.role", role))
+	roles, err := t.next.RevokeRole(ctx, userID, role)
+	end(span, err)
+	return roles, err
+}
+
+func (t *tracingService) GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error) {
+	ctx, span := t.start(ctx, "GetAccountLimits", attribute.Int("account.id", accountID))
+	lim, err := t.next.GetAccountLimits(ctx, accountID)
+	end(span, err)
+	return lim, err
+}
+
+func (t *tracingService) SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error) {
+	ctx, span := t.start(ctx, "SetAccountLimits", attribute.Int("account.id", accountID))
+	lim, err := t.next.SetAccountLimits(ctx, accountID, limits)
+	end(span, err)
+	return lim, err
+}
+
+func (t *tracingService) SetProductLimits(ctx context.Context, product string, limits core.Limits) error {
+	ctx, span := t.start(ctx, "SetProductLimits", attribute.String("account.product", product))
+	err := t.next.SetProductLimits(ctx, product, limits)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) ScanActivity(ctx context.Context, since time.Time) (*core.MonitoringScan, error) {
+	ctx, span := t.start(ctx, "ScanActivity")
+	scan, err := t.next.ScanActivity(ctx, since)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Int("monitoring.transactions", scan.Transactions),
+			attribute.Int("monitoring.cases", len(scan.Cases)),
+		)
+	}
+	end(span, err)
+	return scan, err
+}
+
+func (t *tracingService) ListCases(ctx context.Context, filter core.CaseFilter) ([]*core.Case, error) {
+	ctx, span := t.start(ctx, "ListCases")
+	cases, err := t.next.ListCases(ctx, filter)
+	end(span, err)
+	return cases, err
+}
+
+func (t *tracingService) GetCase(ctx context.Context, id int) (*core.Case, error) {
+	ctx, span := t.start(ctx, "GetCase", attribute.Int("case.id", id))
+	c, err := t.next.GetCase(ctx, id)
+	end(span, err)
+	return c, err
+}
+
+func (t *tracingService) ResolveCase(ctx context.Context, id int, status string, note string) (*core.Case, error) {
+	ctx, span := t.start(ctx, "ResolveCase", attribute.Int("case.id", id), attribute.String("case.status", status))
+	c, err := t.next.ResolveCase(ctx, id, status, note)
+	end(span, err)
+	return c, err
+}
+
+func (t *tracingService) RescreenUsers(ctx context.Context) (*core.Rescreening, error) {
+	ctx, span := t.start(ctx, "RescreenUsers")
+	res, err := t.next.RescreenUsers(ctx)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("screening.list_version", res.ListVersion),
+			attribute.Int("screening.users", res.Users),
+			attribute.Int("screening.hits", len(res.Hits)),
+		)
+	}
+	end(span, err)
+	return res, err
+}
+
+func (t *tracingService) ListScreeningHits(ctx context.Context, filter core.ScreeningHitFilter) ([]*core.ScreeningHit, error) {
+	ctx, span := t.start(ctx, "ListScreeningHits")
+	hits, err := t.next.ListScreeningHits(ctx, filter)
+	end(span, err)
+	return hits, err
+}
+
+func (t *tracingService) GetScreeningHit(ctx context.Context, id int) (*core.ScreeningHit, error) {
+	ctx, span := t.start(ctx, "GetScreeningHit", attribute.Int("screening_hit.id", id))
+	h, err := t.next.GetScreeningHit(ctx, id)
+	end(span, err)
+	return h, err
+}
+
+func (t *tracingService) ResolveScreeningHit(ctx context.Context, id int, status string, note string) (*core.ScreeningHit, error) {
+	ctx, span := t.start(ctx, "ResolveScreeningHit", attribute.Int("screening_hit.id", id), attribute.String("screening_hit.status", status))
+	h, err := t.next.ResolveScreeningHit(ctx, id, status, note)
+	end(span, err)
+	return h, err
+}
+
+func (t *tracingService) GetKYCStatus(ctx context.Context, userID int) (*core.KYCStatus, error) {
+	ctx, span := t.start(ctx, "GetKYCStatus", attribute.Int("user.id", userID))
+	status, err := t.next.GetKYCStatus(ctx, userID)
+	end(span, err)
+	return status, err
+}
+
+func (t *tracingService) SubmitKYC(ctx context.Context, userID int, sub core.KYCSubmission, uploads []core.KYCUpload) (*core.KYCSubmission, error) {
+	ctx, span := t.start(ctx, "SubmitKYC", attribute.Int("user.id", userID), attribute.String("kyc.tier", sub.Tier),
+		attribute.Int("kyc.documents", len(uploads)))
+	created, err := t.next.SubmitKYC(ctx, userID, sub, uploads)
+	end(span, err)
+	return created, err
+}
+
+func (t *tracingService) ListKYCSubmissions(ctx context.Context, filter core.KYCFilter) ([]*core.KYCSubmission, error) {
+	ctx, span := t.start(ctx, "ListKYCSubmissions")
+	subs, err := t.next.ListKYCSubmissions(ctx, filter)
+	end(span, err)
+	return subs, err
+}
+
+func (t *tracingService) GetKYCSubmission(ctx context.Context, id int) (*core.KYCSubmission, error) {
+	ctx, span := t.start(ctx, "GetKYCSubmission", attribute.Int("kyc.submission_id", id))
+	sub, err := t.next.GetKYCSubmission(ctx, id)
+	end(span, err)
+	return sub, err
+}
+
+func (t *tracingService) OpenKYCDocument(ctx context.Context, submissionID int, documentID int) (*core.KYCDocument, io.ReadCloser, error) {
+	ctx, span := t.start(ctx, "OpenKYCDocument", attribute.Int("kyc.submission_id", submissionID), attribute.Int("kyc.document_id", documentID))
+	doc, body, err := t.next.OpenKYCDocument(ctx, submissionID, documentID)
+	end(span, err)
+	return doc, body, err
+}
+
+func (t *tracingService) ReviewKYCSubmission(ctx context.Context, id int, status string, note string) (*core.KYCSubmission, error) {
+	ctx, span := t.start(ctx, "ReviewKYCSubmission", attribute.Int("kyc.submission_id", id), attribute.String("kyc.status", status))
+	sub, err := t.next.ReviewKYCSubmission(ctx, id, status, note)
+	end(span, err)
+	return sub, err
+}
+
+func (t *tracingService) SetTierLimits(ctx context.Context, tier string, limits core.Limits) error {
+	ctx, span := t.start(ctx, "SetTierLimits", attribute.String("kyc.tier", tier))
+	err := t.next.SetTierLimits(ctx, tier, limits)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) CheckPayee(ctx context.Context, accountID int, name string) (*core.PayeeCheck, error) {
+	ctx, span := t.start(ctx, "CheckPayee", attribute.Int("account.id", accountID))
+	check, err := t.next.CheckPayee(ctx, accountID, name)
+	if check != nil {
+		span.SetAttributes(attribute.String("payee.result", check.Result))
+	}
+	end(span, err)
+	return check, err
+}
+
+func (t *tracingService) CreateBeneficiary(ctx context.Context, userID int, nickname string, accountID int, name string) (*core.Beneficiary, error) {
+	ctx, span := t.start(ctx, "CreateBeneficiary", attribute.Int("user.id", userID), attribute.Int("account.id", accountID))
+	b, err := t.next.CreateBeneficiary(ctx, userID, nickname, accountID, name)
+	if b != nil {
+		span.SetAttributes(attribute.Int("beneficiary.id", b.ID))
+	}
+	end(span, err)
+	return b, err
+}
+
+func (t *tracingService) ListBeneficiaries(ctx context.Context, userID int) ([]*core.Beneficiary, error) {
+	ctx, span := t.start(ctx, "ListBeneficiaries", attribute.Int("user.id", userID))
+	bs, err := t.next.ListBeneficiaries(ctx, userID)
+	end(span, err)
+	return bs, err
+}
+
+func (t *tracingService) DeleteBeneficiary(ctx context.Context, userID int, id int) error {
+	ctx, span := t.start(ctx, "DeleteBeneficiary", attribute.Int("user.id", userID), attribute.Int("beneficiary.id", id))
+	err := t.next.DeleteBeneficiary(ctx, userID, id)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) BeneficiaryAccount(ctx context.Context, userID int, id int) (int, error) {
+	ctx, span := t.start(ctx, "BeneficiaryAccount", attribute.Int("user.id", userID), attribute.Int("beneficiary.id", id))
+	accountID, err := t.next.BeneficiaryAccount(ctx, userID, id)
+	end(span, err)
+	return accountID, err
+}
+
+func (t *tracingService) CreatePot(ctx context.Context, p *core.Pot) (*core.Pot, error) {
+	ctx, span := t.start(ctx, "CreatePot", attribute.Int("account.id", p.AccountID))
+	created, err := t.next.CreatePot(ctx, p)
+	if created != nil {
+		span.SetAttributes(attribute.Int("pot.id", created.ID))
+	}
+	end(span, err)
+	return created, err
+}
+
+func (t *tracingService) GetPot(ctx context.Context, id int) (*core.Pot, error) {
+	ctx, span := t.start(ctx, "GetPot", attribute.Int("pot.id", id))
+	p, err := t.next.GetPot(ctx, id)
+	end(span, err)
+	return p, err
+}
+
+func (t *tracingService) ListPots(ctx context.Context, accountID int) ([]*core.Pot, error) {
+	ctx, span := t.start(ctx, "ListPots", attribute.Int("account.id", accountID))
+	ps, err := t.next.ListPots(ctx, accountID)
+	end(span, err)
+	return ps, err
+}
+
+func (t *tracingService) UpdatePot(ctx context.Context, id int, upd core.PotUpdate) (*core.Pot, error) {
+	ctx, span := t.start(ctx, "UpdatePot", attribute.Int("pot.id", id))
+	p, err := t.next.UpdatePot(ctx, id, upd)
+	end(span, err)
+	return p, err
+}
+
+func (t *tracingService) DeletePot(ctx context.Context, id int) error {
+	ctx, span := t.start(ctx, "DeletePot", attribute.Int("pot.id", id))
+	err := t.next.DeletePot(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) MovePot(ctx context.Context, id int, amount int64, reference string) (*core.Pot, *core.Account, error) {
+	ctx, span := t.start(ctx, "MovePot",
+		attribute.Int("pot.id", id),
+		attribute.Int64("amount", amount),
+	)
+	p, acc, err := t.next.MovePot(ctx, id, amount, reference)
+	end(span, err)
+	return p, acc, err
+}
+
+func (t *tracingService) TermDepositRates(ctx context.Context) ([]*core.TermDepositRate, error) {
+	ctx, span := t.start(ctx, "TermDepositRates")
+	rates, err := t.next.TermDepositRates(ctx)
+	end(span, err)
+	return rates, err
+}
+
+func (t *tracingService) SetTermDepositRate(ctx context.Context, rate core.TermDepositRate) error {
+	ctx, span := t.start(ctx, "SetTermDepositRate", attribute.Int("term_deposit.term_months", rate.TermMonths))
+	err := t.next.SetTermDepositRate(ctx, rate)
+	end(span, err)
+	return err
+}
+
+func (t *tracingService) OpenTermDeposit(ctx context.Context, userID int, fromID int, amount int64, termMonths int, rollover bool, reference string) (*core.TermDeposit, error) {
+	ctx, span := t.start(ctx, "OpenTermDeposit",
+		attribute.Int("user.id", userID),
+		attribute.Int("account.from_id", fromID),
+		attribute.Int64("amount", amount),
+		attribute.Int("term_deposit.term_months", termMonths),
+	)
+	d, err := t.next.OpenTermDeposit(ctx, userID, fromID, amount, termMonths, rollover, reference)
+	if err == nil {
+		span.SetAttributes(attribute.Int("term_deposit.id", d.ID))
+	}
+	end(span, err)
+	return d, err
+}
+
+func (t *tracingService) GetTermDeposit(ctx context.Context, userID int, id int) (*core.TermDeposit, error) {
+	ctx, span := t.start(ctx, "GetTermDeposit", attribute.Int("user.id", userID), attribute.Int("term_deposit.id", id))
+	d, err := t.next.GetTermDeposit(ctx, userID, id)
+	end(span, err)
+	return d, err
+}
+
+func (t *tracingService) ListTermDeposits(ctx context.Context, userID int) ([]*core.TermDeposit, error) {
+	ctx, span := t.start(ctx, "ListTermDeposits", attribute.Int("user.id", userID))
+	ds, err := t.next.ListTermDeposits(ctx, userID)
+	end(span, err)
+	return ds, err
+}
+
+func (t *tracingService) SetTermDepositRollover(ctx context.Context, userID int, id int, rollover bool) (*core.TermDeposit, error) {
+	ctx, span := t.start(ctx, "SetTermDepositRollover", attribute.Int("user.id", userID), attribute.Int("term_deposit.id", id))
+	d, err := t.next.SetTermDepositRollover(ctx, userID, id, rollover)
+	end(span, err)
+	return d, err
+}
+
+func (t *tracingService) BreakTermDeposit(ctx context.Context, userID int, id int, reference string) (*core.TermDeposit, error) {
+	ctx, span := t.start(ctx, "BreakTermDeposit", attribute.Int("user.id", userID), attribute.Int("term_deposit.id", id))
+	d, err := t.next.BreakTermDeposit(ctx, userID, id, reference)
+	end(span, err)
+	return d, err
+}
+
+func (t *tracingService) ProcessMaturities(ctx context.Context) (*core.MaturityRun, error) {
+	ctx, span := t.start(ctx, "ProcessMaturities")
+	run, err := t.next.ProcessMaturities(ctx)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Int("term_deposit.due", run.Due),
+			attribute.Int("term_deposit.paid_out", run.PaidOut),
+			attribute.Int("term_deposit.rolled_over", run.RolledOver),
+			attribute.Int("term_deposit.failed", len(run.Failures)),
+		)
+	}
+	end(span, err)
+	return run, err
+}
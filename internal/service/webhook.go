@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+	"mini-bank/internal/webhook"
+)
+
+// deliveryLogLimit caps how many deliveries are returned from the log.
+const deliveryLogLimit = 100
+
+// CreateWebhook subscribes a URL to events on the user's accounts, or
+// only on accountIDs if any are given. A signing secret is generated when
+// none is given. URLs whose host does not resolve to addresses deliveries
+// may be sent to are refused.
+func (s *service) CreateWebhook(ctx context.Context, userID int, url string, eventTypes []string, secret string, accountIDs []int) (*core.WebhookSubscription, error) {
+	if err := s.webhookTargets.CheckURL(ctx, url); err != nil {
+		if errors.Is(err, webhook.ErrForbiddenTarget) {
+			return nil, core.InvalidField("url", "url must point to a public address")
+		}
+		return nil, core.InvalidField("url", "url host could not be resolved")
+	}
+	if secret == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = "whsec_" + base64.RawURLEncoding.EncodeToString(buf)
+	}
+	return s.store.CreateWebhook(ctx, &core.WebhookSubscription{
+		UserID:     userID,
+		URL:        url,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		AccountIDs: accountIDs,
+	})
+}
+
+func (s *service) ListWebhooks(ctx context.Context, userID int) ([]*core.WebhookSubscription, error) {
+	return s.store.ListWebhooks(ctx, userID)
+}
+
+func (s *service) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	if _, err := s.ownWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+func (s *service) ListWebhookDeliveries(ctx context.Context, userID int, subscriptionID int) ([]*core.WebhookDelivery, error) {
+	if _, err := s.ownWebhook(ctx, userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.store.ListWebhookDeliveries(ctx, subscriptionID, deliveryLogLimit)
+}
+
+// ReplayWebhookDelivery queues a past delivery to be sent again. The
+// original stays in the log untouched.
+func (s *service) ReplayWebhookDelivery(ctx context.Context, userID int, subscriptionID int, deliveryID int) (*core.WebhookDelivery, error) {
+	if _, err := s.ownWebhook(ctx, userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	original, err := s.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return nil, storage.ErrDeliveryNotFound
+	}
+
+	replay := []*core.WebhookDelivery{{
+		SubscriptionID: subscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+	}}
+	if err := s.store.CreateWebhookDeliveries(ctx, replay); err != nil {
+		return nil, err
+	}
+	return replay[0], nil
+}
+
+// ownWebhook loads a subscription and hides those of other users.
+func (s *service) ownWebhook(ctx context.Context, userID int, id int) (*core.WebhookSubscription, error) {
+	sub, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, storage.ErrWebhookNotFound
+	}
+	return sub, nil
+}
@@ -1,6 +1,7 @@
 package file
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -14,19 +15,33 @@ import (
 type FileStore struct {
 	accountsFile     string
 	transactionsFile string
+	limitsFile       string
 
 	mu           sync.RWMutex
 	accounts     map[int]*core.Account
 	transactions []*core.Transaction
 	nextID       int
+	limits       storedLimits
+}
+
+// storedLimits holds each product's default limits and the overrides set
+// on single accounts.
+type storedLimits struct {
+	Products map[string]core.Limits
+	Accounts map[int]core.Limits
 }
 
 // NewFileStore creates a new file-based store with given JSON file paths.
-func NewFileStore(accountsFile, transactionsFile string) (*FileStore, error) {
+func NewFileStore(accountsFile, transactionsFile, limitsFile string) (*FileStore, error) {
 	store := &FileStore{
 		accountsFile:     accountsFile,
 		transactionsFile: transactionsFile,
+		limitsFile:       limitsFile,
 		accounts:         make(map[int]*core.Account),
+		limits: storedLimits{
+			Products: map[string]core.Limits{core.DefaultProduct: {}},
+			Accounts: make(map[int]core.Limits),
+		},
 	}
 
 	if err := store.loadAccounts(); err != nil {
@@ -35,6 +50,9 @@ func NewFileStore(accountsFile, transactionsFile string) (*FileStore, error) {
 	if err := store.loadTransactions(); err != nil {
 		return nil, err
 	}
+	if err := store.loadLimits(); err != nil {
+		return nil, err
+	}
 
 	return store, nil
 }
@@ -87,6 +105,30 @@ func (s *FileStore) loadTransactions() error {
 	return nil
 }
 
+// loadLimits reads product and account limits from JSON file.
+func (s *FileStore) loadLimits() error {
+	file, err := os.Open(s.limitsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	var limits storedLimits
+	if err := json.NewDecoder(file).Decode(&limits); err != nil {
+		return err
+	}
+	for product, l := range limits.Products {
+		s.limits.Products[product] = l
+	}
+	for id, l := range limits.Accounts {
+		s.limits.Accounts[id] = l
+	}
+	return nil
+}
+
 // saveAccounts writes accounts to JSON file.
 func (s *FileStore) saveAccounts() error {
 
@@ -112,13 +154,23 @@ func (s *FileStore) saveTransactions() error {
 	return os.WriteFile(s.transactionsFile, data, 0644)
 }
 
+// saveLimits writes product and account limits to JSON file.
+func (s *FileStore) saveLimits() error {
+
+	data, err := json.MarshalIndent(s.limits, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.limitsFile, data, 0644)
+}
+
 // CreateAccount implements Storage interface.
 func (s *FileStore) CreateAccount(ctx context.Context, userID int, initialBalance int64) (*core.Account, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.nextID++
-	acc := &core.Account{ID: s.nextID, UserID: userID, Balance: initialBalance}
+	acc := &core.Account{ID: s.nextID, UserID: userID, Balance: initialBalance, Status: core.AccountActive, Product: core.DefaultProduct}
 	s.accounts[acc.ID] = acc
 
 	if err := s.saveAccounts(); err != nil {
@@ -216,6 +268,10 @@ func (s *FileStore) Transfer(ctx context.Context, fromID, toID int, amount int64
 		return nil, nil, storage.ErrAccountNotFound
 	}
 
+	if err := s.accountLimits(fromAcc).Check(amount); err != nil {
+		return nil, nil, err
+	}
+
 	if fromAcc.Balance < amount {
 		return nil, nil, storage.ErrInsufficientFunds
 	}
@@ -274,6 +330,12 @@ func (s *FileStore) Payment(ctx context.Context, accountID int, amount int64, pa
 		return nil, storage.ErrAccountNotFound
 	}
 
+	if paymentType == storage.Withdraw {
+		if err := s.accountLimits(account).Check(amount); err != nil {
+			return nil, err
+		}
+	}
+
 	if paymentType == storage.Withdraw && account.Balance < amount {
 		return nil, storage.ErrInsufficientFunds
 	}
@@ -311,3 +373,78 @@ func (s *FileStore) Payment(ctx context.Context, accountID int, amount int64, pa
 	accountCopy := *account
 	return &accountCopy, nil
 }
+
+// accountLimits returns the limits of an account, its own or else its
+// product's, and its usage of each. Callers hold s.mu, so the check and
+// the debit it allows happen together.
+func (s *FileStore) accountLimits(acc *core.Account) *core.AccountLimits {
+	product := cmp.Or(acc.Product, core.DefaultProduct)
+	own, def := s.limits.Accounts[acc.ID], s.limits.Products[product]
+	lim := &core.AccountLimits{AccountID: acc.ID, Product: product, MaxSingle: cmp.Or(own.MaxSingle, def.MaxSingle)}
+	lim.Daily.Limit = cmp.Or(own.DailyOutbound, def.DailyOutbound)
+	lim.Monthly.Limit = cmp.Or(own.MonthlyOutbound, def.MonthlyOutbound)
+	lim.Hourly.Limit = cmp.Or(own.HourlyCount, def.HourlyCount)
+
+	var txns []*core.Transaction
+	for _, t := range s.transactions {
+		if t.AccountID == acc.ID {
+			txns = append(txns, t)
+		}
+	}
+	lim.Use(txns, time.Now())
+	return lim
+}
+
+// GetAccountLimits returns the limits in force on an account and how much
+// of each is used.
+func (s *FileStore) GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		return nil, storage.ErrAccountNotFound
+	}
+	return s.accountLimits(acc), nil
+}
+
+// SetAccountLimits replaces an account's overrides of its product's
+// limits. Nil limits fall back to the product's.
+func (s *FileStore) SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		return nil, storage.ErrAccountNotFound
+	}
+	before, had := s.limits.Accounts[accountID]
+	s.limits.Accounts[accountID] = limits
+	if err := s.saveLimits(); err != nil {
+		if had {
+			s.limits.Accounts[accountID] = before
+		} else {
+			delete(s.limits.Accounts, accountID)
+		}
+		return nil, err
+	}
+	return s.accountLimits(acc), nil
+}
+
+// SetProductLimits replaces the default limits of an existing product.
+// Nil limits are no limit.
+func (s *FileStore) SetProductLimits(ctx context.Context, product string, limits core.Limits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.limits.Products[product]
+	if !ok {
+		return storage.ErrProductNotFound
+	}
+	s.limits.Products[product] = limits
+	if err := s.saveLimits(); err != nil {
+		s.limits.Products[product] = before
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+func limit(n int64) *int64 { return &n }
+
+func open(t *testing.T, dir string) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(filepath.Join(dir, "accounts.json"), filepath.Join(dir, "transactions.json"), filepath.Join(dir, "limits.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLimitsAreEnforced(t *testing.T) {
+	ctx := context.Background()
+	s := open(t, t.TempDir())
+	from, _ := s.CreateAccount(ctx, 1, 10000)
+	to, _ := s.CreateAccount(ctx, 2, 0)
+	if _, err := s.SetAccountLimits(ctx, from.ID, core.Limits{MaxSingle: limit(100), DailyOutbound: limit(150)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.Transfer(ctx, from.ID, to.ID, 101, "ref"); !errors.Is(err, core.ErrLimitExceeded) {
+		t.Errorf("transfer over the single limit = %v, want ErrLimitExceeded", err)
+	}
+	if _, err := s.Payment(ctx, from.ID, 100, storage.Withdraw, "ref"); err != nil {
+		t.Fatalf("withdrawal within limits = %v", err)
+	}
+	if _, _, err := s.Transfer(ctx, from.ID, to.ID, 51, "ref"); !errors.Is(err, core.ErrLimitExceeded) {
+		t.Errorf("transfer over the daily limit = %v, want ErrLimitExceeded", err)
+	}
+	if _, err := s.Payment(ctx, from.ID, 500, storage.Deposit, "ref"); err != nil {
+		t.Errorf("deposit = %v, want deposits not limited", err)
+	}
+	acc, _ := s.GetAccount(ctx, from.ID)
+	if acc.Balance != 10400 {
+		t.Errorf("balance = %d, want 10400 after refused debits", acc.Balance)
+	}
+}
+
+func TestLimitsSurviveReopening(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir)
+	acc, _ := s.CreateAccount(ctx, 1, 1000)
+	s.SetProductLimits(ctx, core.DefaultProduct, core.Limits{HourlyCount: limit(1)})
+	s.SetAccountLimits(ctx, acc.ID, core.Limits{MaxSingle: limit(300)})
+	s.Payment(ctx, acc.ID, 100, storage.Withdraw, "ref")
+
+	s = open(t, dir)
+	lim, err := s.GetAccountLimits(ctx, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lim.MaxSingle == nil || *lim.MaxSingle != 300 {
+		t.Errorf("max single = %v, want 300", lim.MaxSingle)
+	}
+	if _, err := s.Payment(ctx, acc.ID, 1, storage.Withdraw, "ref"); !errors.Is(err, core.ErrLimitExceeded) {
+		t.Errorf("second withdrawal in the hour = %v, want ErrLimitExceeded", err)
+	}
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"mini-bank/internal/core"
@@ -15,6 +16,10 @@ type Store struct {
 	accounts     map[int]*core.Account
 	transactions []*core.Transaction
 	nextID       int
+	// products holds each product's default limits and limits the
+	// overrides set on single accounts.
+	products map[string]core.Limits
+	limits   map[int]core.Limits
 
 	locksMu   sync.Mutex
 	acctLocks map[int]*sync.Mutex
@@ -24,6 +29,8 @@ type Store struct {
 func NewStore() *Store {
 	return &Store{
 		accounts:  make(map[int]*core.Account),
+		products:  map[string]core.Limits{core.DefaultProduct: {}},
+		limits:    make(map[int]core.Limits),
 		acctLocks: make(map[int]*sync.Mutex),
 	}
 }
@@ -45,7 +52,7 @@ func (s *Store) CreateAccount(ctx context.Context, userID int, initialBalance in
 	defer s.mu.Unlock()
 
 	s.nextID++
-	acc := &core.Account{ID: s.nextID, UserID: userID, Balance: initialBalance}
+	acc := &core.Account{ID: s.nextID, UserID: userID, Balance: initialBalance, Status: core.AccountActive, Product: core.DefaultProduct}
 	s.accounts[acc.ID] = acc
 
 	s.locksMu.Lock()
@@ -169,6 +176,10 @@ func (s *Store) Transfer(ctx context.Context, fromID, toID int, amount int64, re
 		return nil, nil, storage.ErrAccountNotFound
 	}
 
+	if err := s.checkLimits(fromAcc, amount); err != nil {
+		return nil, nil, err
+	}
+
 	if fromAcc.Balance < amount {
 		return nil, nil, storage.ErrInsufficientFunds
 	}
@@ -224,6 +235,12 @@ func (s *Store) Payment(ctx context.Context, accountID int, amount int64, paymen
 	accountLock.Lock()
 	defer accountLock.Unlock()
 
+	if paymentType == storage.Withdraw {
+		if err := s.checkLimits(account, amount); err != nil {
+			return nil, err
+		}
+	}
+
 	if account.Balance < amount && paymentType == storage.Withdraw {
 		return nil, storage.ErrInsufficientFunds
 	}
@@ -252,3 +269,78 @@ func (s *Store) Payment(ctx context.Context, accountID int, amount int64, paymen
 	accountCopy := *account
 	return &accountCopy, nil
 }
+
+// checkLimits refuses to send amount from acc if that would break one of
+// its limits. Callers hold the account's lock, so concurrent debits from
+// it cannot each pass the check alone.
+func (s *Store) checkLimits(acc *core.Account, amount int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accountLimits(acc).Check(amount)
+}
+
+// accountLimits returns the limits of an account, its own or else its
+// product's, and its usage of each. Callers hold s.mu.
+func (s *Store) accountLimits(acc *core.Account) *core.AccountLimits {
+	product := cmp.Or(acc.Product, core.DefaultProduct)
+	own, def := s.limits[acc.ID], s.products[product]
+	lim := &core.AccountLimits{AccountID: acc.ID, Product: product, MaxSingle: cmp.Or(own.MaxSingle, def.MaxSingle)}
+	lim.Daily.Limit = cmp.Or(own.DailyOutbound, def.DailyOutbound)
+	lim.Monthly.Limit = cmp.Or(own.MonthlyOutbound, def.MonthlyOutbound)
+	lim.Hourly.Limit = cmp.Or(own.HourlyCount, def.HourlyCount)
+
+	var txns []*core.Transaction
+	for _, t := range s.transactions {
+		if t.AccountID == acc.ID {
+			txns = append(txns, t)
+		}
+	}
+	lim.Use(txns, time.Now())
+	return lim
+}
+
+// GetAccountLimits returns the limits in force on an account and how much
+// of each is used.
+func (s *Store) GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		return nil, storage.ErrAccountNotFound
+	}
+	return s.accountLimits(acc), nil
+}
+
+// SetAccountLimits replaces an account's overrides of its product's
+// limits. Nil limits fall back to the product's.
+func (s *Store) SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error) {
+	// Take the account's lock as a debit does, so a debit sees either the
+	// old limits or the new ones.
+	al := s.getAccountLock(accountID)
+	al.Lock()
+	defer al.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		return nil, storage.ErrAccountNotFound
+	}
+	s.limits[accountID] = limits
+	return s.accountLimits(acc), nil
+}
+
+// SetProductLimits replaces the default limits of an existing product.
+// Nil limits are no limit.
+func (s *Store) SetProductLimits(ctx context.Context, product string, limits core.Limits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[product]; !ok {
+		return storage.ErrProductNotFound
+	}
+	s.products[product] = limits
+	return nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+func limit(n int64) *int64 { return &n }
+
+func TestLimitsAreEnforced(t *testing.T) {
+	tests := []struct {
+		name    string
+		product core.Limits
+		account core.Limits
+		// debits are withdrawals, or transfers if negative; the last one
+		// is expected to be refused.
+		debits []int64
+	}{
+		{"single amount", core.Limits{}, core.Limits{MaxSingle: limit(100)}, []int64{100, -101}},
+		{"daily total", core.Limits{}, core.Limits{DailyOutbound: limit(150)}, []int64{100, -51}},
+		{"monthly total", core.Limits{}, core.Limits{MonthlyOutbound: limit(150)}, []int64{-100, 51}},
+		{"hourly count", core.Limits{HourlyCount: limit(2)}, core.Limits{}, []int64{1, -1, 1}},
+		{"account overrides product", core.Limits{MaxSingle: limit(1000)}, core.Limits{MaxSingle: limit(10)}, []int64{11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewStore()
+			from, _ := s.CreateAccount(ctx, 1, 10000)
+			to, _ := s.CreateAccount(ctx, 2, 0)
+			if err := s.SetProductLimits(ctx, core.DefaultProduct, tt.product); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SetAccountLimits(ctx, from.ID, tt.account); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, amount := range tt.debits {
+				var err error
+				if amount < 0 {
+					_, _, err = s.Transfer(ctx, from.ID, to.ID, -amount, "ref")
+				} else {
+					_, err = s.Payment(ctx, from.ID, amount, storage.Withdraw, "ref")
+				}
+				last := i == len(tt.debits)-1
+				if last && !errors.Is(err, core.ErrLimitExceeded) {
+					t.Errorf("debit %d of %d = %v, want ErrLimitExceeded", i, amount, err)
+				}
+				if !last && err != nil {
+					t.Fatalf("debit %d of %d = %v, want nil", i, amount, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDepositsAreNotLimited(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	acc, _ := s.CreateAccount(ctx, 1, 0)
+	from, _ := s.CreateAccount(ctx, 2, 1000)
+	s.SetAccountLimits(ctx, acc.ID, core.Limits{MaxSingle: limit(10), DailyOutbound: limit(10)})
+
+	if _, err := s.Payment(ctx, acc.ID, 500, storage.Deposit, "ref"); err != nil {
+		t.Errorf("deposit over the limits = %v, want nil", err)
+	}
+	if _, _, err := s.Transfer(ctx, from.ID, acc.ID, 500, "ref"); err != nil {
+		t.Errorf("transfer in over the receiver's limits = %v, want nil", err)
+	}
+	lim, err := s.GetAccountLimits(ctx, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lim.Daily.Used != 0 || lim.Hourly.Used != 0 {
+		t.Errorf("daily, hourly used = %d, %d, want 0, 0", lim.Daily.Used, lim.Hourly.Used)
+	}
+}
+
+func TestGetAccountLimitsReportsUsage(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	acc, _ := s.CreateAccount(ctx, 1, 1000)
+	s.SetProductLimits(ctx, core.DefaultProduct, core.Limits{DailyOutbound: limit(500)})
+	s.Payment(ctx, acc.ID, 200, storage.Withdraw, "ref")
+
+	lim, err := s.GetAccountLimits(ctx, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := lim.Daily.Remaining(); r == nil || *r != 300 {
+		t.Errorf("daily remaining = %v, want 300", r)
+	}
+	if lim.Hourly.Used != 1 || lim.Hourly.ResetsAt == nil {
+		t.Errorf("hourly used = %d, resets at %v, want 1 and a reset time", lim.Hourly.Used, lim.Hourly.ResetsAt)
+	}
+	if _, err := s.GetAccountLimits(ctx, 99); !errors.Is(err, storage.ErrAccountNotFound) {
+		t.Errorf("GetAccountLimits(99) = %v, want ErrAccountNotFound", err)
+	}
+	if err := s.SetProductLimits(ctx, "gold", core.Limits{}); !errors.Is(err, storage.ErrProductNotFound) {
+		t.Errorf("SetProductLimits(gold) = %v, want ErrProductNotFound", err)
+	}
+}
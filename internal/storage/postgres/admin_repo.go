@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/events"
+	"mini-bank/internal/storage"
+)
+
+// SetAccountStatus freezes or unfreezes an account. Setting the status an
+// account already has changes nothing and emits no event.
+func (r *Repo) SetAccountStatus(ctx context.Context, id int, status string) (*core.Account, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanAccount(tx.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if before.Status == status {
+		return before, nil
+	}
+
+	const upd = `UPDATE accounts SET status = $1 WHERE id = $2 RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, upd, status, id))
+	if err != nil {
+		return nil, err
+	}
+
+	eventType, action := core.EventAccountUnfrozen, core.AuditAccountUnfreeze
+	if status == core.AccountFrozen {
+		eventType, action = core.EventAccountFrozen, core.AuditAccountFreeze
+	}
+	if err := writeOutbox(ctx, tx, events.New(eventType, acc, 0, "", nil)); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, action, core.TargetAccount, id, audit.AccountState(before), audit.AccountState(acc))); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// AdjustBalance records a manual correction to an account's balance.
+func (r *Repo) AdjustBalance(ctx context.Context, accountID int, amount int64, reason string, reference string) (*core.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	acc, err := adjust(ctx, tx, accountID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, err := insertTransaction(ctx, tx, &core.Transaction{
+		AccountID: accountID,
+		Type:      core.TransactionAdjustment,
+		Amount:    amount,
+		Reference: reference,
+		Reason:    reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeOutbox(ctx, tx, events.New(core.EventAdjustment, acc, amount, reference, nil)); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, balanceEntry(ctx, core.AuditAdjustment, []movement{{acc, amount}}, txn)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// ReverseTransaction books the opposite of a transaction. Reversing a
+// transfer takes the money back from the recipient, so it needs the
+// recipient to still hold it. Money in a term deposit only moves through
+// opening, breaking and paying it out, so nothing touching a fixed_deposit
+// account is reversed.
+func (r *Repo) ReverseTransaction(ctx context.Context, id int, reason string, reference string) ([]*core.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the original so concurrent reversals of it queue up here and
+	// the second one sees the first.
+	orig, err := scanTransaction(tx.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	var reversed bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM transactions WHERE reverses_id = $1)`, id).Scan(&reversed); err != nil {
+		return nil, err
+	}
+	if reversed {
+		return nil, storage.ErrAlreadyReversed
+	}
+
+	products, err := legProducts(ctx, tx, orig)
+	if err != nil {
+		return nil, err
+	}
+	legs, err := reversalLegs(orig, products)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*core.Transaction
+	var evts []core.Event
+	var moves []movement
+	for _, leg := range legs {
+		acc, err := adjust(ctx, tx, leg.AccountID, leg.Amount)
+		if err != nil {
+			return nil, err
+		}
+		leg.Type = core.TransactionReversal
+		leg.Reference = reference
+		leg.Reason = reason
+		leg.ReversesID = &orig.ID
+		txn, err := insertTransaction(ctx, tx, leg)
+		if err != nil {
+			return nil, err
+		}
+		counterparty := leg.FromAccountID
+		if counterparty == nil {
+			counterparty = leg.ToAccountID
+		}
+		res = append(res, txn)
+		evts = append(evts, events.New(core.EventReversal, acc, leg.Amount, reference, counterparty))
+		moves = append(moves, movement{acc, leg.Amount})
+	}
+
+	if err := writeOutbox(ctx, tx, evts...); err != nil {
+		return nil, err
+	}
+
+	entry := balanceEntry(ctx, core.AuditReversal, moves, res...)
+	entry.TargetType, entry.TargetID = core.TargetTransaction, strconv.Itoa(orig.ID)
+	if err := writeAudit(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// legProducts returns the products of the accounts a transaction moved
+// money between.
+func legProducts(ctx context.Context, tx *sql.Tx, t *core.Transaction) (map[int]string, error) {
+	ids := []int{t.AccountID}
+	if t.ToAccountID != nil {
+		ids = append(ids, *t.ToAccountID)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT id, product FROM accounts WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make(map[int]string, len(ids))
+	for rows.Next() {
+		var id int
+		var product string
+		if err := rows.Scan(&id, &product); err != nil {
+			return nil, err
+		}
+		products[id] = product
+	}
+	return products, rows.Err()
+}
+
+// reversalLegs returns the balance changes that undo t, debits first so
+// that a shortfall is found before anything is credited. products holds
+// the product of each account t touches; a fixed_deposit one is refused,
+// since undoing its funding or payout would leave the term deposit without
+// the money it records.
+func reversalLegs(t *core.Transaction, products map[int]string) ([]*core.Transaction, error) {
+	if products[t.AccountID] == core.ProductFixedDeposit || t.ToAccountID != nil && products[*t.ToAccountID] == core.ProductFixedDeposit {
+		return nil, storage.ErrNotReversible.WithMessage("transactions on fixed deposit accounts cannot be reversed; break or pay out the term deposit instead")
+	}
+	switch t.Type {
+	case core.TransactionDeposit, core.TransactionAdjustment:
+		return []*core.Transaction{{AccountID: t.AccountID, Amount: -t.Amount}}, nil
+	case core.TransactionWithdraw:
+		return []*core.Transaction{{AccountID: t.AccountID, Amount: t.Amount}}, nil
+	case core.TransactionTransfer:
+		// Each transfer is recorded once per side; only the sender's row,
+		// which names the recipient, reverses it.
+		if t.ToAccountID == nil {
+			return nil, storage.ErrNotReversible.WithMessage("only the sender's side of a transfer can be reversed")
+		}
+		return []*core.Transaction{
+			{AccountID: *t.ToAccountID, Amount: -t.Amount, ToAccountID: &t.AccountID},
+			{AccountID: t.AccountID, Amount: t.Amount, FromAccountID: t.ToAccountID},
+		}, nil
+	default:
+		return nil, storage.ErrNotReversible.WithMessage("%s transactions cannot be reversed", t.Type)
+	}
+}
+
+// adjust changes a balance by a signed amount regardless of the account's
+// status, refusing to take it below zero.
+func adjust(ctx context.Context, tx *sql.Tx, accountID int, amount int64) (*core.Account, error) {
+	const upd = `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND balance + $1 >= 0 RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, upd, amount, accountID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1)`, accountID).Scan(&exists); err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, storage.ErrAccountNotFound
+			}
+			return nil, storage.ErrInsufficientFunds
+		}
+		return nil, err
+	}
+	return acc, nil
+}
+
+func insertTransaction(ctx context.Context, tx *sql.Tx, t *core.Transaction) (*core.Transaction, error) {
+	const ins = `INSERT INTO transactions (account_id, type, amount, reference, from_account_id, to_account_id, reason, reverses_id, pot_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + transactionColumns
+	return scanTransaction(tx.QueryRowContext(ctx, ins, t.AccountID, t.Type, t.Amount, nullIfEmpty(t.Reference),
+		nullInt(t.FromAccountID), nullInt(t.ToAccountID), nullIfEmpty(t.Reason), nullInt(t.ReversesID), nullInt(t.PotID), time.Now().UTC()))
+}
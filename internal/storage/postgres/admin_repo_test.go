@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+func TestReversalLegs(t *testing.T) {
+	current, deposit, savings := 1, 2, 3
+	products := map[int]string{current: core.ProductCurrent, deposit: core.ProductFixedDeposit, savings: core.ProductSavings}
+	tests := []struct {
+		name     string
+		txn      *core.Transaction
+		wantLegs int
+		wantErr  error
+	}{
+		{"deposit", &core.Transaction{AccountID: current, Type: core.TransactionDeposit, Amount: 100}, 1, nil},
+		{"transfer", &core.Transaction{AccountID: current, Type: core.TransactionTransfer, Amount: 100, ToAccountID: &savings}, 2, nil},
+		{"receiving side", &core.Transaction{AccountID: savings, Type: core.TransactionTransfer, Amount: 100, FromAccountID: &current}, 0, storage.ErrNotReversible},
+		{"term deposit funding", &core.Transaction{AccountID: current, Type: core.TransactionTransfer, Amount: 100, ToAccountID: &deposit}, 0, storage.ErrNotReversible},
+		{"term deposit payout", &core.Transaction{AccountID: deposit, Type: core.TransactionTransfer, Amount: 100, ToAccountID: &current}, 0, storage.ErrNotReversible},
+		{"term deposit interest", &core.Transaction{AccountID: deposit, Type: core.TransactionInterest, Amount: 5}, 0, storage.ErrNotReversible},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs, err := reversalLegs(tt.txn, products)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reversalLegs error = %v, want %v", err, tt.wantErr)
+			}
+			if len(legs) != tt.wantLegs {
+				t.Errorf("reversalLegs returned %d legs, want %d", len(legs), tt.wantLegs)
+			}
+		})
+	}
+}
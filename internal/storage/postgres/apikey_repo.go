@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeMap scans Postgres arrays into Go slices through database/sql.
+var typeMap = pgtype.NewMap()
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, account_ids, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row scanner) (*core.APIKey, error) {
+	var k core.APIKey
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Hash,
+		typeMap.SQLScanner(&k.Scopes), typeMap.SQLScanner(&k.AccountIDs),
+		&k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateAPIKey stores a new API key.
+func (r *Repo) CreateAPIKey(ctx context.Context, key *core.APIKey) (*core.APIKey, error) {
+	accountIDs := key.AccountIDs
+	if accountIDs == nil {
+		accountIDs = []int{}
+	}
+	const q = `INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, account_ids) VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + apiKeyColumns
+	row := r.db.QueryRowContext(ctx, q, key.UserID, key.Name, key.Prefix, key.Hash, key.Scopes, accountIDs)
+	return scanAPIKey(row)
+}
+
+// GetAPIKey retrieves an API key by id.
+func (r *Repo) GetAPIKey(ctx context.Context, id int) (*core.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	return r.getAPIKey(ctx, q, id)
+}
+
+// GetAPIKeyByPrefix retrieves an API key by its public prefix.
+func (r *Repo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*core.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+	return r.getAPIKey(ctx, q, prefix)
+}
+
+func (r *Repo) getAPIKey(ctx context.Context, q string, args ...any) (*core.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, q, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns every API key belonging to a user, including revoked ones.
+func (r *Repo) ListAPIKeys(ctx context.Context, userID int) ([]*core.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, k)
+	}
+	return res, rows.Err()
+}
+
+// RotateAPIKey replaces the secret of an active API key.
+func (r *Repo) RotateAPIKey(ctx context.Context, id int, prefix string, hash string) (*core.APIKey, error) {
+	const q = `UPDATE api_keys SET prefix = $2, key_hash = $3 WHERE id = $1 AND revoked_at IS NULL RETURNING ` + apiKeyColumns
+	return r.getAPIKey(ctx, q, id, prefix, hash)
+}
+
+// RevokeAPIKey marks an API key as revoked. Revoking twice is a no-op.
+func (r *Repo) RevokeAPIKey(ctx context.Context, id int) error {
+	const q = `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records that an API key has just been used.
+func (r *Repo) TouchAPIKey(ctx context.Context, id int) error {
+	const q = `UPDATE api_keys SET last_used_at = now() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, q, id)
+	return err
+}
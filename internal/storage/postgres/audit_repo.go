@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// auditLockKey is the advisory lock that serialises appends to the audit
+// chain. Appends take it last, just before committing, to hold it briefly.
+const auditLockKey = 0x6175646974 // "audit"
+
+const auditColumns = `id, occurred_at, actor_type, actor_id, actor_user_id, action, target_type, target_id, before, after, request_id, prev_hash, hash`
+
+// writeAudit appends entries to the audit chain inside tx, so they commit
+// if and only if the change they record does.
+func writeAudit(ctx context.Context, tx *sql.Tx, entries ...core.AuditEntry) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return err
+	}
+
+	// Under the lock the last committed entry cannot change until we commit.
+	var prev string
+	err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	at := audit.Timestamp()
+	for i := range entries {
+		entries[i].OccurredAt = at
+	}
+	audit.Chain(prev, entries)
+
+	const ins = `INSERT INTO audit_log (occurred_at, actor_type, actor_id, actor_user_id, action, target_type, target_id, before, after, request_id, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	for _, e := range entries {
+		if _, err := tx.ExecContext(ctx, ins, e.OccurredAt, e.Actor.Type, e.Actor.ID, nullInt(e.Actor.UserID), e.Action,
+			e.TargetType, e.TargetID, nullJSON(e.Before), nullJSON(e.After), e.RequestID, e.PrevHash, e.Hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendAudit records entries that go with no other change, such as logins.
+func (r *Repo) AppendAudit(ctx context.Context, entries ...core.AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAuditEntries returns entries matching the filter in chain order.
+func (r *Repo) ListAuditEntries(ctx context.Context, f core.AuditFilter) ([]core.AuditEntry, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorType != "" {
+		where("actor_type = $%d", f.ActorType)
+	}
+	if f.ActorID != "" {
+		where("actor_id = $%d", f.ActorID)
+	}
+	if f.Action != "" {
+		where("action = $%d", f.Action)
+	}
+	if f.TargetType != "" {
+		where("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		where("target_id = $%d", f.TargetID)
+	}
+	if !f.From.IsZero() {
+		where("occurred_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		where("occurred_at < $%d", f.To)
+	}
+	if f.AfterID > 0 {
+		where("id > $%d", f.AfterID)
+	}
+
+	q := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conds) > 0 {
+		q += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	q += ` ORDER BY id`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []core.AuditEntry
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+func scanAuditEntry(row scanner) (core.AuditEntry, error) {
+	var e core.AuditEntry
+	var before, after sql.NullString
+	if err := row.Scan(&e.ID, &e.OccurredAt, &e.Actor.Type, &e.Actor.ID, &e.Actor.UserID, &e.Action, &e.TargetType, &e.TargetID,
+		&before, &after, &e.RequestID, &e.PrevHash, &e.Hash); err != nil {
+		return e, err
+	}
+	if before.Valid {
+		e.Before = []byte(before.String)
+	}
+	if after.Valid {
+		e.After = []byte(after.String)
+	}
+	return e, nil
+}
+
+func nullJSON(m []byte) any {
+	if len(m) == 0 {
+		return nil
+	}
+	return string(m)
+}
+
+// GetUserRoles returns the roles granted to a user.
+func (r *Repo) GetUserRoles(ctx context.Context, userID int) ([]string, error) {
+	var roles []string
+	err := r.db.QueryRowContext(ctx, `SELECT roles FROM users WHERE id = $1`, userID).Scan(typeMap.SQLScanner(&roles))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetUserRole grants or revokes a role. Changing nothing writes no audit
+// entry.
+func (r *Repo) SetUserRole(ctx context.Context, userID int, role string, granted bool) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var before []string
+	err = tx.QueryRowContext(ctx, `SELECT roles FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(typeMap.SQLScanner(&before))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if slices.Contains(before, role) == granted {
+		return before, nil
+	}
+
+	after := []string{}
+	for _, r := range before {
+		if r != role {
+			after = append(after, r)
+		}
+	}
+	action := core.AuditRoleRevoke
+	if granted {
+		after = append(after, role)
+		action = core.AuditRoleGrant
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET roles = $1 WHERE id = $2`, after, userID); err != nil {
+		return nil, err
+	}
+
+	snapshot := func(roles []string) any {
+		return map[string]any{"id": userID, "roles": append([]string{}, roles...)}
+	}
+	if err := writeAudit(ctx, tx, audit.New(ctx, action, core.TargetUser, userID, snapshot(before), snapshot(after))); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// movement is a balance change made to an account, which now looks like
+// account.
+type movement struct {
+	account *core.Account
+	delta   int64
+}
+
+// balanceEntry audits a change that moved money in or out of accounts and
+// booked txns. The first account is the target.
+func balanceEntry(ctx context.Context, action string, moves []movement, txns ...*core.Transaction) core.AuditEntry {
+	before := make([]any, 0, len(moves))
+	after := make([]any, 0, len(moves))
+	for _, m := range moves {
+		before = append(before, audit.AccountBefore(m.account, m.delta))
+		after = append(after, audit.AccountState(m.account))
+	}
+	return audit.New(ctx, action, core.TargetAccount, moves[0].account.ID,
+		map[string]any{"accounts": before},
+		map[string]any{"accounts": after, "transactions": audit.TransactionStates(txns...)})
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// beneficiaryColumns are selected from beneficiaries b joined with the
+// account they name.
+const beneficiaryColumns = `b.id, b.user_id, b.nickname, b.account_id, a.number, b.payee_name, b.name_match, b.created_at`
+
+const beneficiaryFrom = `beneficiaries b JOIN accounts a ON a.id = b.account_id`
+
+// CreateBeneficiary saves a payee and audits it.
+func (r *Repo) CreateBeneficiary(ctx context.Context, b *core.Beneficiary) (*core.Beneficiary, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const q = `WITH b AS (
+			INSERT INTO beneficiaries (user_id, nickname, account_id, payee_name, name_match)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + beneficiaryColumns + ` FROM b JOIN accounts a ON a.id = b.account_id`
+	created, err := scanBeneficiary(tx.QueryRowContext(ctx, q, b.UserID, b.Nickname, b.AccountID, b.PayeeName, b.NameMatch))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch {
+			case pgErr.Code == "23505":
+				return nil, storage.ErrBeneficiaryExists
+			case pgErr.Code == "23503" && pgErr.ConstraintName == "beneficiaries_account_id_fkey":
+				return nil, storage.ErrAccountNotFound
+			case pgErr.Code == "23503":
+				return nil, storage.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditPayeeCreate, core.TargetBeneficiary, created.ID, nil, audit.BeneficiaryState(created))); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repo) GetBeneficiary(ctx context.Context, id int) (*core.Beneficiary, error) {
+	b, err := scanBeneficiary(r.db.QueryRowContext(ctx, `SELECT `+beneficiaryColumns+` FROM `+beneficiaryFrom+` WHERE b.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrBeneficiaryNotFound
+	}
+	return b, err
+}
+
+func (r *Repo) FindBeneficiary(ctx context.Context, userID int, accountID int) (*core.Beneficiary, error) {
+	b, err := scanBeneficiary(r.db.QueryRowContext(ctx, `SELECT `+beneficiaryColumns+` FROM `+beneficiaryFrom+` WHERE b.user_id = $1 AND b.account_id = $2`,
+		userID, accountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrBeneficiaryNotFound
+	}
+	return b, err
+}
+
+// FirstTransferTo finds the user's earliest transfer to an account,
+// leaving out transfers that were reversed.
+func (r *Repo) FirstTransferTo(ctx context.Context, userID int, accountID int) (*time.Time, error) {
+	const q = `SELECT MIN(t.created_at) FROM transactions t JOIN accounts a ON a.id = t.account_id
+		WHERE a.user_id = $1 AND t.type = $2 AND t.to_account_id = $3
+		AND NOT EXISTS (SELECT 1 FROM transactions r WHERE r.reverses_id = t.id)`
+	var first sql.NullTime
+	if err := r.db.QueryRowContext(ctx, q, userID, core.TransactionTransfer, accountID).Scan(&first); err != nil {
+		return nil, err
+	}
+	if !first.Valid {
+		return nil, nil
+	}
+	return &first.Time, nil
+}
+
+// ListBeneficiaries returns a user's payees by nickname.
+func (r *Repo) ListBeneficiaries(ctx context.Context, userID int) ([]*core.Beneficiary, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+beneficiaryColumns+` FROM `+beneficiaryFrom+` WHERE b.user_id = $1 ORDER BY b.nickname, b.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.Beneficiary
+	for rows.Next() {
+		b, err := scanBeneficiary(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, b)
+	}
+	return res, rows.Err()
+}
+
+// DeleteBeneficiary removes a payee and audits it. Past transfers to the
+// account are unaffected.
+func (r *Repo) DeleteBeneficiary(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const q = `WITH b AS (DELETE FROM beneficiaries WHERE id = $1 RETURNING *)
+		SELECT ` + beneficiaryColumns + ` FROM b JOIN accounts a ON a.id = b.account_id`
+	before, err := scanBeneficiary(tx.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrBeneficiaryNotFound
+		}
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditPayeeDelete, core.TargetBeneficiary, id, audit.BeneficiaryState(before), nil)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanBeneficiary(row scanner) (*core.Beneficiary, error) {
+	var b core.Beneficiary
+	if err := row.Scan(&b.ID, &b.UserID, &b.Nickname, &b.AccountID, &b.AccountNumber, &b.PayeeName, &b.NameMatch, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 	
+	"mini-bank/internal/config"
+
+	"github.com/XSAM/otelsql"
 	_"github.com/jackc/pgx/v5/stdlib"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 type DB struct {
@@ -14,20 +17,27 @@ type DB struct {
 }
 
 
-func NewDB(dsn string) (*DB, error){
+// NewDB opens a connection pool sized and timed by cfg and checks that the
+// database is reachable.
+func NewDB(cfg config.Storage) (*DB, error){
 	
-	db, err := sql.Open("pgx", dsn)
+	// Every query gets a span under the caller's, with its SQL but not its
+	// arguments.
+	db, err := otelsql.Open("pgx", cfg.DatabaseURL.Reveal(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, err
 	}
 	
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(30 * time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	
-	db.SetConnMaxIdleTime(10 * time.Minute)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 	
 	if err := db.PingContext(ctx); err != nil {
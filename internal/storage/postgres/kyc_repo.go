@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const submissionColumns = `id, user_id, tier, legal_name, date_of_birth, address, nationality, document_type, document_number,
+	status, note, reviewed_by, reviewed_at, created_at`
+
+const documentColumns = `id, submission_id, kind, filename, content_type, size, sha256, blob_key, created_at`
+
+// GetKYCStatus returns a user's tier and their latest submission, with
+// its documents.
+func (r *Repo) GetKYCStatus(ctx context.Context, userID int) (*core.KYCStatus, error) {
+	status := &core.KYCStatus{UserID: userID}
+	if err := r.db.QueryRowContext(ctx, `SELECT kyc_tier FROM users WHERE id = $1`, userID).Scan(&status.Tier); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	const q = `SELECT ` + submissionColumns + ` FROM kyc_submissions WHERE user_id = $1 ORDER BY id DESC LIMIT 1`
+	sub, err := scanSubmission(r.db.QueryRowContext(ctx, q, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sub.Documents, err = r.listDocuments(ctx, sub.ID); err != nil {
+		return nil, err
+	}
+	status.Latest = sub
+	return status, nil
+}
+
+// CreateKYCSubmission saves a pending submission and its documents and
+// audits it. The partial unique index on pending submissions turns a
+// second one into ErrSubmissionPending.
+func (r *Repo) CreateKYCSubmission(ctx context.Context, sub *core.KYCSubmission) (*core.KYCSubmission, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const ins = `INSERT INTO kyc_submissions (user_id, tier, legal_name, date_of_birth, address, nationality, document_type, document_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + submissionColumns
+	created, err := scanSubmission(tx.QueryRowContext(ctx, ins, sub.UserID, sub.Tier, sub.LegalName, sub.DateOfBirth, sub.Address,
+		sub.Nationality, sub.DocumentType, sub.DocumentNumber))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return nil, storage.ErrSubmissionPending
+			case "23503":
+				return nil, storage.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	const insDoc = `INSERT INTO kyc_documents (submission_id, kind, filename, content_type, size, sha256, blob_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + documentColumns
+	for _, d := range sub.Documents {
+		doc, err := scanDocument(tx.QueryRowContext(ctx, insDoc, created.ID, d.Kind, d.Filename, d.ContentType, d.Size, d.SHA256, d.BlobKey))
+		if err != nil {
+			return nil, err
+		}
+		created.Documents = append(created.Documents, doc)
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditKYCSubmit, core.TargetKYC, created.ID, nil, audit.KYCState(created))); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repo) GetKYCSubmission(ctx context.Context, id int) (*core.KYCSubmission, error) {
+	sub, err := scanSubmission(r.db.QueryRowContext(ctx, `SELECT `+submissionColumns+` FROM kyc_submissions WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrSubmissionNotFound
+		}
+		return nil, err
+	}
+	if sub.Documents, err = r.listDocuments(ctx, id); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (r *Repo) ListKYCSubmissions(ctx context.Context, f core.KYCFilter) ([]*core.KYCSubmission, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Status != "" {
+		where("status = $%d", f.Status)
+	}
+	if f.UserID > 0 {
+		where("user_id = $%d", f.UserID)
+	}
+	if f.AfterID > 0 {
+		where("id > $%d", f.AfterID)
+	}
+
+	q := `SELECT ` + submissionColumns + ` FROM kyc_submissions`
+	if len(conds) > 0 {
+		q += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	q += ` ORDER BY id`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.KYCSubmission
+	for rows.Next() {
+		sub, err := scanSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sub)
+	}
+	return res, rows.Err()
+}
+
+// ReviewKYCSubmission records the decision on a pending submission and,
+// for an approval that raises the user's tier, the new tier, auditing
+// both.
+func (r *Repo) ReviewKYCSubmission(ctx context.Context, id int, status string, note string) (*core.KYCSubmission, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanSubmission(tx.QueryRowContext(ctx, `SELECT `+submissionColumns+` FROM kyc_submissions WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrSubmissionNotFound
+		}
+		return nil, err
+	}
+	if before.Status != core.KYCPending {
+		return nil, storage.ErrSubmissionReviewed
+	}
+
+	const q = `UPDATE kyc_submissions SET status = $2, note = $3, reviewed_by = $4, reviewed_at = now()
+		WHERE id = $1 RETURNING ` + submissionColumns
+	after, err := scanSubmission(tx.QueryRowContext(ctx, q, id, status, nullIfEmpty(note), audit.ActorFrom(ctx).String()))
+	if err != nil {
+		return nil, err
+	}
+	entries := []core.AuditEntry{audit.New(ctx, core.AuditKYCReview, core.TargetKYC, id, audit.KYCState(before), audit.KYCState(after))}
+
+	if status == core.KYCApproved {
+		// Lock the user as a debit's limit check reads the tier through
+		// the account, so a debit sees either the old tier or the new one.
+		var tier string
+		if err := tx.QueryRowContext(ctx, `SELECT kyc_tier FROM users WHERE id = $1 FOR UPDATE`, after.UserID).Scan(&tier); err != nil {
+			return nil, err
+		}
+		if core.TierRank(after.Tier) > core.TierRank(tier) {
+			if _, err := tx.ExecContext(ctx, `UPDATE users SET kyc_tier = $2 WHERE id = $1`, after.UserID, after.Tier); err != nil {
+				return nil, err
+			}
+			entries = append(entries, audit.New(ctx, core.AuditTierChange, core.TargetUser, after.UserID,
+				audit.TierState(tier), audit.TierState(after.Tier)))
+		}
+	}
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if after.Documents, err = r.listDocuments(ctx, id); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// GetUserTier returns a user's KYC tier.
+func (r *Repo) GetUserTier(ctx context.Context, userID int) (string, error) {
+	var tier string
+	if err := r.db.QueryRowContext(ctx, `SELECT kyc_tier FROM users WHERE id = $1`, userID).Scan(&tier); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrUserNotFound
+		}
+		return "", err
+	}
+	return tier, nil
+}
+
+// GetTierLimits returns the limits of a tier.
+func (r *Repo) GetTierLimits(ctx context.Context, tier string) (*core.Limits, error) {
+	l, err := scanLimits(r.db.QueryRowContext(ctx, `SELECT max_single, daily_outbound, monthly_outbound, hourly_count
+		FROM kyc_tier_limits WHERE tier = $1`, tier))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, core.InvalidField("tier", "unknown tier "+tier)
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetTierLimits replaces the limits of an existing tier.
+func (r *Repo) SetTierLimits(ctx context.Context, tier string, limits core.Limits) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanLimits(tx.QueryRowContext(ctx, `SELECT max_single, daily_outbound, monthly_outbound, hourly_count
+		FROM kyc_tier_limits WHERE tier = $1 FOR UPDATE`, tier))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.InvalidField("tier", "unknown tier "+tier)
+		}
+		return err
+	}
+
+	const upd = `UPDATE kyc_tier_limits SET max_single = $2, daily_outbound = $3, monthly_outbound = $4, hourly_count = $5 WHERE tier = $1`
+	if _, err := tx.ExecContext(ctx, upd, tier, limits.MaxSingle, limits.DailyOutbound, limits.MonthlyOutbound, limits.HourlyCount); err != nil {
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditLimitsUpdate, core.TargetTier, tier,
+		audit.LimitsState(before), audit.LimitsState(&limits))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) listDocuments(ctx context.Context, submissionID int) ([]*core.KYCDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+documentColumns+` FROM kyc_documents WHERE submission_id = $1 ORDER BY id`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []*core.KYCDocument{}
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+func scanSubmission(row scanner) (*core.KYCSubmission, error) {
+	var s core.KYCSubmission
+	var note, reviewedBy sql.NullString
+	if err := row.Scan(&s.ID, &s.UserID, &s.Tier, &s.LegalName, &s.DateOfBirth, &s.Address, &s.Nationality, &s.DocumentType,
+		&s.DocumentNumber, &s.Status, &note, &reviewedBy, &s.ReviewedAt, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	s.Note = note.String
+	s.ReviewedBy = reviewedBy.String
+	return &s, nil
+}
+
+func scanDocument(row scanner) (*core.KYCDocument, error) {
+	var d core.KYCDocument
+	if err := row.Scan(&d.ID, &d.SubmissionID, &d.Kind, &d.Filename, &d.ContentType, &d.Size, &d.SHA256, &d.BlobKey, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+// outbound matches the transaction rows that took money out of their
+// account on a customer's behalf.
+const outbound = `(type = 'withdraw' OR (type = 'transfer' AND to_account_id IS NOT NULL))`
+
+// checkLimits locks the account and refuses to send amount from it if
+// that would break one of its limits, capped at tierLimits. Holding the
+// lock until the debit commits stops concurrent debits from each passing
+// the check alone. Frozen and missing accounts pass, so the debit itself
+// reports them.
+func checkLimits(ctx context.Context, tx *sql.Tx, accountID int, amount int64, tierLimits core.Limits) error {
+	lim, status, err := loadLimits(ctx, tx, accountID, true)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+	if status != core.AccountActive {
+		return nil
+	}
+	lim.Cap(tierLimits)
+	return lim.Check(amount)
+}
+
+// loadLimits returns the limits of an account, its own or else its
+// product's, its status and its usage of each limit, optionally locking
+// the account.
+func loadLimits(ctx context.Context, tx *sql.Tx, accountID int, lock bool) (*core.AccountLimits, string, error) {
+	q := `SELECT a.product, a.status,
+			COALESCE(o.max_single, p.max_single),
+			COALESCE(o.daily_outbound, p.daily_outbound),
+			COALESCE(o.monthly_outbound, p.monthly_outbound),
+			COALESCE(o.hourly_count, p.hourly_count)
+		FROM accounts a
+		JOIN product_limits p ON p.product = a.product
+		LEFT JOIN account_limits o ON o.account_id = a.id
+		WHERE a.id = $1`
+	if lock {
+		q += ` FOR UPDATE OF a`
+	}
+	lim := &core.AccountLimits{AccountID: accountID}
+	var status string
+	err := tx.QueryRowContext(ctx, q, accountID).Scan(&lim.Product, &status,
+		&lim.MaxSingle, &lim.Daily.Limit, &lim.Monthly.Limit, &lim.Hourly.Limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", storage.ErrAccountNotFound
+		}
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hour := now.Add(-time.Hour)
+	since := month
+	if hour.Before(since) {
+		since = hour
+	}
+
+	const usage = `SELECT
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= $2), 0)::BIGINT,
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= $3), 0)::BIGINT,
+			COUNT(*) FILTER (WHERE created_at > $4),
+			MIN(created_at) FILTER (WHERE created_at > $4)
+		FROM transactions
+		WHERE account_id = $1 AND created_at >= $5 AND ` + outbound
+	var oldest sql.NullTime
+	if err := tx.QueryRowContext(ctx, usage, accountID, day, month, hour, since).
+		Scan(&lim.Daily.Used, &lim.Monthly.Used, &lim.Hourly.Used, &oldest); err != nil {
+		return nil, "", err
+	}
+
+	if lim.Daily.Used > 0 {
+		t := day.AddDate(0, 0, 1)
+		lim.Daily.ResetsAt = &t
+	}
+	if lim.Monthly.Used > 0 {
+		t := month.AddDate(0, 1, 0)
+		lim.Monthly.ResetsAt = &t
+	}
+	if oldest.Valid {
+		t := oldest.Time.UTC().Add(time.Hour)
+		lim.Hourly.ResetsAt = &t
+	}
+	return lim, status, nil
+}
+
+// GetAccountLimits returns the limits in force on an account and how much
+// of each is used.
+func (r *Repo) GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lim, _, err := loadLimits(ctx, tx, accountID, false)
+	return lim, err
+}
+
+// SetAccountLimits replaces an account's overrides of its product's
+// limits. Nil limits fall back to the product's.
+func (r *Repo) SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the account as a debit's limit check does, so a debit sees
+	// either the old limits or the new ones.
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT true FROM accounts WHERE id = $1 FOR UPDATE`, accountID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	before, err := scanLimits(tx.QueryRowContext(ctx, `SELECT max_single, daily_outbound, monthly_outbound, hourly_count
+		FROM account_limits WHERE account_id = $1`, accountID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	const upsert = `INSERT INTO account_limits (account_id, max_single, daily_outbound, monthly_outbound, hourly_count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id) DO UPDATE SET max_single = $2, daily_outbound = $3, monthly_outbound = $4, hourly_count = $5`
+	if _, err := tx.ExecContext(ctx, upsert, accountID, limits.MaxSingle, limits.DailyOutbound, limits.MonthlyOutbound, limits.HourlyCount); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditLimitsUpdate, core.TargetAccount, accountID,
+		audit.LimitsState(before), audit.LimitsState(&limits))); err != nil {
+		return nil, err
+	}
+
+	lim, _, err := loadLimits(ctx, tx, accountID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return lim, nil
+}
+
+// SetProductLimits replaces the default limits of an existing product.
+// Nil limits are no limit.
+func (r *Repo) SetProductLimits(ctx context.Context, product string, limits core.Limits) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanLimits(tx.QueryRowContext(ctx, `SELECT max_single, daily_outbound, monthly_outbound, hourly_count
+		FROM product_limits WHERE product = $1 FOR UPDATE`, product))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrProductNotFound
+		}
+		return err
+	}
+
+	const upd = `UPDATE product_limits SET max_single = $2, daily_outbound = $3, monthly_outbound = $4, hourly_count = $5 WHERE product = $1`
+	if _, err := tx.ExecContext(ctx, upd, product, limits.MaxSingle, limits.DailyOutbound, limits.MonthlyOutbound, limits.HourlyCount); err != nil {
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditLimitsUpdate, core.TargetProduct, product,
+		audit.LimitsState(before), audit.LimitsState(&limits))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanLimits(row scanner) (*core.Limits, error) {
+	var l core.Limits
+	if err := row.Scan(&l.MaxSingle, &l.DailyOutbound, &l.MonthlyOutbound, &l.HourlyCount); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+const caseColumns = `id, account_id, rule, decision, reason, reference, transaction_type, amount, source, status, note, resolved_by, resolved_at, created_at`
+
+// GetAccountActivity returns an account and its transactions since the
+// given time, oldest first, in one snapshot.
+func (r *Repo) GetAccountActivity(ctx context.Context, accountID int, since time.Time) (*core.AccountActivity, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	acc, err := scanAccount(tx.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1`, accountID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	const q = `SELECT ` + transactionColumns + ` FROM transactions
+		WHERE account_id = $1 AND created_at >= $2
+		ORDER BY created_at, id`
+	rows, err := tx.QueryContext(ctx, q, accountID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := &core.AccountActivity{Account: acc}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		activity.Transactions = append(activity.Transactions, t)
+	}
+	return activity, rows.Err()
+}
+
+// ListAccountActivity returns every account with a transaction since
+// activeSince and its transactions since since, in one snapshot.
+func (r *Repo) ListAccountActivity(ctx context.Context, since, activeSince time.Time) ([]*core.AccountActivity, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const active = `SELECT DISTINCT account_id FROM transactions WHERE created_at >= $1`
+	rows, err := tx.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id IN (`+active+`) ORDER BY id`, activeSince)
+	if err != nil {
+		return nil, err
+	}
+	var res []*core.AccountActivity
+	byID := map[int]*core.AccountActivity{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a := &core.AccountActivity{Account: acc}
+		res = append(res, a)
+		byID[acc.ID] = a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const q = `SELECT ` + transactionColumns + ` FROM transactions
+		WHERE account_id IN (` + active + `) AND created_at >= $2
+		ORDER BY account_id, created_at, id`
+	rows, err = tx.QueryContext(ctx, q, activeSince, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		if a := byID[t.AccountID]; a != nil {
+			a.Transactions = append(a.Transactions, t)
+		}
+	}
+	return res, rows.Err()
+}
+
+// OpenCases inserts the cases that are new and audits each one opened.
+func (r *Repo) OpenCases(ctx context.Context, cases ...*core.Case) ([]*core.Case, error) {
+	if len(cases) == 0 {
+		return nil, nil
+	}
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const q = `INSERT INTO monitoring_cases (account_id, rule, decision, reason, reference, transaction_type, amount, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (account_id, rule, reference) DO NOTHING
+		RETURNING ` + caseColumns
+	var opened []*core.Case
+	var entries []core.AuditEntry
+	for _, c := range cases {
+		created, err := scanCase(tx.QueryRowContext(ctx, q, c.AccountID, c.Rule, c.Decision, c.Reason, c.Reference, c.TransactionType, c.Amount, c.Source))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		opened = append(opened, created)
+		entries = append(entries, audit.New(ctx, core.AuditCaseOpen, core.TargetCase, created.ID, nil, created))
+	}
+	if len(opened) == 0 {
+		return nil, nil
+	}
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+func (r *Repo) GetCase(ctx context.Context, id int) (*core.Case, error) {
+	c, err := scanCase(r.db.QueryRowContext(ctx, `SELECT `+caseColumns+` FROM monitoring_cases WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrCaseNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *Repo) ListCases(ctx context.Context, f core.CaseFilter) ([]*core.Case, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Status != "" {
+		where("status = $%d", f.Status)
+	}
+	if f.AccountID > 0 {
+		where("account_id = $%d", f.AccountID)
+	}
+	if f.Rule != "" {
+		where("rule = $%d", f.Rule)
+	}
+	if f.AfterID > 0 {
+		where("id > $%d", f.AfterID)
+	}
+
+	q := `SELECT ` + caseColumns + ` FROM monitoring_cases`
+	if len(conds) > 0 {
+		q += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	q += ` ORDER BY id`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.Case
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// ResolveCase closes an open case, recording who resolved it, and audits
+// the change.
+func (r *Repo) ResolveCase(ctx context.Context, id int, status string, note string) (*core.Case, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanCase(tx.QueryRowContext(ctx, `SELECT `+caseColumns+` FROM monitoring_cases WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrCaseNotFound
+		}
+		return nil, err
+	}
+	if before.Status != core.CaseOpen {
+		return nil, storage.ErrCaseResolved
+	}
+
+	const q = `UPDATE monitoring_cases SET status = $2, note = $3, resolved_by = $4, resolved_at = now()
+		WHERE id = $1 RETURNING ` + caseColumns
+	after, err := scanCase(tx.QueryRowContext(ctx, q, id, status, note, audit.ActorFrom(ctx).String()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditCaseResolve, core.TargetCase, id,
+		audit.CaseState(before), audit.CaseState(after))); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+func scanCase(row scanner) (*core.Case, error) {
+	var c core.Case
+	var note, resolvedBy sql.NullString
+	if err := row.Scan(&c.ID, &c.AccountID, &c.Rule, &c.Decision, &c.Reason, &c.Reference, &c.TransactionType, &c.Amount,
+		&c.Source, &c.Status, &note, &resolvedBy, &c.ResolvedAt, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.Note = note.String
+	c.ResolvedBy = resolvedBy.String
+	return &c, nil
+}
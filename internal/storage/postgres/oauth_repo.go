@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+const oauthClientColumns = `id, owner_user_id, name, secret_hash, redirect_uris, scopes, created_at`
+
+const oauthConsentColumns = `id, user_id, client_id, scopes, granted_at, revoked_at`
+
+func scanOAuthClient(row scanner) (*core.OAuthClient, error) {
+	var c core.OAuthClient
+	var secretHash sql.NullString
+	if err := row.Scan(&c.ID, &c.OwnerUserID, &c.Name, &secretHash,
+		typeMap.SQLScanner(&c.RedirectURIs), typeMap.SQLScanner(&c.Scopes), &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.SecretHash = secretHash.String
+	return &c, nil
+}
+
+func scanOAuthConsent(row scanner) (*core.OAuthConsent, error) {
+	var c core.OAuthConsent
+	if err := row.Scan(&c.ID, &c.UserID, &c.ClientID, typeMap.SQLScanner(&c.Scopes), &c.GrantedAt, &c.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateOAuthClient registers a new OAuth client.
+func (r *Repo) CreateOAuthClient(ctx context.Context, client *core.OAuthClient) (*core.OAuthClient, error) {
+	const q = `INSERT INTO oauth_clients (id, owner_user_id, name, secret_hash, redirect_uris, scopes) VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + oauthClientColumns
+	row := r.db.QueryRowContext(ctx, q, client.ID, client.OwnerUserID, client.Name, nullIfEmpty(client.SecretHash), client.RedirectURIs, client.Scopes)
+	return scanOAuthClient(row)
+}
+
+// GetOAuthClient retrieves an OAuth client by its client_id.
+func (r *Repo) GetOAuthClient(ctx context.Context, id string) (*core.OAuthClient, error) {
+	const q = `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+	client, err := scanOAuthClient(r.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListOAuthClients returns the clients registered by a user.
+func (r *Repo) ListOAuthClients(ctx context.Context, ownerUserID int) ([]*core.OAuthClient, error) {
+	const q = `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE owner_user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, q, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.OAuthClient
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// SaveOAuthConsent records or replaces the scopes a user granted to a client.
+func (r *Repo) SaveOAuthConsent(ctx context.Context, userID int, clientID string, scopes []string) (*core.OAuthConsent, error) {
+	const q = `INSERT INTO oauth_consents (user_id, client_id, scopes) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = now(), revoked_at = NULL
+		RETURNING ` + oauthConsentColumns
+	return scanOAuthConsent(r.db.QueryRowContext(ctx, q, userID, clientID, scopes))
+}
+
+// GetOAuthConsent retrieves the consent a user gave a client.
+func (r *Repo) GetOAuthConsent(ctx context.Context, userID int, clientID string) (*core.OAuthConsent, error) {
+	const q = `SELECT ` + oauthConsentColumns + ` FROM oauth_consents WHERE user_id = $1 AND client_id = $2`
+	consent, err := scanOAuthConsent(r.db.QueryRowContext(ctx, q, userID, clientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrConsentNotFound
+		}
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ListOAuthConsents returns a user's active consents.
+func (r *Repo) ListOAuthConsents(ctx context.Context, userID int) ([]*core.OAuthConsent, error) {
+	const q = `SELECT ` + oauthConsentColumns + ` FROM oauth_consents WHERE user_id = $1 AND revoked_at IS NULL ORDER BY granted_at`
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.OAuthConsent
+	for rows.Next() {
+		c, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// RevokeOAuthConsent withdraws a user's consent for a client.
+func (r *Repo) RevokeOAuthConsent(ctx context.Context, userID int, clientID string) error {
+	const q = `UPDATE oauth_consents SET revoked_at = now() WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, q, userID, clientID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrConsentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+// outboxLockKey is the advisory lock held by the relay that is currently
+// publishing, so that only one instance publishes at a time and events
+// leave in sequence order.
+const outboxLockKey = 0x6f7574626f78 // "outbox"
+
+// outboxSelect reads events with the numbers of the accounts they name.
+// The joins are outer as accounts may since have been deleted.
+const outboxSelect = `SELECT o.id, o.event_id, o.event_type, o.user_id, o.account_id, o.amount, o.balance, o.reference,
+		o.counterparty_account_id, o.occurred_at, a.number, c.number
+	FROM outbox o
+	LEFT JOIN accounts a ON a.id = o.account_id
+	LEFT JOIN accounts c ON c.id = o.counterparty_account_id`
+
+func scanEvent(row scanner) (core.Event, error) {
+	var e core.Event
+	var ref, number, counterpartyNumber sql.NullString
+	if err := row.Scan(&e.Sequence, &e.ID, &e.Type, &e.UserID, &e.AccountID, &e.Amount, &e.Balance,
+		&ref, &e.CounterpartyAccountID, &e.OccurredAt, &number, &counterpartyNumber); err != nil {
+		return core.Event{}, err
+	}
+	e.Reference = ref.String
+	e.AccountNumber = number.String
+	e.CounterpartyAccountNumber = counterpartyNumber.String
+	return e, nil
+}
+
+// writeOutbox records events inside tx so they commit or roll back with
+// the change they describe.
+func writeOutbox(ctx context.Context, tx *sql.Tx, evts ...core.Event) error {
+	const ins = `INSERT INTO outbox (event_id, event_type, user_id, account_id, amount, balance, reference, counterparty_account_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	for _, e := range evts {
+		if _, err := tx.ExecContext(ctx, ins, e.ID, e.Type, e.UserID, e.AccountID, e.Amount, e.Balance,
+			nullIfEmpty(e.Reference), nullInt(e.CounterpartyAccountID), e.OccurredAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelayOutbox publishes the oldest unpublished events while holding the
+// outbox lock. Events for which publish fails stay unpublished and are
+// offered again on the next call.
+func (r *Repo) RelayOutbox(ctx context.Context, limit int, publish func(context.Context, core.Event) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	const q = outboxSelect + ` WHERE o.published_at IS NULL ORDER BY o.id LIMIT $1`
+	rows, err := tx.QueryContext(ctx, q, limit)
+	if err != nil {
+		return 0, err
+	}
+	var pending []core.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var published []int64
+	for _, e := range pending {
+		if err := publish(ctx, e); err != nil {
+			continue
+		}
+		published = append(published, e.Sequence)
+	}
+	if len(published) == 0 {
+		return 0, nil
+	}
+
+	// A crash between publishing and this update republishes the batch,
+	// which is why delivery is at-least-once.
+	const upd = `UPDATE outbox SET published_at = now() WHERE id = ANY($1)`
+	if _, err := tx.ExecContext(ctx, upd, published); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
+
+// PruneOutbox deletes events published before the given time.
+func (r *Repo) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListAccountEvents returns events for an account after the given sequence.
+func (r *Repo) ListAccountEvents(ctx context.Context, accountID int, afterSequence int64, limit int) ([]core.Event, error) {
+	const q = outboxSelect + ` WHERE o.account_id = $1 AND o.id > $2 ORDER BY o.id LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, q, accountID, afterSequence, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []core.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,343 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/events"
+	"mini-bank/internal/storage"
+)
+
+const potColumns = `id, account_id, name, balance, target_amount, target_date, locked_until, round_up, created_at`
+
+// CreatePot opens an empty pot and audits it. The account is locked so
+// that a new round-up pot takes over from the previous one cleanly.
+func (r *Repo) CreatePot(ctx context.Context, p *core.Pot) (*core.Pot, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockAccount(ctx, tx, p.AccountID); err != nil {
+		return nil, err
+	}
+	var entries []core.AuditEntry
+	if p.RoundUp {
+		if entries, err = clearRoundUp(ctx, tx, p.AccountID, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	const q = `INSERT INTO pots (account_id, name, target_amount, target_date, locked_until, round_up)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + potColumns
+	created, err := scanPot(tx.QueryRowContext(ctx, q, p.AccountID, p.Name, p.TargetAmount, p.TargetDate, p.LockedUntil, p.RoundUp))
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, audit.New(ctx, core.AuditPotCreate, core.TargetPot, created.ID, nil, audit.PotState(created)))
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repo) GetPot(ctx context.Context, id int) (*core.Pot, error) {
+	p, err := scanPot(r.db.QueryRowContext(ctx, `SELECT `+potColumns+` FROM pots WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrPotNotFound
+	}
+	return p, err
+}
+
+// ListPots returns an account's pots, oldest first.
+func (r *Repo) ListPots(ctx context.Context, accountID int) ([]*core.Pot, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+potColumns+` FROM pots WHERE account_id = $1 ORDER BY id`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.Pot
+	for rows.Next() {
+		p, err := scanPot(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// UpdatePot changes a pot and audits it. While a pot is locked its lock
+// can only be extended.
+func (r *Repo) UpdatePot(ctx context.Context, id int, upd core.PotUpdate) (*core.Pot, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	accountID, err := potAccount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockAccount(ctx, tx, accountID); err != nil {
+		return nil, err
+	}
+	before, err := lockPot(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upd.LockedUntil != nil && before.Locked(time.Now()) && upd.LockedUntil.Before(*before.LockedUntil) {
+		return nil, storage.ErrPotLocked.WithMessage("a locked pot's lock can only be extended")
+	}
+
+	var entries []core.AuditEntry
+	if upd.RoundUp != nil && *upd.RoundUp {
+		if entries, err = clearRoundUp(ctx, tx, accountID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	const q = `UPDATE pots SET
+			name = COALESCE($2, name),
+			target_amount = COALESCE($3, target_amount),
+			target_date = COALESCE($4, target_date),
+			locked_until = COALESCE($5, locked_until),
+			round_up = COALESCE($6, round_up)
+		WHERE id = $1
+		RETURNING ` + potColumns
+	p, err := scanPot(tx.QueryRowContext(ctx, q, id, upd.Name, upd.TargetAmount, upd.TargetDate, upd.LockedUntil, upd.RoundUp))
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, audit.New(ctx, core.AuditPotUpdate, core.TargetPot, id, audit.PotState(before), audit.PotState(p)))
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeletePot removes an empty pot and audits it. Its past moves stay in
+// the account's transactions.
+func (r *Repo) DeletePot(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockPot(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if before.Balance > 0 {
+		return storage.ErrPotNotEmpty.WithMessage("move the pot's money out before deleting it")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pots WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditPotDelete, core.TargetPot, id, audit.PotState(before), nil)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MovePot moves amount from a pot's account into the pot, or out of the
+// pot back to the account if amount is negative, and books it on the
+// account. Money cannot leave a locked pot. The account is updated before
+// the pot is locked, in the same order as a withdrawal's round-up.
+func (r *Repo) MovePot(ctx context.Context, id int, amount int64, reference string) (*core.Pot, *core.Account, error) {
+	if amount == 0 {
+		return nil, nil, errors.New("amount must not be zero")
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	accountID, err := potAccount(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	const debit = `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND balance >= $1 AND status = 'active' RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, debit, amount, accountID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, whyNotUpdated(ctx, tx, accountID)
+		}
+		return nil, nil, err
+	}
+
+	before, err := lockPot(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if amount < 0 {
+		if before.Locked(time.Now()) {
+			return nil, nil, storage.ErrPotLocked.WithMessage("pot is locked until %s", before.LockedUntil.UTC().Format(time.RFC3339))
+		}
+		if before.Balance < -amount {
+			return nil, nil, storage.ErrInsufficientFunds.WithMessage("pot does not hold enough")
+		}
+	}
+
+	pot, err := scanPot(tx.QueryRowContext(ctx, `UPDATE pots SET balance = balance + $1 WHERE id = $2 RETURNING `+potColumns, amount, id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	move := &core.Transaction{AccountID: accountID, Type: core.TransactionPotIn, Amount: amount, PotID: &id, Reference: reference}
+	if amount < 0 {
+		move.Type, move.Amount = core.TransactionPotOut, -amount
+	}
+	txn, err := insertTransaction(ctx, tx, move)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeOutbox(ctx, tx, events.New(core.EventPotMoved, acc, -amount, reference, nil)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeAudit(ctx, tx, potMoveEntry(ctx, acc, -amount, txn)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return pot, acc, nil
+}
+
+// roundUp saves what rounds a withdrawal of amount up to a whole
+// RoundUpUnit into the account's round-up pot, if it has one and the
+// balance left covers it. acc is the account after the withdrawal, and is
+// returned as it is after the saving along with the pot_in booked for it;
+// that is nil if nothing was saved.
+func roundUp(ctx context.Context, tx *sql.Tx, acc *core.Account, amount int64, reference string) (*core.Account, *core.Transaction, error) {
+	saving := core.RoundUp(amount)
+	if saving == 0 || acc.Balance < saving {
+		return acc, nil, nil
+	}
+
+	var potID int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM pots WHERE account_id = $1 AND round_up FOR UPDATE`, acc.ID).Scan(&potID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return acc, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	saved, err := scanAccount(tx.QueryRowContext(ctx, `UPDATE accounts SET balance = balance - $1 WHERE id = $2 RETURNING `+accountColumns, saving, acc.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE pots SET balance = balance + $1 WHERE id = $2`, saving, potID); err != nil {
+		return nil, nil, err
+	}
+
+	// References are unique per account, so the saving cannot share the
+	// withdrawal's.
+	if reference != "" {
+		reference += "/round-up"
+	}
+	txn, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: acc.ID, Type: core.TransactionPotIn, Amount: saving, PotID: &potID, Reference: reference})
+	if err != nil {
+		return nil, nil, err
+	}
+	return saved, txn, nil
+}
+
+// potMoveEntry audits a pot move that changed the account acc's balance
+// by delta, as booked by txn. The pot is the target.
+func potMoveEntry(ctx context.Context, acc *core.Account, delta int64, txn *core.Transaction) core.AuditEntry {
+	entry := balanceEntry(ctx, core.AuditPotMove, []movement{{acc, delta}}, txn)
+	entry.TargetType, entry.TargetID = core.TargetPot, strconv.Itoa(*txn.PotID)
+	return entry
+}
+
+// clearRoundUp takes the round-up off an account's pots other than
+// except, auditing each one that changes.
+func clearRoundUp(ctx context.Context, tx *sql.Tx, accountID int, except int) ([]core.AuditEntry, error) {
+	const q = `UPDATE pots SET round_up = false WHERE account_id = $1 AND round_up AND id <> $2 RETURNING ` + potColumns
+	rows, err := tx.QueryContext(ctx, q, accountID, except)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []core.AuditEntry
+	for rows.Next() {
+		p, err := scanPot(rows)
+		if err != nil {
+			return nil, err
+		}
+		prev := *p
+		prev.RoundUp = true
+		entries = append(entries, audit.New(ctx, core.AuditPotUpdate, core.TargetPot, p.ID, audit.PotState(&prev), audit.PotState(p)))
+	}
+	return entries, rows.Err()
+}
+
+// potAccount returns the account a pot belongs to.
+func potAccount(ctx context.Context, tx *sql.Tx, id int) (int, error) {
+	var accountID int
+	if err := tx.QueryRowContext(ctx, `SELECT account_id FROM pots WHERE id = $1`, id).Scan(&accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrPotNotFound
+		}
+		return 0, err
+	}
+	return accountID, nil
+}
+
+// lockAccount locks an account's row until the transaction ends.
+func lockAccount(ctx context.Context, tx *sql.Tx, id int) error {
+	var locked int
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrAccountNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// lockPot reads a pot for a change, so the audit log records what
+// changed.
+func lockPot(ctx context.Context, tx *sql.Tx, id int) (*core.Pot, error) {
+	p, err := scanPot(tx.QueryRowContext(ctx, `SELECT `+potColumns+` FROM pots WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrPotNotFound
+	}
+	return p, err
+}
+
+func scanPot(row scanner) (*core.Pot, error) {
+	var p core.Pot
+	if err := row.Scan(&p.ID, &p.AccountID, &p.Name, &p.Balance, &p.TargetAmount, &p.TargetDate, &p.LockedUntil, &p.RoundUp, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+// ledgerAmount is the signed change a transaction row made to its
+// account's balance.
+const ledgerAmount = `CASE
+		WHEN t.type = 'withdraw' THEN -t.amount
+		WHEN t.type = 'transfer' AND t.to_account_id IS NOT NULL THEN -t.amount
+		WHEN t.type = 'pot_in' THEN -t.amount
+		ELSE t.amount
+	END`
+
+// ledgerEntries is every transaction row with the change it made to its
+// account's balance, and whether it moved money between accounts rather
+// than into or out of the bank. Each pot move adds a second entry, with
+// the pot's id, for the opposite change to the pot's balance, so it
+// balances like a transfer.
+const ledgerEntries = `SELECT t.account_id, NULL::INT AS pot_id, t.reference, ` + ledgerAmount + ` AS amount,
+		(t.type IN ('transfer', 'pot_in', 'pot_out') OR COALESCE(o.type, '') = 'transfer') AS internal
+	FROM transactions t
+	LEFT JOIN transactions o ON o.id = t.reverses_id
+	UNION ALL
+	SELECT t.account_id, t.pot_id, t.reference, -(` + ledgerAmount + `), true
+	FROM transactions t
+	WHERE t.type IN ('pot_in', 'pot_out')`
+
+// Reconcile checks the ledger inside one read-only repeatable read
+// transaction, so every check sees the same committed state even while
+// payments continue.
+func (r *Repo) Reconcile(ctx context.Context) (*core.Reconciliation, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rec := &core.Reconciliation{CheckedAt: time.Now().UTC()}
+
+	const accounts = `SELECT COUNT(*), (COALESCE(SUM(balance), 0) + (SELECT COALESCE(SUM(balance), 0) FROM pots))::BIGINT FROM accounts`
+	if err := tx.QueryRowContext(ctx, accounts).Scan(&rec.Accounts, &rec.TotalBalance); err != nil {
+		return nil, err
+	}
+
+	const totals = `SELECT COUNT(*) FILTER (WHERE pot_id IS NULL),
+			COALESCE(SUM(amount) FILTER (WHERE NOT internal), 0)::BIGINT,
+			COALESCE(SUM(amount) FILTER (WHERE internal), 0)::BIGINT
+		FROM (` + ledgerEntries + `) e`
+	if err := tx.QueryRowContext(ctx, totals).Scan(&rec.Transactions, &rec.ExternalNet, &rec.InternalNet); err != nil {
+		return nil, err
+	}
+
+	if rec.Mismatches, err = ledgerMismatches(ctx, tx); err != nil {
+		return nil, err
+	}
+	if rec.ImbalancedTransfers, err = imbalancedTransfers(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ledgerMismatches returns the accounts whose balance differs from the
+// sum of their transactions, and the pots whose balance differs from the
+// sum of their moves.
+func ledgerMismatches(ctx context.Context, tx *sql.Tx) ([]core.LedgerMismatch, error) {
+	const q = `SELECT a.id, NULL::INT, a.balance, COALESCE(l.total, 0)
+		FROM accounts a
+		LEFT JOIN (SELECT account_id, SUM(amount)::BIGINT AS total FROM (` + ledgerEntries + `) e WHERE pot_id IS NULL GROUP BY account_id) l
+			ON l.account_id = a.id
+		WHERE a.balance <> COALESCE(l.total, 0)
+		UNION ALL
+		SELECT p.account_id, p.id, p.balance, COALESCE(l.total, 0)
+		FROM pots p
+		LEFT JOIN (SELECT pot_id, SUM(amount)::BIGINT AS total FROM (` + ledgerEntries + `) e WHERE pot_id IS NOT NULL GROUP BY pot_id) l
+			ON l.pot_id = p.id
+		WHERE p.balance <> COALESCE(l.total, 0)
+		ORDER BY 1, 2 NULLS FIRST`
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []core.LedgerMismatch
+	for rows.Next() {
+		var m core.LedgerMismatch
+		if err := rows.Scan(&m.AccountID, &m.PotID, &m.Balance, &m.LedgerBalance); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, rows.Err()
+}
+
+// imbalancedTransfers returns the transfers, transfer reversals and pot
+// moves whose two legs, which share a reference, do not cancel out. Legs without a
+// reference cannot be paired and only count towards the internal net.
+func imbalancedTransfers(ctx context.Context, tx *sql.Tx) ([]core.TransferImbalance, error) {
+	const q = `SELECT reference, array_agg(DISTINCT account_id ORDER BY account_id), COUNT(*), SUM(amount)::BIGINT
+		FROM (` + ledgerEntries + `) e
+		WHERE internal AND reference IS NOT NULL
+		GROUP BY reference
+		HAVING COUNT(*) <> 2 OR SUM(amount) <> 0
+		ORDER BY reference`
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []core.TransferImbalance
+	for rows.Next() {
+		var t core.TransferImbalance
+		if err := rows.Scan(&t.Reference, typeMap.SQLScanner(&t.AccountIDs), &t.Legs, &t.Net); err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/events"
+	"mini-bank/internal/storage"
+)
+
+const hitColumns = `id, subject_type, subject_id, name, entry_id, entry_name, program, score, decision, list_version, status, note, resolved_by, resolved_at, created_at`
+
+// RecordScreeningHits inserts the hits that are new and audits each one.
+// A new blocking hit on a user freezes their active accounts, with the
+// events and audit entries a freeze by hand would have.
+func (r *Repo) RecordScreeningHits(ctx context.Context, hits ...*core.ScreeningHit) ([]*core.ScreeningHit, error) {
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Hits without a subject never conflict, so every refused signup is
+	// kept.
+	const q = `INSERT INTO screening_hits (subject_type, subject_id, name, entry_id, entry_name, program, score, decision, list_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (subject_type, subject_id, entry_id, name) DO NOTHING
+		RETURNING ` + hitColumns
+	var recorded []*core.ScreeningHit
+	var entries []core.AuditEntry
+	freeze := map[int]bool{}
+	for _, h := range hits {
+		created, err := scanHit(tx.QueryRowContext(ctx, q, h.SubjectType, h.SubjectID, h.Name, h.EntryID, h.EntryName, h.Program,
+			h.Score, h.Decision, h.ListVersion))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		recorded = append(recorded, created)
+		entries = append(entries, audit.New(ctx, core.AuditScreeningHit, core.TargetScreening, created.ID, nil, created))
+		if created.Decision == core.DecisionBlock && created.SubjectType == core.ScreeningSubjectUser && created.SubjectID != nil {
+			freeze[*created.SubjectID] = true
+		}
+	}
+	if len(recorded) == 0 {
+		return nil, nil
+	}
+
+	var evts []core.Event
+	const upd = `UPDATE accounts SET status = $1 WHERE user_id = $2 AND status = $3 RETURNING ` + accountColumns
+	for userID := range freeze {
+		rows, err := tx.QueryContext(ctx, upd, core.AccountFrozen, userID, core.AccountActive)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			acc, err := scanAccount(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			before := *acc
+			before.Status = core.AccountActive
+			evts = append(evts, events.New(core.EventAccountFrozen, acc, 0, "", nil))
+			entries = append(entries, audit.New(ctx, core.AuditAccountFreeze, core.TargetAccount, acc.ID,
+				audit.AccountState(&before), audit.AccountState(acc)))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeOutbox(ctx, tx, evts...); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+func (r *Repo) GetScreeningHit(ctx context.Context, id int) (*core.ScreeningHit, error) {
+	h, err := scanHit(r.db.QueryRowContext(ctx, `SELECT `+hitColumns+` FROM screening_hits WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrHitNotFound
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+func (r *Repo) ListScreeningHits(ctx context.Context, f core.ScreeningHitFilter) ([]*core.ScreeningHit, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Status != "" {
+		where("status = $%d", f.Status)
+	}
+	if f.SubjectType != "" {
+		where("subject_type = $%d", f.SubjectType)
+	}
+	if f.SubjectID > 0 {
+		where("subject_id = $%d", f.SubjectID)
+	}
+	if f.AfterID > 0 {
+		where("id > $%d", f.AfterID)
+	}
+
+	q := `SELECT ` + hitColumns + ` FROM screening_hits`
+	if len(conds) > 0 {
+		q += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	q += ` ORDER BY id`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.ScreeningHit
+	for rows.Next() {
+		h, err := scanHit(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, h)
+	}
+	return res, rows.Err()
+}
+
+// ResolveScreeningHit closes an open hit, recording who resolved it, and
+// audits the change. Clearing a blocking hit does not unfreeze accounts;
+// that is done separately once the customer checks out.
+func (r *Repo) ResolveScreeningHit(ctx context.Context, id int, status string, note string) (*core.ScreeningHit, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanHit(tx.QueryRowContext(ctx, `SELECT `+hitColumns+` FROM screening_hits WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrHitNotFound
+		}
+		return nil, err
+	}
+	if before.Status != core.CaseOpen {
+		return nil, storage.ErrCaseResolved.WithMessage("screening hit has already been resolved")
+	}
+
+	const q = `UPDATE screening_hits SET status = $2, note = $3, resolved_by = $4, resolved_at = now()
+		WHERE id = $1 RETURNING ` + hitColumns
+	after, err := scanHit(tx.QueryRowContext(ctx, q, id, status, note, audit.ActorFrom(ctx).String()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditHitResolve, core.TargetScreening, id,
+		audit.HitState(before), audit.HitState(after))); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+func scanHit(row scanner) (*core.ScreeningHit, error) {
+	var h core.ScreeningHit
+	var subjectID sql.NullInt64
+	var note, resolvedBy sql.NullString
+	if err := row.Scan(&h.ID, &h.SubjectType, &subjectID, &h.Name, &h.EntryID, &h.EntryName, &h.Program, &h.Score, &h.Decision,
+		&h.ListVersion, &h.Status, &note, &resolvedBy, &h.ResolvedAt, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	if subjectID.Valid {
+		id := int(subjectID.Int64)
+		h.SubjectID = &id
+	}
+	h.Note = note.String
+	h.ResolvedBy = resolvedBy.String
+	return &h, nil
+}
@@ -0,0 +1,413 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mini-bank/internal/audit"
+	"mini-bank/internal/core"
+	"mini-bank/internal/events"
+	"mini-bank/internal/storage"
+)
+
+const termDepositColumns = `id, user_id, account_id, payout_account_id, principal, term_months, rate_bps, break_rate_bps,
+	rollover, status, started_at, matures_at, interest_paid, closed_at, created_at`
+
+// TermDepositRates returns the terms offered, shortest first.
+func (r *Repo) TermDepositRates(ctx context.Context) ([]*core.TermDepositRate, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT term_months, rate_bps, break_rate_bps FROM term_deposit_rates ORDER BY term_months`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.TermDepositRate
+	for rows.Next() {
+		var rate core.TermDepositRate
+		if err := rows.Scan(&rate.TermMonths, &rate.RateBPS, &rate.BreakRateBPS); err != nil {
+			return nil, err
+		}
+		res = append(res, &rate)
+	}
+	return res, rows.Err()
+}
+
+// SetTermDepositRate offers a term at new rates and audits the change.
+func (r *Repo) SetTermDepositRate(ctx context.Context, rate core.TermDepositRate) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := termRate(ctx, tx, rate.TermMonths)
+	if err != nil && !errors.Is(err, storage.ErrTermNotOffered) {
+		return err
+	}
+
+	const q = `INSERT INTO term_deposit_rates (term_months, rate_bps, break_rate_bps) VALUES ($1, $2, $3)
+		ON CONFLICT (term_months) DO UPDATE SET rate_bps = EXCLUDED.rate_bps, break_rate_bps = EXCLUDED.break_rate_bps`
+	if _, err := tx.ExecContext(ctx, q, rate.TermMonths, rate.RateBPS, rate.BreakRateBPS); err != nil {
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditRateUpdate, core.TargetTermRate, rate.TermMonths,
+		audit.RateState(before), audit.RateState(&rate))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// OpenTermDeposit opens a fixed_deposit account for the deposit and funds
+// it with a transfer from the payout account, which counts towards that
+// account's limits. The owner is locked first, as CreateAccount does.
+func (r *Repo) OpenTermDeposit(ctx context.Context, d *core.TermDeposit, reference string, tierLimits core.Limits) (*core.TermDeposit, error) {
+	if d.Principal <= 0 {
+		return nil, errors.New("principal must be positive")
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockUser(ctx, tx, d.UserID); err != nil {
+		return nil, err
+	}
+	rate, err := termRate(ctx, tx, d.TermMonths)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLimits(ctx, tx, d.PayoutAccountID, d.Principal, tierLimits); err != nil {
+		return nil, err
+	}
+
+	acc, err := insertAccount(ctx, tx, &core.Account{
+		UserID:  d.UserID,
+		Product: core.ProductFixedDeposit,
+		Name:    fmt.Sprintf("%d-month term deposit", d.TermMonths),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := transfer(ctx, tx, d.PayoutAccountID, acc.ID, d.Principal, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	const q = `INSERT INTO term_deposits (user_id, account_id, payout_account_id, principal, term_months, rate_bps, break_rate_bps, rollover, started_at, matures_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + termDepositColumns
+	opened, err := scanTermDeposit(tx.QueryRowContext(ctx, q, d.UserID, acc.ID, d.PayoutAccountID, d.Principal, d.TermMonths,
+		rate.RateBPS, rate.BreakRateBPS, d.Rollover, start, core.MaturityDate(start, d.TermMonths)))
+	if err != nil {
+		return nil, err
+	}
+
+	evts := append([]core.Event{events.New(core.EventAccountCreated, acc, 0, "", nil)}, m.events()...)
+	if err := writeOutbox(ctx, tx, evts...); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx,
+		audit.New(ctx, core.AuditAccountCreate, core.TargetAccount, acc.ID, nil, map[string]any{"account": audit.AccountState(acc)}),
+		m.entry(ctx),
+		audit.New(ctx, core.AuditDepositOpen, core.TargetTermDeposit, opened.ID, nil, audit.TermDepositState(opened)),
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+func (r *Repo) GetTermDeposit(ctx context.Context, id int) (*core.TermDeposit, error) {
+	d, err := scanTermDeposit(r.db.QueryRowContext(ctx, `SELECT `+termDepositColumns+` FROM term_deposits WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrTermDepositNotFound
+	}
+	return d, err
+}
+
+// ListTermDeposits returns a user's term deposits, newest first.
+func (r *Repo) ListTermDeposits(ctx context.Context, userID int) ([]*core.TermDeposit, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+termDepositColumns+` FROM term_deposits WHERE user_id = $1 ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.TermDeposit
+	for rows.Next() {
+		d, err := scanTermDeposit(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// SetTermDepositRollover changes whether an active deposit rolls over and
+// audits it.
+func (r *Repo) SetTermDepositRollover(ctx context.Context, id int, rollover bool) (*core.TermDeposit, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTermDeposit(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if before.Status != core.TermDepositActive {
+		return nil, storage.ErrTermDepositClosed
+	}
+
+	d, err := scanTermDeposit(tx.QueryRowContext(ctx, `UPDATE term_deposits SET rollover = $2 WHERE id = $1 RETURNING `+termDepositColumns, id, rollover))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditDepositUpdate, core.TargetTermDeposit, id,
+		audit.TermDepositState(before), audit.TermDepositState(d))); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DueTermDeposits returns the IDs of the active deposits matured by now.
+func (r *Repo) DueTermDeposits(ctx context.Context, now time.Time) ([]int, error) {
+	const q = `SELECT id FROM term_deposits WHERE status = 'active' AND matures_at <= $1 ORDER BY matures_at, id`
+	rows, err := r.db.QueryContext(ctx, q, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MatureTermDeposit credits a matured deposit's interest to its account.
+// A deposit that rolls over then starts its next term where the last one
+// ended, at the rates now offered, unless its term no longer is; any
+// other deposit pays everything in its account out to the payout
+// account. The deposit is locked first, so a deposit is only ever matured
+// once however many instances run the job.
+func (r *Repo) MatureTermDeposit(ctx context.Context, id int, reference string) (*core.TermDeposit, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTermDeposit(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if before.Status != core.TermDepositActive {
+		return nil, storage.ErrTermDepositClosed
+	}
+	now := time.Now()
+	if now.Before(before.MaturesAt) {
+		return nil, fmt.Errorf("term deposit %d does not mature until %s", id, before.MaturesAt.UTC().Format(time.RFC3339))
+	}
+
+	interest := before.Interest()
+	s, err := settleInterest(ctx, tx, before, interest, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	after.InterestPaid += interest
+	rate, err := termRate(ctx, tx, before.TermMonths)
+	switch {
+	case before.Rollover && err == nil:
+		after.Principal += interest
+		after.RateBPS, after.BreakRateBPS = rate.RateBPS, rate.BreakRateBPS
+		after.StartedAt = before.MaturesAt
+		after.MaturesAt = core.MaturityDate(after.StartedAt, after.TermMonths)
+	case err != nil && !errors.Is(err, storage.ErrTermNotOffered):
+		return nil, err
+	default:
+		if err := s.payOut(ctx, tx, before.PayoutAccountID, reference); err != nil {
+			return nil, err
+		}
+		after.Status, after.ClosedAt = core.TermDepositMatured, &now
+	}
+
+	return s.finish(ctx, tx, before, &after, core.AuditDepositMature)
+}
+
+// BreakTermDeposit pays out an active deposit early. Interest is paid at
+// the break rate for the UTC calendar days held. A deposit already due is left
+// to mature instead.
+func (r *Repo) BreakTermDeposit(ctx context.Context, id int, reference string) (*core.TermDeposit, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTermDeposit(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if before.Status != core.TermDepositActive {
+		return nil, storage.ErrTermDepositClosed
+	}
+	now := time.Now()
+	if !now.Before(before.MaturesAt) {
+		return nil, storage.ErrTermDepositClosed.WithMessage("term deposit has matured and is being paid out")
+	}
+
+	interest := before.BreakInterest(now)
+	s, err := settleInterest(ctx, tx, before, interest, reference)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.payOut(ctx, tx, before.PayoutAccountID, reference); err != nil {
+		return nil, err
+	}
+
+	after := *before
+	after.InterestPaid += interest
+	after.Status, after.ClosedAt = core.TermDepositBroken, &now
+	return s.finish(ctx, tx, before, &after, core.AuditDepositBreak)
+}
+
+// settlement collects what maturing or breaking a deposit did, to be
+// announced and audited when it is saved.
+type settlement struct {
+	// acc is the deposit's account as it is now.
+	acc     *core.Account
+	events  []core.Event
+	entries []core.AuditEntry
+}
+
+// settleInterest credits interest to a deposit's account and books it;
+// nothing is booked if there is none. The account is locked either way.
+func settleInterest(ctx context.Context, tx *sql.Tx, d *core.TermDeposit, interest int64, reference string) (*settlement, error) {
+	acc, err := scanAccount(tx.QueryRowContext(ctx, `UPDATE accounts SET balance = balance + $1 WHERE id = $2 RETURNING `+accountColumns, interest, d.AccountID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	s := &settlement{acc: acc}
+	if interest == 0 {
+		return s, nil
+	}
+
+	// References are unique per account, so the interest cannot share the
+	// payout's.
+	reference += "/interest"
+	txn, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: acc.ID, Type: core.TransactionInterest, Amount: interest, Reference: reference})
+	if err != nil {
+		return nil, err
+	}
+	s.events = append(s.events, events.New(core.EventInterestPaid, acc, interest, reference, nil))
+	s.entries = append(s.entries, balanceEntry(ctx, core.AuditInterest, []movement{{acc, interest}}, txn))
+	return s, nil
+}
+
+// payOut transfers everything in the deposit's account to the payout
+// account. A frozen account on either side stops it, leaving the deposit
+// active.
+func (s *settlement) payOut(ctx context.Context, tx *sql.Tx, payoutAccountID int, reference string) error {
+	if s.acc.Balance == 0 {
+		return nil
+	}
+	m, err := transfer(ctx, tx, s.acc.ID, payoutAccountID, s.acc.Balance, reference)
+	if err != nil {
+		return err
+	}
+	s.acc = m.from
+	s.events = append(s.events, m.events()...)
+	s.entries = append(s.entries, m.entry(ctx))
+	return nil
+}
+
+// finish saves the deposit as after, audits the change under action along
+// with the money moved, and commits.
+func (s *settlement) finish(ctx context.Context, tx *sql.Tx, before, after *core.TermDeposit, action string) (*core.TermDeposit, error) {
+	const q = `UPDATE term_deposits SET principal = $2, rate_bps = $3, break_rate_bps = $4, status = $5,
+			started_at = $6, matures_at = $7, interest_paid = $8, closed_at = $9
+		WHERE id = $1
+		RETURNING ` + termDepositColumns
+	d, err := scanTermDeposit(tx.QueryRowContext(ctx, q, after.ID, after.Principal, after.RateBPS, after.BreakRateBPS, after.Status,
+		after.StartedAt, after.MaturesAt, after.InterestPaid, after.ClosedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.events) > 0 {
+		if err := writeOutbox(ctx, tx, s.events...); err != nil {
+			return nil, err
+		}
+	}
+	entries := append(s.entries, audit.New(ctx, action, core.TargetTermDeposit, d.ID, audit.TermDepositState(before), audit.TermDepositState(d)))
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// termRate reads the rates offered on a term, keeping them from changing
+// until the transaction ends.
+func termRate(ctx context.Context, tx *sql.Tx, months int) (*core.TermDepositRate, error) {
+	rate := core.TermDepositRate{TermMonths: months}
+	err := tx.QueryRowContext(ctx, `SELECT rate_bps, break_rate_bps FROM term_deposit_rates WHERE term_months = $1 FOR SHARE`, months).
+		Scan(&rate.RateBPS, &rate.BreakRateBPS)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrTermNotOffered
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// lockTermDeposit reads a term deposit for a change, so the audit log
+// records what changed.
+func lockTermDeposit(ctx context.Context, tx *sql.Tx, id int) (*core.TermDeposit, error) {
+	d, err := scanTermDeposit(tx.QueryRowContext(ctx, `SELECT `+termDepositColumns+` FROM term_deposits WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrTermDepositNotFound
+	}
+	return d, err
+}
+
+func scanTermDeposit(row scanner) (*core.TermDeposit, error) {
+	var d core.TermDeposit
+	if err := row.Scan(&d.ID, &d.UserID, &d.AccountID, &d.PayoutAccountID, &d.Principal, &d.TermMonths, &d.RateBPS, &d.BreakRateBPS,
+		&d.Rollover, &d.Status, &d.StartedAt, &d.MaturesAt, &d.InterestPaid, &d.ClosedAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -5,9 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
+	"mini-bank/internal/audit"
 	"mini-bank/internal/core"
+	"mini-bank/internal/events"
 	"mini-bank/internal/storage"
 
 	"github.com/jackc/pgx/v5/pgconn"
@@ -24,17 +25,140 @@ func NewRepo(db *DB) *Repo {
 	return &Repo{db: db}
 }
 
-// CreateAccount creates a new account
-func (r *Repo) CreateAccount(ctx context.Context, userID int, balance int64) (*core.Account, error) {
-	const q = `INSERT INTO accounts (user_id, balance) VALUES ($1, $2) RETURNING id, user_id, balance, created_at`
-	row := r.db.QueryRowContext(ctx, q, userID, balance)
-	return scanAccount(row)
+// CreateAccount creates a new account. The owner is locked so that two
+// accounts opened at once cannot both become primary.
+func (r *Repo) CreateAccount(ctx context.Context, acc *core.Account) (*core.Account, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockUser(ctx, tx, acc.UserID); err != nil {
+		return nil, err
+	}
+	balance := acc.Balance
+	acc, err = insertAccount(ctx, tx, acc)
+	if err != nil {
+		return nil, err
+	}
+
+	// An opening balance is a deposit, so the ledger accounts for it.
+	var txns []*core.Transaction
+	if balance > 0 {
+		txn, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: acc.ID, Type: core.TransactionDeposit, Amount: balance})
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, txn)
+	}
+
+	if err := writeOutbox(ctx, tx, events.New(core.EventAccountCreated, acc, balance, "", nil)); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditAccountCreate, core.TargetAccount, acc.ID, nil,
+		map[string]any{"account": audit.AccountState(acc), "transactions": audit.TransactionStates(txns...)})); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return acc, nil
 }
 
+// UpdateAccount renames an account and makes it primary, auditing each
+// account that changes. The owner is locked first, as CreateAccount does.
+func (r *Repo) UpdateAccount(ctx context.Context, id int, name *string, primary bool) (*core.Account, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	if err := tx.QueryRowContext(ctx, `SELECT user_id FROM accounts WHERE id = $1`, id).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if _, err := lockUser(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	before, err := scanAccount(tx.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	var entries []core.AuditEntry
+	if primary && !before.Primary {
+		const demote = `UPDATE accounts SET is_primary = false WHERE user_id = $1 AND is_primary RETURNING ` + accountColumns
+		old, err := scanAccount(tx.QueryRowContext(ctx, demote, userID))
+		switch {
+		case err == nil:
+			prev := *old
+			prev.Primary = true
+			entries = append(entries, audit.New(ctx, core.AuditAccountUpdate, core.TargetAccount, old.ID, audit.AccountState(&prev), audit.AccountState(old)))
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+	}
+
+	const upd = `UPDATE accounts SET name = COALESCE($2, name), is_primary = is_primary OR $3 WHERE id = $1 RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, upd, id, name, primary))
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, audit.New(ctx, core.AuditAccountUpdate, core.TargetAccount, id, audit.AccountState(before), audit.AccountState(acc)))
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+const accountColumns = `id, number, user_id, balance, status, product, name, is_primary, created_at`
+
+// numberAttempts caps how many random account numbers are tried for a new
+// account before giving up.
+const numberAttempts = 5
+
+// insertAccount creates an account under a fresh account number, making
+// it primary if it is its owner's first current account. Numbers are
+// random, so the rare one already taken is replaced with another.
+func insertAccount(ctx context.Context, tx *sql.Tx, acc *core.Account) (*core.Account, error) {
+	const q = `INSERT INTO accounts (number, user_id, balance, product, name, is_primary)
+		VALUES ($1, $2, $3, $4, $5, $4 = 'current' AND NOT EXISTS (SELECT 1 FROM accounts WHERE user_id = $2 AND is_primary))
+		ON CONFLICT (number) DO NOTHING
+		RETURNING ` + accountColumns
+	for range numberAttempts {
+		created, err := scanAccount(tx.QueryRowContext(ctx, q, core.NewAccountNumber(), acc.UserID, acc.Balance, acc.Product, acc.Name))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, storage.ErrProductNotFound
+		}
+		return created, err
+	}
+	return nil, errors.New("failed to find an unused account number")
+}
+
+const transactionColumns = `id, account_id, type, amount, reference, from_account_id, to_account_id, reason, reverses_id, pot_id, created_at`
+
 // Helper to scan account
 func scanAccount(row scanner) (*core.Account, error) {
 	var a core.Account
-	if err := row.Scan(&a.ID, &a.UserID, &a.Balance, &a.CreatedAt); err != nil {
+	if err := row.Scan(&a.ID, &a.Number, &a.UserID, &a.Balance, &a.Status, &a.Product, &a.Name, &a.Primary, &a.CreatedAt); err != nil {
 		return nil, err
 	}
 	return &a, nil
@@ -42,9 +166,12 @@ func scanAccount(row scanner) (*core.Account, error) {
 
 func scanTransaction(row scanner) (*core.Transaction, error) {
 	var t core.Transaction
-	if err := row.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount, &t.Reference, &t.FromAccountID, &t.ToAccountID, &t.Timestamp); err != nil {
+	var ref, reason sql.NullString
+	if err := row.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount, &ref, &t.FromAccountID, &t.ToAccountID, &reason, &t.ReversesID, &t.PotID, &t.Timestamp); err != nil {
 		return nil, err
 	}
+	t.Reference = ref.String
+	t.Reason = reason.String
 	return &t, nil
 }
 
@@ -62,7 +189,7 @@ type scanner interface {
 
 // GetAccount retrieves an account by id
 func (r *Repo) GetAccount(ctx context.Context, id int) (*core.Account, error) {
-	const q = `SELECT id, user_id, balance, created_at FROM accounts WHERE id = $1`
+	const q = `SELECT ` + accountColumns + ` FROM accounts WHERE id = $1`
 	row := r.db.QueryRowContext(ctx, q, id)
 	acc, err := scanAccount(row)
 	if err != nil {
@@ -74,9 +201,46 @@ func (r *Repo) GetAccount(ctx context.Context, id int) (*core.Account, error) {
 	return acc, nil
 }
 
+// GetAccountByNumber retrieves an account by its account number
+func (r *Repo) GetAccountByNumber(ctx context.Context, number string) (*core.Account, error) {
+	const q = `SELECT ` + accountColumns + ` FROM accounts WHERE number = $1`
+	acc, err := scanAccount(r.db.QueryRowContext(ctx, q, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return acc, nil
+}
+
+// AccountNumbers maps account ids to their account numbers, leaving out
+// ids with no account.
+func (r *Repo) AccountNumbers(ctx context.Context, ids []int) (map[int]string, error) {
+	numbers := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return numbers, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT id, number FROM accounts WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var number string
+		if err := rows.Scan(&id, &number); err != nil {
+			return nil, err
+		}
+		numbers[id] = number
+	}
+	return numbers, rows.Err()
+}
+
 // ListAccounts returns all accounts
 func (r *Repo) ListAccounts(ctx context.Context) ([]*core.Account, error) {
-	const q = `SELECT id, user_id, balance, created_at FROM accounts ORDER BY id`
+	const q = `SELECT ` + accountColumns + ` FROM accounts ORDER BY id`
 	rows, err := r.db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
@@ -107,29 +271,39 @@ func (r *Repo) Deposit(ctx context.Context, accountID int, amount int64, referen
 	defer tx.Rollback()
 
 	// Update balance and return account details
-	const upd = `UPDATE accounts SET balance = balance + $1 WHERE id = $2 RETURNING id, user_id, balance, created_at`
-	var acc core.Account
-	if err := tx.QueryRowContext(ctx, upd, amount, accountID).Scan(&acc.ID, &acc.UserID, &acc.Balance, &acc.CreatedAt); err != nil {
+	const upd = `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND status = 'active' RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, upd, amount, accountID))
+	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, storage.ErrAccountNotFound
+			return nil, whyNotUpdated(ctx, tx, accountID)
 		}
 		return nil, err
 	}
 
 	// Insert transaction
-	const ins = `INSERT INTO transactions (account_id, type, amount, reference, created_at) VALUES ($1, $2, $3, $4, $5)`
-	if _, err := tx.ExecContext(ctx, ins, accountID, "deposit", amount, nullIfEmpty(reference), time.Now().UTC()); err != nil {
+	txn, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: accountID, Type: core.TransactionDeposit, Amount: amount, Reference: reference})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeOutbox(ctx, tx, events.New(core.EventDeposit, acc, amount, reference, nil)); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, balanceEntry(ctx, core.AuditDeposit, []movement{{acc, amount}}, txn)); err != nil {
 		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return &acc, nil
+	return acc, nil
 }
 
 // Withdraw performs an atomic withdrawal and returns the updated account.
-func (r *Repo) Withdraw(ctx context.Context, accountID int, amount int64, reference string) (*core.Account, error) {
+// If the account has a round-up pot, the round-up is saved into it in the
+// same transaction.
+func (r *Repo) Withdraw(ctx context.Context, accountID int, amount int64, reference string, tierLimits core.Limits) (*core.Account, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be positive")
 	}
@@ -140,29 +314,45 @@ func (r *Repo) Withdraw(ctx context.Context, accountID int, amount int64, refere
 	}
 	defer tx.Rollback()
 
+	if err := checkLimits(ctx, tx, accountID, amount, tierLimits); err != nil {
+		return nil, err
+	}
+
 	// Attempt to debit if sufficient funds exist; RETURNING gives new account details
-	const debit = `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND balance >= $1 RETURNING id, user_id, balance, created_at`
-	var acc core.Account
-	if err := tx.QueryRowContext(ctx, debit, amount, accountID).Scan(&acc.ID, &acc.UserID, &acc.Balance, &acc.CreatedAt); err != nil {
+	const debit = `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND balance >= $1 AND status = 'active' RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRowContext(ctx, debit, amount, accountID))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			// The atomic update failed. Let's find out why.
-			var exists bool
-			// Check if the account exists at all.
-			err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM accounts WHERE id=$1)`, accountID).Scan(&exists)
-			if err != nil {
-				return nil, err // A different database error occurred
-			}
-			if !exists {
-				return nil, storage.ErrAccountNotFound
-			}
-			return nil, storage.ErrInsufficientFunds
+			return nil, whyNotUpdated(ctx, tx, accountID)
 		}
 		return nil, err
 	}
 
 	// Insert transaction record
-	const ins = `INSERT INTO transactions (account_id, type, amount, reference, created_at) VALUES ($1, $2, $3, $4, $5)`
-	if _, err := tx.ExecContext(ctx, ins, accountID, "withdraw", amount, nullIfEmpty(reference), time.Now().UTC()); err != nil {
+	txn, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: accountID, Type: core.TransactionWithdraw, Amount: amount, Reference: reference})
+	if err != nil {
+		return nil, err
+	}
+
+	evts := []core.Event{events.New(core.EventWithdrawal, acc, amount, reference, nil)}
+	entries := []core.AuditEntry{balanceEntry(ctx, core.AuditWithdrawal, []movement{{acc, -amount}}, txn)}
+
+	saved, saving, err := roundUp(ctx, tx, acc, amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	if saving != nil {
+		acc = saved
+		evts = append(evts, events.New(core.EventPotMoved, acc, -saving.Amount, saving.Reference, nil))
+		entries = append(entries, potMoveEntry(ctx, acc, -saving.Amount, saving))
+	}
+
+	if err := writeOutbox(ctx, tx, evts...); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(ctx, tx, entries...); err != nil {
 		return nil, err
 	}
 
@@ -170,7 +360,7 @@ func (r *Repo) Withdraw(ctx context.Context, accountID int, amount int64, refere
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return &acc, nil
+	return acc, nil
 }
 
 // RecordTransaction is a more generic method to append a transaction to the log.
@@ -185,7 +375,7 @@ func (r *Repo) RecordTransaction(ctx context.Context, txn *core.Transaction) err
 
 // ListTransactions returns transactions for an account
 func (r *Repo) ListTransactions(ctx context.Context, accountID int) ([]*core.Transaction, error) {
-	const q = `SELECT id, account_id, type, amount, reference, from_account_id, to_account_id, created_at FROM transactions WHERE account_id = $1 ORDER BY created_at DESC`
+	const q = `SELECT ` + transactionColumns + ` FROM transactions WHERE account_id = $1 ORDER BY created_at DESC`
 	rows, err := r.db.QueryContext(ctx, q, accountID)
 	if err != nil {
 		return nil, err
@@ -194,38 +384,17 @@ func (r *Repo) ListTransactions(ctx context.Context, accountID int) ([]*core.Tra
 
 	var res []*core.Transaction
 	for rows.Next() {
-		var t core.Transaction
-		var from sql.NullInt64
-		var to sql.NullInt64
-		var ref sql.NullString
-		if err := rows.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount, &ref, &from, &to, &t.Timestamp); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
-		if ref.Valid {
-			t.Reference = ref.String
-		}
-		if from.Valid {
-			v := int(from.Int64)
-			t.FromAccountID = &v
-		}
-		if to.Valid {
-			v := int(to.Int64)
-			t.ToAccountID = &v
-		}
-		res = append(res, &t)
+		res = append(res, t)
 	}
 	return res, rows.Err()
 }
 
-// UpdateBalance updates an account's balance.
-func (r *Repo) UpdateBalance(ctx context.Context, id int, newBalance int64) error {
-	const q = `UPDATE accounts SET balance = $1 WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, q, newBalance, id)
-	return err
-}
-
 // Transfer performs a transactional transfer between two accounts.
-func (r *Repo) Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error) {
+func (r *Repo) Transfer(ctx context.Context, fromID, toID int, amount int64, reference string, tierLimits core.Limits) (*core.Account, *core.Account, error) {
 	if amount <= 0 {
 		return nil, nil, errors.New("amount must be positive")
 	}
@@ -236,61 +405,121 @@ func (r *Repo) Transfer(ctx context.Context, fromID, toID int, amount int64, ref
 	}
 	defer tx.Rollback()
 
+	if err := checkLimits(ctx, tx, fromID, amount, tierLimits); err != nil {
+		return nil, nil, err
+	}
+
+	m, err := transfer(ctx, tx, fromID, toID, amount, reference)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeOutbox(ctx, tx, m.events()...); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeAudit(ctx, tx, m.entry(ctx)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return m.from, m.to, nil
+}
+
+// transferred is money moved between two accounts by transfer.
+type transferred struct {
+	from, to       *core.Account
+	sent, received *core.Transaction
+	amount         int64
+	reference      string
+}
+
+// transfer moves amount between two active accounts inside tx and books a
+// leg on each, sharing the reference. The sender must hold the amount;
+// limits are the caller's to check.
+func transfer(ctx context.Context, tx *sql.Tx, fromID, toID int, amount int64, reference string) (*transferred, error) {
 	// Withdraw from sender
-	const debit = `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND balance >= $1 RETURNING id, user_id, balance, created_at`
-	var fromAcc core.Account
-	if err := tx.QueryRowContext(ctx, debit, amount, fromID).Scan(&fromAcc.ID, &fromAcc.UserID, &fromAcc.Balance, &fromAcc.CreatedAt); err != nil {
+	const debit = `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND balance >= $1 AND status = 'active' RETURNING ` + accountColumns
+	fromAcc, err := scanAccount(tx.QueryRowContext(ctx, debit, amount, fromID))
+	if err != nil {
 		if err == sql.ErrNoRows {
-			// This could mean insufficient funds or the account doesn't exist.
-			// A more robust implementation could check for existence first.
-			return nil, nil, storage.ErrInsufficientFunds
+			return nil, whyNotUpdated(ctx, tx, fromID)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Deposit to receiver
-	const credit = `UPDATE accounts SET balance = balance + $1 WHERE id = $2 RETURNING id, user_id, balance, created_at`
-	var toAcc core.Account
-	if err := tx.QueryRowContext(ctx, credit, amount, toID).Scan(&toAcc.ID, &toAcc.UserID, &toAcc.Balance, &toAcc.CreatedAt); err != nil {
+	const credit = `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND status = 'active' RETURNING ` + accountColumns
+	toAcc, err := scanAccount(tx.QueryRowContext(ctx, credit, amount, toID))
+	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil, storage.ErrAccountNotFound
+			return nil, whyNotUpdated(ctx, tx, toID)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Record transaction for sender
-	const insFrom = `INSERT INTO transactions (account_id, type, amount, to_account_id, reference, created_at) VALUES ($1, 'transfer', $2, $3, $4, $5)`
-	if _, err := tx.ExecContext(ctx, insFrom, fromID, amount, toID, nullIfEmpty(reference), time.Now().UTC()); err != nil {
-		return nil, nil, err
+	sent, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: fromID, Type: core.TransactionTransfer, Amount: amount, ToAccountID: &toID, Reference: reference})
+	if err != nil {
+		return nil, err
 	}
 
 	// Record transaction for receiver
-	const insTo = `INSERT INTO transactions (account_id, type, amount, from_account_id, reference, created_at) VALUES ($1, 'transfer', $2, $3, $4, $5)`
-	if _, err := tx.ExecContext(ctx, insTo, toID, amount, fromID, nullIfEmpty(reference), time.Now().UTC()); err != nil {
-		return nil, nil, err
+	received, err := insertTransaction(ctx, tx, &core.Transaction{AccountID: toID, Type: core.TransactionTransfer, Amount: amount, FromAccountID: &fromID, Reference: reference})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, nil, err
+	return &transferred{from: fromAcc, to: toAcc, sent: sent, received: received, amount: amount, reference: reference}, nil
+}
+
+// events are the outbox events announcing the transfer to both sides.
+func (m *transferred) events() []core.Event {
+	return []core.Event{
+		events.New(core.EventTransferSent, m.from, m.amount, m.reference, &m.to.ID),
+		events.New(core.EventTransferReceived, m.to, m.amount, m.reference, &m.from.ID),
 	}
+}
+
+// entry audits the transfer, with the sender as the target.
+func (m *transferred) entry(ctx context.Context) core.AuditEntry {
+	return balanceEntry(ctx, core.AuditTransfer, []movement{{m.from, -m.amount}, {m.to, m.amount}}, m.sent, m.received)
+}
 
-	return &fromAcc, &toAcc, nil
+// whyNotUpdated explains why a conditional balance update matched no row:
+// the account does not exist, is frozen or, for a debit, lacks funds.
+func whyNotUpdated(ctx context.Context, tx *sql.Tx, accountID int) error {
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM accounts WHERE id = $1`, accountID).Scan(&status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return storage.ErrAccountNotFound
+	case err != nil:
+		return err
+	case status == core.AccountFrozen:
+		return storage.ErrAccountFrozen
+	default:
+		return storage.ErrInsufficientFunds
+	}
 }
 
 // Payment performs a deposit or withdrawal and returns the updated account.
-func (r *Repo) Payment(ctx context.Context, accountID int, amount int64, paymentType storage.PaymentType, reference string) (*core.Account, error) {
+func (r *Repo) Payment(ctx context.Context, accountID int, amount int64, paymentType storage.PaymentType, reference string, tierLimits core.Limits) (*core.Account, error) {
 	switch paymentType {
 	case storage.Deposit:
 		return r.Deposit(ctx, accountID, amount, reference)
 	case storage.Withdraw:
-		return r.Withdraw(ctx, accountID, amount, reference)
+		return r.Withdraw(ctx, accountID, amount, reference, tierLimits)
 	default:
 		return nil, fmt.Errorf("unknown payment type: %s", paymentType)
 	}
 }
 
 func (r *Repo) GetTransaction(ctx context.Context, ref string) (*core.Transaction, error) {
-	const q = `SELECT id, account_id, type, amount, reference, from_account_id, to_account_id, created_at FROM transactions WHERE reference = $1`
+	const q = `SELECT ` + transactionColumns + ` FROM transactions WHERE reference = $1 ORDER BY id LIMIT 1`
 
 	row := r.db.QueryRowContext(ctx, q, ref)
 	trx, err := scanTransaction(row)
@@ -307,9 +536,15 @@ func (r *Repo) GetTransaction(ctx context.Context, ref string) (*core.Transactio
 func (r *Repo) CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error) {
 	const ins = `INSERT INTO users (first_name, last_name, email, password) VALUES ($1, $2, $3, $4) RETURNING id`
 
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	var id int
 
-	row := r.db.QueryRowContext(ctx, ins, firstName, lastName, email, password)
+	row := tx.QueryRowContext(ctx, ins, firstName, lastName, email, password)
 	if err := row.Scan(&id); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -323,7 +558,15 @@ func (r *Repo) CreateUser(ctx context.Context, firstName string, lastName string
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return &core.User{ID: id, FirstName: firstName, LastName: lastName, Email: email}, nil
+	user := &core.User{ID: id, FirstName: firstName, LastName: lastName, Email: email}
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditUserCreate, core.TargetUser, id, nil, audit.UserState(user))); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
 func (r *Repo) GetUsers(ctx context.Context) ([]*core.User, error) {
@@ -345,8 +588,15 @@ func (r *Repo) GetUsers(ctx context.Context) ([]*core.User, error) {
 	return users, nil
 }
 
+// userBalance sums the balances of the accounts of the user u and of
+// their pots.
+const userBalance = `(SELECT COALESCE(SUM(a.balance + ` + potsBalance + `), 0)::BIGINT FROM accounts a WHERE a.user_id = u.id)`
+
+// potsBalance sums the balances of the pots of the account a.
+const potsBalance = `(SELECT COALESCE(SUM(p.balance), 0) FROM pots p WHERE p.account_id = a.id)`
+
 func (r *Repo) GetUser(ctx context.Context, userId int) (*core.User, error) {
-	q := `SELECT u.id, u.first_name, u.last_name, u.email, a.balance FROM users u INNER JOIN accounts a ON u.id = a.user_id WHERE u.id = $1`
+	q := `SELECT u.id, u.first_name, u.last_name, u.email, ` + userBalance + ` FROM users u WHERE u.id = $1`
 	row := r.db.QueryRowContext(ctx, q, userId)
 	user, err := scanUser(row)
 	if err != nil {
@@ -358,10 +608,47 @@ func (r *Repo) GetUser(ctx context.Context, userId int) (*core.User, error) {
 	return user, nil
 }
 
+// UserBalances totals a user's account and pot balances by product. A
+// user with no accounts has no products and a total of zero.
+func (r *Repo) UserBalances(ctx context.Context, userID int) (*core.UserBalances, error) {
+	const q = `SELECT a.product, COUNT(*), SUM(a.balance)::BIGINT, SUM(` + potsBalance + `)::BIGINT, MIN(a.number) FILTER (WHERE a.is_primary)
+		FROM accounts a WHERE a.user_id = $1 GROUP BY a.product ORDER BY a.product`
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &core.UserBalances{UserID: userID, Products: []core.ProductBalance{}}
+	for rows.Next() {
+		var pb core.ProductBalance
+		var primary sql.NullString
+		if err := rows.Scan(&pb.Product, &pb.Accounts, &pb.Balance, &pb.PotBalance, &primary); err != nil {
+			return nil, err
+		}
+		if primary.Valid {
+			res.PrimaryAccountNumber = primary.String
+		}
+		res.Total += pb.Balance + pb.PotBalance
+		res.Products = append(res.Products, pb)
+	}
+	return res, rows.Err()
+}
+
 func (r *Repo) UpdateUser(ctx context.Context, id int, firstName, lastName, email string) (*core.User, error) {
-	var user *core.User
-	q := `UPDATE users u SET first_name = $2, last_name = $3, email = $4 FROM accounts a WHERE u.id = $1 AND a.user_id = u.id RETURNING u.id, u.first_name, u.last_name, u.email, a.balance`
-	row := r.db.QueryRowContext(ctx, q, id, firstName, lastName, email)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockUser(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `UPDATE users u SET first_name = $2, last_name = $3, email = $4 WHERE u.id = $1 RETURNING u.id, u.first_name, u.last_name, u.email, ` + userBalance
+	row := tx.QueryRowContext(ctx, q, id, firstName, lastName, email)
 	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -369,26 +656,53 @@ func (r *Repo) UpdateUser(ctx context.Context, id int, firstName, lastName, emai
 		}
 		return nil, err
 	}
+
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditUserUpdate, core.TargetUser, id, audit.UserState(before), audit.UserState(user))); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 func (r *Repo) DeleteUser(ctx context.Context, id int) error {
-	q := `DELETE FROM users WHERE id = $1`
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := r.db.ExecContext(ctx, q, id)
+	before, err := lockUser(ctx, tx, id)
 	if err != nil {
+		return err
+	}
+
+	q := `DELETE FROM users WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, id); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	if err := writeAudit(ctx, tx, audit.New(ctx, core.AuditUserDelete, core.TargetUser, id, audit.UserState(before), nil)); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	if rowsAffected == 0 {
-		return storage.ErrUserNotFound
+// lockUser reads a user for an update or deletion, so the audit log
+// records what changed.
+func lockUser(ctx context.Context, tx *sql.Tx, id int) (*core.User, error) {
+	var u core.User
+	err := tx.QueryRowContext(ctx, `SELECT id, email, first_name, last_name FROM users WHERE id = $1 FOR UPDATE`, id).
+		Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, err
 	}
-	return nil
+	return &u, nil
 }
 
 func (r *Repo) GetUserByEmail(ctx context.Context, email string) (*core.User, error) {
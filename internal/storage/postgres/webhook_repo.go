@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-bank/internal/core"
+	"mini-bank/internal/storage"
+)
+
+const webhookColumns = `id, user_id, url, event_types, secret, account_ids, created_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at, delivered_at`
+
+func scanWebhook(row scanner) (*core.WebhookSubscription, error) {
+	var s core.WebhookSubscription
+	if err := row.Scan(&s.ID, &s.UserID, &s.URL, typeMap.SQLScanner(&s.EventTypes), &s.Secret, typeMap.SQLScanner(&s.AccountIDs), &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func scanDelivery(row scanner) (*core.WebhookDelivery, error) {
+	var d core.WebhookDelivery
+	var lastError sql.NullString
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &lastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+		return nil, err
+	}
+	d.LastError = lastError.String
+	return &d, nil
+}
+
+func (r *Repo) listWebhooks(ctx context.Context, q string, args ...any) ([]*core.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.WebhookSubscription
+	for rows.Next() {
+		s, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) listDeliveries(ctx context.Context, q string, args ...any) ([]*core.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*core.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// CreateWebhook stores a new webhook subscription.
+func (r *Repo) CreateWebhook(ctx context.Context, sub *core.WebhookSubscription) (*core.WebhookSubscription, error) {
+	const q = `INSERT INTO webhook_subscriptions (user_id, url, event_types, secret, account_ids) VALUES ($1, $2, $3, $4, $5) RETURNING ` + webhookColumns
+	accountIDs := sub.AccountIDs
+	if accountIDs == nil {
+		accountIDs = []int{}
+	}
+	return scanWebhook(r.db.QueryRowContext(ctx, q, sub.UserID, sub.URL, sub.EventTypes, sub.Secret, accountIDs))
+}
+
+// GetWebhook retrieves a webhook subscription by id.
+func (r *Repo) GetWebhook(ctx context.Context, id int) (*core.WebhookSubscription, error) {
+	const q = `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	sub, err := scanWebhook(r.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListWebhooks returns a user's webhook subscriptions.
+func (r *Repo) ListWebhooks(ctx context.Context, userID int) ([]*core.WebhookSubscription, error) {
+	const q = `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE user_id = $1 ORDER BY id`
+	return r.listWebhooks(ctx, q, userID)
+}
+
+// ListWebhooksForEvent returns a user's subscriptions that want an event type.
+func (r *Repo) ListWebhooksForEvent(ctx context.Context, userID int, eventType string) ([]*core.WebhookSubscription, error) {
+	const q = `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE user_id = $1 AND $2 = ANY(event_types) ORDER BY id`
+	return r.listWebhooks(ctx, q, userID, eventType)
+}
+
+// DeleteWebhook removes a subscription together with its delivery log.
+func (r *Repo) DeleteWebhook(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// CreateWebhookDeliveries queues deliveries, filling in their ids.
+func (r *Repo) CreateWebhookDeliveries(ctx context.Context, deliveries []*core.WebhookDelivery) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const ins = `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload) VALUES ($1, $2, $3, $4) RETURNING ` + deliveryColumns
+	for i, d := range deliveries {
+		saved, err := scanDelivery(tx.QueryRowContext(ctx, ins, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload)))
+		if err != nil {
+			return err
+		}
+		deliveries[i] = saved
+	}
+	return tx.Commit()
+}
+
+// GetWebhookDelivery retrieves a delivery by id.
+func (r *Repo) GetWebhookDelivery(ctx context.Context, id int) (*core.WebhookDelivery, error) {
+	const q = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListWebhookDeliveries returns the most recent deliveries for a subscription.
+func (r *Repo) ListWebhookDeliveries(ctx context.Context, subscriptionID int, limit int) ([]*core.WebhookDelivery, error) {
+	const q = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY id DESC LIMIT $2`
+	return r.listDeliveries(ctx, q, subscriptionID, limit)
+}
+
+// ClaimDueWebhookDeliveries leases due deliveries by pushing their next
+// attempt past the lease. SKIP LOCKED lets several workers poll at once.
+func (r *Repo) ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*core.WebhookDelivery, error) {
+	const q = `UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+	return r.listDeliveries(ctx, q, limit, time.Now().Add(lease).UTC())
+}
+
+// UpdateWebhookDelivery saves the outcome of a delivery attempt.
+func (r *Repo) UpdateWebhookDelivery(ctx context.Context, d *core.WebhookDelivery) error {
+	const q = `UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+		response_status = $6, last_error = $7, delivered_at = $8 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, q, d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastAttemptAt,
+		d.ResponseStatus, nullIfEmpty(d.LastError), d.DeliveredAt)
+	return err
+}
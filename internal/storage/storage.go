@@ -2,18 +2,52 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"mini-bank/internal/core"
 )
 
+// Errors returned by storage implementations. They are the domain errors
+// from core, so callers can match either.
 var (
-	ErrAccountNotFound     = errors.New("account not found")
-	ErrInsufficientFunds   = errors.New("insufficient funds")
-	ErrTransactionNotFound = errors.New("transaction not found")
-	ErrUserNotFound        = errors.New("user not found")
-	ErrDuplicateEmail      = errors.New("duplicate email")
-	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrAccountNotFound     = core.ErrAccountNotFound
+	ErrInsufficientFunds   = core.ErrInsufficientFunds
+	ErrAccountFrozen       = core.ErrAccountFrozen
+	ErrAlreadyReversed     = core.ErrAlreadyReversed
+	ErrNotReversible       = core.ErrNotReversible
+	ErrLimitExceeded       = core.ErrLimitExceeded
+	ErrProductNotFound     = core.ErrProductNotFound
+	ErrProductRestricted   = core.ErrProductRestricted
+	ErrTransactionBlocked  = core.ErrTransactionBlocked
+	ErrCaseNotFound        = core.ErrCaseNotFound
+	ErrCaseResolved        = core.ErrCaseResolved
+	ErrScreeningBlocked    = core.ErrScreeningBlocked
+	ErrHitNotFound         = core.ErrHitNotFound
+	ErrSubmissionNotFound  = core.ErrSubmissionNotFound
+	ErrSubmissionPending   = core.ErrSubmissionPending
+	ErrSubmissionReviewed  = core.ErrSubmissionReviewed
+	ErrDocumentNotFound    = core.ErrDocumentNotFound
+	ErrBeneficiaryNotFound = core.ErrBeneficiaryNotFound
+	ErrBeneficiaryExists   = core.ErrBeneficiaryExists
+	ErrPayeeMismatch       = core.ErrPayeeMismatch
+	ErrCoolingOff          = core.ErrCoolingOff
+	ErrPotNotFound         = core.ErrPotNotFound
+	ErrPotLocked           = core.ErrPotLocked
+	ErrPotNotEmpty         = core.ErrPotNotEmpty
+	ErrTermDepositNotFound = core.ErrTermDepositNotFound
+	ErrTermDepositClosed   = core.ErrTermDepositClosed
+	ErrTermNotOffered      = core.ErrTermNotOffered
+	ErrTransactionNotFound = core.ErrTransactionNotFound
+	ErrUserNotFound        = core.ErrUserNotFound
+	ErrDuplicateEmail      = core.ErrDuplicateEmail
+	ErrInvalidCredentials  = core.ErrInvalidCredentials
+	ErrAPIKeyNotFound      = core.ErrAPIKeyNotFound
+	ErrInvalidAPIKey       = core.ErrInvalidAPIKey
+	ErrOAuthClientNotFound = core.ErrOAuthClientNotFound
+	ErrInvalidClient       = core.ErrInvalidClient
+	ErrConsentNotFound     = core.ErrConsentNotFound
+	ErrWebhookNotFound     = core.ErrWebhookNotFound
+	ErrDeliveryNotFound    = core.ErrDeliveryNotFound
 )
 
 type PaymentType string
@@ -25,21 +59,277 @@ const (
 
 // Storage defines how accounts and transactions are persisted.
 type Storage interface {
-	CreateAccount(ctx context.Context, userID int, initialBalance int64) (*core.Account, error)
+	// CreateAccount opens an account for acc.UserID on acc.Product, named
+	// acc.Name, with acc.Balance deposited. A user's first current account
+	// becomes their primary one.
+	CreateAccount(ctx context.Context, acc *core.Account) (*core.Account, error)
+	// UpdateAccount renames an account if name is not nil and, if primary
+	// is set, makes it its owner's primary account in place of any other.
+	UpdateAccount(ctx context.Context, id int, name *string, primary bool) (*core.Account, error)
 	GetAccount(ctx context.Context, id int) (*core.Account, error)
+	GetAccountByNumber(ctx context.Context, number string) (*core.Account, error)
+	// AccountNumbers maps account ids to their account numbers.
+	AccountNumbers(ctx context.Context, ids []int) (map[int]string, error)
 	ListAccounts(ctx context.Context) ([]*core.Account, error)
-	UpdateBalance(ctx context.Context, id int, newBalance int64) error
 
 	RecordTransaction(ctx context.Context, tx *core.Transaction) error
 	ListTransactions(ctx context.Context, accountID int) ([]*core.Transaction, error)
 	GetTransaction(ctx context.Context, ref string) (*core.Transaction, error)
 
-	Transfer(ctx context.Context, fromID, toID int, amount int64, reference string) (*core.Account, *core.Account, error)
-	Payment(ctx context.Context, accountID int, amount int64, paymentType PaymentType, reference string) (*core.Account, error)
+	// Transfer and Payment check the limits of the account money leaves,
+	// capped at tierLimits, in the same database transaction as the debit.
+	Transfer(ctx context.Context, fromID, toID int, amount int64, reference string, tierLimits core.Limits) (*core.Account, *core.Account, error)
+	Payment(ctx context.Context, accountID int, amount int64, paymentType PaymentType, reference string, tierLimits core.Limits) (*core.Account, error)
 	CreateUser(ctx context.Context, firstName string, lastName string, email string, password string) (*core.User, error)
 	GetUsers(ctx context.Context) ([]*core.User, error)
+	// GetUser returns a user with the total balance of all their accounts.
 	GetUser(ctx context.Context, id int) (*core.User, error)
+	// UserBalances totals a user's account balances by product.
+	UserBalances(ctx context.Context, userID int) (*core.UserBalances, error)
 	UpdateUser(ctx context.Context, id int, firstName string, lastName string, email string) (*core.User, error)
 	DeleteUser(ctx context.Context, id int) error
 	GetUserByEmail(ctx context.Context, email string) (*core.User, error)
+
+	APIKeyStorage
+	OAuthStorage
+	WebhookStorage
+	OutboxStorage
+	AdminStorage
+	AuditStorage
+	LimitStorage
+	MonitoringStorage
+	ScreeningStorage
+	KYCStorage
+	BeneficiaryStorage
+	PotStorage
+	TermDepositStorage
+}
+
+// APIKeyStorage persists API keys.
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key *core.APIKey) (*core.APIKey, error)
+	GetAPIKey(ctx context.Context, id int) (*core.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*core.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int) ([]*core.APIKey, error)
+	RotateAPIKey(ctx context.Context, id int, prefix string, hash string) (*core.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// OAuthStorage persists OAuth clients and user consents.
+type OAuthStorage interface {
+	CreateOAuthClient(ctx context.Context, client *core.OAuthClient) (*core.OAuthClient, error)
+	GetOAuthClient(ctx context.Context, id string) (*core.OAuthClient, error)
+	ListOAuthClients(ctx context.Context, ownerUserID int) ([]*core.OAuthClient, error)
+
+	SaveOAuthConsent(ctx context.Context, userID int, clientID string, scopes []string) (*core.OAuthConsent, error)
+	GetOAuthConsent(ctx context.Context, userID int, clientID string) (*core.OAuthConsent, error)
+	ListOAuthConsents(ctx context.Context, userID int) ([]*core.OAuthConsent, error)
+	RevokeOAuthConsent(ctx context.Context, userID int, clientID string) error
+}
+
+// WebhookStorage persists webhook subscriptions and their delivery queue.
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, sub *core.WebhookSubscription) (*core.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id int) (*core.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context, userID int) ([]*core.WebhookSubscription, error)
+	ListWebhooksForEvent(ctx context.Context, userID int, eventType string) ([]*core.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id int) error
+
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*core.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id int) (*core.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, subscriptionID int, limit int) ([]*core.WebhookDelivery, error)
+	// ClaimDueWebhookDeliveries leases up to limit pending deliveries that
+	// are due, hiding them from other workers for the lease duration.
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*core.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *core.WebhookDelivery) error
+}
+
+// OutboxStorage publishes events recorded in the transactional outbox.
+// Account operations write their events in the same transaction as the
+// change itself, so events exist if and only if the change committed.
+type OutboxStorage interface {
+	// RelayOutbox passes up to limit unpublished events to publish in
+	// sequence order and marks those it accepted as published. Only one
+	// relay runs at a time; others get 0 events until it finishes.
+	RelayOutbox(ctx context.Context, limit int, publish func(context.Context, core.Event) error) (int, error)
+	// PruneOutbox deletes events published before the given time.
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+	// ListAccountEvents returns up to limit events for an account with a
+	// sequence greater than afterSequence, oldest first.
+	ListAccountEvents(ctx context.Context, accountID int, afterSequence int64, limit int) ([]core.Event, error)
+}
+
+// AdminStorage carries out support operations. They may touch frozen
+// accounts, but every balance change is still recorded as a transaction
+// with an event, in the same database transaction as the change.
+type AdminStorage interface {
+	// SetAccountStatus freezes or unfreezes an account.
+	SetAccountStatus(ctx context.Context, id int, status string) (*core.Account, error)
+	// AdjustBalance changes a balance by a signed amount, which may not
+	// take it below zero.
+	AdjustBalance(ctx context.Context, accountID int, amount int64, reason string, reference string) (*core.Transaction, error)
+	// ReverseTransaction undoes a deposit, withdrawal, adjustment or the
+	// sender's side of a transfer, returning one reversal per account
+	// touched. Each transaction can be reversed once.
+	ReverseTransaction(ctx context.Context, id int, reason string, reference string) ([]*core.Transaction, error)
+	// Reconcile recomputes every balance from transaction history and
+	// checks that transfers conserve money, all in one snapshot.
+	Reconcile(ctx context.Context) (*core.Reconciliation, error)
+}
+
+// AuditStorage keeps the hash-chained audit log and the staff roles that
+// govern who may read it. Changes made through Storage append their own
+// entries in the same database transaction as the change.
+type AuditStorage interface {
+	// AppendAudit records entries for events that change nothing else,
+	// such as logins.
+	AppendAudit(ctx context.Context, entries ...core.AuditEntry) error
+	// ListAuditEntries returns the entries matching the filter, oldest
+	// first.
+	ListAuditEntries(ctx context.Context, filter core.AuditFilter) ([]core.AuditEntry, error)
+
+	GetUserRoles(ctx context.Context, userID int) ([]string, error)
+	// SetUserRole grants or revokes a role and returns the user's roles.
+	SetUserRole(ctx context.Context, userID int, role string, granted bool) ([]string, error)
+}
+
+// LimitStorage keeps the limits on money leaving accounts. Withdrawals and
+// sent transfers check them in the same database transaction as the
+// debit, and fail with ErrLimitExceeded. The limits returned are the
+// account's and its product's; KYC tier limits are applied on top by the
+// caller.
+type LimitStorage interface {
+	GetAccountLimits(ctx context.Context, accountID int) (*core.AccountLimits, error)
+	// SetAccountLimits replaces an account's overrides of its product's
+	// limits; nil limits fall back to the product's.
+	SetAccountLimits(ctx context.Context, accountID int, limits core.Limits) (*core.AccountLimits, error)
+	// SetProductLimits replaces a product's limits; nil limits are no
+	// limit.
+	SetProductLimits(ctx context.Context, product string, limits core.Limits) error
+}
+
+// MonitoringStorage provides the history fraud and AML rules judge
+// movements against, and keeps the cases they open.
+type MonitoringStorage interface {
+	// GetAccountActivity returns an account and its transactions since the
+	// given time.
+	GetAccountActivity(ctx context.Context, accountID int, since time.Time) (*core.AccountActivity, error)
+	// ListAccountActivity returns every account with a transaction since
+	// activeSince, with its transactions since the earlier time since.
+	ListAccountActivity(ctx context.Context, since, activeSince time.Time) ([]*core.AccountActivity, error)
+
+	// OpenCases records flagged activity and returns the cases opened.
+	// Activity already flagged by the same rule opens no second case.
+	OpenCases(ctx context.Context, cases ...*core.Case) ([]*core.Case, error)
+	GetCase(ctx context.Context, id int) (*core.Case, error)
+	// ListCases returns the cases matching the filter, oldest first.
+	ListCases(ctx context.Context, filter core.CaseFilter) ([]*core.Case, error)
+	// ResolveCase closes an open case as cleared or confirmed.
+	ResolveCase(ctx context.Context, id int, status string, note string) (*core.Case, error)
+}
+
+// ScreeningStorage keeps the hits found by screening names against the
+// watchlist.
+type ScreeningStorage interface {
+	// RecordScreeningHits saves the hits that are new and returns them. A
+	// name already matched to the same entry is not recorded again. New
+	// blocking hits on an existing user freeze all of their active
+	// accounts in the same transaction.
+	RecordScreeningHits(ctx context.Context, hits ...*core.ScreeningHit) ([]*core.ScreeningHit, error)
+	GetScreeningHit(ctx context.Context, id int) (*core.ScreeningHit, error)
+	// ListScreeningHits returns the hits matching the filter, oldest first.
+	ListScreeningHits(ctx context.Context, filter core.ScreeningHitFilter) ([]*core.ScreeningHit, error)
+	// ResolveScreeningHit closes an open hit as cleared or confirmed.
+	ResolveScreeningHit(ctx context.Context, id int, status string, note string) (*core.ScreeningHit, error)
+}
+
+// KYCStorage keeps users' tiers, the limits of each tier and the
+// submissions awaiting review. Document content is kept in a blob store;
+// only its metadata is stored here.
+type KYCStorage interface {
+	// GetKYCStatus returns a user's tier and their latest submission.
+	GetKYCStatus(ctx context.Context, userID int) (*core.KYCStatus, error)
+	// CreateKYCSubmission saves a pending submission and its documents.
+	// It returns ErrSubmissionPending if the user already has one.
+	CreateKYCSubmission(ctx context.Context, sub *core.KYCSubmission) (*core.KYCSubmission, error)
+	GetKYCSubmission(ctx context.Context, id int) (*core.KYCSubmission, error)
+	// ListKYCSubmissions returns the submissions matching the filter,
+	// oldest first, without their documents.
+	ListKYCSubmissions(ctx context.Context, filter core.KYCFilter) ([]*core.KYCSubmission, error)
+	// ReviewKYCSubmission approves or rejects a pending submission. An
+	// approval raises the user to the submission's tier, never lowering it.
+	ReviewKYCSubmission(ctx context.Context, id int, status string, note string) (*core.KYCSubmission, error)
+	// GetUserTier returns a user's KYC tier.
+	GetUserTier(ctx context.Context, userID int) (string, error)
+	// GetTierLimits returns the limits of a tier. Nil limits are no limit.
+	GetTierLimits(ctx context.Context, tier string) (*core.Limits, error)
+	// SetTierLimits replaces the limits of a tier. Nil limits are no limit.
+	SetTierLimits(ctx context.Context, tier string, limits core.Limits) error
+}
+
+// BeneficiaryStorage persists the payees users have saved.
+type BeneficiaryStorage interface {
+	// CreateBeneficiary saves a payee. It returns ErrBeneficiaryExists if
+	// the user already saved the account.
+	CreateBeneficiary(ctx context.Context, b *core.Beneficiary) (*core.Beneficiary, error)
+	GetBeneficiary(ctx context.Context, id int) (*core.Beneficiary, error)
+	// FindBeneficiary returns the user's beneficiary for an account, or
+	// ErrBeneficiaryNotFound if they have not saved it.
+	FindBeneficiary(ctx context.Context, userID int, accountID int) (*core.Beneficiary, error)
+	// FirstTransferTo returns when one of the user's accounts first sent
+	// money to an account that was not reversed, or nil if none has.
+	FirstTransferTo(ctx context.Context, userID int, accountID int) (*time.Time, error)
+	ListBeneficiaries(ctx context.Context, userID int) ([]*core.Beneficiary, error)
+	DeleteBeneficiary(ctx context.Context, id int) error
+}
+
+// PotStorage persists the pots money is set aside in inside accounts.
+type PotStorage interface {
+	// CreatePot opens an empty pot. A round-up pot takes over from the
+	// account's previous one.
+	CreatePot(ctx context.Context, p *core.Pot) (*core.Pot, error)
+	GetPot(ctx context.Context, id int) (*core.Pot, error)
+	ListPots(ctx context.Context, accountID int) ([]*core.Pot, error)
+	// UpdatePot changes a pot. It returns ErrPotLocked for an attempt to
+	// shorten the lock of a locked pot.
+	UpdatePot(ctx context.Context, id int, upd core.PotUpdate) (*core.Pot, error)
+	// DeletePot removes a pot. It returns ErrPotNotEmpty if the pot still
+	// holds money.
+	DeletePot(ctx context.Context, id int) error
+	// MovePot moves amount from the pot's account into the pot, or back
+	// out if amount is negative, and returns both. It returns ErrPotLocked
+	// for money leaving a locked pot.
+	MovePot(ctx context.Context, id int, amount int64, reference string) (*core.Pot, *core.Account, error)
+}
+
+// TermDepositStorage persists term deposits and the rates they are offered
+// at, and moves their money.
+type TermDepositStorage interface {
+	// TermDepositRates returns the terms offered, shortest first.
+	TermDepositRates(ctx context.Context) ([]*core.TermDepositRate, error)
+	// SetTermDepositRate offers a term at the given rates, replacing any
+	// it was offered at. Deposits already open keep their rates.
+	SetTermDepositRate(ctx context.Context, rate core.TermDepositRate) error
+	// OpenTermDeposit opens a fixed_deposit account for d and transfers
+	// its principal there from its payout account, at the rates offered
+	// for its term, checking the payout account's limits as Transfer does.
+	// It returns ErrTermNotOffered if the term is not offered.
+	OpenTermDeposit(ctx context.Context, d *core.TermDeposit, reference string, tierLimits core.Limits) (*core.TermDeposit, error)
+	GetTermDeposit(ctx context.Context, id int) (*core.TermDeposit, error)
+	ListTermDeposits(ctx context.Context, userID int) ([]*core.TermDeposit, error)
+	// SetTermDepositRollover decides whether an active deposit starts
+	// another term at maturity. It returns ErrTermDepositClosed once the
+	// deposit has been paid out.
+	SetTermDepositRollover(ctx context.Context, id int, rollover bool) (*core.TermDeposit, error)
+	// DueTermDeposits returns the IDs of the active deposits that have
+	// matured by now, the longest overdue first.
+	DueTermDeposits(ctx context.Context, now time.Time) ([]int, error)
+	// MatureTermDeposit credits a matured deposit's interest and pays
+	// everything out, or starts another term if the deposit rolls over.
+	// It returns ErrTermDepositClosed if the deposit was already paid out.
+	MatureTermDeposit(ctx context.Context, id int, reference string) (*core.TermDeposit, error)
+	// BreakTermDeposit pays out an active deposit before it matures, with
+	// interest at its break rate.
+	BreakTermDeposit(ctx context.Context, id int, reference string) (*core.TermDeposit, error)
 }
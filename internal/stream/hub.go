@@ -0,0 +1,190 @@
+// Package stream fans account events out to clients connected to any
+// instance of the API.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"mini-bank/internal/core"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// channel is the Redis pub/sub channel events are broadcast on.
+const channel = "events:accounts"
+
+// bufferSize is how many undelivered updates a subscriber may fall behind
+// before it is dropped.
+const bufferSize = 64
+
+// Update is an account event as sent to stream clients. Accounts are
+// named by their account numbers.
+type Update struct {
+	Sequence                  int64     `json:"sequence"`
+	EventID                   string    `json:"event_id"`
+	Type                      string    `json:"type"`
+	AccountNumber             string    `json:"account_number"`
+	Amount                    int64     `json:"amount"`
+	Balance                   int64     `json:"balance"`
+	Reference                 string    `json:"reference,omitempty"`
+	CounterpartyAccountNumber string    `json:"counterparty_account_number,omitempty"`
+	OccurredAt                time.Time `json:"occurred_at"`
+}
+
+// NewUpdate converts an event to an update.
+func NewUpdate(e core.Event) Update {
+	return Update{
+		Sequence:                  e.Sequence,
+		EventID:                   e.ID,
+		Type:                      e.Type,
+		AccountNumber:             e.AccountNumber,
+		Amount:                    e.Amount,
+		Balance:                   e.Balance,
+		Reference:                 e.Reference,
+		CounterpartyAccountNumber: e.CounterpartyAccountNumber,
+		OccurredAt:                e.OccurredAt,
+	}
+}
+
+// Hub broadcasts events through Redis pub/sub and delivers them to the
+// subscribers connected to this instance. It implements events.Publisher.
+type Hub struct {
+	rdb    *redis.Client
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub creates a hub that broadcasts through rdb.
+func NewHub(rdb *redis.Client, logger *slog.Logger) *Hub {
+	return &Hub{rdb: rdb, logger: logger, subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Publish broadcasts the event to every instance.
+func (h *Hub) Publish(ctx context.Context, event core.Event) error {
+	msg, err := json.Marshal(NewUpdate(event))
+	if err != nil {
+		return err
+	}
+	return h.rdb.Publish(ctx, channel, msg).Err()
+}
+
+// Run receives broadcast events and delivers them to local subscribers
+// until ctx is cancelled.
+func (h *Hub) Run(ctx context.Context) {
+	pubsub := h.rdb.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	msgs := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			var u Update
+			if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil {
+				h.logger.Warn("ignoring malformed stream message", "err", err)
+				continue
+			}
+			h.dispatch(u)
+		}
+	}
+}
+
+// dispatch hands the update to the account's subscribers. Subscribers
+// whose buffer is full are dropped rather than allowed to hold up others;
+// they can reconnect and resume from their last event.
+func (h *Hub) dispatch(u Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs[u.AccountNumber] {
+		select {
+		case sub.updates <- u:
+		default:
+			h.logger.Warn("dropping slow stream subscriber", "account_number", u.AccountNumber)
+			h.removeLocked(sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+// Subscribe registers for updates to the account with the given number.
+// Close the subscription when done.
+func (h *Hub) Subscribe(accountNumber string) *Subscription {
+	sub := &Subscription{
+		hub:           h,
+		accountNumber: accountNumber,
+		updates:       make(chan Update, bufferSize),
+		dropped:       make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.dropped)
+		return sub
+	}
+	if h.subs[accountNumber] == nil {
+		h.subs[accountNumber] = make(map[*Subscription]struct{})
+	}
+	h.subs[accountNumber][sub] = struct{}{}
+	return sub
+}
+
+// Close drops every subscriber so open streams end, e.g. on server
+// shutdown. Later subscriptions are dropped immediately.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			close(sub.dropped)
+		}
+	}
+	h.subs = make(map[string]map[*Subscription]struct{})
+}
+
+func (h *Hub) removeLocked(sub *Subscription) {
+	delete(h.subs[sub.accountNumber], sub)
+	if len(h.subs[sub.accountNumber]) == 0 {
+		delete(h.subs, sub.accountNumber)
+	}
+}
+
+// Subscription receives updates for one account.
+type Subscription struct {
+	hub           *Hub
+	accountNumber string
+	updates       chan Update
+	dropped       chan struct{}
+}
+
+// Updates returns the channel updates are delivered on.
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Dropped is closed when the subscriber fell too far behind or the hub
+// was closed.
+func (s *Subscription) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+// Close unregisters the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s)
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook URLs that resolve to an
+// address deliveries may not be sent to.
+var ErrForbiddenTarget = errors.New("webhook target is not a public address")
+
+// nonPublic are the ranges, beyond loopback, private, link-local,
+// multicast and unspecified addresses, that are not reachable on the
+// public internet or that route back into the operator's network.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which can embed any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// Targets decides which addresses webhooks may be delivered to. The zero
+// value allows public addresses only, so subscribers cannot make the
+// delivery worker reach the loopback interface, the internal network or
+// a cloud metadata service such as 169.254.169.254.
+type Targets struct {
+	// AllowPrivate also allows non-public addresses, for receivers on the
+	// local machine or network during development.
+	AllowPrivate bool
+}
+
+// Allowed reports whether deliveries may be sent to ip.
+func (t Targets) Allowed(ip netip.Addr) bool {
+	if t.AllowPrivate {
+		return true
+	}
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL resolves the host of a webhook URL and returns
+// ErrForbiddenTarget unless every address it resolves to is allowed.
+// The worker checks the address again when it connects, since the name
+// may resolve differently by then.
+func (t Targets) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !t.Allowed(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, ip)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, ip := range addrs {
+		if !t.Allowed(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, ip)
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that only connects to allowed addresses.
+// The check runs on the address being dialled, after resolution, so it
+// also covers redirects and names rebound to another address after the
+// subscription was checked. Proxies from the environment are ignored, as
+// they would be dialled instead of the target.
+func (t Targets) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !t.Allowed(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
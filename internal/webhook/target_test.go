@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestTargetsAllowed(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := (Targets{}).Allowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+		if !(Targets{AllowPrivate: true}).Allowed(netip.MustParseAddr(tt.ip)) {
+			t.Errorf("Allowed(%s) with AllowPrivate = false, want true", tt.ip)
+		}
+	}
+}
+
+func TestTargetsCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"https://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://localhost/hook",
+	} {
+		if err := (Targets{}).CheckURL(ctx, u); !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("CheckURL(%q) = %v, want ErrForbiddenTarget", u, err)
+		}
+	}
+	if err := (Targets{}).CheckURL(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckURL(public address) = %v, want nil", err)
+	}
+	if err := (Targets{AllowPrivate: true}).CheckURL(ctx, "http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("CheckURL(loopback) with AllowPrivate = %v, want nil", err)
+	}
+}
+
+func TestTargetsClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := (Targets{}).Client(time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenTarget) {
+		t.Fatalf("Get(%s) = %v, want ErrForbiddenTarget", srv.URL, err)
+	}
+
+	resp, err := (Targets{AllowPrivate: true}).Client(time.Second).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) with AllowPrivate = %v", srv.URL, err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+// Headers set on every webhook request.
+const (
+	HeaderSignature = "MiniBank-Signature"
+	HeaderEvent     = "MiniBank-Event"
+	HeaderDelivery  = "MiniBank-Delivery"
+)
+
+// Store is the persistence the webhook subsystem needs.
+type Store interface {
+	GetWebhook(ctx context.Context, id int) (*core.WebhookSubscription, error)
+	ListWebhooksForEvent(ctx context.Context, userID int, eventType string) ([]*core.WebhookSubscription, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*core.WebhookDelivery) error
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*core.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *core.WebhookDelivery) error
+}
+
+// payload is the JSON body POSTed to subscribers.
+type payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      payloadData `json:"data"`
+}
+
+// payloadData names accounts by their account numbers.
+type payloadData struct {
+	AccountNumber             string `json:"account_number"`
+	Amount                    int64  `json:"amount"`
+	Balance                   int64  `json:"balance"`
+	Reference                 string `json:"reference,omitempty"`
+	CounterpartyAccountNumber string `json:"counterparty_account_number,omitempty"`
+}
+
+// Dispatcher queues a delivery for every subscription interested in an
+// event. It implements events.Publisher.
+type Dispatcher struct {
+	store  Store
+	logger *slog.Logger
+}
+
+// NewDispatcher creates a dispatcher that queues deliveries in store.
+func NewDispatcher(store Store, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{store: store, logger: logger}
+}
+
+// Publish queues the event for every matching subscription of its owner
+// that covers the event's account.
+func (d *Dispatcher) Publish(ctx context.Context, event core.Event) error {
+	subs, err := d.store.ListWebhooksForEvent(ctx, event.UserID, event.Type)
+	if err != nil {
+		d.logger.Error("failed to find webhooks for event", "event_id", event.ID, "err", err)
+		return err
+	}
+	body, err := json.Marshal(payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.OccurredAt,
+		Data: payloadData{
+			AccountNumber:             event.AccountNumber,
+			Amount:                    event.Amount,
+			Balance:                   event.Balance,
+			Reference:                 event.Reference,
+			CounterpartyAccountNumber: event.CounterpartyAccountNumber,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]*core.WebhookDelivery, 0, len(subs))
+	for _, sub := range subs {
+		if !sub.AllowsAccount(event.AccountID) {
+			continue
+		}
+		deliveries = append(deliveries, &core.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.store.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		d.logger.Error("failed to queue webhook deliveries", "event_id", event.ID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// Sign computes the signature header value for a payload sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + computeMAC(secret, ts, body)
+}
+
+// Verify checks a signature header against the payload and rejects
+// signatures older than tolerance, to limit replays.
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp: %w", err)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(computeMAC(secret, ts, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func computeMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
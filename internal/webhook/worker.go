@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"mini-bank/internal/core"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before giving up.
+	maxAttempts = 10
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	pollInterval = 2 * time.Second
+	batchSize    = 20
+	// lease hides claimed deliveries from other workers while they are sent.
+	lease = time.Minute
+)
+
+// Worker sends queued deliveries and reschedules failures with
+// exponential backoff.
+type Worker struct {
+	store  Store
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewWorker creates a delivery worker. A nil client uses one with a 10s
+// timeout that only connects to public addresses.
+func NewWorker(store Store, client *http.Client, logger *slog.Logger) *Worker {
+	if client == nil {
+		client = Targets{}.Client(10 * time.Second)
+	}
+	return &Worker{store: store, client: client, logger: logger}
+}
+
+// Run polls for due deliveries until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain sends due deliveries in batches until none are left.
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := w.store.ClaimDueWebhookDeliveries(ctx, batchSize, lease)
+		if err != nil {
+			w.logger.Error("failed to claim webhook deliveries", "err", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		subs := make(map[int]*core.WebhookSubscription)
+		for _, d := range deliveries {
+			sub, ok := subs[d.SubscriptionID]
+			if !ok {
+				sub, err = w.store.GetWebhook(ctx, d.SubscriptionID)
+				if err != nil {
+					// The subscription may have been deleted; its
+					// deliveries went with it.
+					w.logger.Warn("skipping delivery without subscription", "delivery_id", d.ID, "err", err)
+					continue
+				}
+				subs[d.SubscriptionID] = sub
+			}
+			w.deliver(ctx, sub, d)
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome.
+func (w *Worker) deliver(ctx context.Context, sub *core.WebhookSubscription, d *core.WebhookDelivery) {
+	now := time.Now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+
+	status, err := w.send(ctx, sub, d, now)
+	d.ResponseStatus = status
+	switch {
+	case err == nil:
+		d.Status = core.DeliverySucceeded
+		d.DeliveredAt = &now
+		d.LastError = ""
+	case d.Attempts >= maxAttempts:
+		d.Status = core.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.NextAttemptAt = now.Add(Backoff(d.Attempts))
+		d.LastError = err.Error()
+	}
+
+	if err != nil {
+		w.logger.Warn("webhook delivery failed",
+			"delivery_id", d.ID, "subscription_id", sub.ID, "attempt", d.Attempts, "err", err)
+	}
+	if err := w.store.UpdateWebhookDelivery(ctx, d); err != nil {
+		w.logger.Error("failed to record webhook delivery", "delivery_id", d.ID, "err", err)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, sub *core.WebhookSubscription, d *core.WebhookDelivery, now time.Time) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mini-bank-webhooks/1")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, fmt.Sprint(d.ID))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, now, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	status := resp.StatusCode
+	if status < 200 || status >= 300 {
+		return &status, fmt.Errorf("receiver responded %d", status)
+	}
+	return &status, nil
+}
+
+// Backoff returns the delay before retrying after the given number of
+// failed attempts: 30s, 1m, 2m, ... capped at 6h.
+func Backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  prefix VARCHAR(32) NOT NULL UNIQUE, -- public part of the key, used for lookup
+  key_hash CHAR(64) NOT NULL,         -- hex SHA-256 of the full key
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  account_ids INT[] NOT NULL DEFAULT '{}', -- empty means all of the user's accounts
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
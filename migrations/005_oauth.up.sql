@@ -0,0 +1,21 @@
+CREATE TABLE oauth_clients (
+  id VARCHAR(64) PRIMARY KEY, -- client_id
+  owner_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  secret_hash CHAR(64), -- NULL for public clients
+  redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE TABLE oauth_consents (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  granted_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  UNIQUE (user_id, client_id)
+);
+
+CREATE INDEX idx_oauth_clients_owner_user_id ON oauth_clients(owner_user_id);
@@ -0,0 +1,29 @@
+CREATE TABLE webhook_subscriptions (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  event_types TEXT[] NOT NULL DEFAULT '{}',
+  secret VARCHAR(255) NOT NULL, -- needed in clear to sign payloads
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+-- Deliveries double as the durable queue and the delivery log.
+CREATE TABLE webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  subscription_id INT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(50) NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, succeeded, failed
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  last_attempt_at TIMESTAMP WITH TIME ZONE,
+  response_status INT,
+  last_error TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+  delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_webhook_subscriptions_user_id ON webhook_subscriptions(user_id);
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries(subscription_id, id DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
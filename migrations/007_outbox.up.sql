@@ -0,0 +1,18 @@
+-- Events are written here in the same transaction as the change they
+-- describe, then published by the relay. The id gives the publish order.
+CREATE TABLE outbox (
+  id BIGSERIAL PRIMARY KEY,
+  event_id UUID NOT NULL UNIQUE,
+  event_type VARCHAR(50) NOT NULL,
+  user_id INT NOT NULL,
+  account_id INT NOT NULL,
+  amount BIGINT NOT NULL DEFAULT 0,
+  balance BIGINT NOT NULL,
+  reference VARCHAR(255),
+  counterparty_account_id INT,
+  occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_account_id ON outbox(account_id, id);
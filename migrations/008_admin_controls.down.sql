@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_transactions_reference;
+DROP INDEX IF EXISTS idx_transactions_account_reference;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reference ON transactions(reference) WHERE reference IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_transactions_reverses_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reverses_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reason;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS status;
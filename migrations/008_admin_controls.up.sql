@@ -0,0 +1,15 @@
+-- Frozen accounts refuse customer payments and transfers.
+ALTER TABLE accounts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+-- Adjustments and reversals record why support made them, and reversals
+-- point at the transaction they undo. A transaction is reversed at most
+-- once per account it touched.
+ALTER TABLE transactions ADD COLUMN reason TEXT;
+ALTER TABLE transactions ADD COLUMN reverses_id BIGINT REFERENCES transactions(id);
+CREATE UNIQUE INDEX idx_transactions_reverses_id ON transactions(reverses_id, account_id) WHERE reverses_id IS NOT NULL;
+
+-- Both legs of a transfer share its reference, so references are unique
+-- per account rather than globally.
+DROP INDEX IF EXISTS idx_transactions_reference;
+CREATE UNIQUE INDEX idx_transactions_account_reference ON transactions(account_id, reference) WHERE reference IS NOT NULL;
+CREATE INDEX idx_transactions_reference ON transactions(reference);
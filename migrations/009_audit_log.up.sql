@@ -0,0 +1,38 @@
+-- Staff roles, such as auditor, granted with bankctl.
+ALTER TABLE users ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
+
+-- Every audited change, hash-chained in id order. Snapshots are JSON rather
+-- than JSONB so their text, which the hash covers, is kept exactly.
+CREATE TABLE audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  actor_type VARCHAR(20) NOT NULL,
+  actor_id VARCHAR(255) NOT NULL DEFAULT '',
+  actor_user_id INT,
+  action VARCHAR(50) NOT NULL,
+  target_type VARCHAR(20) NOT NULL,
+  target_id VARCHAR(255) NOT NULL,
+  before JSON,
+  after JSON,
+  request_id VARCHAR(128) NOT NULL DEFAULT '',
+  prev_hash VARCHAR(64) NOT NULL,
+  hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_type, actor_id, id);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id, id);
+CREATE INDEX idx_audit_log_action ON audit_log(action, id);
+CREATE INDEX idx_audit_log_occurred_at ON audit_log(occurred_at);
+
+-- The log is append-only. This stops mistakes and casual edits; anyone
+-- able to drop the trigger could rewrite history, which the hash chain
+-- then exposes.
+CREATE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_immutable
+  BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_account_created_at;
+DROP TABLE IF EXISTS account_limits;
+ALTER TABLE accounts DROP COLUMN IF EXISTS product;
+DROP TABLE IF EXISTS product_limits;
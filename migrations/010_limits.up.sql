@@ -0,0 +1,26 @@
+-- Products set the default limits on money leaving an account. A NULL
+-- limit is no limit.
+CREATE TABLE product_limits (
+  product VARCHAR(30) PRIMARY KEY,
+  max_single BIGINT,
+  daily_outbound BIGINT,
+  monthly_outbound BIGINT,
+  hourly_count BIGINT
+);
+
+INSERT INTO product_limits (product, max_single, daily_outbound, monthly_outbound, hourly_count)
+VALUES ('standard', 1000000, 2500000, 10000000, 20);
+
+ALTER TABLE accounts ADD COLUMN product VARCHAR(30) NOT NULL DEFAULT 'standard' REFERENCES product_limits(product);
+
+-- Per-account overrides. A NULL limit falls back to the product's.
+CREATE TABLE account_limits (
+  account_id INT PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+  max_single BIGINT,
+  daily_outbound BIGINT,
+  monthly_outbound BIGINT,
+  hourly_count BIGINT
+);
+
+-- Limit checks sum an account's recent outbound transactions.
+CREATE INDEX idx_transactions_account_created_at ON transactions(account_id, created_at);
@@ -0,0 +1,25 @@
+-- Activity flagged by the fraud and AML monitoring rules, for compliance
+-- staff to review. A rule flags a transaction at most once, whether it
+-- was caught as it was made or by a later scan.
+CREATE TABLE monitoring_cases (
+  id SERIAL PRIMARY KEY,
+  account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  rule VARCHAR(100) NOT NULL,
+  decision VARCHAR(10) NOT NULL CHECK (decision IN ('review', 'block')),
+  reason TEXT NOT NULL,
+  reference VARCHAR(255) NOT NULL,
+  transaction_type VARCHAR(20) NOT NULL,
+  amount BIGINT NOT NULL,
+  source VARCHAR(10) NOT NULL CHECK (source IN ('realtime', 'scan')),
+  status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'cleared', 'confirmed')),
+  note TEXT,
+  resolved_by VARCHAR(255),
+  resolved_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  UNIQUE (account_id, rule, reference)
+);
+
+CREATE INDEX idx_monitoring_cases_status ON monitoring_cases(status, id);
+
+-- Scans look for accounts with recent transactions.
+CREATE INDEX idx_transactions_created_at ON transactions(created_at);
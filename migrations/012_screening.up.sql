@@ -0,0 +1,25 @@
+-- Names that resembled a watchlist entry when screened, for compliance
+-- staff to review. A name matches an entry at most once per subject, so
+-- screening again after the list changes only records new matches.
+-- subject_id identifies the subject within its type; refused signups
+-- have none and are kept for every attempt.
+CREATE TABLE screening_hits (
+  id SERIAL PRIMARY KEY,
+  subject_type VARCHAR(20) NOT NULL,
+  subject_id INT,
+  name VARCHAR(255) NOT NULL,
+  entry_id VARCHAR(100) NOT NULL,
+  entry_name VARCHAR(255) NOT NULL,
+  program VARCHAR(100) NOT NULL DEFAULT '',
+  score NUMERIC(5, 4) NOT NULL,
+  decision VARCHAR(10) NOT NULL CHECK (decision IN ('review', 'block')),
+  list_version VARCHAR(64) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'cleared', 'confirmed')),
+  note TEXT,
+  resolved_by VARCHAR(255),
+  resolved_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  UNIQUE (subject_type, subject_id, entry_id, name)
+);
+
+CREATE INDEX idx_screening_hits_status ON screening_hits(status, id);
@@ -0,0 +1,56 @@
+-- KYC tiers cap the money leaving a user's accounts on top of each
+-- account's own limits; the lower of the two applies. A NULL limit is no
+-- limit, so fully verified users are only bound by their accounts'.
+CREATE TABLE kyc_tier_limits (
+  tier VARCHAR(20) PRIMARY KEY,
+  max_single BIGINT,
+  daily_outbound BIGINT,
+  monthly_outbound BIGINT,
+  hourly_count BIGINT
+);
+
+INSERT INTO kyc_tier_limits (tier, max_single, daily_outbound, monthly_outbound, hourly_count) VALUES
+  ('unverified', 20000, 20000, 50000, 5),
+  ('basic', 250000, 500000, 2000000, NULL),
+  ('full', NULL, NULL, NULL, NULL);
+
+-- Existing customers keep moving money within the basic tier until they
+-- verify; new ones start unverified.
+ALTER TABLE users ADD COLUMN kyc_tier VARCHAR(20) NOT NULL DEFAULT 'basic' REFERENCES kyc_tier_limits(tier);
+ALTER TABLE users ALTER COLUMN kyc_tier SET DEFAULT 'unverified';
+
+CREATE TABLE kyc_submissions (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  tier VARCHAR(20) NOT NULL CHECK (tier IN ('basic', 'full')),
+  legal_name VARCHAR(255) NOT NULL,
+  date_of_birth DATE NOT NULL,
+  address TEXT NOT NULL,
+  nationality CHAR(2) NOT NULL,
+  document_type VARCHAR(20) NOT NULL,
+  document_number VARCHAR(50) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  note TEXT,
+  reviewed_by VARCHAR(255),
+  reviewed_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- A user has at most one submission awaiting review.
+CREATE UNIQUE INDEX idx_kyc_submissions_pending ON kyc_submissions(user_id) WHERE status = 'pending';
+CREATE INDEX idx_kyc_submissions_status ON kyc_submissions(status, id);
+
+-- Document content lives in the blob store under blob_key.
+CREATE TABLE kyc_documents (
+  id SERIAL PRIMARY KEY,
+  submission_id INT NOT NULL REFERENCES kyc_submissions(id) ON DELETE CASCADE,
+  kind VARCHAR(20) NOT NULL CHECK (kind IN ('identity', 'proof_of_address')),
+  filename VARCHAR(255) NOT NULL,
+  content_type VARCHAR(100) NOT NULL,
+  size BIGINT NOT NULL,
+  sha256 CHAR(64) NOT NULL,
+  blob_key VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_kyc_documents_submission ON kyc_documents(submission_id);
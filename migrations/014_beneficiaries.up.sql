@@ -0,0 +1,13 @@
+-- Payees users have saved. payee_name is the account owner's name as
+-- confirmed when the beneficiary was saved, and name_match how closely
+-- the name the user gave matched it. A user saves an account only once.
+CREATE TABLE beneficiaries (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  nickname VARCHAR(50) NOT NULL,
+  account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  payee_name VARCHAR(511) NOT NULL,
+  name_match VARCHAR(20) NOT NULL CHECK (name_match IN ('match', 'close_match')),
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  UNIQUE (user_id, account_id)
+);
@@ -0,0 +1,23 @@
+-- Account numbers identify accounts outside the bank, so the guessable
+-- SERIAL id need not be shown: eight random digits, the first not zero,
+-- and two ISO 7064 MOD 97-10 check digits. The server generates them for
+-- new accounts; existing accounts get one here.
+ALTER TABLE accounts ADD COLUMN number VARCHAR(10) UNIQUE;
+
+DO $$
+DECLARE
+  acc_id INT;
+  candidate VARCHAR(10);
+  base BIGINT;
+BEGIN
+  FOR acc_id IN SELECT id FROM accounts WHERE number IS NULL ORDER BY id LOOP
+    LOOP
+      base := 10000000 + floor(random() * 90000000)::BIGINT;
+      candidate := base::TEXT || lpad((98 - base * 100 % 97)::TEXT, 2, '0');
+      EXIT WHEN NOT EXISTS (SELECT 1 FROM accounts WHERE number = candidate);
+    END LOOP;
+    UPDATE accounts SET number = candidate WHERE id = acc_id;
+  END LOOP;
+END $$;
+
+ALTER TABLE accounts ALTER COLUMN number SET NOT NULL;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_accounts_primary;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_primary_check;
+ALTER TABLE accounts DROP COLUMN IF EXISTS is_primary;
+ALTER TABLE accounts DROP COLUMN IF EXISTS name;
+
+ALTER TABLE product_limits DROP CONSTRAINT IF EXISTS product_limits_product_check;
+
+INSERT INTO product_limits (product, max_single, daily_outbound, monthly_outbound, hourly_count)
+SELECT 'standard', max_single, daily_outbound, monthly_outbound, hourly_count FROM product_limits WHERE product = 'current';
+
+ALTER TABLE accounts ALTER COLUMN product SET DEFAULT 'standard';
+UPDATE accounts SET product = 'standard';
+DELETE FROM product_limits WHERE product <> 'standard';
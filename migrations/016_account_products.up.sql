@@ -0,0 +1,27 @@
+-- Accounts are opened on one of three products, whose rules the server
+-- enforces: current accounts, savings accounts that only pay out to their
+-- owner's other accounts, and fixed deposits that pay out nothing. The
+-- standard product becomes the current one.
+INSERT INTO product_limits (product, max_single, daily_outbound, monthly_outbound, hourly_count)
+SELECT 'current', max_single, daily_outbound, monthly_outbound, hourly_count FROM product_limits WHERE product = 'standard';
+
+ALTER TABLE accounts ALTER COLUMN product SET DEFAULT 'current';
+UPDATE accounts SET product = 'current' WHERE product = 'standard';
+DELETE FROM product_limits WHERE product = 'standard';
+
+INSERT INTO product_limits (product, max_single, daily_outbound, monthly_outbound, hourly_count)
+VALUES ('savings', 1000000, 2500000, 10000000, 5),
+       ('fixed_deposit', NULL, NULL, NULL, NULL);
+
+ALTER TABLE product_limits ADD CONSTRAINT product_limits_product_check
+  CHECK (product IN ('current', 'savings', 'fixed_deposit'));
+
+-- Users name their accounts and keep one current account as primary. Each
+-- user's oldest account becomes primary.
+ALTER TABLE accounts ADD COLUMN name VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN is_primary BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE accounts ADD CONSTRAINT accounts_primary_check CHECK (NOT is_primary OR product = 'current');
+
+UPDATE accounts SET is_primary = true WHERE id IN (SELECT MIN(id) FROM accounts GROUP BY user_id);
+
+CREATE UNIQUE INDEX idx_accounts_primary ON accounts(user_id) WHERE is_primary;
@@ -0,0 +1,9 @@
+-- Return the money in pots to their accounts, whose ledgers then balance
+-- without the pot moves.
+UPDATE accounts a SET balance = a.balance + p.total
+FROM (SELECT account_id, SUM(balance) AS total FROM pots GROUP BY account_id) p
+WHERE p.account_id = a.id;
+DELETE FROM transactions WHERE type IN ('pot_in', 'pot_out');
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS pot_id;
+DROP TABLE IF EXISTS pots;
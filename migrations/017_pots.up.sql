@@ -0,0 +1,21 @@
+-- Pots set money aside inside an account. A pot's balance is kept apart
+-- from the account's, so money in pots cannot be spent until it is moved
+-- back. locked_until keeps it in the pot until then, and an account has
+-- at most one pot that receives the round-up of its withdrawals.
+CREATE TABLE pots (
+  id SERIAL PRIMARY KEY,
+  account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+  target_amount BIGINT CHECK (target_amount > 0),
+  target_date TIMESTAMP WITH TIME ZONE,
+  locked_until TIMESTAMP WITH TIME ZONE,
+  round_up BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_pots_account_id ON pots(account_id);
+CREATE UNIQUE INDEX idx_pots_round_up ON pots(account_id) WHERE round_up;
+
+-- pot_in and pot_out transactions name the pot they moved money to or
+-- from. Pots are only deleted once empty, so their moves still balance.
+ALTER TABLE transactions ADD COLUMN pot_id INT REFERENCES pots(id) ON DELETE SET NULL;
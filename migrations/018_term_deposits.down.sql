@@ -0,0 +1,37 @@
+-- Undo the deposits as if they had never been opened, as the pots
+-- migration does for pots. Every transaction on a deposit's fixed_deposit
+-- account goes, its interest included, along with the other side of its
+-- transfers and any reversal of them, and the other accounts get back
+-- the change those made: a payout account regains what it put into a
+-- deposit less what it was paid back, so interest already paid out is
+-- taken back with the rest. The fixed_deposit accounts are then deleted.
+WITH deposit_accounts AS (
+  SELECT account_id AS id FROM term_deposits
+),
+linked AS (
+  SELECT t.id FROM transactions t
+  WHERE t.account_id IN (SELECT id FROM deposit_accounts)
+    OR t.from_account_id IN (SELECT id FROM deposit_accounts)
+    OR t.to_account_id IN (SELECT id FROM deposit_accounts)
+),
+undone AS (
+  SELECT t.id, t.account_id, CASE
+      WHEN t.type = 'withdraw' THEN -t.amount
+      WHEN t.type = 'transfer' AND t.to_account_id IS NOT NULL THEN -t.amount
+      WHEN t.type = 'pot_in' THEN -t.amount
+      ELSE t.amount
+    END AS amount
+  FROM transactions t
+  WHERE t.id IN (SELECT id FROM linked) OR t.reverses_id IN (SELECT id FROM linked)
+),
+deleted AS (
+  DELETE FROM transactions WHERE id IN (SELECT id FROM undone)
+)
+UPDATE accounts a SET balance = a.balance - u.total
+FROM (SELECT account_id, SUM(amount) AS total FROM undone GROUP BY account_id) u
+WHERE u.account_id = a.id AND a.id NOT IN (SELECT account_id FROM term_deposits);
+
+DELETE FROM accounts WHERE id IN (SELECT account_id FROM term_deposits);
+
+DROP TABLE IF EXISTS term_deposits;
+DROP TABLE IF EXISTS term_deposit_rates;
@@ -0,0 +1,36 @@
+-- Rates offered on term deposits, in basis points a year, by term. A
+-- deposit broken before it matures earns the break rate instead, for the
+-- days it was held. Rates are set with bankctl; a deposit keeps the rates
+-- its current term started at.
+CREATE TABLE term_deposit_rates (
+  term_months INT PRIMARY KEY CHECK (term_months > 0),
+  rate_bps INT NOT NULL CHECK (rate_bps >= 0),
+  break_rate_bps INT NOT NULL CHECK (break_rate_bps >= 0 AND break_rate_bps <= rate_bps)
+);
+
+INSERT INTO term_deposit_rates (term_months, rate_bps, break_rate_bps)
+VALUES (3, 250, 50), (6, 300, 75), (12, 375, 100), (24, 400, 100);
+
+-- Term deposits hold their money in a fixed_deposit account of their own,
+-- funded by a transfer from the payout account. At maturity the account
+-- is credited with interest and pays everything back to the payout
+-- account, or starts another term if rollover is set.
+CREATE TABLE term_deposits (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  account_id INT NOT NULL UNIQUE REFERENCES accounts(id) ON DELETE CASCADE,
+  payout_account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  principal BIGINT NOT NULL CHECK (principal > 0),
+  term_months INT NOT NULL CHECK (term_months > 0),
+  rate_bps INT NOT NULL,
+  break_rate_bps INT NOT NULL,
+  rollover BOOLEAN NOT NULL DEFAULT false,
+  status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'matured', 'broken')),
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  matures_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  interest_paid BIGINT NOT NULL DEFAULT 0,
+  closed_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_term_deposits_user_id ON term_deposits(user_id);
+CREATE INDEX idx_term_deposits_due ON term_deposits(matures_at) WHERE status = 'active';
@@ -0,0 +1,3 @@
+-- Subscriptions made with an API key restricted to some accounts only
+-- receive events for those accounts.
+ALTER TABLE webhook_subscriptions ADD COLUMN account_ids INT[] NOT NULL DEFAULT '{}'; -- empty means all of the user's accounts